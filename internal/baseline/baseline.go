@@ -0,0 +1,139 @@
+// Package baseline records the vulnerabilities found by a scan, so that later
+// scans can tell apart new vulnerabilities from the ones that were already
+// known.
+package baseline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+const currentVersion = 1
+
+// Baseline is a set of vulnerability fingerprints.
+type Baseline struct {
+	entries map[string]entry
+}
+
+type file struct {
+	Version int     `json:"version"`
+	Entries []entry `json:"vulnerabilities"`
+}
+
+type entry struct {
+	Fingerprint   string `json:"fingerprint"`
+	RuleID        string `json:"ruleId"`
+	File          string `json:"file,omitempty"`
+	ResourceID    string `json:"resourceId,omitempty"`
+	FormattedPath string `json:"formattedPath,omitempty"`
+}
+
+// New creates a baseline containing the provided vulnerabilities.
+func New(vulnerabilities []results.Vulnerability) *Baseline {
+	b := Baseline{
+		entries: make(map[string]entry),
+	}
+
+	for _, v := range vulnerabilities {
+		e := newEntry(v)
+		b.entries[e.Fingerprint] = e
+	}
+
+	return &b
+}
+
+// Read parses the content of a baseline file.
+func Read(data []byte) (*Baseline, error) {
+	var f file
+
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("unmarshal baseline: %v", err)
+	}
+
+	if f.Version != currentVersion {
+		return nil, fmt.Errorf("unsupported baseline version: %d", f.Version)
+	}
+
+	b := Baseline{
+		entries: make(map[string]entry),
+	}
+
+	for _, e := range f.Entries {
+		if e.Fingerprint == "" {
+			return nil, fmt.Errorf("empty fingerprint for rule %q", e.RuleID)
+		}
+
+		b.entries[e.Fingerprint] = e
+	}
+
+	return &b, nil
+}
+
+// Write serializes the baseline. Entries are sorted by fingerprint, so that
+// the same baseline is always serialized the same way.
+func (b *Baseline) Write(w io.Writer) error {
+	f := file{
+		Version: currentVersion,
+		Entries: []entry{},
+	}
+
+	for _, e := range b.entries {
+		f.Entries = append(f.Entries, e)
+	}
+
+	sort.Slice(f.Entries, func(i, j int) bool {
+		return f.Entries[i].Fingerprint < f.Entries[j].Fingerprint
+	})
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(f); err != nil {
+		return fmt.Errorf("encode baseline: %v", err)
+	}
+
+	return nil
+}
+
+// Contains returns true if the vulnerability is part of the baseline.
+func (b *Baseline) Contains(v results.Vulnerability) bool {
+	_, ok := b.entries[Fingerprint(v)]
+	return ok
+}
+
+// Fingerprint computes a stable identifier for a vulnerability. The
+// fingerprint only depends on the rule ID, the file, the formatted path and the
+// resource ID, so it is not affected by changes in line numbers, messages or
+// severities.
+func Fingerprint(v results.Vulnerability) string {
+	hash := sha256.New()
+
+	for _, part := range []string{
+		v.Rule.ID,
+		filepath.ToSlash(v.Resource.File),
+		v.Resource.FormattedPath,
+		v.Resource.ID,
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func newEntry(v results.Vulnerability) entry {
+	return entry{
+		Fingerprint:   Fingerprint(v),
+		RuleID:        v.Rule.ID,
+		File:          filepath.ToSlash(v.Resource.File),
+		ResourceID:    v.Resource.ID,
+		FormattedPath: v.Resource.FormattedPath,
+	}
+}
@@ -0,0 +1,114 @@
+package baseline_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/baseline"
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+func newVulnerability(ruleID, file, path, resourceID string) results.Vulnerability {
+	return results.Vulnerability{
+		Rule: results.Rule{ID: ruleID},
+		Resource: results.Resource{
+			ID:            resourceID,
+			File:          file,
+			FormattedPath: path,
+		},
+	}
+}
+
+func TestFingerprintIsStable(t *testing.T) {
+	a := newVulnerability("SNYK-CC-TF-1", "main.tf", "resource.aws_s3_bucket[a].acl", "aws_s3_bucket.a")
+	b := a
+
+	b.Resource.Line = 42
+	b.Message = "a different message"
+	b.Severity = "critical"
+
+	require.Equal(t, baseline.Fingerprint(a), baseline.Fingerprint(b))
+}
+
+func TestFingerprintIsUnique(t *testing.T) {
+	vulnerabilities := []results.Vulnerability{
+		newVulnerability("SNYK-CC-TF-1", "main.tf", "resource.aws_s3_bucket[a].acl", "aws_s3_bucket.a"),
+		newVulnerability("SNYK-CC-TF-2", "main.tf", "resource.aws_s3_bucket[a].acl", "aws_s3_bucket.a"),
+		newVulnerability("SNYK-CC-TF-1", "other.tf", "resource.aws_s3_bucket[a].acl", "aws_s3_bucket.a"),
+		newVulnerability("SNYK-CC-TF-1", "main.tf", "resource.aws_s3_bucket[a].tags", "aws_s3_bucket.a"),
+		newVulnerability("SNYK-CC-TF-1", "main.tf", "resource.aws_s3_bucket[a].acl", "aws_s3_bucket.b"),
+		// Part boundaries are part of the fingerprint.
+		newVulnerability("SNYK-CC-TF-1main.tf", "", "resource.aws_s3_bucket[a].acl", "aws_s3_bucket.a"),
+	}
+
+	fingerprints := make(map[string]bool)
+
+	for _, v := range vulnerabilities {
+		fingerprints[baseline.Fingerprint(v)] = true
+	}
+
+	require.Len(t, fingerprints, len(vulnerabilities))
+}
+
+func TestWriteAndRead(t *testing.T) {
+	known := newVulnerability("SNYK-CC-TF-1", "main.tf", "resource.aws_s3_bucket[a].acl", "aws_s3_bucket.a")
+	unknown := newVulnerability("SNYK-CC-TF-2", "main.tf", "resource.aws_s3_bucket[a].acl", "aws_s3_bucket.a")
+
+	var buf bytes.Buffer
+
+	require.NoError(t, baseline.New([]results.Vulnerability{known}).Write(&buf))
+
+	b, err := baseline.Read(buf.Bytes())
+	require.NoError(t, err)
+
+	require.True(t, b.Contains(known))
+	require.False(t, b.Contains(unknown))
+}
+
+func TestWriteIsDeterministic(t *testing.T) {
+	vulnerabilities := []results.Vulnerability{
+		newVulnerability("SNYK-CC-TF-1", "main.tf", "", "aws_s3_bucket.a"),
+		newVulnerability("SNYK-CC-TF-2", "main.tf", "", "aws_s3_bucket.a"),
+		newVulnerability("SNYK-CC-TF-3", "main.tf", "", "aws_s3_bucket.a"),
+	}
+
+	var first, second bytes.Buffer
+
+	require.NoError(t, baseline.New(vulnerabilities).Write(&first))
+	require.NoError(t, baseline.New([]results.Vulnerability{vulnerabilities[2], vulnerabilities[0], vulnerabilities[1]}).Write(&second))
+
+	require.Equal(t, first.String(), second.String())
+}
+
+func TestReadInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "invalid JSON",
+			input: "{",
+			err:   "unmarshal baseline",
+		},
+		{
+			name:  "unsupported version",
+			input: `{"version": 2}`,
+			err:   "unsupported baseline version: 2",
+		},
+		{
+			name:  "empty fingerprint",
+			input: `{"version": 1, "vulnerabilities": [{"ruleId": "SNYK-CC-TF-1"}]}`,
+			err:   `empty fingerprint for rule "SNYK-CC-TF-1"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := baseline.Read([]byte(test.input))
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+// Package cdk reads the cloud assemblies synthesized by the AWS CDK, usually
+// in a cdk.out directory, and maps the resources of their CloudFormation
+// templates back to the constructs that define them.
+package cdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// ManifestFile is the name of the file that describes a cloud assembly.
+const ManifestFile = "manifest.json"
+
+// TemplateSuffix is the suffix of the CloudFormation templates synthesized by
+// the CDK.
+const TemplateSuffix = ".template.json"
+
+// PathMetadata is the key of the metadata of a resource that holds the path
+// of its construct.
+const PathMetadata = "aws:cdk:path"
+
+const (
+	artifactTypeStack    = "aws:cloudformation:stack"
+	artifactTypeAssembly = "cdk:cloud-assembly"
+	metadataLogicalID    = "aws:cdk:logicalId"
+)
+
+type manifest struct {
+	Version   string              `json:"version"`
+	Artifacts map[string]artifact `json:"artifacts"`
+}
+
+type artifact struct {
+	Type        string                     `json:"type"`
+	DisplayName string                     `json:"displayName"`
+	Properties  artifactProperties         `json:"properties"`
+	Metadata    map[string][]metadataEntry `json:"metadata"`
+}
+
+type artifactProperties struct {
+	TemplateFile  string `json:"templateFile"`
+	DirectoryName string `json:"directoryName"`
+}
+
+type metadataEntry struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// IsAssembly returns true if the directory contains the manifest of a cloud
+// assembly with at least a stack or a nested assembly.
+func IsAssembly(fsys afero.Fs, dir string) bool {
+	m, err := readManifest(fsys, dir)
+	if err != nil {
+		return false
+	}
+
+	for _, a := range m.Artifacts {
+		if a.Type == artifactTypeStack || a.Type == artifactTypeAssembly {
+			return true
+		}
+	}
+
+	return false
+}
+
+func readManifest(fsys afero.Fs, dir string) (*manifest, error) {
+	data, err := afero.ReadFile(fsys, filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %v", err)
+	}
+
+	var m manifest
+
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("unmarshal manifest: %v", err)
+	}
+
+	return &m, nil
+}
+
+// Assembly is a cloud assembly, with the stacks of its nested assemblies.
+type Assembly struct {
+	Dir    string
+	Stacks []Stack
+
+	// Files are the manifests read to load the assembly.
+	Files []string
+}
+
+// Stack is a stack of a cloud assembly.
+type Stack struct {
+	// Name is the display name of the stack, which is the path of the stack
+	// construct, e.g. Prod/Api for the stack Api of the stage Prod.
+	Name string
+
+	// Template is the path of the CloudFormation template of the stack.
+	Template string
+
+	// Constructs maps the logical ID of every resource of the template to the
+	// path of the construct that defines it.
+	Constructs map[string]string
+}
+
+// ReadAssembly reads the cloud assembly in a directory. The stacks are read
+// from the manifests of the assembly and of its nested assemblies. The
+// templates that are not the template of a stack, like the templates of the
+// nested stacks, are returned as stacks named after their file.
+func ReadAssembly(fsys afero.Fs, dir string) (*Assembly, error) {
+	assembly := Assembly{
+		Dir: dir,
+	}
+
+	if err := assembly.read(fsys, dir, map[string]bool{}); err != nil {
+		return nil, err
+	}
+
+	return &assembly, nil
+}
+
+func (a *Assembly) read(fsys afero.Fs, dir string, visited map[string]bool) error {
+	dir = filepath.Clean(dir)
+
+	if visited[dir] {
+		return nil
+	}
+
+	visited[dir] = true
+
+	m, err := readManifest(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("%s: %v", dir, err)
+	}
+
+	a.Files = append(a.Files, filepath.Join(dir, ManifestFile))
+
+	ids := make([]string, 0, len(m.Artifacts))
+
+	for id := range m.Artifacts {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	templates := map[string]bool{}
+
+	for _, id := range ids {
+		artifact := m.Artifacts[id]
+
+		switch artifact.Type {
+		case artifactTypeStack:
+			stack := Stack{
+				Name:       id,
+				Template:   filepath.Join(dir, artifact.Properties.TemplateFile),
+				Constructs: map[string]string{},
+			}
+
+			if artifact.DisplayName != "" {
+				stack.Name = artifact.DisplayName
+			}
+
+			if artifact.Properties.TemplateFile == "" {
+				stack.Template = filepath.Join(dir, id+TemplateSuffix)
+			}
+
+			for path, entries := range artifact.Metadata {
+				for _, entry := range entries {
+					if logicalID, ok := entry.Data.(string); ok && entry.Type == metadataLogicalID {
+						stack.Constructs[logicalID] = strings.TrimPrefix(path, "/")
+					}
+				}
+			}
+
+			templates[stack.Template] = true
+			a.Stacks = append(a.Stacks, stack)
+		case artifactTypeAssembly:
+			if err := a.read(fsys, filepath.Join(dir, artifact.Properties.DirectoryName), visited); err != nil {
+				return err
+			}
+		}
+	}
+
+	entries, err := afero.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("%s: %v", dir, err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), TemplateSuffix) || templates[path] {
+			continue
+		}
+
+		a.Stacks = append(a.Stacks, Stack{
+			Name:       strings.TrimSuffix(strings.TrimSuffix(entry.Name(), TemplateSuffix), ".nested"),
+			Template:   path,
+			Constructs: map[string]string{},
+		})
+	}
+
+	return nil
+}
+
+// ReadConstructs adds the construct paths recorded in the metadata of the
+// resources of the template to the constructs of the stack. The paths in the
+// template take precedence over the paths in the manifest.
+func (s *Stack) ReadConstructs(data []byte) error {
+	var template struct {
+		Resources map[string]struct {
+			Metadata map[string]any `json:"Metadata"`
+		} `json:"Resources"`
+	}
+
+	if err := json.Unmarshal(data, &template); err != nil {
+		return fmt.Errorf("unmarshal %s: %v", s.Template, err)
+	}
+
+	for logicalID, resource := range template.Resources {
+		if path, ok := resource.Metadata[PathMetadata].(string); ok && path != "" {
+			s.Constructs[logicalID] = path
+		}
+	}
+
+	return nil
+}
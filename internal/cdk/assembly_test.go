@@ -0,0 +1,130 @@
+package cdk_test
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/cdk"
+)
+
+const manifest = `{
+  "version": "36.0.0",
+  "artifacts": {
+    "App": {
+      "type": "aws:cloudformation:stack",
+      "properties": {"templateFile": "App.template.json"},
+      "metadata": {
+        "/App/Bucket/Resource": [{"type": "aws:cdk:logicalId", "data": "Bucket83908E77"}],
+        "/App/Bucket/Policy": [{"type": "aws:cdk:warning", "data": "ignored"}]
+      },
+      "displayName": "App"
+    },
+    "assembly-Prod": {
+      "type": "cdk:cloud-assembly",
+      "properties": {"directoryName": "assembly-Prod"}
+    }
+  }
+}`
+
+const nestedManifest = `{
+  "version": "36.0.0",
+  "artifacts": {
+    "ProdApi1A2B3C4D": {
+      "type": "aws:cloudformation:stack",
+      "properties": {"templateFile": "ProdApi1A2B3C4D.template.json"},
+      "displayName": "Prod/Api"
+    }
+  }
+}`
+
+func newFs(t *testing.T, files map[string]string) afero.Fs {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0644))
+	}
+
+	return fs
+}
+
+func TestIsAssembly(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"cdk.out/manifest.json": manifest,
+		"other/manifest.json":   `{"name": "extension", "version": "1.0.0"}`,
+		"invalid/manifest.json": `{`,
+	})
+
+	require.True(t, cdk.IsAssembly(fs, "cdk.out"))
+	require.False(t, cdk.IsAssembly(fs, "other"))
+	require.False(t, cdk.IsAssembly(fs, "invalid"))
+	require.False(t, cdk.IsAssembly(fs, "missing"))
+}
+
+func TestReadAssembly(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"cdk.out/manifest.json":                                   manifest,
+		"cdk.out/App.template.json":                               `{"Resources": {}}`,
+		"cdk.out/AppDatabaseNestedStackABCD.nested.template.json": `{"Resources": {}}`,
+		"cdk.out/assembly-Prod/manifest.json":                     nestedManifest,
+		"cdk.out/assembly-Prod/ProdApi1A2B3C4D.template.json":     `{"Resources": {}}`,
+	})
+
+	assembly, err := cdk.ReadAssembly(fs, "cdk.out")
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"cdk.out/manifest.json", "cdk.out/assembly-Prod/manifest.json"}, assembly.Files)
+	require.Equal(t, []cdk.Stack{
+		{
+			Name:     "App",
+			Template: "cdk.out/App.template.json",
+			Constructs: map[string]string{
+				"Bucket83908E77": "App/Bucket/Resource",
+			},
+		},
+		{
+			Name:       "Prod/Api",
+			Template:   "cdk.out/assembly-Prod/ProdApi1A2B3C4D.template.json",
+			Constructs: map[string]string{},
+		},
+		{
+			Name:       "AppDatabaseNestedStackABCD",
+			Template:   "cdk.out/AppDatabaseNestedStackABCD.nested.template.json",
+			Constructs: map[string]string{},
+		},
+	}, assembly.Stacks)
+}
+
+func TestReadConstructs(t *testing.T) {
+	stack := cdk.Stack{
+		Name:     "App",
+		Template: "cdk.out/App.template.json",
+		Constructs: map[string]string{
+			"Bucket83908E77": "App/Bucket/Default",
+			"Queue4A7E3555":  "App/Queue/Resource",
+		},
+	}
+
+	err := stack.ReadConstructs([]byte(`{
+  "Resources": {
+    "Bucket83908E77": {
+      "Type": "AWS::S3::Bucket",
+      "Metadata": {"aws:cdk:path": "App/Bucket/Resource"}
+    },
+    "Queue4A7E3555": {
+      "Type": "AWS::SQS::Queue"
+    }
+  }
+}`))
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		"Bucket83908E77": "App/Bucket/Resource",
+		"Queue4A7E3555":  "App/Queue/Resource",
+	}, stack.Constructs)
+
+	require.Error(t, stack.ReadConstructs([]byte(`{`)))
+}
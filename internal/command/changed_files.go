@@ -0,0 +1,353 @@
+package command
+
+import (
+	iofs "io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	"github.com/snyk/cli-extension-iac/internal/cdk"
+	"github.com/snyk/cli-extension-iac/internal/helm"
+	"github.com/snyk/cli-extension-iac/internal/kustomize"
+	"github.com/snyk/cli-extension-iac/internal/results"
+	"github.com/snyk/cli-extension-iac/internal/terragrunt"
+	"github.com/snyk/cli-extension-iac/internal/tfvars"
+)
+
+// changedFiles is the set of files changed since a Git reference. Files are
+// stored as absolute paths.
+type changedFiles map[string]bool
+
+func newChangedFiles(files []string) changedFiles {
+	changed := make(changedFiles)
+
+	for _, f := range files {
+		changed[filepath.Clean(f)] = true
+	}
+
+	return changed
+}
+
+// addDependents adds the files and directories that depend on a changed file,
+// so that they are scanned and their resources are reported:
+//   - the Terraform files of the modules loading a changed var file, either
+//     automatically or because it is one of the var files of the scan;
+//   - the directories of the Helm charts, Kustomize overlays, Terragrunt units
+//     and CDK cloud assemblies containing a changed file, which are scanned as
+//     a whole, and whose resources are reported in their directory;
+//   - the directories of the Kustomize overlays including a changed file or
+//     overlay, and of the Terragrunt units using a module with a changed file.
+//
+// Only the kustomizations and the units under the paths to scan are
+// considered. Paths are relative to cwd.
+func (c changedFiles) addDependents(fs afero.Fs, cwd string, paths []string, vars []tfvars.Arg) {
+	for _, arg := range vars {
+		varFile := arg.VarFile
+
+		if varFile == "" {
+			continue
+		}
+
+		if !filepath.IsAbs(varFile) {
+			varFile = filepath.Join(cwd, varFile)
+		}
+
+		if c[filepath.Clean(varFile)] {
+			for _, path := range paths {
+				c.addTerraformFiles(fs, filepath.Join(cwd, path), true)
+			}
+		}
+	}
+
+	for file := range c {
+		if isAutoVarFile(file) {
+			c.addTerraformFiles(fs, filepath.Dir(file), false)
+		}
+	}
+
+	var dirs []string
+
+	for file := range c {
+		if dir, ok := projectDir(fs, file, cwd); ok {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	for _, dir := range dirs {
+		c[dir] = true
+	}
+
+	kustomizations, modules := findDependencies(fs, cwd, paths)
+
+	for added := true; added; {
+		added = false
+
+		for dir, includes := range kustomizations {
+			for _, include := range includes {
+				if !c[dir] && c[include] {
+					c[dir], added = true, true
+				}
+			}
+		}
+
+		for dir, module := range modules {
+			if !c[dir] && c.containsAny(module) {
+				c[dir], added = true, true
+			}
+		}
+	}
+}
+
+// addTerraformFiles adds the Terraform files of a directory and, if recursive
+// is true, of its subdirectories.
+func (c changedFiles) addTerraformFiles(fs afero.Fs, root string, recursive bool) {
+	_ = afero.Walk(fs, root, func(path string, info iofs.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info.IsDir() {
+			if path != root && (!recursive || strings.HasPrefix(info.Name(), ".")) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if isTerraformFile(path) && !tfvars.IsVarFile(path) {
+			c[filepath.Clean(path)] = true
+		}
+
+		return nil
+	})
+}
+
+// containsAny returns true if a changed file is in a directory.
+func (c changedFiles) containsAny(dir string) bool {
+	for file := range c {
+		if containsPath(dir, file) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contains returns true if the file, absolute or relative to cwd, changed.
+func (c changedFiles) contains(file string, cwd string) bool {
+	if file == "" {
+		return false
+	}
+
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(cwd, file)
+	}
+
+	return c[filepath.Clean(file)]
+}
+
+// touches returns true if the resource is defined in, or has any source
+// location pointing to, a changed file.
+func (c changedFiles) touches(r results.Resource, cwd string) bool {
+	if c.contains(r.File, cwd) {
+		return true
+	}
+
+	for _, location := range r.SourceLocation {
+		if c.contains(location.File, cwd) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// restrictPathsToChangedFiles restricts the paths to scan to the changed files
+// they contain. A changed Terraform file is replaced by its module directory,
+// so that references across the files of the module can still be resolved,
+// and a changed file of a Helm chart or a Kustomize overlay is replaced by the
+// directory of the chart or the overlay.
+// Paths and the returned paths are relative to cwd.
+func restrictPathsToChangedFiles(fs afero.Fs, paths []string, changed changedFiles, cwd string) []string {
+	var result []string
+
+	for file := range changed {
+		rel, err := filepath.Rel(cwd, file)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		for _, path := range paths {
+			if !containsPath(path, rel) {
+				continue
+			}
+
+			if dir, ok := projectDir(fs, file, cwd); ok && changed[dir] {
+				// The directory of the chart or the overlay is part of the
+				// changed files, and is scanned instead of the file.
+				continue
+			}
+
+			if !isTerraformFile(rel) {
+				if exists(fs, file) {
+					result = append(result, rel)
+				}
+				continue
+			}
+
+			module := filepath.Dir(rel)
+
+			if filepath.Clean(path) != rel {
+				if exists(fs, filepath.Join(cwd, module)) {
+					result = append(result, module)
+				}
+				continue
+			}
+
+			// The path is a single file, which means that the input directory
+			// has been expanded to its files. Scan the files of the module
+			// that are part of the paths, instead of the whole directory.
+			for _, sibling := range paths {
+				if filepath.Dir(filepath.Clean(sibling)) == module && isTerraformFile(sibling) {
+					result = append(result, sibling)
+				}
+			}
+		}
+	}
+
+	sort.Strings(result)
+
+	return deduplicatePaths(result)
+}
+
+// filterResultsByChangedFiles keeps only the resources and vulnerabilities,
+// including the passed, ignored and baseline vulnerabilities, touching a
+// changed file.
+func filterResultsByChangedFiles(r *results.Results, changed changedFiles, cwd string) *results.Results {
+	if r == nil {
+		return nil
+	}
+
+	filterVulnerabilities := func(vulnerabilities []results.Vulnerability) []results.Vulnerability {
+		var kept []results.Vulnerability
+
+		for _, v := range vulnerabilities {
+			if changed.touches(v.Resource, cwd) {
+				kept = append(kept, v)
+			}
+		}
+
+		return kept
+	}
+
+	var resources []results.Resource
+
+	for _, resource := range r.Resources {
+		if changed.touches(resource, cwd) {
+			resources = append(resources, resource)
+		}
+	}
+
+	result := *r
+
+	result.Resources = resources
+	result.Vulnerabilities = filterVulnerabilities(r.Vulnerabilities)
+	result.PassedVulnerabilities = filterVulnerabilities(r.PassedVulnerabilities)
+	result.BaselineVulnerabilities = filterVulnerabilities(r.BaselineVulnerabilities)
+	result.IgnoredVulnerabilities = filterVulnerabilities(r.IgnoredVulnerabilities)
+	result.Metadata.IgnoredCount = len(result.IgnoredVulnerabilities)
+
+	return &result
+}
+
+// projectDir returns the closest directory containing a file that is the
+// directory of a Helm chart, a Kustomize overlay, a Terragrunt unit or a CDK
+// cloud assembly. Only the directories under cwd are considered.
+func projectDir(fs afero.Fs, file string, cwd string) (string, bool) {
+	for dir := filepath.Dir(file); containsPath(cwd, dir); dir = filepath.Dir(dir) {
+		if helm.IsChart(fs, dir) || kustomize.IsKustomization(fs, dir) ||
+			terragrunt.IsTerragrunt(fs, dir) || cdk.IsAssembly(fs, dir) {
+			return dir, true
+		}
+
+		if dir == filepath.Clean(cwd) {
+			break
+		}
+	}
+
+	return "", false
+}
+
+// findDependencies returns the files and directories included by the
+// kustomizations under the paths, and the local module directories used by
+// the Terragrunt units under the paths, both by directory. Hidden directories
+// are skipped.
+func findDependencies(fs afero.Fs, cwd string, paths []string) (map[string][]string, map[string]string) {
+	kustomizations := make(map[string][]string)
+	modules := make(map[string]string)
+
+	for _, root := range paths {
+		root = filepath.Join(cwd, root)
+
+		_ = afero.Walk(fs, root, func(path string, info iofs.FileInfo, err error) error {
+			if err != nil || !info.IsDir() {
+				return nil
+			}
+
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			if kustomize.IsKustomization(fs, path) {
+				kustomizations[path] = kustomize.Includes(fs, path)
+			}
+
+			if terragrunt.IsTerragrunt(fs, path) {
+				config, err := terragrunt.Load(fs, path)
+				if err == nil && config.ModuleDir != "" && filepath.Clean(config.ModuleDir) != filepath.Clean(path) {
+					modules[path] = filepath.Clean(config.ModuleDir)
+				}
+			}
+
+			return nil
+		})
+	}
+
+	return kustomizations, modules
+}
+
+func containsPath(parent, child string) bool {
+	parent = filepath.Clean(parent)
+
+	if parent == "." || parent == child {
+		return true
+	}
+
+	return strings.HasPrefix(child, parent+string(filepath.Separator))
+}
+
+func isTerraformFile(path string) bool {
+	for _, ext := range []string{".tf", ".tf.json", ".tfvars", ".tfvars.json"} {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isAutoVarFile returns true if a file is a var file that Terraform loads
+// automatically from the directory of a module.
+func isAutoVarFile(path string) bool {
+	base := filepath.Base(path)
+
+	return base == "terraform.tfvars" || base == "terraform.tfvars.json" ||
+		strings.HasSuffix(base, ".auto.tfvars") || strings.HasSuffix(base, ".auto.tfvars.json")
+}
+
+func exists(fs afero.Fs, path string) bool {
+	_, err := fs.Stat(path)
+	return err == nil
+}
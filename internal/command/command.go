@@ -17,9 +17,15 @@ import (
 	"github.com/spf13/afero"
 
 	"github.com/snyk/cli-extension-iac/internal/cloudapi"
+	"github.com/snyk/cli-extension-iac/internal/compliance"
 	"github.com/snyk/cli-extension-iac/internal/engine"
+	"github.com/snyk/cli-extension-iac/internal/exitcode"
+	"github.com/snyk/cli-extension-iac/internal/junit"
 	"github.com/snyk/cli-extension-iac/internal/results"
+	"github.com/snyk/cli-extension-iac/internal/rules"
+	"github.com/snyk/cli-extension-iac/internal/sarif"
 	"github.com/snyk/cli-extension-iac/internal/settings"
+	"github.com/snyk/cli-extension-iac/internal/tfvars"
 )
 
 var ErrPathNotAllowed = errors.New("the --exclude argument must be a comma separated list of directory or file names and cannot contain a path.")
@@ -40,6 +46,10 @@ type BundleDownloader interface {
 	DownloadLatestBundle(policyEngineVersion string, w io.Writer) error
 }
 
+type PinnedBundleDownloader interface {
+	DownloadPinnedBundle(pinnedBundleVersion string, policyEngineVersion string, w io.Writer) error
+}
+
 type Command struct {
 	Output string
 	Logger *zerolog.Logger
@@ -54,7 +64,9 @@ type Command struct {
 	SnykClient              cloudapi.Client
 	Scan                    string
 	DetectionDepth          int
-	VarFile                 string
+	TerraformVars           []tfvars.Arg
+	Env                     []string
+	HelmValues              []string
 	SettingsReader          SettingsReader
 	BundleDownloader        BundleDownloader
 	ReadPolicyEngineVersion func() (string, error)
@@ -62,10 +74,31 @@ type Command struct {
 	AllowAnalytics          bool
 	Report                  bool
 	IacNewEngine            bool
+	SarifOutput             string
+	JUnitOutput             string
+	ComplianceOutput        string
+	ComplianceMarkdown      string
+	ChangedSince            string
+	ListChangedFiles        func(path string, ref string) ([]string, error)
+	FailOnStaleIgnores      bool
+	Offline                 bool
+	BundleVersion           string
+	PinnedBundleDownloader  PinnedBundleDownloader
+	ExitCodePolicy          *exitcode.Policy
+	// LocalRules are the paths of the local custom rules, loaded in addition
+	// to the custom rules of the organization. The custom rules of the
+	// organization are not downloaded in offline mode.
+	LocalRules []string
+	// SelectRules resolves the IDs of the rules to evaluate from the metadata
+	// of the loaded rules. Every rule is evaluated if SelectRules is nil.
+	SelectRules func(metadata []engine.MetadataResult) ([]string, error)
 }
 
+// Run runs the scan and writes its output. It returns 1 if the scan couldn't
+// run or its output couldn't be written, and 0 otherwise. The exit code
+// decided by ExitCodePolicy is only returned by RunWithReport.
 func (c Command) Run() int {
-	if _, err := c.RunWithError(); err != nil {
+	if _, err := c.RunWithReport(); err != nil {
 		c.Logger.Error().Err(err).Send()
 		return 1
 	}
@@ -73,16 +106,90 @@ func (c Command) Run() int {
 	return 0
 }
 
-func (c Command) RunWithError() (bool, error) {
+// Report is the outcome of a scan, as presented to the user.
+type Report struct {
+	Results  *results.Results
+	Errors   []ScanMessage
+	Warnings []ScanMessage
+	Decision exitcode.Decision
+}
+
+// ScanMessage is a scan error or a scan warning. Path is empty if the message
+// is not about a file or a directory.
+type ScanMessage struct {
+	Message string
+	Code    int
+	Path    string
+}
+
+// InvalidRuleSelection returns the message of the scan error reported when the
+// rule selection doesn't match the loaded rules, if any.
+func (r Report) InvalidRuleSelection() (string, bool) {
+	for _, e := range r.Errors {
+		if e.Code == int(errorCodeInvalidRuleSelection) {
+			return e.Message, true
+		}
+	}
+
+	return "", false
+}
+
+// RunWithReport runs the scan and writes its output. It returns the processed
+// scan results, the scan errors and the scan warnings, and the exit code
+// decided by ExitCodePolicy, with its reason. The exit code is always zero if
+// ExitCodePolicy is nil. The results might be nil if the scan failed.
+func (c Command) RunWithReport() (Report, error) {
+	output, err := c.run()
+	if err != nil {
+		return Report{}, err
+	}
+
+	return Report{
+		Results:  output.scanResults,
+		Errors:   scanMessages(output.scanErrors),
+		Warnings: scanMessages(output.scanWarnings),
+		Decision: c.decideExitCode(output),
+	}, nil
+}
+
+func (c Command) decideExitCode(output scanOutput) exitcode.Decision {
+	if c.ExitCodePolicy == nil {
+		return exitcode.Decision{Code: exitcode.Success}
+	}
+
+	return c.ExitCodePolicy.Evaluate(exitcode.Outcome{
+		Results:      output.scanResults,
+		ScanErrors:   len(output.scanErrors),
+		ScanWarnings: len(output.scanWarnings),
+	})
+}
+
+func (c Command) run() (scanOutput, error) {
 	output := c.scan()
-	isSuccessful := len(output.scanErrors) == 0
 
-	printErr := c.print(output)
-	if printErr != nil {
-		return false, printErr
+	if err := c.print(output); err != nil {
+		return output, err
+	}
+
+	if err := c.printReport(c.SarifOutput, output, sarif.Write); err != nil {
+		return output, fmt.Errorf("write SARIF output: %v", err)
 	}
-	return isSuccessful, nil
+
+	if err := c.printReport(c.JUnitOutput, output, junit.Write); err != nil {
+		return output, fmt.Errorf("write JUnit output: %v", err)
+	}
+
+	if err := c.printReport(c.ComplianceOutput, output, compliance.WriteJSON); err != nil {
+		return output, fmt.Errorf("write compliance output: %v", err)
+	}
+
+	if err := c.printReport(c.ComplianceMarkdown, output, compliance.WriteMarkdown); err != nil {
+		return output, fmt.Errorf("write compliance Markdown output: %v", err)
+	}
+
+	return output, nil
 }
+
 func (c Command) scan() scanOutput {
 	var output scanOutput
 	ctx := context.Background()
@@ -131,7 +238,30 @@ func (c Command) scan() scanOutput {
 		return output.addScanErrors(errNoPaths)
 	}
 
+	var changed changedFiles
+
+	if c.ChangedSince != "" {
+		files, err := c.ListChangedFiles(cwd, c.ChangedSince)
+		if err != nil {
+			c.Logger.Error().Err(err).Msg("list changed files")
+			return output.addScanErrors(errListChangedFiles)
+		}
+
+		changed = newChangedFiles(files)
+		changed.addDependents(c.FS, cwd, enginePaths, c.TerraformVars)
+
+		enginePaths = restrictPathsToChangedFiles(c.FS, enginePaths, changed, cwd)
+		if len(enginePaths) == 0 {
+			c.Logger.Info().Msgf("no files changed since %s", c.ChangedSince)
+			return output.setScanResults(&results.Results{})
+		}
+	}
+
 	bundle, err := c.openBundle()
+	if errors.Is(err, rules.ErrInvalidSignature) {
+		c.Logger.Error().Err(err).Msg("open bundle")
+		return output.addScanErrors(errInvalidBundleSignature)
+	}
 	if err != nil {
 		return output.addScanErrors(errOpenBundle)
 	}
@@ -139,6 +269,14 @@ func (c Command) scan() scanOutput {
 		defer func() { _ = bundle.Close() }()
 	}
 
+	var localErr *localRulesError
+
+	localRules, err := c.localRuleBundles()
+	if errors.As(err, &localErr) {
+		c.Logger.Error().Err(err).Msg("read local custom rules")
+		return output.addScanErrors(newScanError("failed to load rules", errorCodeFailedToLoadRules, map[string]any{"path": localErr.path}))
+	}
+
 	customRules, err := c.customRuleBundles(ctx, userSettings.OrgPublicID)
 	if err != nil {
 		return output.addScanErrors(errFetchCustomRulesBundles)
@@ -148,12 +286,16 @@ func (c Command) scan() scanOutput {
 		Paths:                enginePaths,
 		SnykBundle:           bundle,
 		CustomRuleBundles:    customRules,
+		LocalRuleBundles:     localRules,
 		OrgPublicID:          userSettings.OrgPublicID,
 		SnykCloudEnvironment: c.SnykCloudEnvironment,
 		SnykClient:           c.SnykClient,
 		Scan:                 c.Scan,
 		DetectionDepth:       c.DetectionDepth,
-		VarFile:              c.VarFile,
+		TerraformVars:        c.TerraformVars,
+		Env:                  c.Env,
+		HelmValues:           c.HelmValues,
+		SelectRules:          c.SelectRules,
 		Logger:               c.Logger,
 	})
 
@@ -168,7 +310,26 @@ func (c Command) scan() scanOutput {
 		c.Logger.Error().Err(err).Msg("process results")
 		output = output.addScanErrors(errProcessResults)
 	} else {
+		if changed != nil {
+			scanResults = filterResultsByChangedFiles(scanResults, changed, cwd)
+		}
 		output = output.setScanResults(scanResults)
+
+		if scanResults != nil && len(scanResults.StaleIgnores) > 0 {
+			staleIgnores := scanResults.StaleIgnores
+
+			// Only a subset of the files was scanned, so an ignore that doesn't
+			// match any issue is not necessarily unused.
+			if changed != nil {
+				staleIgnores = expiredIgnores(staleIgnores)
+			}
+
+			output = output.addScanWarnings(staleIgnoresToScanWarnings(staleIgnores)...)
+
+			if c.FailOnStaleIgnores && len(staleIgnores) > 0 {
+				output = output.addScanErrors(errStaleIgnores)
+			}
+		}
 	}
 
 	return output
@@ -189,6 +350,27 @@ func (c Command) print(output scanOutput) (e error) {
 	return c.writeOutput(w, output)
 }
 
+// printReport renders the processed scan results to the file at path. If path
+// is empty, printReport does nothing.
+func (c Command) printReport(path string, output scanOutput, render func(io.Writer, *results.Results) error) (e error) {
+	if path == "" {
+		return nil
+	}
+
+	f, err := c.FS.Create(path)
+	if err != nil {
+		return fmt.Errorf("create file: %v", err)
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil && e == nil {
+			e = fmt.Errorf("close file: %v", err)
+		}
+	}()
+
+	return render(f, output.scanResults)
+}
+
 func (c Command) writeOutput(w io.Writer, output scanOutput) error {
 	type ignoreSettings struct {
 		AdminOnly                  bool `json:"adminOnly"`
@@ -226,12 +408,40 @@ func (c Command) writeOutput(w io.Writer, output scanOutput) error {
 	return json.NewEncoder(w).Encode(data)
 }
 
+// RuleBundles opens the Snyk rule bundle and the custom rule bundles that are
+// loaded by a scan for the organization orgID. The caller must close the
+// Snyk rule bundle.
+func (c Command) RuleBundles(ctx context.Context, orgID string) (io.ReadCloser, []bundle.Reader, error) {
+	snykBundle, err := c.openBundle()
+	if err != nil {
+		return nil, nil, fmt.Errorf("open bundle: %w", err)
+	}
+
+	localRules, err := c.localRuleBundles()
+	if err != nil {
+		_ = snykBundle.Close()
+		return nil, nil, err
+	}
+
+	customRules, err := c.customRuleBundles(ctx, orgID)
+	if err != nil {
+		_ = snykBundle.Close()
+		return nil, nil, err
+	}
+
+	return snykBundle, append(customRules, localRules...), nil
+}
+
 func (c Command) openBundle() (io.ReadCloser, error) {
 	if c.Bundle != "" {
 		c.Logger.Info().Msg("using the local bundle")
 		return c.FS.Open(c.Bundle)
 	}
 
+	if c.Offline {
+		return nil, fmt.Errorf("a local bundle is required in offline mode")
+	}
+
 	policyEngineVersion, err := c.ReadPolicyEngineVersion()
 
 	if err != nil {
@@ -244,15 +454,27 @@ func (c Command) openBundle() (io.ReadCloser, error) {
 
 	var buffer bytes.Buffer
 
+	if c.BundleVersion != "" {
+		c.Logger.Info().Msgf("using the pinned bundle version: %s", c.BundleVersion)
+
+		if err := c.PinnedBundleDownloader.DownloadPinnedBundle(c.BundleVersion, policyEngineVersion, &buffer); err != nil {
+			return nil, fmt.Errorf("download pinned bundle: %w", err)
+		}
+
+		return io.NopCloser(&buffer), nil
+	}
+
 	if err := c.BundleDownloader.DownloadLatestBundle(policyEngineVersion, &buffer); err != nil {
-		return nil, fmt.Errorf("download bundle: %v", err)
+		return nil, fmt.Errorf("download bundle: %w", err)
 	}
 
 	return io.NopCloser(&buffer), nil
 }
 
+// customRuleBundles downloads the custom rules of the organization. They are
+// not downloaded in offline mode, where the custom rules are local.
 func (c Command) customRuleBundles(ctx context.Context, orgID string) ([]bundle.Reader, error) {
-	if orgID == "" {
+	if c.Offline || orgID == "" {
 		return nil, nil
 	}
 
@@ -269,6 +491,33 @@ func (c Command) customRuleBundles(ctx context.Context, orgID string) ([]bundle.
 	return bundles, nil
 }
 
+// localRulesError is returned when the local custom rules at path can't be
+// opened.
+type localRulesError struct {
+	path string
+	err  error
+}
+
+func (e *localRulesError) Error() string {
+	return fmt.Sprintf("read local custom rules %s: %v", e.path, e.err)
+}
+
+// localRuleBundles opens the local custom rules, in the order of LocalRules.
+func (c Command) localRuleBundles() ([]bundle.Reader, error) {
+	var bundles []bundle.Reader
+
+	for _, path := range c.LocalRules {
+		b, err := rules.ReadLocalBundles(c.FS, path)
+		if err != nil {
+			return nil, &localRulesError{path: path, err: err}
+		}
+
+		bundles = append(bundles, b...)
+	}
+
+	return bundles, nil
+}
+
 func (c Command) openOutput() (io.WriteCloser, error) {
 	if c.Output != "" {
 		return c.FS.Create(c.Output)
@@ -1,13 +1,18 @@
 package command_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/snyk/policy-engine/pkg/bundle"
@@ -18,8 +23,11 @@ import (
 	"github.com/snyk/cli-extension-iac/internal/cloudapi"
 	"github.com/snyk/cli-extension-iac/internal/command"
 	"github.com/snyk/cli-extension-iac/internal/engine"
+	"github.com/snyk/cli-extension-iac/internal/exitcode"
 	"github.com/snyk/cli-extension-iac/internal/results"
+	"github.com/snyk/cli-extension-iac/internal/rules"
 	"github.com/snyk/cli-extension-iac/internal/settings"
+	"github.com/snyk/cli-extension-iac/internal/tfvars"
 )
 
 type scanError struct {
@@ -518,6 +526,32 @@ func TestScanError(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "invalid rule selection",
+			actual: engine.Error{
+				Message: "no rule matches the rule selection",
+				Code:    engine.ErrorCodeInvalidRuleSelection,
+			},
+			expected: scanError{
+				Message: "no rule matches the rule selection",
+				Code:    2002,
+			},
+		},
+		{
+			name: "unsupported feature error",
+			actual: engine.UnsupportedFeatureError{
+				Message: "field transformers is not supported",
+				Code:    engine.ErrorCodeUnsupportedFeature,
+				Path:    "/path",
+			},
+			expected: scanError{
+				Message: "field transformers is not supported",
+				Code:    3005,
+				Fields: map[string]interface{}{
+					"path": "/path",
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -842,6 +876,244 @@ func TestRawResults(t *testing.T) {
 	}
 }
 
+func TestSarifOutput(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewMemMapFs()
+
+	policyEngine := mockEngine{
+		run: func(ctx context.Context, options engine.RunOptions) (*engine.Results, results.ScanAnalytics, []error, []error) {
+			return &engine.Results{}, results.ScanAnalytics{}, nil, nil
+		},
+	}
+
+	resultsProcessor := mockResultsProcessor{
+		processResults: func(rawResults *engine.Results, scanAnalytics results.ScanAnalytics) (*results.Results, error) {
+			return &results.Results{
+				Vulnerabilities: []results.Vulnerability{
+					{
+						Rule:     results.Rule{ID: "SNYK-CC-TF-1"},
+						Severity: "high",
+					},
+				},
+			}, nil
+		},
+	}
+
+	userSettings := settings.Settings{
+		Entitlements: settings.Entitlements{
+			InfrastructureAsCode: true,
+		},
+	}
+
+	settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+		return &userSettings, nil
+	})
+
+	require.Nil(t, afero.WriteFile(fs, "bundle.tar.gz", nil, 0644))
+
+	cmd := command.Command{
+		FS:               fs,
+		Engine:           policyEngine,
+		Paths:            []string{"."},
+		Bundle:           "bundle.tar.gz",
+		ResultsProcessor: resultsProcessor,
+		SettingsReader:   settingsReader,
+		Output:           outputFilePath,
+		SarifOutput:      "test.sarif",
+		Logger:           &logger,
+	}
+
+	require.Equal(t, 0, cmd.Run())
+
+	requireNoError(t, fs)
+
+	data, err := afero.ReadFile(fs, "test.sarif")
+	require.NoError(t, err)
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Results []struct {
+				RuleID string
+				Level  string
+			}
+		}
+	}
+
+	require.NoError(t, json.Unmarshal(data, &log))
+	require.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	require.Len(t, log.Runs[0].Results, 1)
+	require.Equal(t, "SNYK-CC-TF-1", log.Runs[0].Results[0].RuleID)
+	require.Equal(t, "error", log.Runs[0].Results[0].Level)
+}
+
+func TestComplianceOutput(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewMemMapFs()
+
+	policyEngine := mockEngine{
+		run: func(ctx context.Context, options engine.RunOptions) (*engine.Results, results.ScanAnalytics, []error, []error) {
+			return &engine.Results{}, results.ScanAnalytics{}, nil, nil
+		},
+	}
+
+	resultsProcessor := mockResultsProcessor{
+		processResults: func(rawResults *engine.Results, scanAnalytics results.ScanAnalytics) (*results.Results, error) {
+			return &results.Results{
+				Vulnerabilities: []results.Vulnerability{
+					{
+						Rule:     results.Rule{ID: "SNYK-CC-TF-1"},
+						Resource: results.Resource{ID: "aws_s3_bucket.public"},
+					},
+				},
+				RuleControls: map[string][]string{
+					"SNYK-CC-TF-1": {"CIS-AWS_v1.4.0_2.1.5"},
+				},
+			}, nil
+		},
+	}
+
+	settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+		return &settings.Settings{
+			Entitlements: settings.Entitlements{
+				InfrastructureAsCode: true,
+			},
+		}, nil
+	})
+
+	require.Nil(t, afero.WriteFile(fs, "bundle.tar.gz", nil, 0644))
+
+	cmd := command.Command{
+		FS:                 fs,
+		Engine:             policyEngine,
+		Paths:              []string{"."},
+		Bundle:             "bundle.tar.gz",
+		ResultsProcessor:   resultsProcessor,
+		SettingsReader:     settingsReader,
+		Output:             outputFilePath,
+		ComplianceOutput:   "compliance.json",
+		ComplianceMarkdown: "compliance.md",
+		Logger:             &logger,
+	}
+
+	require.Equal(t, 0, cmd.Run())
+
+	requireNoError(t, fs)
+
+	data, err := afero.ReadFile(fs, "compliance.json")
+	require.NoError(t, err)
+
+	var summary struct {
+		Frameworks []struct {
+			Name           string
+			FailedControls int
+		}
+	}
+
+	require.NoError(t, json.Unmarshal(data, &summary))
+	require.Len(t, summary.Frameworks, 1)
+	require.Equal(t, "CIS-AWS_v1.4.0", summary.Frameworks[0].Name)
+	require.Equal(t, 1, summary.Frameworks[0].FailedControls)
+
+	data, err = afero.ReadFile(fs, "compliance.md")
+	require.NoError(t, err)
+	require.Contains(t, string(data), "| CIS-AWS_v1.4.0_2.1.5 | 0 | 1 | 0.00% |")
+}
+
+func TestExitCodePolicy(t *testing.T) {
+	settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+		return &settings.Settings{
+			Entitlements: settings.Entitlements{
+				InfrastructureAsCode: true,
+			},
+		}, nil
+	})
+
+	resultsProcessor := mockResultsProcessor{
+		processResults: func(rawResults *engine.Results, scanAnalytics results.ScanAnalytics) (*results.Results, error) {
+			return &results.Results{
+				Vulnerabilities: []results.Vulnerability{
+					{
+						Rule:     results.Rule{ID: "SNYK-CC-TF-1"},
+						Severity: "high",
+					},
+				},
+			}, nil
+		},
+	}
+
+	tests := []struct {
+		name     string
+		policy   *exitcode.Policy
+		errors   []error
+		warnings []error
+		expected int
+	}{
+		{
+			name:     "without policy",
+			errors:   []error{errors.New("boom")},
+			expected: exitcode.Success,
+		},
+		{
+			name:     "findings",
+			policy:   &exitcode.Policy{},
+			expected: exitcode.Findings,
+		},
+		{
+			name:     "findings within the limits",
+			policy:   &exitcode.Policy{Limits: map[string]int{"high": 1}},
+			expected: exitcode.Success,
+		},
+		{
+			name:     "scan errors",
+			policy:   &exitcode.Policy{},
+			errors:   []error{errors.New("boom")},
+			expected: exitcode.ScanErrors,
+		},
+		{
+			name:     "partial scan",
+			policy:   &exitcode.Policy{Limits: map[string]int{"high": 1}, FailOnWarnings: true},
+			warnings: []error{errors.New("partial")},
+			expected: exitcode.PartialScan,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := zerolog.Nop()
+			fs := afero.NewMemMapFs()
+
+			require.Nil(t, afero.WriteFile(fs, "bundle.tar.gz", nil, 0644))
+
+			policyEngine := mockEngine{
+				run: func(ctx context.Context, options engine.RunOptions) (*engine.Results, results.ScanAnalytics, []error, []error) {
+					return &engine.Results{}, results.ScanAnalytics{}, tt.errors, tt.warnings
+				},
+			}
+
+			cmd := command.Command{
+				FS:               fs,
+				Engine:           policyEngine,
+				Paths:            []string{"."},
+				Bundle:           "bundle.tar.gz",
+				ResultsProcessor: resultsProcessor,
+				SettingsReader:   settingsReader,
+				Output:           outputFilePath,
+				ExitCodePolicy:   tt.policy,
+				Logger:           &logger,
+			}
+
+			report, err := cmd.RunWithReport()
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, report.Decision.Code)
+
+			// Run only fails if the scan couldn't run.
+			require.Equal(t, 0, cmd.Run())
+		})
+	}
+}
+
 func TestExcludeFiltering(t *testing.T) {
 	logger := zerolog.Nop()
 	fs := afero.NewMemMapFs()
@@ -1058,3 +1330,944 @@ func normalizeToSlash(in []string) []string {
 	}
 	return out
 }
+
+func TestChangedSince(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewOsFs()
+
+	withinDir(t, t.TempDir())
+
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile("bundle.tar.gz", nil, 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join("root", "a"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join("root", "b"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join("root", "a", "main.tf"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join("root", "a", "variables.tf"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join("root", "b", "main.tf"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join("root", "pod.yaml"), nil, 0644))
+
+	var capturedPaths []string
+
+	policyEngine := mockEngine{
+		run: func(ctx context.Context, options engine.RunOptions) (*engine.Results, results.ScanAnalytics, []error, []error) {
+			capturedPaths = options.Paths
+			return &engine.Results{}, results.ScanAnalytics{}, nil, nil
+		},
+	}
+
+	resultsProcessor := mockResultsProcessor{
+		processResults: func(rawResults *engine.Results, scanAnalytics results.ScanAnalytics) (*results.Results, error) {
+			return &results.Results{
+				Vulnerabilities: []results.Vulnerability{
+					{
+						Rule: results.Rule{ID: "module-a"},
+						Resource: results.Resource{
+							File: filepath.Join("root", "a", "main.tf"),
+							SourceLocation: []results.Location{
+								{File: filepath.Join("root", "a", "main.tf")},
+								{File: filepath.Join("root", "a", "variables.tf")},
+							},
+						},
+					},
+					{
+						Rule: results.Rule{ID: "module-b"},
+						Resource: results.Resource{
+							File: filepath.Join("root", "b", "main.tf"),
+						},
+					},
+					{
+						Rule: results.Rule{ID: "pod"},
+						Resource: results.Resource{
+							File: filepath.Join("root", "pod.yaml"),
+						},
+					},
+				},
+			}, nil
+		},
+	}
+
+	settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+		return &settings.Settings{
+			Entitlements: settings.Entitlements{
+				InfrastructureAsCode: true,
+			},
+		}, nil
+	})
+
+	cmd := command.Command{
+		FS:               fs,
+		Engine:           policyEngine,
+		Paths:            []string{"root"},
+		Bundle:           "bundle.tar.gz",
+		ResultsProcessor: resultsProcessor,
+		SettingsReader:   settingsReader,
+		Output:           outputFilePath,
+		Logger:           &logger,
+		ChangedSince:     "main",
+		ListChangedFiles: func(path string, ref string) ([]string, error) {
+			require.Equal(t, cwd, path)
+			require.Equal(t, "main", ref)
+
+			return []string{
+				filepath.Join(cwd, "root", "a", "variables.tf"),
+				filepath.Join(cwd, "root", "deleted.yaml"),
+				filepath.Join(cwd, "root", "pod.yaml"),
+				filepath.Join(cwd, "other", "main.tf"),
+			}, nil
+		},
+	}
+
+	require.Equal(t, 0, cmd.Run())
+
+	requireNoError(t, fs)
+
+	require.Equal(t, []string{"root/a", "root/pod.yaml"}, normalizeToSlash(capturedPaths))
+
+	var output struct {
+		Results struct {
+			Vulnerabilities []struct {
+				Rule struct {
+					ID string
+				}
+			}
+		}
+	}
+
+	readOutput(t, fs, &output)
+
+	require.Len(t, output.Results.Vulnerabilities, 2)
+	require.Equal(t, "module-a", output.Results.Vulnerabilities[0].Rule.ID)
+	require.Equal(t, "pod", output.Results.Vulnerabilities[1].Rule.ID)
+}
+
+func TestChangedSinceChartsAndOverlays(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewOsFs()
+
+	withinDir(t, t.TempDir())
+
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+
+	for _, file := range []string{
+		"bundle.tar.gz",
+		filepath.Join("root", "chart", "Chart.yaml"),
+		filepath.Join("root", "chart", "templates", "pod.yaml"),
+		filepath.Join("root", "overlay", "kustomization.yaml"),
+		filepath.Join("root", "overlay", "patch.yaml"),
+		filepath.Join("root", "other", "Chart.yaml"),
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		require.NoError(t, os.WriteFile(file, nil, 0644))
+	}
+
+	var capturedPaths []string
+
+	policyEngine := mockEngine{
+		run: func(ctx context.Context, options engine.RunOptions) (*engine.Results, results.ScanAnalytics, []error, []error) {
+			capturedPaths = options.Paths
+			return &engine.Results{}, results.ScanAnalytics{}, nil, nil
+		},
+	}
+
+	vulnerability := func(id string, dir string) results.Vulnerability {
+		return results.Vulnerability{
+			Rule:     results.Rule{ID: id},
+			Resource: results.Resource{File: filepath.Join("root", dir)},
+		}
+	}
+
+	resultsProcessor := mockResultsProcessor{
+		processResults: func(rawResults *engine.Results, scanAnalytics results.ScanAnalytics) (*results.Results, error) {
+			return &results.Results{
+				Vulnerabilities: []results.Vulnerability{
+					vulnerability("chart", "chart"),
+					vulnerability("overlay", "overlay"),
+					vulnerability("other", "other"),
+				},
+				IgnoredVulnerabilities: []results.Vulnerability{
+					vulnerability("ignored-chart", "chart"),
+					vulnerability("ignored-other", "other"),
+				},
+				BaselineVulnerabilities: []results.Vulnerability{
+					vulnerability("baseline-other", "other"),
+				},
+				Metadata: results.Metadata{IgnoredCount: 2},
+			}, nil
+		},
+	}
+
+	settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+		return &settings.Settings{
+			Entitlements: settings.Entitlements{
+				InfrastructureAsCode: true,
+			},
+		}, nil
+	})
+
+	cmd := command.Command{
+		FS:               fs,
+		Engine:           policyEngine,
+		Paths:            []string{"root"},
+		Bundle:           "bundle.tar.gz",
+		ResultsProcessor: resultsProcessor,
+		SettingsReader:   settingsReader,
+		Output:           outputFilePath,
+		Logger:           &logger,
+		ChangedSince:     "main",
+		ListChangedFiles: func(path string, ref string) ([]string, error) {
+			return []string{
+				filepath.Join(cwd, "root", "chart", "templates", "pod.yaml"),
+				filepath.Join(cwd, "root", "chart", "templates", "deleted.yaml"),
+				filepath.Join(cwd, "root", "overlay", "patch.yaml"),
+			}, nil
+		},
+	}
+
+	require.Equal(t, 0, cmd.Run())
+
+	requireNoError(t, fs)
+
+	require.Equal(t, []string{"root/chart", "root/overlay"}, normalizeToSlash(capturedPaths))
+
+	type vulnerabilities []struct {
+		Rule struct {
+			ID string
+		}
+	}
+
+	var output struct {
+		Results struct {
+			Vulnerabilities         vulnerabilities
+			IgnoredVulnerabilities  vulnerabilities
+			BaselineVulnerabilities vulnerabilities
+			Metadata                struct {
+				IgnoredCount int
+			}
+		}
+	}
+
+	readOutput(t, fs, &output)
+
+	require.Len(t, output.Results.Vulnerabilities, 2)
+	require.Equal(t, "chart", output.Results.Vulnerabilities[0].Rule.ID)
+	require.Equal(t, "overlay", output.Results.Vulnerabilities[1].Rule.ID)
+
+	require.Len(t, output.Results.IgnoredVulnerabilities, 1)
+	require.Equal(t, "ignored-chart", output.Results.IgnoredVulnerabilities[0].Rule.ID)
+	require.Equal(t, 1, output.Results.Metadata.IgnoredCount)
+
+	require.Empty(t, output.Results.BaselineVulnerabilities)
+}
+
+func TestChangedSinceDependents(t *testing.T) {
+	manifest := `{"artifacts": {"App": {"type": "aws:cloudformation:stack", "properties": {"templateFile": "App.template.json"}}}}`
+
+	tests := []struct {
+		name            string
+		files           map[string]string
+		vars            []tfvars.Arg
+		changed         []string
+		paths           []string
+		vulnerabilities []string
+	}{
+		{
+			name: "overlays, units, modules and assemblies",
+			files: map[string]string{
+				"root/base/kustomization.yaml":    "resources:\n- deployment.yaml\n",
+				"root/base/deployment.yaml":       "",
+				"root/overlay/kustomization.yaml": "resources:\n- ../base\n",
+				"root/prod/kustomization.yaml":    "resources:\n- ../overlay\n",
+				"root/other/kustomization.yaml":   "resources:\n- service.yaml\n",
+				"root/other/service.yaml":         "",
+				"root/modules/vpc/main.tf":        "",
+				"root/live/terragrunt.hcl":        "terraform {\n  source = \"../modules/vpc\"\n}\n",
+				"root/app/main.tf":                "",
+				"root/app/terraform.tfvars":       "",
+				"root/cdk.out/manifest.json":      manifest,
+				"root/cdk.out/App.template.json":  "",
+			},
+			changed: []string{
+				"root/base/deployment.yaml",
+				"root/modules/vpc/main.tf",
+				"root/app/terraform.tfvars",
+				"root/cdk.out/App.template.json",
+			},
+			paths: []string{
+				"root/app",
+				"root/base",
+				"root/cdk.out",
+				"root/live",
+				"root/modules/vpc",
+				"root/overlay",
+				"root/prod",
+			},
+			vulnerabilities: []string{"root/app/main.tf", "root/cdk.out", "root/live", "root/prod"},
+		},
+		{
+			name: "var files of the scan",
+			files: map[string]string{
+				"common.tfvars":      "",
+				"root/a/main.tf":     "",
+				"root/b/main.tf":     "",
+				"root/pod.yaml":      "",
+				"other/variables.tf": "",
+			},
+			vars:            []tfvars.Arg{{VarFile: "common.tfvars"}},
+			changed:         []string{"common.tfvars"},
+			paths:           []string{"root/a", "root/b"},
+			vulnerabilities: []string{"root/a/main.tf", "root/b/main.tf"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := zerolog.Nop()
+			fs := afero.NewOsFs()
+
+			withinDir(t, t.TempDir())
+
+			cwd, err := os.Getwd()
+			require.NoError(t, err)
+
+			require.NoError(t, os.WriteFile("bundle.tar.gz", nil, 0644))
+
+			for file, content := range tt.files {
+				require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+				require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+			}
+
+			var capturedPaths []string
+
+			policyEngine := mockEngine{
+				run: func(ctx context.Context, options engine.RunOptions) (*engine.Results, results.ScanAnalytics, []error, []error) {
+					capturedPaths = options.Paths
+					return &engine.Results{}, results.ScanAnalytics{}, nil, nil
+				},
+			}
+
+			resultsProcessor := mockResultsProcessor{
+				processResults: func(rawResults *engine.Results, scanAnalytics results.ScanAnalytics) (*results.Results, error) {
+					var vulnerabilities []results.Vulnerability
+
+					for _, file := range []string{
+						"root/app/main.tf", "root/cdk.out", "root/live", "root/prod", "root/other",
+						"root/a/main.tf", "root/b/main.tf", "root/pod.yaml",
+					} {
+						vulnerabilities = append(vulnerabilities, results.Vulnerability{
+							Rule:     results.Rule{ID: file},
+							Resource: results.Resource{File: filepath.FromSlash(file)},
+						})
+					}
+
+					return &results.Results{Vulnerabilities: vulnerabilities}, nil
+				},
+			}
+
+			settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+				return &settings.Settings{
+					Entitlements: settings.Entitlements{
+						InfrastructureAsCode: true,
+					},
+				}, nil
+			})
+
+			cmd := command.Command{
+				FS:               fs,
+				Engine:           policyEngine,
+				Paths:            []string{"root"},
+				Bundle:           "bundle.tar.gz",
+				ResultsProcessor: resultsProcessor,
+				SettingsReader:   settingsReader,
+				Output:           outputFilePath,
+				Logger:           &logger,
+				TerraformVars:    tt.vars,
+				ChangedSince:     "main",
+				ListChangedFiles: func(path string, ref string) ([]string, error) {
+					var files []string
+
+					for _, file := range tt.changed {
+						files = append(files, filepath.Join(cwd, filepath.FromSlash(file)))
+					}
+
+					return files, nil
+				},
+			}
+
+			require.Equal(t, 0, cmd.Run())
+
+			requireNoError(t, fs)
+
+			require.Equal(t, tt.paths, normalizeToSlash(capturedPaths))
+
+			var output struct {
+				Results struct {
+					Vulnerabilities []struct {
+						Rule struct {
+							ID string
+						}
+					}
+				}
+			}
+
+			readOutput(t, fs, &output)
+
+			var ids []string
+
+			for _, v := range output.Results.Vulnerabilities {
+				ids = append(ids, v.Rule.ID)
+			}
+
+			require.Equal(t, tt.vulnerabilities, ids)
+		})
+	}
+}
+
+func TestChangedSinceError(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewMemMapFs()
+
+	require.Nil(t, afero.WriteFile(fs, "bundle.tar.gz", nil, 0644))
+
+	settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+		return &settings.Settings{
+			Entitlements: settings.Entitlements{
+				InfrastructureAsCode: true,
+			},
+		}, nil
+	})
+
+	cmd := command.Command{
+		FS:             fs,
+		Paths:          []string{"."},
+		Bundle:         "bundle.tar.gz",
+		SettingsReader: settingsReader,
+		Output:         outputFilePath,
+		Logger:         &logger,
+		ChangedSince:   "main",
+		ListChangedFiles: func(path string, ref string) ([]string, error) {
+			return nil, errors.New("not a repository")
+		},
+	}
+
+	require.Equal(t, 0, cmd.Run())
+
+	requireError(t, fs, scanError{
+		Message: "unable to list the changed files",
+		Code:    2006,
+	})
+}
+
+func TestStaleIgnores(t *testing.T) {
+	expires := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		failOnStaleIgnores bool
+		errors             []scanError
+	}{
+		{
+			name: "warnings only",
+		},
+		{
+			name:               "fail on stale ignores",
+			failOnStaleIgnores: true,
+			errors: []scanError{
+				{
+					Message: "the policy file contains stale ignores",
+					Code:    2007,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := zerolog.Nop()
+			fs := afero.NewMemMapFs()
+
+			require.Nil(t, afero.WriteFile(fs, "bundle.tar.gz", nil, 0644))
+
+			policyEngine := mockEngine{
+				run: func(ctx context.Context, options engine.RunOptions) (*engine.Results, results.ScanAnalytics, []error, []error) {
+					return &engine.Results{}, results.ScanAnalytics{}, nil, nil
+				},
+			}
+
+			resultsProcessor := mockResultsProcessor{
+				processResults: func(rawResults *engine.Results, scanAnalytics results.ScanAnalytics) (*results.Results, error) {
+					return &results.Results{
+						StaleIgnores: []results.StaleIgnore{
+							{RuleID: "RULE-1", Path: "*"},
+							{RuleID: "RULE-2", Path: "main.tf > *", Expires: &expires, Expired: true},
+						},
+					}, nil
+				},
+			}
+
+			settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+				return &settings.Settings{
+					Entitlements: settings.Entitlements{
+						InfrastructureAsCode: true,
+					},
+				}, nil
+			})
+
+			cmd := command.Command{
+				FS:                 fs,
+				Engine:             policyEngine,
+				Paths:              []string{"."},
+				Bundle:             "bundle.tar.gz",
+				ResultsProcessor:   resultsProcessor,
+				SettingsReader:     settingsReader,
+				Output:             outputFilePath,
+				Logger:             &logger,
+				FailOnStaleIgnores: tt.failOnStaleIgnores,
+			}
+
+			require.Equal(t, 0, cmd.Run())
+
+			var output struct {
+				Errors   []scanError
+				Warnings []scanError
+			}
+
+			readOutput(t, fs, &output)
+
+			require.Equal(t, tt.errors, output.Errors)
+			require.Equal(t, []scanError{
+				{
+					Message: "the ignore doesn't match any issue",
+					Code:    3004,
+					Fields: map[string]interface{}{
+						"id":     "RULE-1",
+						"path":   "*",
+						"status": "unused",
+					},
+				},
+				{
+					Message: "the ignore is expired",
+					Code:    3004,
+					Fields: map[string]interface{}{
+						"id":      "RULE-2",
+						"path":    "main.tf > *",
+						"status":  "expired",
+						"expires": "2025-01-01T00:00:00Z",
+					},
+				},
+			}, output.Warnings)
+		})
+	}
+}
+
+func newCustomRuleBundle(t *testing.T) []byte {
+	var customBundle bytes.Buffer
+
+	gw := gzip.NewWriter(&customBundle)
+	tw := tar.NewWriter(gw)
+	rule := []byte("package rules.custom\n")
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "rules/custom/rule.rego", Mode: 0644, Size: int64(len(rule))}))
+	_, err := tw.Write(rule)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	return customBundle.Bytes()
+}
+
+func TestOffline(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewMemMapFs()
+
+	require.Nil(t, afero.WriteFile(fs, "bundle.tar.gz", nil, 0644))
+	require.Nil(t, afero.WriteFile(fs, filepath.Join("rules", "custom.tar.gz"), newCustomRuleBundle(t), 0644))
+	require.Nil(t, afero.WriteFile(fs, filepath.Join("rules", "README.md"), nil, 0644))
+
+	var customRuleBundles, localRuleBundles []bundle.Reader
+
+	policyEngine := mockEngine{
+		run: func(ctx context.Context, options engine.RunOptions) (*engine.Results, results.ScanAnalytics, []error, []error) {
+			customRuleBundles = options.CustomRuleBundles
+			localRuleBundles = options.LocalRuleBundles
+			return nil, results.ScanAnalytics{}, nil, nil
+		},
+	}
+
+	resultsProcessor := mockResultsProcessor{
+		processResults: func(rawResults *engine.Results, scanAnalytics results.ScanAnalytics) (*results.Results, error) {
+			return nil, nil
+		},
+	}
+
+	cloudApiClient := mockCloudApiClient{
+		customRules: func(ctx context.Context, orgID string) (readers []bundle.Reader, e error) {
+			t.Fatal("custom rules must not be requested in offline mode")
+			return nil, nil
+		},
+	}
+
+	settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+		return &settings.Settings{
+			OrgPublicID: "org-public-id",
+			Entitlements: settings.Entitlements{
+				InfrastructureAsCode: true,
+			},
+		}, nil
+	})
+
+	cmd := command.Command{
+		FS:               fs,
+		Engine:           policyEngine,
+		Paths:            []string{"."},
+		Bundle:           "bundle.tar.gz",
+		ResultsProcessor: resultsProcessor,
+		SettingsReader:   settingsReader,
+		SnykClient:       &cloudApiClient,
+		Output:           outputFilePath,
+		Logger:           &logger,
+		Offline:          true,
+		LocalRules:       []string{"rules"},
+	}
+
+	require.Equal(t, 0, cmd.Run())
+
+	requireNoError(t, fs)
+
+	require.Empty(t, customRuleBundles)
+	require.Len(t, localRuleBundles, 1)
+	require.Equal(t, filepath.Join("rules", "custom.tar.gz"), localRuleBundles[0].Info().FileInfo.Path)
+}
+
+func TestOfflineWithoutBundle(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewMemMapFs()
+
+	settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+		return &settings.Settings{
+			Entitlements: settings.Entitlements{
+				InfrastructureAsCode: true,
+			},
+		}, nil
+	})
+
+	bundleDownloader := downloadBundleFunc(func(peVersion string, w io.Writer) error {
+		t.Fatal("the bundle must not be downloaded in offline mode")
+		return nil
+	})
+
+	cmd := command.Command{
+		FS:                      fs,
+		Paths:                   []string{"."},
+		SettingsReader:          settingsReader,
+		BundleDownloader:        bundleDownloader,
+		ReadPolicyEngineVersion: func() (string, error) { return "v1.0.0", nil },
+		Output:                  outputFilePath,
+		Logger:                  &logger,
+		Offline:                 true,
+	}
+
+	require.Equal(t, 0, cmd.Run())
+
+	requireError(t, fs, scanError{
+		Message: "unable to open bundle",
+		Code:    2004,
+	})
+}
+
+type downloadPinnedBundleFunc func(pinnedBundleVersion string, peVersion string, w io.Writer) error
+
+func (f downloadPinnedBundleFunc) DownloadPinnedBundle(pinnedBundleVersion string, peVersion string, w io.Writer) error {
+	return f(pinnedBundleVersion, peVersion, w)
+}
+
+func TestPinnedBundleVersion(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewMemMapFs()
+
+	policyEngine := mockEngine{
+		run: func(ctx context.Context, options engine.RunOptions) (*engine.Results, results.ScanAnalytics, []error, []error) {
+			data, err := io.ReadAll(options.SnykBundle)
+			require.NoError(t, err)
+			require.Equal(t, "pinned", string(data))
+			return nil, results.ScanAnalytics{}, nil, nil
+		},
+	}
+
+	resultsProcessor := mockResultsProcessor{
+		processResults: func(rawResults *engine.Results, scanAnalytics results.ScanAnalytics) (*results.Results, error) {
+			return nil, nil
+		},
+	}
+
+	settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+		return &settings.Settings{
+			Entitlements: settings.Entitlements{
+				InfrastructureAsCode: true,
+			},
+		}, nil
+	})
+
+	bundleDownloader := downloadBundleFunc(func(peVersion string, w io.Writer) error {
+		t.Fatal("the latest bundle must not be downloaded when a version is pinned")
+		return nil
+	})
+
+	pinnedBundleDownloader := downloadPinnedBundleFunc(func(pinnedBundleVersion string, peVersion string, w io.Writer) error {
+		require.Equal(t, "v1.2.3", pinnedBundleVersion)
+		require.Equal(t, "v1.0.0", peVersion)
+		_, err := w.Write([]byte("pinned"))
+		return err
+	})
+
+	cmd := command.Command{
+		FS:                      fs,
+		Engine:                  policyEngine,
+		Paths:                   []string{"."},
+		ResultsProcessor:        resultsProcessor,
+		SettingsReader:          settingsReader,
+		BundleDownloader:        bundleDownloader,
+		PinnedBundleDownloader:  pinnedBundleDownloader,
+		BundleVersion:           "v1.2.3",
+		ReadPolicyEngineVersion: func() (string, error) { return "v1.0.0", nil },
+		Output:                  outputFilePath,
+		Logger:                  &logger,
+	}
+
+	require.Equal(t, 0, cmd.Run())
+
+	requireNoError(t, fs)
+}
+
+func TestInvalidBundleSignature(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewMemMapFs()
+
+	settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+		return &settings.Settings{
+			Entitlements: settings.Entitlements{
+				InfrastructureAsCode: true,
+			},
+		}, nil
+	})
+
+	bundleDownloader := downloadBundleFunc(func(peVersion string, w io.Writer) error {
+		return fmt.Errorf("download bundle: %w", rules.ErrInvalidSignature)
+	})
+
+	cmd := command.Command{
+		FS:                      fs,
+		Paths:                   []string{"."},
+		SettingsReader:          settingsReader,
+		BundleDownloader:        bundleDownloader,
+		ReadPolicyEngineVersion: func() (string, error) { return "v1.0.0", nil },
+		Output:                  outputFilePath,
+		Logger:                  &logger,
+	}
+
+	require.Equal(t, 0, cmd.Run())
+
+	requireErrors(t, fs, []scanError{
+		{
+			Message: "unable to verify the signature of the bundle",
+			Code:    2008,
+		},
+	})
+}
+
+func TestLocalRules(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewMemMapFs()
+
+	require.Nil(t, afero.WriteFile(fs, "bundle.tar.gz", nil, 0644))
+	require.Nil(t, afero.WriteFile(fs, filepath.Join("custom", "custom.rego"), []byte("package rules.custom\n"), 0644))
+	require.Nil(t, afero.WriteFile(fs, filepath.Join("custom", "lib", "utils.rego"), []byte("package lib.utils\n"), 0644))
+
+	var customRuleBundles, localRuleBundles []bundle.Reader
+
+	policyEngine := mockEngine{
+		run: func(ctx context.Context, options engine.RunOptions) (*engine.Results, results.ScanAnalytics, []error, []error) {
+			customRuleBundles = options.CustomRuleBundles
+			localRuleBundles = options.LocalRuleBundles
+			return nil, results.ScanAnalytics{}, nil, nil
+		},
+	}
+
+	resultsProcessor := mockResultsProcessor{
+		processResults: func(rawResults *engine.Results, scanAnalytics results.ScanAnalytics) (*results.Results, error) {
+			return nil, nil
+		},
+	}
+
+	remoteBundle := bundle.NewDirReader("remote")
+
+	cloudApiClient := mockCloudApiClient{
+		customRules: func(ctx context.Context, orgID string) (readers []bundle.Reader, e error) {
+			return []bundle.Reader{remoteBundle}, nil
+		},
+	}
+
+	settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+		return &settings.Settings{
+			OrgPublicID: "org-public-id",
+			Entitlements: settings.Entitlements{
+				InfrastructureAsCode: true,
+			},
+		}, nil
+	})
+
+	cmd := command.Command{
+		FS:               fs,
+		Engine:           policyEngine,
+		Paths:            []string{"."},
+		Bundle:           "bundle.tar.gz",
+		ResultsProcessor: resultsProcessor,
+		SettingsReader:   settingsReader,
+		SnykClient:       &cloudApiClient,
+		Output:           outputFilePath,
+		Logger:           &logger,
+		LocalRules:       []string{"custom"},
+	}
+
+	require.Equal(t, 0, cmd.Run())
+
+	requireNoError(t, fs)
+
+	require.Equal(t, []bundle.Reader{remoteBundle}, customRuleBundles)
+	require.Len(t, localRuleBundles, 1)
+
+	localBundle := localRuleBundles[0]
+	require.Equal(t, "custom", localBundle.Info().FileInfo.Path)
+
+	manifest, err := localBundle.Manifest()
+	require.NoError(t, err)
+	require.Equal(t, "v1", manifest.BundleFormatVersion)
+
+	var paths []string
+
+	require.NoError(t, localBundle.WalkFiles(func(path string, f io.Reader) error {
+		paths = append(paths, path)
+		return nil
+	}))
+
+	require.ElementsMatch(t, []string{"manifest.json", "rules/custom.rego", "lib/utils.rego"}, paths)
+}
+
+func TestLocalRulesNotFound(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewMemMapFs()
+
+	require.Nil(t, afero.WriteFile(fs, "bundle.tar.gz", nil, 0644))
+	policyEngine := mockEngine{
+		run: func(ctx context.Context, options engine.RunOptions) (*engine.Results, results.ScanAnalytics, []error, []error) {
+			t.Fatal("the engine must not run without the custom rules")
+			return nil, results.ScanAnalytics{}, nil, nil
+		},
+	}
+
+	settingsReader := readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+		return &settings.Settings{
+			OrgPublicID: "org-public-id",
+			Entitlements: settings.Entitlements{
+				InfrastructureAsCode: true,
+			},
+		}, nil
+	})
+
+	cmd := command.Command{
+		FS:             fs,
+		Engine:         policyEngine,
+		Paths:          []string{"."},
+		Bundle:         "bundle.tar.gz",
+		SettingsReader: settingsReader,
+		Output:         outputFilePath,
+		Logger:         &logger,
+		LocalRules:     []string{"missing.tar.gz"},
+	}
+
+	require.Equal(t, 0, cmd.Run())
+
+	requireError(t, fs, scanError{
+		Message: "failed to load rules",
+		Code:    2111,
+		Fields: map[string]interface{}{
+			"path": "missing.tar.gz",
+		},
+	})
+}
+
+func TestRuleBundles(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewMemMapFs()
+
+	require.Nil(t, afero.WriteFile(fs, "bundle.tar.gz", nil, 0644))
+	require.Nil(t, afero.WriteFile(fs, filepath.Join("custom", "custom.rego"), []byte("package rules.custom\n"), 0644))
+	require.Nil(t, afero.WriteFile(fs, filepath.Join("bundles", "custom.tar.gz"), newCustomRuleBundle(t), 0644))
+
+	remoteBundle := bundle.NewDirReader("remote")
+
+	// The local custom rules are loaded in addition to the custom rules of
+	// the organization.
+	cmd := command.Command{
+		FS:         fs,
+		Bundle:     "bundle.tar.gz",
+		LocalRules: []string{"custom", "bundles"},
+		SnykClient: &mockCloudApiClient{
+			customRules: func(ctx context.Context, orgID string) (readers []bundle.Reader, e error) {
+				require.Equal(t, "org-public-id", orgID)
+				return []bundle.Reader{remoteBundle}, nil
+			},
+		},
+		Logger: &logger,
+	}
+
+	snykBundle, customRules, err := cmd.RuleBundles(context.Background(), "org-public-id")
+	require.NoError(t, err)
+	require.NotNil(t, snykBundle)
+	require.NoError(t, snykBundle.Close())
+	require.Len(t, customRules, 3)
+	require.Equal(t, remoteBundle, customRules[0])
+	require.Equal(t, "custom", customRules[1].Info().FileInfo.Path)
+	require.Equal(t, filepath.Join("bundles", "custom.tar.gz"), customRules[2].Info().FileInfo.Path)
+
+	cmd.LocalRules = []string{"missing"}
+
+	_, _, err = cmd.RuleBundles(context.Background(), "org-public-id")
+	require.Error(t, err)
+}
+
+func TestInvalidRuleSelection(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewMemMapFs()
+
+	require.Nil(t, afero.WriteFile(fs, "bundle.tar.gz", nil, 0644))
+
+	cmd := command.Command{
+		FS: fs,
+		Engine: mockEngine{
+			run: func(ctx context.Context, options engine.RunOptions) (*engine.Results, results.ScanAnalytics, []error, []error) {
+				return nil, results.ScanAnalytics{}, []error{engine.Error{Message: "unknown rule", Code: engine.ErrorCodeInvalidRuleSelection}}, nil
+			},
+		},
+		Paths:  []string{"."},
+		Bundle: "bundle.tar.gz",
+		ResultsProcessor: mockResultsProcessor{
+			processResults: func(rawResults *engine.Results, scanAnalytics results.ScanAnalytics) (*results.Results, error) {
+				return nil, nil
+			},
+		},
+		SettingsReader: readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+			return &settings.Settings{Entitlements: settings.Entitlements{InfrastructureAsCode: true}}, nil
+		}),
+		Output: outputFilePath,
+		Logger: &logger,
+	}
+
+	report, err := cmd.RunWithReport()
+	require.NoError(t, err)
+
+	message, ok := report.InvalidRuleSelection()
+	require.True(t, ok)
+	require.Equal(t, "unknown rule", message)
+}
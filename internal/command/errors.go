@@ -2,6 +2,7 @@ package command
 
 import (
 	"github.com/snyk/cli-extension-iac/internal/engine"
+	"github.com/snyk/cli-extension-iac/internal/results"
 )
 
 // errorCode is a unique error code that is assigned to a user error.
@@ -27,12 +28,16 @@ type scanError struct {
 const (
 	errorCodeNoPaths errorCode = 2000 + iota
 	errorCodeInvalidExcludes
+	errorCodeInvalidRuleSelection
 )
 
 const (
 	errorCodeCwdTraversal errorCode = 2003 + iota
 	errorCodeOpenBundle
 	errorCodeFetchCustomRuleBundles
+	errorCodeListChangedFiles
+	errorCodeStaleIgnores
+	errorCodeInvalidBundleSignature
 )
 
 const (
@@ -67,6 +72,8 @@ const (
 	errorCodeMissingRemoteSubmodulesError
 	errorCodeEvaluationError
 	errorCodeMissingTermError
+	errorCodeStaleIgnoreWarning
+	errorCodeUnsupportedFeature
 )
 
 var errNoPaths = scanError{
@@ -84,11 +91,26 @@ var errOpenBundle = scanError{
 	Code:    errorCodeOpenBundle,
 }
 
+var errInvalidBundleSignature = scanError{
+	Message: "unable to verify the signature of the bundle",
+	Code:    errorCodeInvalidBundleSignature,
+}
+
 var errFetchCustomRulesBundles = scanError{
 	Message: "unable to fetch custom rule bundles",
 	Code:    errorCodeFetchCustomRuleBundles,
 }
 
+var errListChangedFiles = scanError{
+	Message: "unable to list the changed files",
+	Code:    errorCodeListChangedFiles,
+}
+
+var errStaleIgnores = scanError{
+	Message: "the policy file contains stale ignores",
+	Code:    errorCodeStaleIgnores,
+}
+
 var errScan = scanError{
 	Message: "unable to scan",
 	Code:    errorCodeScan,
@@ -116,6 +138,43 @@ func cwdTraversalError(path string) scanError {
 	return newScanError("current working directory traversal", errorCodeCwdTraversal, map[string]any{"path": path})
 }
 
+func staleIgnoresToScanWarnings(staleIgnores []results.StaleIgnore) []scanError {
+	var warnings []scanError
+
+	for _, staleIgnore := range staleIgnores {
+		fields := map[string]any{
+			"id":   staleIgnore.RuleID,
+			"path": staleIgnore.Path,
+		}
+
+		if staleIgnore.Expires != nil {
+			fields["expires"] = staleIgnore.Expires
+		}
+
+		if staleIgnore.Expired {
+			fields["status"] = "expired"
+			warnings = append(warnings, newScanError("the ignore is expired", errorCodeStaleIgnoreWarning, fields))
+		} else {
+			fields["status"] = "unused"
+			warnings = append(warnings, newScanError("the ignore doesn't match any issue", errorCodeStaleIgnoreWarning, fields))
+		}
+	}
+
+	return warnings
+}
+
+func expiredIgnores(staleIgnores []results.StaleIgnore) []results.StaleIgnore {
+	var expired []results.StaleIgnore
+
+	for _, staleIgnore := range staleIgnores {
+		if staleIgnore.Expired {
+			expired = append(expired, staleIgnore)
+		}
+	}
+
+	return expired
+}
+
 func errorsToScanErrors(errors []error) []scanError {
 	var result []scanError
 
@@ -167,6 +226,8 @@ func errorToScanError(err error) scanError {
 			return newScanError(result.Message, errorCodeFailedToMakeResourcesResolvers, fields)
 		case engine.ErrorCodeResourcesResolverError:
 			return newScanError(result.Message, errorCodeResourcesResolverError, fields)
+		case engine.ErrorCodeInvalidRuleSelection:
+			return newScanError(result.Message, errorCodeInvalidRuleSelection, fields)
 		}
 	}
 	if result, ok := err.(engine.SubmoduleLoadingError); ok {
@@ -181,9 +242,28 @@ func errorToScanError(err error) scanError {
 	if result, ok := err.(engine.MissingTermError); ok {
 		return newScanError(result.Message, errorCodeMissingTermError, map[string]any{"path": result.Path, "term": result.Term})
 	}
+	if result, ok := err.(engine.UnsupportedFeatureError); ok {
+		return newScanError(result.Message, errorCodeUnsupportedFeature, map[string]any{"path": result.Path})
+	}
 	return errScan
 }
 
+func scanMessages(scanErrors []scanError) []ScanMessage {
+	var messages []ScanMessage
+
+	for _, scanError := range scanErrors {
+		path, _ := scanError.Fields["path"].(string)
+
+		messages = append(messages, ScanMessage{
+			Message: scanError.Message,
+			Code:    int(scanError.Code),
+			Path:    path,
+		})
+	}
+
+	return messages
+}
+
 func newScanError(msg string, code errorCode, fields map[string]any) scanError {
 	return scanError{
 		Message: msg,
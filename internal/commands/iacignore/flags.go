@@ -0,0 +1,27 @@
+package iacignore
+
+import "github.com/spf13/pflag"
+
+const (
+	FlagID         = "id"
+	FlagPath       = "path"
+	FlagReason     = "reason"
+	FlagExpiry     = "expiry"
+	FlagPolicyPath = "policy-path"
+	FlagForce      = "force"
+	FlagOffline    = "offline"
+)
+
+func GetIaCIgnoreFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("snyk-cli-extension-iac-ignore", pflag.ExitOnError)
+
+	flagSet.String(FlagID, "", "The ID of the rule to ignore.")
+	flagSet.String(FlagPath, "", "The path of the issues to ignore, as a list of components separated by ' > ' (e.g. 'main.tf > resource > aws_s3_bucket[public]'). Defaults to all paths when adding an ignore.")
+	flagSet.String(FlagReason, "", "The reason for ignoring the issues.")
+	flagSet.String(FlagExpiry, "", "The expiration date of the ignore, as a date (e.g. 2027-01-01) or as an RFC 3339 timestamp.")
+	flagSet.String(FlagPolicyPath, "", "Path to the .snyk policy file, or to the directory containing it.")
+	flagSet.Bool(FlagForce, false, "Add the ignore even if it doesn't match any issue reported by the latest scan.")
+	flagSet.Bool(FlagOffline, false, "Edit the policy file without any request to the Snyk API. The organization settings are read from a local file.")
+
+	return flagSet
+}
@@ -0,0 +1,414 @@
+package iacignore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/snyk/error-catalog-golang-public/cli"
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/workflow"
+
+	"github.com/snyk/cli-extension-iac/internal/commands/iactest"
+	"github.com/snyk/cli-extension-iac/internal/ignore"
+	"github.com/snyk/cli-extension-iac/internal/registry"
+	"github.com/snyk/cli-extension-iac/internal/results"
+	"github.com/snyk/cli-extension-iac/internal/settings"
+)
+
+const (
+	ActionAdd    = "add"
+	ActionList   = "list"
+	ActionExpire = "expire"
+	ActionRemove = "remove"
+)
+
+var actions = []string{ActionAdd, ActionList, ActionExpire, ActionRemove}
+
+// valueFlags are the flags whose value can be passed as a separate argument.
+var valueFlags = []string{FlagID, FlagPath, FlagReason, FlagExpiry, FlagPolicyPath, "org"}
+
+// maxSuggestedPaths is the maximum number of paths suggested to the user when
+// the path of a new ignore doesn't match any issue.
+const maxSuggestedPaths = 5
+
+var WorkflowID = workflow.NewWorkflowIdentifier("iac.ignore")
+
+func RegisterWorkflows(e workflow.Engine) error {
+	flagSet := GetIaCIgnoreFlagSet()
+
+	c := workflow.ConfigurationOptionsFromFlagset(flagSet)
+
+	if _, err := e.Register(WorkflowID, c, IgnoreWorkflow); err != nil {
+		return fmt.Errorf("error while registering %s workflow: %w", WorkflowID, err)
+	}
+
+	return nil
+}
+
+func IgnoreWorkflow(
+	ictx workflow.InvocationContext,
+	_ []workflow.Data,
+) ([]workflow.Data, error) {
+	config := ictx.GetConfiguration()
+	logger := ictx.GetEnhancedLogger()
+
+	action, err := determineAction(os.Args[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("error getting current working directory: %v", err)
+	}
+
+	registryClient := registry.NewClient(registry.ClientConfig{
+		HTTPClient: iactest.NewHTTPClient(ictx),
+		URL:        config.GetString(configuration.API_URL),
+	})
+
+	cmd := ignoreCommand{
+		Action:     action,
+		ID:         config.GetString(FlagID),
+		Path:       config.GetString(FlagPath),
+		Reason:     config.GetString(FlagReason),
+		Expiry:     config.GetString(FlagExpiry),
+		PolicyPath: policyPath(config.GetString(FlagPolicyPath), cwd),
+		Force:      config.GetBool(FlagForce),
+		SettingsReader: &settings.Reader{
+			RegistryClient: iactest.NewSettingsRegistryClient(config, registryClient),
+			Org:            config.GetString(configuration.ORGANIZATION),
+		},
+		ReadLatestResults: func() (*results.Results, error) {
+			path := iactest.LatestResultsPath(config, cwd)
+			if path == "" {
+				return nil, os.ErrNotExist
+			}
+			return iactest.ReadLatestResults(path)
+		},
+		Now:    time.Now,
+		Logger: logger,
+	}
+
+	output, err := cmd.run()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ictx.GetUserInterface().Output(output); err != nil {
+		return nil, fmt.Errorf("display output: %v", err)
+	}
+
+	return []workflow.Data{}, nil
+}
+
+// determineAction returns the action passed as the first positional argument
+// of the command. The values of the flags of the command are skipped.
+func determineAction(args []string) (string, error) {
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if strings.HasPrefix(arg, "-") {
+			if slices.Contains(valueFlags, strings.TrimLeft(arg, "-")) {
+				i++
+			}
+			continue
+		}
+
+		if len(positional) == 0 && (arg == "iac" || arg == "ignore") {
+			continue
+		}
+
+		positional = append(positional, arg)
+	}
+
+	if len(positional) != 1 || !slices.Contains(actions, positional[0]) {
+		return "", cli.NewCommandArgsError(fmt.Sprintf("Please specify one of the actions: %s", strings.Join(actions, ", ")))
+	}
+
+	return positional[0], nil
+}
+
+// policyPath returns the path of the .snyk policy file. The flag value can be
+// the path of the policy file, or the path of the directory containing it.
+func policyPath(flag string, cwd string) string {
+	if flag == "" {
+		return filepath.Join(cwd, iactest.DotSnykPolicy)
+	}
+
+	if info, err := os.Stat(flag); err == nil && info.IsDir() {
+		return filepath.Join(flag, iactest.DotSnykPolicy)
+	}
+
+	return flag
+}
+
+type SettingsReader interface {
+	ReadSettings(ctx context.Context) (*settings.Settings, error)
+}
+
+type ignoreCommand struct {
+	Action            string
+	ID                string
+	Path              string
+	Reason            string
+	Expiry            string
+	PolicyPath        string
+	Force             bool
+	SettingsReader    SettingsReader
+	ReadLatestResults func() (*results.Results, error)
+	Now               func() time.Time
+	Logger            *zerolog.Logger
+}
+
+func (c ignoreCommand) run() (string, error) {
+	editor, err := c.readPolicy()
+	if err != nil {
+		return "", err
+	}
+
+	if c.Action == ActionList {
+		rules, err := editor.Rules()
+		if err != nil {
+			return "", fmt.Errorf("read ignores: %v", err)
+		}
+
+		return renderRules(rules, c.ID, c.Now()), nil
+	}
+
+	if c.ID == "" {
+		return "", cli.NewEmptyFlagOptionError(fmt.Sprintf("The --%s flag is required to %s an ignore", FlagID, c.Action))
+	}
+
+	userSettings, err := c.SettingsReader.ReadSettings(context.Background())
+	if err != nil {
+		return "", cli.NewGeneralIACFailureError(err.Error(), snyk_errors.WithCause(err))
+	}
+
+	if userSettings.IgnoreSettings.AdminOnly {
+		return "", cli.NewGeneralIACFailureError("Only administrators of the organization can change ignores, so the policy file was not modified")
+	}
+
+	var message string
+
+	switch c.Action {
+	case ActionAdd:
+		message, err = c.add(editor, userSettings)
+	case ActionExpire:
+		message, err = c.expire(editor)
+	case ActionRemove:
+		message, err = c.remove(editor)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.writePolicy(editor); err != nil {
+		return "", err
+	}
+
+	return message, nil
+}
+
+func (c ignoreCommand) add(editor *ignore.PolicyEditor, userSettings *settings.Settings) (string, error) {
+	rule := ignore.Rule{
+		ID:      c.ID,
+		Pattern: c.Path,
+		Reason:  c.Reason,
+		Created: c.Now(),
+	}
+
+	if rule.Pattern == "" {
+		rule.Pattern = "*"
+	}
+
+	if userSettings.IgnoreSettings.ReasonRequired && strings.TrimSpace(rule.Reason) == "" {
+		return "", cli.NewEmptyFlagOptionError(fmt.Sprintf("The organization requires a reason for every ignore, please provide one with --%s", FlagReason))
+	}
+
+	if c.Expiry != "" {
+		expires, err := ignore.ParseExpires(c.Expiry)
+		if err != nil {
+			return "", cli.NewInvalidFlagOptionError(fmt.Sprintf("Invalid --%s: %v", FlagExpiry, err))
+		}
+
+		rule.Expires = expires
+	}
+
+	if !c.Force {
+		if err := c.validateRule(rule); err != nil {
+			return "", err
+		}
+	}
+
+	if err := editor.Add(rule); err != nil {
+		return "", cli.NewInvalidFlagOptionError(fmt.Sprintf("Invalid ignore: %v", err))
+	}
+
+	return fmt.Sprintf("Ignored %s for path '%s' in %s\n", rule.ID, rule.Pattern, c.PolicyPath), nil
+}
+
+// validateRule checks that the rule matches at least one issue reported by
+// the latest scan. This catches typos in the rule ID or in the path, that
+// would otherwise result in an ignore that silently matches nothing.
+func (c ignoreCommand) validateRule(rule ignore.Rule) error {
+	latest, err := c.ReadLatestResults()
+	if os.IsNotExist(err) {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("No scan results found to validate the ignore. Run snyk iac test first, or use --%s to add the ignore anyway", FlagForce))
+	}
+	if err != nil {
+		return fmt.Errorf("read latest results: %v", err)
+	}
+
+	var matcher ignore.Matcher
+
+	if err := matcher.AddRule(ignore.Rule{ID: rule.ID, Pattern: rule.Pattern}); err != nil {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("Invalid ignore: %v", err))
+	}
+
+	var (
+		found bool
+		paths []string
+	)
+
+	now := c.Now()
+
+	for _, vulnerabilities := range [][]results.Vulnerability{latest.Vulnerabilities, latest.IgnoredVulnerabilities, latest.BaselineVulnerabilities} {
+		for _, v := range vulnerabilities {
+			if v.Rule.ID != rule.ID {
+				continue
+			}
+
+			found = true
+
+			parts := ignore.PathParts(v.Resource.File, v.Resource.FormattedPath)
+
+			if matcher.Match(rule.ID, now, parts...) {
+				return nil
+			}
+
+			if path := strings.Join(parts, " > "); len(paths) < maxSuggestedPaths && !slices.Contains(paths, path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	if !found {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("%s was not reported by the latest scan. Use --%s to add the ignore anyway", rule.ID, FlagForce))
+	}
+
+	return cli.NewInvalidFlagOptionError(fmt.Sprintf("The path '%s' doesn't match any issue for %s reported by the latest scan. Paths reported for %s include:\n  %s", rule.Pattern, rule.ID, rule.ID, strings.Join(paths, "\n  ")))
+}
+
+func (c ignoreCommand) expire(editor *ignore.PolicyEditor) (string, error) {
+	n, err := editor.Expire(c.ID, c.Path, c.Now())
+	if err != nil {
+		return "", fmt.Errorf("expire ignores: %v", err)
+	}
+
+	if n == 0 {
+		return "", c.noIgnoresError()
+	}
+
+	return fmt.Sprintf("Expired %d ignore(s) for %s in %s\n", n, c.ID, c.PolicyPath), nil
+}
+
+func (c ignoreCommand) remove(editor *ignore.PolicyEditor) (string, error) {
+	n, err := editor.Remove(c.ID, c.Path)
+	if err != nil {
+		return "", fmt.Errorf("remove ignores: %v", err)
+	}
+
+	if n == 0 {
+		return "", c.noIgnoresError()
+	}
+
+	return fmt.Sprintf("Removed %d ignore(s) for %s from %s\n", n, c.ID, c.PolicyPath), nil
+}
+
+func (c ignoreCommand) noIgnoresError() error {
+	if c.Path != "" {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("No ignore found for %s with path '%s' in %s", c.ID, c.Path, c.PolicyPath))
+	}
+
+	return cli.NewInvalidFlagOptionError(fmt.Sprintf("No ignore found for %s in %s", c.ID, c.PolicyPath))
+}
+
+func (c ignoreCommand) readPolicy() (*ignore.PolicyEditor, error) {
+	data, err := os.ReadFile(c.PolicyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read policy file: %v", err)
+	}
+
+	editor, err := ignore.NewPolicyEditor(data)
+	if err != nil {
+		return nil, cli.NewInvalidFlagOptionError(fmt.Sprintf("Invalid policy file %s: %v", c.PolicyPath, err))
+	}
+
+	return editor, nil
+}
+
+func (c ignoreCommand) writePolicy(editor *ignore.PolicyEditor) error {
+	data, err := editor.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(c.PolicyPath, data, 0644); err != nil {
+		return fmt.Errorf("write policy file: %v", err)
+	}
+
+	c.Logger.Debug().Msgf("policy file written to %s", c.PolicyPath)
+
+	return nil
+}
+
+// renderRules renders the ignore rules, optionally restricted to a rule ID.
+func renderRules(rules []ignore.Rule, id string, now time.Time) string {
+	var b strings.Builder
+
+	for _, rule := range rules {
+		if id != "" && rule.ID != id {
+			continue
+		}
+
+		fmt.Fprintf(&b, "%s '%s'\n", rule.ID, rule.Pattern)
+
+		if rule.Reason != "" {
+			fmt.Fprintf(&b, "  Reason: %s\n", rule.Reason)
+		}
+
+		if !rule.Expires.IsZero() {
+			expired := ""
+			if !now.Before(rule.Expires) {
+				expired = " (expired)"
+			}
+
+			fmt.Fprintf(&b, "  Expires: %s%s\n", rule.Expires.Format(time.RFC3339), expired)
+		}
+
+		if !rule.Created.IsZero() {
+			fmt.Fprintf(&b, "  Created: %s\n", rule.Created.Format(time.RFC3339))
+		}
+
+		if rule.IgnoredBy != nil && rule.IgnoredBy.Name != "" {
+			fmt.Fprintf(&b, "  Ignored by: %s\n", rule.IgnoredBy.Name)
+		}
+	}
+
+	if b.Len() == 0 {
+		return "No ignores found.\n"
+	}
+
+	return b.String()
+}
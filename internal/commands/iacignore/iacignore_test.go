@@ -0,0 +1,288 @@
+package iacignore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/results"
+	"github.com/snyk/cli-extension-iac/internal/settings"
+)
+
+type readSettingsFunc func(ctx context.Context) (*settings.Settings, error)
+
+func (f readSettingsFunc) ReadSettings(ctx context.Context) (*settings.Settings, error) {
+	return f(ctx)
+}
+
+const existingPolicy = `# Snyk (https://snyk.io) policy file
+version: v1.25.0
+ignore:
+  # reviewed by the platform team
+  SNYK-CC-K8S-4:
+    - pod.yaml:
+        reason: privileged by design
+patch: {}
+`
+
+var now = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+var latestResults = &results.Results{
+	Vulnerabilities: []results.Vulnerability{
+		{
+			Rule: results.Rule{ID: "SNYK-CC-TF-1"},
+			Resource: results.Resource{
+				File:          "main.tf",
+				FormattedPath: "resource.aws_s3_bucket[public].acl",
+			},
+		},
+	},
+}
+
+func newTestCommand(t *testing.T, action string, ignoreSettings settings.IgnoreSettings) ignoreCommand {
+	t.Helper()
+
+	policyPath := filepath.Join(t.TempDir(), ".snyk")
+	require.NoError(t, os.WriteFile(policyPath, []byte(existingPolicy), 0644))
+
+	logger := zerolog.Nop()
+
+	return ignoreCommand{
+		Action:     action,
+		PolicyPath: policyPath,
+		SettingsReader: readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+			return &settings.Settings{IgnoreSettings: ignoreSettings}, nil
+		}),
+		ReadLatestResults: func() (*results.Results, error) {
+			return latestResults, nil
+		},
+		Now:    func() time.Time { return now },
+		Logger: &logger,
+	}
+}
+
+func readPolicy(t *testing.T, cmd ignoreCommand) string {
+	t.Helper()
+
+	data, err := os.ReadFile(cmd.PolicyPath)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func requireErrorDetail(t *testing.T, err error, detail string) {
+	t.Helper()
+
+	var snykErr snyk_errors.Error
+
+	require.ErrorAs(t, err, &snykErr)
+	require.Contains(t, snykErr.Detail, detail)
+}
+
+func TestDetermineAction(t *testing.T) {
+	tests := []struct {
+		args   []string
+		action string
+	}{
+		{args: []string{"iac", "ignore", "add", "--id=SNYK-CC-TF-1"}, action: ActionAdd},
+		{args: []string{"iac", "ignore", "--id", "SNYK-CC-TF-1", "--debug", "remove"}, action: ActionRemove},
+		{args: []string{"iac", "ignore", "list", "--force"}, action: ActionList},
+		{args: []string{"iac", "ignore", "--reason", "not used", "expire"}, action: ActionExpire},
+	}
+
+	for _, test := range tests {
+		action, err := determineAction(test.args)
+		require.NoError(t, err)
+		require.Equal(t, test.action, action)
+	}
+
+	for _, args := range [][]string{
+		{"iac", "ignore"},
+		{"iac", "ignore", "create"},
+		{"iac", "ignore", "add", "list"},
+	} {
+		_, err := determineAction(args)
+		require.Error(t, err)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	cmd := newTestCommand(t, ActionAdd, settings.IgnoreSettings{})
+	cmd.ID = "SNYK-CC-TF-1"
+	cmd.Path = "main.tf > resource > aws_s3_bucket[public] > acl"
+	cmd.Reason = "public by design"
+	cmd.Expiry = "2027-01-01"
+
+	output, err := cmd.run()
+	require.NoError(t, err)
+	require.Contains(t, output, "Ignored SNYK-CC-TF-1")
+
+	require.Equal(t, `# Snyk (https://snyk.io) policy file
+version: v1.25.0
+ignore:
+  # reviewed by the platform team
+  SNYK-CC-K8S-4:
+    - pod.yaml:
+        reason: privileged by design
+  SNYK-CC-TF-1:
+    - 'main.tf > resource > aws_s3_bucket[public] > acl':
+        reason: public by design
+        expires: 2027-01-01T00:00:00.000Z
+        created: 2026-10-01T00:00:00.000Z
+patch: {}
+`, readPolicy(t, cmd))
+}
+
+func TestAddNewPolicyFile(t *testing.T) {
+	cmd := newTestCommand(t, ActionAdd, settings.IgnoreSettings{})
+	cmd.ID = "SNYK-CC-TF-1"
+	cmd.PolicyPath = filepath.Join(t.TempDir(), ".snyk")
+
+	_, err := cmd.run()
+	require.NoError(t, err)
+
+	require.Equal(t, `version: v1.25.0
+ignore:
+  SNYK-CC-TF-1:
+    - '*':
+        created: 2026-10-01T00:00:00.000Z
+`, readPolicy(t, cmd))
+}
+
+func TestAddValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		id    string
+		path  string
+		force bool
+		error string
+	}{
+		{
+			name:  "unknown rule",
+			id:    "SNYK-CC-TF-2",
+			error: "SNYK-CC-TF-2 was not reported by the latest scan",
+		},
+		{
+			name:  "path with a typo",
+			id:    "SNYK-CC-TF-1",
+			path:  "main.tf > resource > aws_s3_bucket[pubilc] > acl",
+			error: "main.tf > resource > aws_s3_bucket[public] > acl",
+		},
+		{
+			name:  "path with the wrong separator",
+			id:    "SNYK-CC-TF-1",
+			path:  "main.tf.resource.aws_s3_bucket[public].acl",
+			error: "doesn't match any issue for SNYK-CC-TF-1",
+		},
+		{
+			name:  "forced",
+			id:    "SNYK-CC-TF-2",
+			path:  "main.tf",
+			force: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := newTestCommand(t, ActionAdd, settings.IgnoreSettings{})
+			cmd.ID = test.id
+			cmd.Path = test.path
+			cmd.Force = test.force
+
+			_, err := cmd.run()
+
+			if test.error == "" {
+				require.NoError(t, err)
+				require.NotEqual(t, existingPolicy, readPolicy(t, cmd))
+			} else {
+				requireErrorDetail(t, err, test.error)
+				require.Equal(t, existingPolicy, readPolicy(t, cmd))
+			}
+		})
+	}
+}
+
+func TestAddWithoutLatestResults(t *testing.T) {
+	cmd := newTestCommand(t, ActionAdd, settings.IgnoreSettings{})
+	cmd.ID = "SNYK-CC-TF-1"
+	cmd.ReadLatestResults = func() (*results.Results, error) {
+		return nil, os.ErrNotExist
+	}
+
+	_, err := cmd.run()
+	requireErrorDetail(t, err, "No scan results found")
+}
+
+func TestAddReasonRequired(t *testing.T) {
+	cmd := newTestCommand(t, ActionAdd, settings.IgnoreSettings{ReasonRequired: true})
+	cmd.ID = "SNYK-CC-TF-1"
+
+	_, err := cmd.run()
+	requireErrorDetail(t, err, "requires a reason")
+	require.Equal(t, existingPolicy, readPolicy(t, cmd))
+}
+
+func TestAdminOnly(t *testing.T) {
+	for _, action := range []string{ActionAdd, ActionExpire, ActionRemove} {
+		t.Run(action, func(t *testing.T) {
+			cmd := newTestCommand(t, action, settings.IgnoreSettings{AdminOnly: true})
+			cmd.ID = "SNYK-CC-K8S-4"
+
+			_, err := cmd.run()
+			requireErrorDetail(t, err, "Only administrators")
+			require.Equal(t, existingPolicy, readPolicy(t, cmd))
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	cmd := newTestCommand(t, ActionList, settings.IgnoreSettings{AdminOnly: true})
+
+	output, err := cmd.run()
+	require.NoError(t, err)
+	require.Equal(t, "SNYK-CC-K8S-4 'pod.yaml'\n  Reason: privileged by design\n", output)
+
+	cmd.ID = "SNYK-CC-TF-1"
+
+	output, err = cmd.run()
+	require.NoError(t, err)
+	require.Equal(t, "No ignores found.\n", output)
+}
+
+func TestExpire(t *testing.T) {
+	cmd := newTestCommand(t, ActionExpire, settings.IgnoreSettings{})
+	cmd.ID = "SNYK-CC-K8S-4"
+
+	output, err := cmd.run()
+	require.NoError(t, err)
+	require.Contains(t, output, "Expired 1 ignore(s)")
+	require.Contains(t, readPolicy(t, cmd), "expires: 2026-10-01T00:00:00.000Z")
+
+	cmd.Action = ActionList
+
+	output, err = cmd.run()
+	require.NoError(t, err)
+	require.Contains(t, output, "Expires: 2026-10-01T00:00:00Z (expired)")
+}
+
+func TestRemove(t *testing.T) {
+	cmd := newTestCommand(t, ActionRemove, settings.IgnoreSettings{})
+	cmd.ID = "SNYK-CC-K8S-4"
+	cmd.Path = "other.yaml"
+
+	_, err := cmd.run()
+	requireErrorDetail(t, err, "No ignore found for SNYK-CC-K8S-4 with path 'other.yaml'")
+
+	cmd.Path = ""
+
+	output, err := cmd.run()
+	require.NoError(t, err)
+	require.Contains(t, output, "Removed 1 ignore(s)")
+	require.Equal(t, "# Snyk (https://snyk.io) policy file\nversion: v1.25.0\nignore: {}\npatch: {}\n", readPolicy(t, cmd))
+}
@@ -0,0 +1,33 @@
+package iacrules
+
+import "github.com/spf13/pflag"
+
+const (
+	FlagRules            = "rules"
+	FlagJUnitFileOutput  = "junit-file-output"
+	FlagJson             = "json"
+	FlagInputType        = "input-type"
+	FlagControlFramework = "control-framework"
+	FlagOffline          = "offline"
+)
+
+func GetIaCRulesTestFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("snyk-cli-extension-iac-rules-test", pflag.ExitOnError)
+
+	flagSet.String(FlagRules, "", "Path to a directory of Rego files or a .tar.gz bundle containing the custom rules to test.")
+	flagSet.String(FlagJUnitFileOutput, "", "Save the test results as a JUnit XML report to the specified file.")
+
+	return flagSet
+}
+
+func GetIaCRulesListFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("snyk-cli-extension-iac-rules-list", pflag.ExitOnError)
+
+	flagSet.String(FlagRules, "", "Path to a directory of Rego files or a .tar.gz bundle containing custom rules to list.")
+	flagSet.Bool(FlagJson, false, "Print the rules as JSON.")
+	flagSet.String(FlagInputType, "", "Only list the rules for these input types, as a comma-separated list (arm, cloudformation, kubernetes, terraform).")
+	flagSet.String(FlagControlFramework, "", "Only list the rules mapped to controls of these frameworks, as a comma-separated list (e.g. CIS-AWS,HIPAA).")
+	flagSet.Bool(FlagOffline, false, "List the rules without any request to the Snyk API. The rule bundle and the organization settings are read from local files.")
+
+	return flagSet
+}
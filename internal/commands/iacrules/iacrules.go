@@ -0,0 +1,179 @@
+package iacrules
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/snyk/error-catalog-golang-public/cli"
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+	"github.com/snyk/go-application-framework/pkg/workflow"
+	"github.com/spf13/afero"
+
+	"github.com/snyk/cli-extension-iac/internal/junit"
+	"github.com/snyk/cli-extension-iac/internal/rules"
+	"github.com/snyk/cli-extension-iac/internal/rulestest"
+)
+
+// valueFlags are the flags whose value can be passed as a separate argument.
+var valueFlags = []string{FlagRules, FlagJUnitFileOutput}
+
+var TestWorkflowID = workflow.NewWorkflowIdentifier("iac.rules.test")
+
+func RegisterWorkflows(e workflow.Engine) error {
+	c := workflow.ConfigurationOptionsFromFlagset(GetIaCRulesTestFlagSet())
+
+	if _, err := e.Register(TestWorkflowID, c, TestWorkflow); err != nil {
+		return fmt.Errorf("error while registering %s workflow: %w", TestWorkflowID, err)
+	}
+
+	c = workflow.ConfigurationOptionsFromFlagset(GetIaCRulesListFlagSet())
+
+	if _, err := e.Register(ListWorkflowID, c, ListWorkflow); err != nil {
+		return fmt.Errorf("error while registering %s workflow: %w", ListWorkflowID, err)
+	}
+
+	return nil
+}
+
+func TestWorkflow(
+	ictx workflow.InvocationContext,
+	_ []workflow.Data,
+) ([]workflow.Data, error) {
+	config := ictx.GetConfiguration()
+
+	fixtures, err := determineFixtures(os.Args[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	var output strings.Builder
+
+	cmd := testCommand{
+		FS:          afero.NewOsFs(),
+		Rules:       config.GetString(FlagRules),
+		Fixtures:    fixtures,
+		JUnitOutput: config.GetString(FlagJUnitFileOutput),
+		Output:      &output,
+		Logger:      ictx.GetEnhancedLogger(),
+	}
+
+	runErr := cmd.run()
+
+	if output.Len() > 0 {
+		if err := ictx.GetUserInterface().Output(output.String()); err != nil {
+			return nil, fmt.Errorf("display output: %v", err)
+		}
+	}
+
+	if runErr != nil {
+		return nil, runErr
+	}
+
+	return []workflow.Data{}, nil
+}
+
+// determineFixtures returns the directory of fixtures passed as positional
+// argument of the command, or the current directory. The values of the flags
+// of the command are skipped.
+func determineFixtures(args []string) (string, error) {
+	var positional []string
+
+	// The words of the command are skipped in order, so that a directory of
+	// fixtures named "test" is not mistaken for the command.
+	command := []string{"iac", "rules", "test"}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if strings.HasPrefix(arg, "-") {
+			if slices.Contains(valueFlags, strings.TrimLeft(arg, "-")) {
+				i++
+			}
+			continue
+		}
+
+		if len(positional) == 0 && len(command) > 0 && arg == command[0] {
+			command = command[1:]
+			continue
+		}
+
+		positional = append(positional, arg)
+	}
+
+	switch len(positional) {
+	case 0:
+		return ".", nil
+	case 1:
+		return positional[0], nil
+	default:
+		return "", cli.NewCommandArgsError("Please specify a single directory of fixtures")
+	}
+}
+
+type testCommand struct {
+	FS          afero.Fs
+	Rules       string
+	Fixtures    string
+	JUnitOutput string
+	Output      io.Writer
+	Logger      *zerolog.Logger
+}
+
+func (c testCommand) run() error {
+	if c.Rules == "" {
+		return cli.NewEmptyFlagOptionError(fmt.Sprintf("The --%s flag is required to test custom rules", FlagRules))
+	}
+
+	customRules, err := rules.ReadLocalBundle(c.FS, c.Rules)
+	if err != nil {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("Invalid --%s: %v", FlagRules, err))
+	}
+
+	runner := rulestest.Runner{
+		FS:       c.FS,
+		Rules:    customRules,
+		Fixtures: c.Fixtures,
+		Logger:   c.Logger,
+	}
+
+	report, err := runner.Run(context.Background())
+	if err != nil {
+		return cli.NewGeneralIACFailureError(err.Error(), snyk_errors.WithCause(err))
+	}
+
+	if err := rulestest.Render(c.Output, report); err != nil {
+		return fmt.Errorf("render report: %v", err)
+	}
+
+	if c.JUnitOutput != "" {
+		if err := c.writeJUnit(report); err != nil {
+			return fmt.Errorf("write JUnit output: %v", err)
+		}
+	}
+
+	if failures := report.Failures(); failures > 0 {
+		return cli.NewGeneralIACFailureError(fmt.Sprintf("%d of %d rule tests failed", failures, len(report.Cases)))
+	}
+
+	return nil
+}
+
+func (c testCommand) writeJUnit(report *rulestest.Report) (e error) {
+	f, err := c.FS.Create(c.JUnitOutput)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil && e == nil {
+			e = err
+		}
+	}()
+
+	return junit.Encode(f, rulestest.JUnit(report))
+}
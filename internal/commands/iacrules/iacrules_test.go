@@ -0,0 +1,112 @@
+package iacrules
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const rule = `package rules.custom_s3_acl
+
+metadata := {
+	"id": "CUSTOM-1",
+	"title": "S3 bucket is public",
+	"severity": "high",
+}
+
+input_type := "tf"
+
+resource_type := "aws_s3_bucket"
+
+deny[info] {
+	input.acl == "public-read"
+	info := {"message": "The bucket is public"}
+}
+`
+
+const fixture = `resource "aws_s3_bucket" "public" {
+  acl = "public-read"
+}
+`
+
+func newTestCommand(t *testing.T, expected string) (testCommand, *bytes.Buffer) {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("rules", "s3.rego"), []byte(rule), 0644))
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("tests", "s3.tf"), []byte(fixture), 0644))
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("tests", "s3.tf.expected.json"), []byte(expected), 0644))
+
+	logger := zerolog.Nop()
+
+	var output bytes.Buffer
+
+	return testCommand{
+		FS:          fs,
+		Rules:       "rules",
+		Fixtures:    "tests",
+		JUnitOutput: "junit.xml",
+		Output:      &output,
+		Logger:      &logger,
+	}, &output
+}
+
+func TestRun(t *testing.T) {
+	cmd, output := newTestCommand(t, `{"CUSTOM-1": {"failing": ["aws_s3_bucket.public"]}}`)
+
+	require.NoError(t, cmd.run())
+	require.Equal(t, "PASS s3.tf: CUSTOM-1\n\n1 tests, 0 failed\n", output.String())
+
+	junit, err := afero.ReadFile(cmd.FS, "junit.xml")
+	require.NoError(t, err)
+	require.Contains(t, string(junit), `<testsuites name="Snyk IaC custom rules" tests="1" failures="0" skipped="0">`)
+}
+
+func TestRunFailure(t *testing.T) {
+	cmd, output := newTestCommand(t, `{"CUSTOM-1": {"failing": []}}`)
+
+	err := cmd.run()
+
+	var snykErr snyk_errors.Error
+	require.ErrorAs(t, err, &snykErr)
+	require.Equal(t, "1 of 1 rule tests failed", snykErr.Detail)
+
+	require.Contains(t, output.String(), "FAIL s3.tf: CUSTOM-1\n    + failing aws_s3_bucket.public\n")
+
+	junit, err := afero.ReadFile(cmd.FS, "junit.xml")
+	require.NoError(t, err)
+	require.Contains(t, string(junit), `<failure message="unexpected rule results" type="assertion">+ failing aws_s3_bucket.public</failure>`)
+}
+
+func TestRunWithoutRules(t *testing.T) {
+	cmd, _ := newTestCommand(t, `{}`)
+	cmd.Rules = ""
+
+	require.Error(t, cmd.run())
+}
+
+func TestDetermineFixtures(t *testing.T) {
+	tests := []struct {
+		args     []string
+		fixtures string
+	}{
+		{args: []string{"iac", "rules", "test", "--rules=./rules"}, fixtures: "."},
+		{args: []string{"iac", "rules", "test", "--rules", "./rules", "fixtures"}, fixtures: "fixtures"},
+		{args: []string{"iac", "rules", "test", "--junit-file-output", "junit.xml", "test"}, fixtures: "test"},
+	}
+
+	for _, test := range tests {
+		fixtures, err := determineFixtures(test.args)
+		require.NoError(t, err)
+		require.Equal(t, test.fixtures, fixtures)
+	}
+
+	_, err := determineFixtures([]string{"iac", "rules", "test", "a", "b"})
+	require.Error(t, err)
+}
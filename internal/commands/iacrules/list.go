@@ -0,0 +1,346 @@
+package iacrules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog"
+	"github.com/snyk/error-catalog-golang-public/cli"
+	"github.com/snyk/error-catalog-golang-public/snyk_errors"
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/workflow"
+	"github.com/snyk/policy-engine/pkg/bundle"
+	"github.com/snyk/policy-engine/pkg/input"
+	"github.com/snyk/policy-engine/pkg/policy"
+	"github.com/spf13/afero"
+
+	"github.com/snyk/cli-extension-iac/internal/cloudapi"
+	"github.com/snyk/cli-extension-iac/internal/command"
+	"github.com/snyk/cli-extension-iac/internal/commands/iactest"
+	"github.com/snyk/cli-extension-iac/internal/controls"
+	engine "github.com/snyk/cli-extension-iac/internal/policyengine"
+	"github.com/snyk/cli-extension-iac/internal/registry"
+	"github.com/snyk/cli-extension-iac/internal/settings"
+)
+
+var ListWorkflowID = workflow.NewWorkflowIdentifier("iac.rules.list")
+
+// inputTypes maps the input types accepted by --input-type to the input types
+// listed for the rules.
+var inputTypes = map[string]string{
+	"arm":            "arm",
+	"cfn":            "cloudformation",
+	"cloudformation": "cloudformation",
+	"k8s":            "kubernetes",
+	"kubernetes":     "kubernetes",
+	"tf":             "terraform",
+	"terraform":      "terraform",
+}
+
+// listedInputTypes are the input types listed for the rules, and the input
+// types of the policy-engine they stand for.
+var listedInputTypes = []struct {
+	name      string
+	inputType *input.Type
+}{
+	{name: "arm", inputType: input.Arm},
+	{name: "cloudformation", inputType: input.CloudFormation},
+	{name: "kubernetes", inputType: input.Kubernetes},
+	{name: "terraform", inputType: input.Terraform},
+}
+
+func ListWorkflow(
+	ictx workflow.InvocationContext,
+	_ []workflow.Data,
+) ([]workflow.Data, error) {
+	config := ictx.GetConfiguration()
+	logger := ictx.GetEnhancedLogger()
+	httpClient := iactest.NewHTTPClient(ictx)
+
+	rulesClient, err := iactest.NewRulesClient(config, httpClient, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	apiURL := config.GetString(configuration.API_URL)
+
+	registryClient := registry.NewClient(registry.ClientConfig{
+		HTTPClient: httpClient,
+		URL:        apiURL,
+	})
+
+	cloudapiClient := cloudapi.NewClient(cloudapi.ClientConfig{
+		HTTPClient: httpClient,
+		URL:        apiURL,
+		Version:    "2022-04-13~experimental",
+	})
+
+	var output strings.Builder
+
+	cmd := listCommand{
+		Bundles: command.Command{
+			FS:                      afero.NewOsFs(),
+			Bundle:                  config.GetString(iactest.RulesBundlePath),
+			BundleDownloader:        rulesClient,
+			ReadPolicyEngineVersion: command.ReadRuntimePolicyEngineVersion,
+			LocalRules:              iactest.LocalRulePaths(config),
+			SnykClient:              cloudapiClient,
+			Offline:                 config.GetBool(FlagOffline),
+			Logger:                  logger,
+		},
+		SettingsReader: &settings.Reader{
+			RegistryClient: iactest.NewSettingsRegistryClient(config, registryClient),
+			Org:            config.GetString(configuration.ORGANIZATION),
+		},
+		InputTypes:        splitList(config.GetString(FlagInputType)),
+		ControlFrameworks: splitList(config.GetString(FlagControlFramework)),
+		JSON:              config.GetBool(FlagJson),
+		Output:            &output,
+		Logger:            logger,
+	}
+
+	if err := cmd.run(); err != nil {
+		return nil, err
+	}
+
+	if err := ictx.GetUserInterface().Output(output.String()); err != nil {
+		return nil, fmt.Errorf("display output: %v", err)
+	}
+
+	return []workflow.Data{}, nil
+}
+
+type SettingsReader interface {
+	ReadSettings(ctx context.Context) (*settings.Settings, error)
+}
+
+type RuleBundlesReader interface {
+	RuleBundles(ctx context.Context, orgID string) (io.ReadCloser, []bundle.Reader, error)
+}
+
+// Rule is the metadata of a rule printed by the list command.
+type Rule struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Severity   string   `json:"severity"`
+	InputTypes []string `json:"input_types"`
+	Category   string   `json:"category,omitempty"`
+	Labels     []string `json:"labels,omitempty"`
+	Controls   []string `json:"controls,omitempty"`
+}
+
+type listCommand struct {
+	Bundles           RuleBundlesReader
+	SettingsReader    SettingsReader
+	InputTypes        []string
+	ControlFrameworks []string
+	JSON              bool
+	Output            io.Writer
+	Logger            *zerolog.Logger
+}
+
+func (c listCommand) run() error {
+	var wantedInputTypes []string
+
+	for _, inputType := range c.InputTypes {
+		normalized, ok := inputTypes[strings.ToLower(inputType)]
+		if !ok {
+			return cli.NewInvalidFlagOptionError(fmt.Sprintf("Unsupported value %s provided to --%s. Supported values are: arm, cloudformation, kubernetes, terraform", inputType, FlagInputType))
+		}
+
+		wantedInputTypes = append(wantedInputTypes, normalized)
+	}
+
+	ctx := context.Background()
+
+	userSettings, err := c.SettingsReader.ReadSettings(ctx)
+	if err != nil {
+		return cli.NewGeneralIACFailureError(err.Error(), snyk_errors.WithCause(err))
+	}
+
+	snykBundle, customRules, err := c.Bundles.RuleBundles(ctx, userSettings.OrgPublicID)
+	if err != nil {
+		return cli.NewGeneralIACFailureError(fmt.Sprintf("Unable to load the rules: %v", err), snyk_errors.WithCause(err))
+	}
+
+	// The Snyk bundle is read once by the engine and once more to find the
+	// input types of its policies.
+	var snykBundleData []byte
+
+	if snykBundle != nil {
+		snykBundleData, err = io.ReadAll(snykBundle)
+		_ = snykBundle.Close()
+		if err != nil {
+			return cli.NewGeneralIACFailureError(fmt.Sprintf("Unable to load the rules: %v", err), snyk_errors.WithCause(err))
+		}
+	}
+
+	e := engine.NewEngine(ctx, engine.EngineOptions{
+		SnykBundle:        bundleReadCloser(snykBundleData),
+		CustomRuleBundles: customRules,
+		Logger:            c.Logger,
+	})
+
+	// The rules of the bundles that were loaded are still listed.
+	for _, err := range e.InitializationErrors() {
+		c.Logger.Warn().Err(err).Msg("load rules")
+	}
+
+	metadata, err := e.Metadata(ctx)
+	if err != nil {
+		return fmt.Errorf("read rules metadata: %v", err)
+	}
+
+	policyInputTypes := engine.PolicyInputTypes(ctx, bundleReadCloser(snykBundleData), customRules)
+
+	var rules []Rule
+
+	for _, m := range metadata {
+		if m.Error != "" {
+			c.Logger.Warn().Msgf("read metadata of %s: %s", m.Package, m.Error)
+			continue
+		}
+
+		if m.Metadata.ID == "" {
+			continue
+		}
+
+		rule := Rule{
+			ID:         m.Metadata.ID,
+			Title:      m.Metadata.Title,
+			Severity:   m.Metadata.Severity,
+			InputTypes: ruleInputTypes(policyInputTypes[m.Package]),
+			Category:   m.Metadata.Category,
+			Labels:     m.Metadata.Labels,
+			Controls:   m.Metadata.Controls,
+		}
+
+		if matchesInputTypes(rule, wantedInputTypes) && matchesControlFrameworks(rule, c.ControlFrameworks) {
+			rules = append(rules, rule)
+		}
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+
+	if c.JSON {
+		return renderRulesJSON(c.Output, rules)
+	}
+
+	return renderRulesTable(c.Output, rules)
+}
+
+// bundleReadCloser returns a reader of the Snyk bundle, or nil if there is no
+// Snyk bundle.
+func bundleReadCloser(data []byte) io.ReadCloser {
+	if data == nil {
+		return nil
+	}
+
+	return io.NopCloser(bytes.NewReader(data))
+}
+
+// ruleInputTypes returns the listed input types matching the input type of the
+// policy of a rule. A policy for any input type matches all of them, and an
+// unknown input type is listed as it is.
+func ruleInputTypes(policyInputType string) []string {
+	result := []string{}
+
+	if policyInputType == "" {
+		return result
+	}
+
+	t, err := policy.SupportedInputTypes.FromString(policyInputType)
+	if err != nil {
+		return append(result, policyInputType)
+	}
+
+	for _, listed := range listedInputTypes {
+		if listed.inputType.Matches(t.Name) || t.Matches(listed.inputType.Name) {
+			result = append(result, listed.name)
+		}
+	}
+
+	return result
+}
+
+func matchesInputTypes(rule Rule, inputTypes []string) bool {
+	if len(inputTypes) == 0 {
+		return true
+	}
+
+	for _, inputType := range inputTypes {
+		for _, ruleInputType := range rule.InputTypes {
+			if inputType == ruleInputType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchesControlFrameworks returns true if the rule is mapped to a control of
+// one of the frameworks. A framework can be given with or without its version.
+func matchesControlFrameworks(rule Rule, frameworks []string) bool {
+	if len(frameworks) == 0 {
+		return true
+	}
+
+	return controls.MatchesAny(rule.Controls, frameworks)
+}
+
+func renderRulesJSON(w io.Writer, rules []Rule) error {
+	if rules == nil {
+		rules = []Rule{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(rules)
+}
+
+func renderRulesTable(w io.Writer, rules []Rule) error {
+	if len(rules) == 0 {
+		_, err := io.WriteString(w, "No rules found\n")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "ID\tTITLE\tSEVERITY\tINPUT TYPES\tCATEGORY\tLABELS\tCONTROLS")
+
+	for _, rule := range rules {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			rule.ID,
+			rule.Title,
+			rule.Severity,
+			strings.Join(rule.InputTypes, ", "),
+			rule.Category,
+			strings.Join(rule.Labels, ", "),
+			strings.Join(rule.Controls, ", "),
+		)
+	}
+
+	return tw.Flush()
+}
+
+func splitList(v string) []string {
+	var result []string
+
+	for _, s := range strings.Split(v, ",") {
+		if s := strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
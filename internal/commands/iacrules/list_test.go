@@ -0,0 +1,206 @@
+package iacrules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/snyk/policy-engine/pkg/bundle"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/rules"
+	"github.com/snyk/cli-extension-iac/internal/settings"
+)
+
+const k8sRule = `package rules.custom_k8s_privileged
+
+metadata := {
+	"id": "CUSTOM-K8S-1",
+	"title": "Container is privileged",
+	"severity": "high",
+	"category": "Security",
+	"labels": ["containers"],
+	"remediation": {"kubernetes": "Set privileged to false"},
+	"controls": {"CIS-Kubernetes": {"v1.6.0": ["5.2.1"]}},
+}
+
+input_type := "k8s"
+
+resource_type := "Pod"
+
+deny[info] {
+	input.spec.containers[_].securityContext.privileged
+	info := {}
+}
+`
+
+const armRule = `package rules.custom_arm_https
+
+metadata := {
+	"id": "CUSTOM-ARM-1",
+	"title": "Storage account allows HTTP",
+	"severity": "medium",
+	"controls": {"CIS-Azure": {"v1.3.0": ["3.1"]}},
+}
+
+input_type := "arm"
+
+resource_type := "Microsoft.Storage/storageAccounts"
+
+deny[info] {
+	not input.properties.supportsHttpsTrafficOnly
+	info := {}
+}
+`
+
+type ruleBundlesFunc func(ctx context.Context, orgID string) (io.ReadCloser, []bundle.Reader, error)
+
+func (f ruleBundlesFunc) RuleBundles(ctx context.Context, orgID string) (io.ReadCloser, []bundle.Reader, error) {
+	return f(ctx, orgID)
+}
+
+type readSettingsFunc func(ctx context.Context) (*settings.Settings, error)
+
+func (f readSettingsFunc) ReadSettings(ctx context.Context) (*settings.Settings, error) {
+	return f(ctx)
+}
+
+func newListCommand(t *testing.T) (listCommand, *bytes.Buffer) {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("rules", "k8s.rego"), []byte(k8sRule), 0644))
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("rules", "arm.rego"), []byte(armRule), 0644))
+
+	customRules, err := rules.ReadLocalBundle(fs, "rules")
+	require.NoError(t, err)
+
+	logger := zerolog.Nop()
+
+	var output bytes.Buffer
+
+	return listCommand{
+		Bundles: ruleBundlesFunc(func(ctx context.Context, orgID string) (io.ReadCloser, []bundle.Reader, error) {
+			require.Equal(t, "org-public-id", orgID)
+			return nil, []bundle.Reader{customRules}, nil
+		}),
+		SettingsReader: readSettingsFunc(func(ctx context.Context) (*settings.Settings, error) {
+			return &settings.Settings{OrgPublicID: "org-public-id"}, nil
+		}),
+		Output: &output,
+		Logger: &logger,
+	}, &output
+}
+
+func TestList(t *testing.T) {
+	cmd, output := newListCommand(t)
+	cmd.JSON = true
+
+	require.NoError(t, cmd.run())
+
+	var listed []Rule
+	require.NoError(t, json.Unmarshal(output.Bytes(), &listed))
+
+	require.Equal(t, []Rule{
+		{
+			ID:         "CUSTOM-ARM-1",
+			Title:      "Storage account allows HTTP",
+			Severity:   "medium",
+			InputTypes: []string{"arm"},
+			Controls:   []string{"CIS-Azure_v1.3.0_3.1"},
+		},
+		{
+			ID:         "CUSTOM-K8S-1",
+			Title:      "Container is privileged",
+			Severity:   "high",
+			InputTypes: []string{"kubernetes"},
+			Category:   "Security",
+			Labels:     []string{"containers"},
+			Controls:   []string{"CIS-Kubernetes_v1.6.0_5.2.1"},
+		},
+	}, listed)
+}
+
+func TestListTable(t *testing.T) {
+	cmd, output := newListCommand(t)
+	cmd.InputTypes = []string{"k8s"}
+
+	require.NoError(t, cmd.run())
+	require.Equal(t, ""+
+		"ID            TITLE                    SEVERITY  INPUT TYPES  CATEGORY  LABELS      CONTROLS\n"+
+		"CUSTOM-K8S-1  Container is privileged  high      kubernetes   Security  containers  CIS-Kubernetes_v1.6.0_5.2.1\n",
+		output.String())
+}
+
+func TestListFilters(t *testing.T) {
+	tests := []struct {
+		name              string
+		inputTypes        []string
+		controlFrameworks []string
+		ids               []string
+	}{
+		{name: "input type alias", inputTypes: []string{"kubernetes"}, ids: []string{"CUSTOM-K8S-1"}},
+		{name: "several input types", inputTypes: []string{"ARM", "k8s"}, ids: []string{"CUSTOM-ARM-1", "CUSTOM-K8S-1"}},
+		{name: "unmatched input type", inputTypes: []string{"terraform"}, ids: nil},
+		{name: "control framework", controlFrameworks: []string{"cis-azure"}, ids: []string{"CUSTOM-ARM-1"}},
+		{name: "control framework version", controlFrameworks: []string{"CIS-Kubernetes_v1.6.0"}, ids: []string{"CUSTOM-K8S-1"}},
+		{name: "unmatched control framework version", controlFrameworks: []string{"CIS-Kubernetes_v1.5.0"}, ids: nil},
+		{name: "both filters", inputTypes: []string{"arm"}, controlFrameworks: []string{"CIS-Kubernetes"}, ids: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, output := newListCommand(t)
+			cmd.JSON = true
+			cmd.InputTypes = tt.inputTypes
+			cmd.ControlFrameworks = tt.controlFrameworks
+
+			require.NoError(t, cmd.run())
+
+			var listed []Rule
+			require.NoError(t, json.Unmarshal(output.Bytes(), &listed))
+
+			var ids []string
+			for _, rule := range listed {
+				ids = append(ids, rule.ID)
+			}
+
+			require.Equal(t, tt.ids, ids)
+		})
+	}
+}
+
+func TestListInvalidInputType(t *testing.T) {
+	cmd, _ := newListCommand(t)
+	cmd.InputTypes = []string{"helm"}
+
+	require.Error(t, cmd.run())
+}
+
+func TestRuleInputTypes(t *testing.T) {
+	tests := []struct {
+		policyInputType string
+		inputTypes      []string
+	}{
+		{policyInputType: "arm", inputTypes: []string{"arm"}},
+		{policyInputType: "cfn", inputTypes: []string{"cloudformation"}},
+		{policyInputType: "k8s", inputTypes: []string{"kubernetes"}},
+		{policyInputType: "tf", inputTypes: []string{"terraform"}},
+		{policyInputType: "tf_hcl", inputTypes: []string{"terraform"}},
+		{policyInputType: "any", inputTypes: []string{"arm", "cloudformation", "kubernetes", "terraform"}},
+		{policyInputType: "custom", inputTypes: []string{"custom"}},
+		{policyInputType: "", inputTypes: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policyInputType, func(t *testing.T) {
+			require.Equal(t, tt.inputTypes, ruleInputTypes(tt.policyInputType))
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 const (
 	FlagReport                     = "report"
 	FlagSeverityThreshold          = "severity-threshold"
+	FlagSeverityOverrides          = "severity-overrides"
 	FlagIgnorePolicy               = "ignore-policy"
 	FlagPolicyPath                 = "policy-path"
 	FlagTargetReference            = "target-reference"
@@ -16,16 +17,35 @@ const (
 	FlagScan                       = "scan"
 	FlagDepthDetection             = "detection-depth"
 	FlagVarFile                    = "var-file"
+	FlagVar                        = "var"
+	FlagHelmValues                 = "helm-values"
 	FlagJson                       = "json"
 	FlagJsonFileOutput             = "json-file-output"
 	FlagSarif                      = "sarif"
 	FlagSarifFileOutput            = "sarif-file-output"
+	FlagJUnitFileOutput            = "junit-file-output"
+	FlagComplianceFileOutput       = "compliance-file-output"
+	FlagComplianceMarkdownOutput   = "compliance-markdown-file-output"
 	FlagProjectBusinessCriticality = "project-business-criticality"
 	FlagProjectEnvironment         = "project-environment"
 	FlagProjectLifecycle           = "project-lifecycle"
 	FlagProjectTags                = "project-tags"
 	FlagRules                      = "rules"
 	FlagExclude                    = "exclude"
+	FlagBaseline                   = "baseline"
+	FlagBaselineWrite              = "baseline-write"
+	FlagChangedSince               = "changed-since"
+	FlagFailOnStaleIgnores         = "fail-on-stale-ignores"
+	FlagFailOn                     = "fail-on"
+	FlagMaxIssues                  = "max-issues"
+	FlagFailOnWarnings             = "fail-on-warnings"
+	FlagOffline                    = "offline"
+	FlagRulesBundleVersion         = "rules-bundle-version"
+	FlagIncludeRules               = "include-rules"
+	FlagExcludeRules               = "exclude-rules"
+	FlagIncludeLabels              = "include-labels"
+	FlagCategories                 = "categories"
+	FlagControls                   = "controls"
 )
 
 func GetIaCTestFlagSet() *pflag.FlagSet {
@@ -42,10 +62,13 @@ func GetIaCTestFlagSet() *pflag.FlagSet {
 	flagSet.String(FlagSnykCloudEnvironment, "", "ID of the Snyk Cloud environment to get context for scan.")
 	//nolint:lll // Long flag description
 	flagSet.String(FlagScan, "resource-changes", "Use this dedicated option for Terraform plan scanning modes to control whether the scan analyzes the full final state or the proposed changes only.")
-	flagSet.String(FlagVarFile, "", "Use this option to load a terraform variable definitions file that is located in a different directory from the scanned one.")
-	flagSet.Bool(FlagIgnorePolicy, false, "Ignore the policy file.")
+	flagSet.StringArray(FlagVarFile, nil, "Use this option to load a terraform variable definitions file (.tfvars or .tfvars.json) that is located in a different directory from the scanned one. Can be repeated, later files take precedence.")
+	flagSet.StringArray(FlagVar, nil, "Set a terraform variable, in the key=value format. Can be repeated, and takes precedence over the variable definitions files.")
+	flagSet.String(FlagHelmValues, "", "Values files used to render the Helm charts, in addition to the values of the charts (comma-separated). Later files take precedence.")
+	flagSet.Bool(FlagIgnorePolicy, false, "Ignore the policy file and the inline ignore comments.")
 	flagSet.String(FlagPolicyPath, "", "Path to a .snyk policy file.")
 	flagSet.String(FlagSeverityThreshold, "", "Report only vulnerabilities at the specified level or higher.")
+	flagSet.String(FlagSeverityOverrides, "", "Path to a file overriding the severity of rules, on top of the custom severities of the organization.")
 	flagSet.Bool(FlagReport, false, "Share results with the Snyk Web UI.")
 	flagSet.String(FlagTargetName, "", "Used in Share Results to set or override the project name for the repository. ")
 	flagSet.String(FlagTargetReference, "", "Used in Share Results to specify a reference which differentiates this project, e.g. a branch name or version.")
@@ -54,12 +77,29 @@ func GetIaCTestFlagSet() *pflag.FlagSet {
 	flagSet.String(FlagJsonFileOutput, "", "Save test output as a JSON data structure directly to the specified file, regardless of whether or not you use the --json option.")
 	flagSet.Bool(FlagSarif, false, "Return results in SARIF format.")
 	flagSet.String(FlagSarifFileOutput, "", "Save test output in SARIF format directly to the specified file, regardless of whether or not you use the --sarif option.")
+	flagSet.String(FlagJUnitFileOutput, "", "Save test output as a JUnit XML report directly to the specified file.")
+	flagSet.String(FlagComplianceFileOutput, "", "Save a summary of the results by compliance framework and control as a JSON data structure directly to the specified file.")
+	flagSet.String(FlagComplianceMarkdownOutput, "", "Save a summary of the results by compliance framework and control as a Markdown document directly to the specified file.")
 	flagSet.String(FlagProjectBusinessCriticality, "", "Set the project business criticality project attribute to one or more values (comma-separated).")
 	flagSet.String(FlagProjectEnvironment, "", "Set the project environment project attribute to one or more values (comma-separated).")
 	flagSet.String(FlagProjectLifecycle, "", "Set the project lifecycle project attribute to one or more values (comma-separated).")
 	flagSet.String(FlagProjectTags, "", "Set the project tags to one or more values (comma-separated key value pairs with an \"=\" separator).")
-	flagSet.String(FlagRules, "", "Path to a directory containing custom rules.")
+	flagSet.String(FlagRules, "", "Path to a directory of Rego files or a .tar.gz bundle containing custom rules.")
 	flagSet.String(FlagExclude, "", "Exclude files or directories from scan (comma-separated, relative to input directory).")
+	flagSet.String(FlagBaseline, "", "Path to a baseline file. Issues recorded in the baseline are reported separately and do not fail the test.")
+	flagSet.String(FlagBaselineWrite, "", "Record every issue found by the test in a baseline file at the specified path.")
+	flagSet.String(FlagChangedSince, "", "Only report issues in files changed since the specified Git reference, including uncommitted changes.")
+	flagSet.Bool(FlagOffline, false, "Run the test without any request to the Snyk API. The rule bundle and the organization settings are read from local files.")
+	flagSet.String(FlagRulesBundleVersion, "", "Use the specified version of the Snyk rules bundle instead of the latest compatible one.")
+	flagSet.String(FlagIncludeRules, "", "Only evaluate the rules with the specified IDs (comma-separated).")
+	flagSet.String(FlagExcludeRules, "", "Do not evaluate the rules with the specified IDs (comma-separated).")
+	flagSet.String(FlagIncludeLabels, "", "Only evaluate the rules with one of the specified labels (comma-separated).")
+	flagSet.String(FlagCategories, "", "Only evaluate the rules in one of the specified categories (comma-separated).")
+	flagSet.String(FlagControls, "", "Only evaluate the rules mapped to the specified compliance frameworks or controls (comma-separated), e.g. CIS-AWS_v1.4.0.")
+	flagSet.Bool(FlagFailOnStaleIgnores, false, "Fail the test if the policy file contains ignores that are expired or that don't match any issue.")
+	flagSet.String(FlagFailOn, "", "Fail the test only for new issues (new), for issues including the baseline (all), or for new issues with a remediation (upgradable).")
+	flagSet.String(FlagMaxIssues, "", "Fail the test only if the number of issues of a severity is greater than its limit (comma-separated), e.g. critical=0,high=5.")
+	flagSet.Bool(FlagFailOnWarnings, false, "Fail the test with a dedicated exit code if the scan was partial because of warnings.")
 
 	return flagSet
 }
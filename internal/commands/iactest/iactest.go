@@ -1,6 +1,7 @@
 package iactest
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -18,13 +19,14 @@ import (
 	"github.com/snyk/cli-extension-iac/internal/cloudapi"
 	"github.com/snyk/cli-extension-iac/internal/command"
 	"github.com/snyk/cli-extension-iac/internal/engine"
+	"github.com/snyk/cli-extension-iac/internal/exitcode"
 	"github.com/snyk/cli-extension-iac/internal/git"
 	"github.com/snyk/cli-extension-iac/internal/platform"
 	"github.com/snyk/cli-extension-iac/internal/processor"
 	"github.com/snyk/cli-extension-iac/internal/registry"
 	"github.com/snyk/cli-extension-iac/internal/results"
-	"github.com/snyk/cli-extension-iac/internal/rules"
 	"github.com/snyk/cli-extension-iac/internal/settings"
+	"github.com/snyk/cli-extension-iac/internal/tfvars"
 )
 
 const (
@@ -32,10 +34,16 @@ const (
 
 	FeatureFlagNewEngine            = "iacNewEngine"
 	FeatureFlagIntegratedExperience = "iacIntegratedExperience"
+	FeatureFlagNativeReport         = "iacNativeReport"
 
 	// configuration keys.
-	RulesClientURL  = "snyk_iac_rules_client_url"
-	RulesBundlePath = "snyk_iac_bundle_path"
+	RulesClientURL                 = "snyk_iac_rules_client_url"
+	RulesBundlePath                = "snyk_iac_bundle_path"
+	OrgSettingsPath                = "snyk_iac_org_settings_path"
+	CustomRulesPath                = "snyk_iac_custom_rules_path"
+	RulesManifestTTL               = "snyk_iac_rules_manifest_ttl"
+	SkipRulesSignatureVerification = "snyk_iac_rules_skip_signature_verification"
+	RulesMirrorURLs                = "snyk_iac_rules_mirror_urls"
 
 	DotSnykPolicy = ".snyk"
 )
@@ -43,6 +51,10 @@ const (
 // injected in the CLI build process
 var internalRulesClientURL string
 
+// injected in the CLI build process, as a comma-separated list of
+// base64-encoded ed25519 public keys
+var internalRulesPublicKeys string
+
 var WorkflowID = workflow.NewWorkflowIdentifier("iac.test")
 
 func RegisterWorkflows(e workflow.Engine) error {
@@ -56,6 +68,7 @@ func RegisterWorkflows(e workflow.Engine) error {
 
 	config_utils.AddFeatureFlagToConfig(e, FeatureFlagNewEngine, FeatureFlagNewEngine)
 	config_utils.AddFeatureFlagToConfig(e, FeatureFlagIntegratedExperience, FeatureFlagIntegratedExperience)
+	config_utils.AddFeatureFlagToConfig(e, FeatureFlagNativeReport, FeatureFlagNativeReport)
 	return nil
 }
 
@@ -67,7 +80,11 @@ func TestWorkflow(
 	workflowEngine := ictx.GetEngine()
 	args := os.Args[1:]
 
-	if config.GetBool(FeatureFlagNewEngine) || config.GetBool(FeatureFlagIntegratedExperience) {
+	var decision exitcode.Decision
+
+	newEngine := newEngineEnabled(config) || integratedExperienceEnabled(config)
+
+	if newEngine {
 		config.AddDefaultValue(RulesClientURL, configuration.StandardDefaultValueFunction(internalRulesClientURL))
 		err := validateConfig(config)
 		if err != nil {
@@ -79,20 +96,78 @@ func TestWorkflow(
 			return nil, fmt.Errorf("error getting current working directory: %v", err)
 		}
 		inputPaths := DetermineInputPaths(args, cwd)
-		outputFile, err := runNewEngine(ictx, inputPaths, cwd)
+		var outputFile string
+		outputFile, decision, err = runNewEngine(ictx, inputPaths, cwd)
 		if err != nil {
 			return nil, err
 		}
+
+		// The human-readable report was already rendered by the extension, the
+		// legacy CLI doesn't need to be invoked. Like the legacy CLI, the test
+		// fails for any issue if the exit code policy is not set.
+		if useNativeReport(config) {
+			if decision.Code != exitcode.Success {
+				return nil, &exitcode.Error{Decision: decision}
+			}
+			return []workflow.Data{}, nil
+		}
+
 		args = append(args, fmt.Sprintf("--%s=%s", LegacyFlagOutputFile, outputFile))
+
+		// The SARIF, JUnit and compliance files are rendered by the extension,
+		// the legacy CLI must not overwrite them.
+		args = removeFlag(args, FlagSarifFileOutput)
+		args = removeFlag(args, FlagJUnitFileOutput)
+		args = removeFlag(args, FlagComplianceFileOutput)
+		args = removeFlag(args, FlagComplianceMarkdownOutput)
+
+		// The exit code policy is evaluated by the extension, the legacy CLI
+		// doesn't support the same values.
+		args = removeFlag(args, FlagFailOn)
+		args = removeFlag(args, FlagMaxIssues)
+
+		// The Terraform variables are applied by the extension, the legacy
+		// CLI only supports a single .tfvars file.
+		args = removeFlag(args, FlagVarFile)
+		args = removeFlag(args, FlagVar)
 	}
 
 	// The legacy workflow is invoked for both the new and legacy IaC engines
 	config.Set(configuration.RAW_CMD_ARGS, args)
 	config.Set(configuration.WORKFLOW_USE_STDIO, true)
-	return workflowEngine.InvokeWithConfig(workflow.NewWorkflowIdentifier("legacycli"), config)
+
+	data, err := workflowEngine.InvokeWithConfig(workflow.NewWorkflowIdentifier("legacycli"), config)
+	if err := legacyExitError(err, decision, newEngine && enforceExitCodePolicy(config)); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// legacyExitError returns the error of a test whose results were rendered by
+// the legacy CLI. The legacy CLI fails the test for any issue in the output
+// file, so the decision of the exit code policy replaces its exit code when
+// the policy is enforced. The other failures of the legacy CLI are returned as
+// they are.
+func legacyExitError(err error, decision exitcode.Decision, enforce bool) error {
+	if !enforce {
+		return err
+	}
+
+	var exitErr interface{ ExitCode() int }
+
+	if err != nil && (!errors.As(err, &exitErr) || exitErr.ExitCode() != exitcode.Findings) {
+		return err
+	}
+
+	if decision.Code != exitcode.Success {
+		return &exitcode.Error{Decision: decision}
+	}
+
+	return nil
 }
 
-func runNewEngine(ictx workflow.InvocationContext, inputPaths []string, cwd string) (string, error) {
+func runNewEngine(ictx workflow.InvocationContext, inputPaths []string, cwd string) (string, exitcode.Decision, error) {
 	config := ictx.GetConfiguration()
 	debugLogger := ictx.GetEnhancedLogger()
 
@@ -105,14 +180,21 @@ func runNewEngine(ictx workflow.InvocationContext, inputPaths []string, cwd stri
 	ui.StartProgressBar()
 
 	successful := true
+	var report command.Report
 	defer func() {
 		ui.ClearProgressBar()
+		if useNativeReport(config) {
+			ui.DisplayResults(report.Results)
+			ui.DisplayScanMessages(report.Errors, report.Warnings)
+		}
 		if successful {
 			ui.DisplayCompleted()
 		}
 	}()
 
-	httpClient := ictx.GetNetworkAccess().GetHttpClient()
+	offline := config.GetBool(FlagOffline)
+
+	httpClient := NewHTTPClient(ictx)
 
 	fs := afero.NewOsFs()
 
@@ -120,11 +202,9 @@ func runNewEngine(ictx workflow.InvocationContext, inputPaths []string, cwd stri
 		FS: fs,
 	}
 
-	rulesClientURL := config.GetString(RulesClientURL)
-
-	rulesClient := rules.Client{
-		HTTPClient: httpClient,
-		URL:        rulesClientURL,
+	cachedRulesClient, err := NewRulesClient(config, httpClient, debugLogger)
+	if err != nil {
+		return "", exitcode.Decision{}, err
 	}
 
 	var apiURL = config.GetString(configuration.API_URL)
@@ -135,7 +215,7 @@ func runNewEngine(ictx workflow.InvocationContext, inputPaths []string, cwd stri
 	})
 
 	settingsReader := settings.Reader{
-		RegistryClient: registryClient,
+		RegistryClient: NewSettingsRegistryClient(config, registryClient),
 		Org:            config.GetString(configuration.ORGANIZATION),
 	}
 
@@ -147,7 +227,7 @@ func runNewEngine(ictx workflow.InvocationContext, inputPaths []string, cwd stri
 		HTTPClient:   httpClient,
 		URL:          apiURL,
 		Version:      "2022-04-13~experimental",
-		IacNewEngine: config.GetBool(FeatureFlagNewEngine),
+		IacNewEngine: newEngineEnabled(config),
 	})
 
 	snykPlatform := platform.SnykPlatformClient{
@@ -169,6 +249,7 @@ func runNewEngine(ictx workflow.InvocationContext, inputPaths []string, cwd stri
 		SnykPlatform:                 &snykPlatform,
 		Report:                       config.GetBool(FlagReport),
 		SeverityThreshold:            config.GetString(FlagSeverityThreshold),
+		SeverityOverridesPath:        config.GetString(FlagSeverityOverrides),
 		TargetReference:              config.GetString(FlagTargetReference),
 		TargetName:                   config.GetString(FlagTargetName),
 		RemoteRepoUrl:                config.GetString(FlagRemoteRepoURL),
@@ -181,45 +262,173 @@ func runNewEngine(ictx workflow.InvocationContext, inputPaths []string, cwd stri
 		GetOriginUrl:                 git.GetOriginUrl,
 		SettingsReader:               &cachedSettingsReader,
 		PolicyPath:                   policyPath,
+		BaselinePath:                 config.GetString(FlagBaseline),
+		BaselineWritePath:            config.GetString(FlagBaselineWrite),
+		DisableInlineIgnores:         config.GetBool(FlagIgnorePolicy),
 		IncludePassedVulnerabilities: true,
-		IacNewEngine:                 config.GetBool(FeatureFlagNewEngine),
+		IacNewEngine:                 newEngineEnabled(config),
 		Logger:                       debugLogger,
 	}
 
+	ruleSelection := newRuleSelection(config)
+
 	outputFile := filepath.Join(config.GetString(configuration.TEMP_DIR_PATH), fmt.Sprintf("snyk-iac-test-output-%s.json", uuid.NewString()))
 
 	cmd := command.Command{
 		Output:                  outputFile,
+		SarifOutput:             config.GetString(FlagSarifFileOutput),
+		JUnitOutput:             config.GetString(FlagJUnitFileOutput),
+		ComplianceOutput:        config.GetString(FlagComplianceFileOutput),
+		ComplianceMarkdown:      config.GetString(FlagComplianceMarkdownOutput),
 		Logger:                  debugLogger,
 		Engine:                  &policyEngine,
 		FS:                      fs,
 		Paths:                   inputPaths,
-		Exclude:                 parseExcludeFlag(config.GetString(FlagExclude)),
+		Exclude:                 parseListFlag(config.GetString(FlagExclude)),
 		Bundle:                  config.GetString(RulesBundlePath),
 		ResultsProcessor:        &resultsProcessor,
 		SnykCloudEnvironment:    config.GetString(FlagSnykCloudEnvironment),
 		SnykClient:              cloudapiClient,
 		Scan:                    config.GetString(FlagScan),
 		DetectionDepth:          config.GetInt(FlagDepthDetection),
-		VarFile:                 config.GetString(FlagVarFile),
+		TerraformVars:           terraformVars(os.Args[1:], config),
+		Env:                     os.Environ(),
+		HelmValues:              parseListFlag(config.GetString(FlagHelmValues)),
 		SettingsReader:          &cachedSettingsReader,
-		BundleDownloader:        &rulesClient,
+		BundleDownloader:        cachedRulesClient,
 		ReadPolicyEngineVersion: command.ReadRuntimePolicyEngineVersion,
 		ExcludeRawResults:       true,
 		AllowAnalytics:          !config.GetBool(configuration.ANALYTICS_DISABLED),
 		Report:                  config.GetBool(FlagReport),
-		IacNewEngine:            config.GetBool(FeatureFlagNewEngine),
+		IacNewEngine:            newEngineEnabled(config),
+		ChangedSince:            config.GetString(FlagChangedSince),
+		ListChangedFiles:        git.ChangedFiles,
+		FailOnStaleIgnores:      config.GetBool(FlagFailOnStaleIgnores),
+		Offline:                 offline,
+		BundleVersion:           config.GetString(FlagRulesBundleVersion),
+		LocalRules:              LocalRulePaths(config),
+		PinnedBundleDownloader:  cachedRulesClient,
+		ExitCodePolicy:          newExitCodePolicy(config),
+	}
+
+	if !ruleSelection.isEmpty() {
+		cmd.SelectRules = ruleSelection.selectRules
 	}
 
-	successful, err := cmd.RunWithError()
+	report, err = cmd.RunWithReport()
+	successful = err == nil && report.Decision.Code != exitcode.ScanErrors
 	if err != nil {
-		return "", cli.NewGeneralIACFailureError(err.Error(), snyk_errors.WithCause(err))
+		return "", exitcode.Decision{}, cli.NewGeneralIACFailureError(err.Error(), snyk_errors.WithCause(err))
+	}
+	if message, ok := report.InvalidRuleSelection(); ok {
+		return "", exitcode.Decision{}, cli.NewInvalidFlagOptionError(message)
+	}
+
+	// The results are stored to validate the ignores created by the iac.ignore
+	// workflow.
+	if path := LatestResultsPath(config, cwd); path != "" && report.Results != nil {
+		if err := WriteLatestResults(path, report.Results); err != nil {
+			debugLogger.Warn().Err(err).Msg("write latest results")
+		}
+	}
+
+	return outputFile, report.Decision, nil
+}
+
+// newExitCodePolicy returns the exit code policy set by the flags. The flags
+// are validated by validateConfig.
+func newExitCodePolicy(config configuration.Configuration) *exitcode.Policy {
+	limits, _ := exitcode.ParseLimits(config.GetString(FlagMaxIssues))
+
+	return &exitcode.Policy{
+		FailOn:         config.GetString(FlagFailOn),
+		Limits:         limits,
+		FailOnWarnings: config.GetBool(FlagFailOnWarnings),
+	}
+}
+
+// enforceExitCodePolicy returns true if the exit code policy decides the exit
+// code of the test. Without any of its flags, the exit code is the one of the
+// legacy CLI.
+func enforceExitCodePolicy(config configuration.Configuration) bool {
+	return config.IsSet(FlagFailOn) || config.IsSet(FlagMaxIssues) || config.GetBool(FlagFailOnWarnings)
+}
+
+// useNativeReport returns true if the human-readable report should be rendered
+// by the extension instead of the legacy CLI, which is enabled for an
+// organization by the FeatureFlagNativeReport feature flag. The
+// machine-readable formats, including the file written with
+// --json-file-output, are still rendered by the legacy CLI. The report of an
+// offline test is always rendered by the extension, because the legacy CLI
+// sends requests to the Snyk API, and the machine-readable formats are not
+// supported in offline mode.
+func useNativeReport(config configuration.Configuration) bool {
+	if config.GetBool(FlagOffline) {
+		return true
+	}
+
+	return config.GetBool(FeatureFlagNativeReport) && !config.GetBool(FlagJson) && !config.GetBool(FlagSarif) && config.GetString(FlagJsonFileOutput) == ""
+}
+
+// getStringArray returns the values of a repeatable flag. A flag set to a
+// single string, e.g. by the configuration, has one value.
+func getStringArray(config configuration.Configuration, name string) []string {
+	switch v := config.Get(name).(type) {
+	case []string:
+		return v
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	}
+
+	return nil
+}
+
+// terraformVars returns the var files and the variables set by the --var-file
+// and --var flags, in the order of the command line, which is their order of
+// precedence in Terraform. The values that are not on the command line, e.g.
+// the ones set by the configuration, come last.
+func terraformVars(args []string, config configuration.Configuration) []tfvars.Arg {
+	var (
+		varFiles = getStringArray(config, FlagVarFile)
+		vars     = getStringArray(config, FlagVar)
+		result   []tfvars.Arg
+	)
+
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		if !strings.HasPrefix(arg, "--") {
+			continue
+		}
+
+		name, _, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+
+		switch {
+		case name == FlagVarFile && len(varFiles) > 0:
+			result = append(result, tfvars.Arg{VarFile: varFiles[0]})
+			varFiles = varFiles[1:]
+		case name == FlagVar && len(vars) > 0:
+			result = append(result, tfvars.Arg{Var: vars[0]})
+			vars = vars[1:]
+		}
 	}
 
-	return outputFile, nil
+	for _, varFile := range varFiles {
+		result = append(result, tfvars.Arg{VarFile: varFile})
+	}
+
+	for _, v := range vars {
+		result = append(result, tfvars.Arg{Var: v})
+	}
+
+	return result
 }
 
-func parseExcludeFlag(v string) []string {
+func parseListFlag(v string) []string {
 	if strings.TrimSpace(v) == "" {
 		return nil
 	}
@@ -233,3 +442,26 @@ func parseExcludeFlag(v string) []string {
 	}
 	return out
 }
+
+// removeFlag removes every occurrence of a flag, and its value, from the
+// command line arguments. Both the "--flag=value" and "--flag value" forms are
+// supported.
+func removeFlag(args []string, name string) []string {
+	var out []string
+
+	flag := "--" + name
+
+	for i := 0; i < len(args); i++ {
+		if args[i] == flag {
+			// skip the value of the flag, too
+			i++
+			continue
+		}
+		if strings.HasPrefix(args[i], flag+"=") {
+			continue
+		}
+		out = append(out, args[i])
+	}
+
+	return out
+}
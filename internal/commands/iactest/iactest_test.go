@@ -0,0 +1,134 @@
+package iactest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/exitcode"
+	"github.com/snyk/cli-extension-iac/internal/results"
+	"github.com/snyk/cli-extension-iac/internal/tfvars"
+)
+
+func TestRemoveFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "flag not present",
+			args: []string{"iac", "test", "--json"},
+			want: []string{"iac", "test", "--json"},
+		},
+		{
+			name: "flag with equal sign",
+			args: []string{"iac", "test", "--sarif-file-output=out.sarif", "--json"},
+			want: []string{"iac", "test", "--json"},
+		},
+		{
+			name: "flag with separate value",
+			args: []string{"iac", "test", "--sarif-file-output", "out.sarif", "--json"},
+			want: []string{"iac", "test", "--json"},
+		},
+		{
+			name: "similar flag",
+			args: []string{"iac", "test", "--sarif-file-output-other=out.sarif"},
+			want: []string{"iac", "test", "--sarif-file-output-other=out.sarif"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, removeFlag(tt.args, FlagSarifFileOutput))
+		})
+	}
+}
+
+func TestTerraformVars(t *testing.T) {
+	config := setupMockConfig(map[string]any{
+		FlagVarFile: []string{"first.tfvars", "second.tfvars", "config.tfvars"},
+		FlagVar:     []string{"region=cli", "name=cli"},
+	})
+
+	args := []string{
+		"iac", "test",
+		"--var=region=cli",
+		"--var-file", "first.tfvars",
+		"--var", "name=cli",
+		"--var-file=second.tfvars",
+		"--", "--var-file=ignored.tfvars",
+	}
+
+	assert.Equal(t, []tfvars.Arg{
+		{Var: "region=cli"},
+		{VarFile: "first.tfvars"},
+		{Var: "name=cli"},
+		{VarFile: "second.tfvars"},
+		{VarFile: "config.tfvars"},
+	}, terraformVars(args, config))
+}
+
+// legacyCLIError is the error returned when the legacy CLI exits with a
+// non-zero exit code.
+type legacyCLIError struct {
+	code int
+}
+
+func (e legacyCLIError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func (e legacyCLIError) ExitCode() int {
+	return e.code
+}
+
+func TestLegacyExitError(t *testing.T) {
+	highFinding := &results.Results{
+		Vulnerabilities: []results.Vulnerability{{Severity: "high"}},
+	}
+
+	evaluate := func(flags map[string]any, r *results.Results) (exitcode.Decision, bool) {
+		config := setupMockConfig(flags)
+		return newExitCodePolicy(config).Evaluate(exitcode.Outcome{Results: r}), enforceExitCodePolicy(config)
+	}
+
+	t.Run("issues below the limits", func(t *testing.T) {
+		decision, enforce := evaluate(map[string]any{FlagMaxIssues: "high=5"}, highFinding)
+		assert.NoError(t, legacyExitError(legacyCLIError{code: 1}, decision, enforce))
+	})
+
+	t.Run("issues above the limits", func(t *testing.T) {
+		decision, enforce := evaluate(map[string]any{FlagMaxIssues: "high=0"}, highFinding)
+		err := legacyExitError(nil, decision, enforce)
+
+		var exitErr *exitcode.Error
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcode.Findings, exitErr.ExitCode())
+	})
+
+	t.Run("policy not enforced", func(t *testing.T) {
+		decision, enforce := evaluate(map[string]any{}, highFinding)
+		assert.Equal(t, legacyCLIError{code: 1}, legacyExitError(legacyCLIError{code: 1}, decision, enforce))
+	})
+
+	t.Run("failure of the legacy CLI", func(t *testing.T) {
+		decision, enforce := evaluate(map[string]any{FlagMaxIssues: "high=5"}, highFinding)
+		assert.Equal(t, legacyCLIError{code: 2}, legacyExitError(legacyCLIError{code: 2}, decision, enforce))
+
+		err := errors.New("legacy CLI not found")
+		assert.Equal(t, err, legacyExitError(err, decision, enforce))
+	})
+}
+
+func TestUseNativeReport(t *testing.T) {
+	assert.True(t, useNativeReport(setupMockConfig(map[string]any{FeatureFlagNativeReport: true})))
+	assert.True(t, useNativeReport(setupMockConfig(map[string]any{FlagOffline: true})))
+	assert.False(t, useNativeReport(setupMockConfig(map[string]any{})))
+	assert.False(t, useNativeReport(setupMockConfig(map[string]any{FeatureFlagNativeReport: true, FlagJson: true})))
+	assert.False(t, useNativeReport(setupMockConfig(map[string]any{FeatureFlagNativeReport: true, FlagSarif: true})))
+	assert.False(t, useNativeReport(setupMockConfig(map[string]any{FeatureFlagNativeReport: true, FlagJsonFileOutput: "results.json"})))
+}
@@ -0,0 +1,58 @@
+package iactest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+// LatestResultsPath returns the path of the file where the results of the
+// latest scan run in the directory dir are stored. LatestResultsPath returns
+// an empty string if the cache directory is not configured.
+func LatestResultsPath(config configuration.Configuration, dir string) string {
+	cacheDir := config.GetString(configuration.CACHE_PATH)
+	if cacheDir == "" {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(dir))
+
+	return filepath.Join(cacheDir, "snyk-iac", fmt.Sprintf("latest-results-%s.json", hex.EncodeToString(hash[:8])))
+}
+
+// WriteLatestResults stores the results of a scan at path.
+func WriteLatestResults(path string, r *results.Results) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("marshal results: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("create directory: %v", err)
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// ReadLatestResults reads the results of a scan stored at path.
+func ReadLatestResults(path string) (*results.Results, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r results.Results
+
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("unmarshal results: %v", err)
+	}
+
+	return &r, nil
+}
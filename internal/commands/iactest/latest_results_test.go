@@ -0,0 +1,34 @@
+package iactest
+
+import (
+	"testing"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+func TestLatestResults(t *testing.T) {
+	config := configuration.NewWithOpts()
+
+	require.Empty(t, LatestResultsPath(config, "/project"))
+
+	config.Set(configuration.CACHE_PATH, t.TempDir())
+
+	path := LatestResultsPath(config, "/project")
+	require.NotEmpty(t, path)
+	require.NotEqual(t, path, LatestResultsPath(config, "/other-project"))
+
+	input := &results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			{Rule: results.Rule{ID: "SNYK-CC-TF-1"}},
+		},
+	}
+
+	require.NoError(t, WriteLatestResults(path, input))
+
+	output, err := ReadLatestResults(path)
+	require.NoError(t, err)
+	require.Equal(t, input, output)
+}
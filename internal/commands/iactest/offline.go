@@ -0,0 +1,73 @@
+package iactest
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/workflow"
+
+	"github.com/snyk/cli-extension-iac/internal/settings"
+)
+
+// offlineTransport is an HTTP transport that refuses every request. It is used
+// in offline mode, so that a request to the Snyk API fails instead of reaching
+// the network.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("request to %s refused in offline mode", req.URL.Redacted())
+}
+
+// NewHTTPClient returns the HTTP client of a workflow. In offline mode, the
+// client refuses every request.
+func NewHTTPClient(ictx workflow.InvocationContext) *http.Client {
+	if ictx.GetConfiguration().GetBool(FlagOffline) {
+		return &http.Client{Transport: offlineTransport{}}
+	}
+
+	return ictx.GetNetworkAccess().GetHttpClient()
+}
+
+// NewSettingsRegistryClient returns the client that reads the settings of the
+// organization. In offline mode, the settings are read from the local file set
+// by OrgSettingsPath instead of the registry.
+func NewSettingsRegistryClient(config configuration.Configuration, registryClient settings.RegistryClient) settings.RegistryClient {
+	if config.GetBool(FlagOffline) {
+		return &settings.FileRegistryClient{
+			Path: config.GetString(OrgSettingsPath),
+		}
+	}
+
+	return registryClient
+}
+
+// LocalRulePaths returns the paths of the local custom rules: the custom
+// rules passed with --rules, and the custom rule bundles of the organization
+// stored in the directory set by CustomRulesPath. They are loaded in addition
+// to the custom rules of the organization, which are not downloaded in
+// offline mode.
+func LocalRulePaths(config configuration.Configuration) []string {
+	var paths []string
+
+	for _, path := range []string{config.GetString(FlagRules), config.GetString(CustomRulesPath)} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+// newEngineEnabled returns true if the scan runs with the new engine. The
+// feature flags are read from the Snyk API, so they are not resolved in
+// offline mode, which always uses the new engine.
+func newEngineEnabled(config configuration.Configuration) bool {
+	return config.GetBool(FlagOffline) || config.GetBool(FeatureFlagNewEngine)
+}
+
+// integratedExperienceEnabled returns true if the integrated experience is
+// enabled. It is never enabled in offline mode.
+func integratedExperienceEnabled(config configuration.Configuration) bool {
+	return !config.GetBool(FlagOffline) && config.GetBool(FeatureFlagIntegratedExperience)
+}
@@ -0,0 +1,104 @@
+package iactest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/snyk/go-application-framework/pkg/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineTransport(t *testing.T) {
+	var requests int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: offlineTransport{}}
+
+	_, err := client.Get(server.URL)
+	require.ErrorContains(t, err, "refused in offline mode")
+	require.Zero(t, requests)
+}
+
+func TestOfflineWorkflowSendsNoRequest(t *testing.T) {
+	bundlePath, err := filepath.Abs(filepath.Join("..", "..", "rules", "testdata", "bundle.tar.gz"))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	settingsPath := filepath.Join(dir, "settings.json")
+	require.NoError(t, os.WriteFile(settingsPath, []byte(`{"entitlements": {"infrastructureAsCode": true}, "meta": {"org": "org", "orgPublicId": "org-public-id"}}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`resource "aws_s3_bucket" "bucket" {}`), 0644))
+
+	// Any request sent through the default transport fails the test.
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected request to %s", req.URL)
+		return nil, errors.New("unexpected request")
+	})
+	t.Cleanup(func() { http.DefaultTransport = defaultTransport })
+
+	args := os.Args
+	os.Args = []string{"snyk", "iac", "test", "--offline"}
+	t.Cleanup(func() { os.Args = args })
+
+	t.Chdir(dir)
+
+	config := configuration.New()
+	config.Set(FlagOffline, true)
+	config.Set(RulesBundlePath, bundlePath)
+	config.Set(OrgSettingsPath, settingsPath)
+	config.Set(configuration.TEMP_DIR_PATH, t.TempDir())
+
+	// The feature flags are read from the Snyk API.
+	for _, flag := range []string{FeatureFlagNewEngine, FeatureFlagIntegratedExperience, FeatureFlagNativeReport} {
+		config.AddDefaultValue(flag, func(configuration.Configuration, any) (any, error) {
+			t.Errorf("feature flag %s resolved in offline mode", flag)
+			return false, nil
+		})
+	}
+
+	ctrl := gomock.NewController(t)
+	logger := zerolog.Nop()
+
+	progressBar := mocks.NewMockProgressBar(ctrl)
+	progressBar.EXPECT().SetTitle(gomock.Any()).AnyTimes()
+	progressBar.EXPECT().UpdateProgress(gomock.Any()).AnyTimes()
+	progressBar.EXPECT().Clear().AnyTimes()
+
+	var output strings.Builder
+
+	userInterface := mocks.NewMockUserInterface(ctrl)
+	userInterface.EXPECT().NewProgressBar().Return(progressBar).AnyTimes()
+	userInterface.EXPECT().Output(gomock.Any()).Do(func(s string) { output.WriteString(s) }).Return(nil).AnyTimes()
+
+	// The network access and the workflow engine, which invokes the legacy
+	// CLI, are not expected to be used.
+	ictx := mocks.NewMockInvocationContext(ctrl)
+	ictx.EXPECT().GetConfiguration().Return(config).AnyTimes()
+	ictx.EXPECT().GetEngine().Return(mocks.NewMockEngine(ctrl)).AnyTimes()
+	ictx.EXPECT().GetEnhancedLogger().Return(&logger).AnyTimes()
+	ictx.EXPECT().GetUserInterface().Return(userInterface).AnyTimes()
+
+	_, err = TestWorkflow(ictx, nil)
+	require.NoError(t, err)
+
+	// The report is rendered by the extension.
+	require.Contains(t, output.String(), SummaryTitle)
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
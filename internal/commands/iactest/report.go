@@ -0,0 +1,227 @@
+package iactest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/snyk/cli-extension-iac/internal/command"
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+const (
+	IssuesTitle        = "Infrastructure As Code issues:"
+	IgnoredIssuesTitle = "Ignored issues:"
+	SummaryTitle       = "Test Summary"
+	NoIssuesText       = "No issues found."
+	ScanErrorsTitle    = "Scan errors:"
+	ScanWarningsTitle  = "Scan warnings:"
+)
+
+// severityOrder lists the known severities from the most to the least severe.
+var severityOrder = []string{"critical", "high", "medium", "low"}
+
+var severityColors = map[string]lipgloss.Color{
+	"critical": lipgloss.Color("5"),
+	"high":     lipgloss.Color("1"),
+	"medium":   lipgloss.Color("3"),
+	"low":      lipgloss.Color("7"),
+}
+
+// renderReport renders a human-readable report of the scan results. Findings
+// are grouped by file and, inside each file, by severity. The report ends with
+// a summary of the counts per severity, ignored findings and passed checks.
+func renderReport(r *results.Results) string {
+	var b strings.Builder
+
+	if len(r.Vulnerabilities) == 0 {
+		fmt.Fprintf(&b, "\n%s %s\n", renderGreen("✔"), NoIssuesText)
+	} else {
+		fmt.Fprintf(&b, "\n%s\n", renderBold(IssuesTitle))
+
+		for _, file := range vulnerabilitiesByFile(r.Vulnerabilities) {
+			fmt.Fprintf(&b, "\n%s %s\n", renderBold("File:"), file.name)
+
+			for _, severity := range severityOrder {
+				for _, v := range file.vulnerabilities[severity] {
+					renderVulnerability(&b, v)
+				}
+			}
+
+			for _, v := range file.vulnerabilities[""] {
+				renderVulnerability(&b, v)
+			}
+		}
+	}
+
+	if len(r.IgnoredVulnerabilities) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", renderBold(IgnoredIssuesTitle))
+
+		for _, v := range r.IgnoredVulnerabilities {
+			renderIgnoredVulnerability(&b, v)
+		}
+	}
+
+	renderSummary(&b, r)
+
+	return b.String()
+}
+
+func renderIgnoredVulnerability(b *strings.Builder, v results.Vulnerability) {
+	fmt.Fprintf(b, "\n  %s %s\n", renderSeverity(v.Severity), v.Rule.Title)
+	fmt.Fprintf(b, "    File: %s\n", v.Resource.File)
+
+	if v.Ignore == nil {
+		return
+	}
+
+	if v.Ignore.Reason != "" {
+		fmt.Fprintf(b, "    Reason: %s\n", v.Ignore.Reason)
+	}
+
+	if by := v.Ignore.IgnoredBy; by != nil && by.Name != "" {
+		fmt.Fprintf(b, "    Ignored by: %s\n", by.Name)
+	}
+
+	if v.Ignore.Expires != nil {
+		fmt.Fprintf(b, "    Expires: %s\n", v.Ignore.Expires.Format(time.DateOnly))
+	}
+}
+
+func renderVulnerability(b *strings.Builder, v results.Vulnerability) {
+	fmt.Fprintf(b, "\n  %s %s\n", renderSeverity(v.Severity), v.Rule.Title)
+
+	if v.Resource.FormattedPath != "" {
+		fmt.Fprintf(b, "    Path: %s\n", v.Resource.FormattedPath)
+	}
+
+	if v.Resource.ConstructPath != "" {
+		fmt.Fprintf(b, "    Construct: %s\n", v.Resource.ConstructPath)
+	} else if v.Resource.Stack != "" {
+		fmt.Fprintf(b, "    Stack: %s\n", v.Resource.Stack)
+	}
+
+	// Some inputs, like Helm charts, have no column information.
+	if v.Resource.Line > 0 && v.Resource.Column > 0 {
+		fmt.Fprintf(b, "    Line: %d, Column: %d\n", v.Resource.Line, v.Resource.Column)
+	} else if v.Resource.Line > 0 {
+		fmt.Fprintf(b, "    Line: %d\n", v.Resource.Line)
+	}
+
+	if v.Remediation != "" {
+		fmt.Fprintf(b, "    Remediation: %s\n", v.Remediation)
+	}
+
+	if v.Rule.Documentation != "" {
+		fmt.Fprintf(b, "    Info: %s\n", v.Rule.Documentation)
+	}
+}
+
+func renderSummary(b *strings.Builder, r *results.Results) {
+	counts := make(map[string]int)
+
+	for _, v := range r.Vulnerabilities {
+		counts[v.Severity]++
+	}
+
+	var parts []string
+
+	for _, severity := range severityOrder {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[severity], severity))
+	}
+
+	fmt.Fprintf(b, "\n%s\n\n", renderBold(SummaryTitle))
+	fmt.Fprintf(b, "  Total issues: %d [ %s ]\n", len(r.Vulnerabilities), strings.Join(parts, ", "))
+	fmt.Fprintf(b, "  Ignored issues: %d\n", r.Metadata.IgnoredCount)
+	if len(r.BaselineVulnerabilities) > 0 {
+		fmt.Fprintf(b, "  Baseline issues: %d\n", len(r.BaselineVulnerabilities))
+	}
+	fmt.Fprintf(b, "  Passed checks: %d\n", len(r.PassedVulnerabilities))
+}
+
+// renderScanMessages renders the scan errors, which make the results
+// incomplete or missing, and the scan warnings, which make the scan partial.
+func renderScanMessages(errors, warnings []command.ScanMessage) string {
+	var b strings.Builder
+
+	for _, section := range []struct {
+		title    string
+		messages []command.ScanMessage
+	}{
+		{title: ScanErrorsTitle, messages: errors},
+		{title: ScanWarningsTitle, messages: warnings},
+	} {
+		if len(section.messages) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n%s\n", renderBold(section.title))
+
+		for _, m := range section.messages {
+			fmt.Fprintf(&b, "\n  %s\n", m.Message)
+
+			if m.Path != "" {
+				fmt.Fprintf(&b, "    Path: %s\n", m.Path)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+type fileVulnerabilities struct {
+	name            string
+	vulnerabilities map[string][]results.Vulnerability
+}
+
+// vulnerabilitiesByFile groups vulnerabilities by file, and then by severity.
+// Vulnerabilities with an unknown severity are grouped under the empty string.
+// Files are sorted by name.
+func vulnerabilitiesByFile(vulnerabilities []results.Vulnerability) []fileVulnerabilities {
+	byFile := make(map[string]map[string][]results.Vulnerability)
+
+	for _, v := range vulnerabilities {
+		severity := v.Severity
+		if _, ok := severityColors[severity]; !ok {
+			severity = ""
+		}
+
+		if byFile[v.Resource.File] == nil {
+			byFile[v.Resource.File] = make(map[string][]results.Vulnerability)
+		}
+
+		byFile[v.Resource.File][severity] = append(byFile[v.Resource.File][severity], v)
+	}
+
+	files := make([]fileVulnerabilities, 0, len(byFile))
+
+	for name, bySeverity := range byFile {
+		files = append(files, fileVulnerabilities{
+			name:            name,
+			vulnerabilities: bySeverity,
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].name < files[j].name
+	})
+
+	return files
+}
+
+func renderSeverity(severity string) string {
+	label := fmt.Sprintf("✗ [%s]", strings.ToUpper(severity))
+
+	if severity == "" {
+		label = "✗"
+	}
+
+	if color, ok := severityColors[severity]; ok {
+		return lipgloss.NewStyle().Foreground(color).Render(label)
+	}
+
+	return label
+}
@@ -0,0 +1,123 @@
+package iactest
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+
+	"github.com/snyk/cli-extension-iac/internal/controls"
+	"github.com/snyk/cli-extension-iac/internal/engine"
+)
+
+// ruleSelection selects the rules to evaluate. A rule is included if it
+// matches every flag that is set among the rule IDs, labels, categories and
+// controls, and it matches a flag if it matches one of its values. Every rule
+// is included if none of them is set. The excluded rules are removed from the
+// included rules.
+type ruleSelection struct {
+	IncludeRules  []string
+	ExcludeRules  []string
+	IncludeLabels []string
+	Categories    []string
+	Controls      []string
+}
+
+func newRuleSelection(config configuration.Configuration) *ruleSelection {
+	return &ruleSelection{
+		IncludeRules:  parseListFlag(config.GetString(FlagIncludeRules)),
+		ExcludeRules:  parseListFlag(config.GetString(FlagExcludeRules)),
+		IncludeLabels: parseListFlag(config.GetString(FlagIncludeLabels)),
+		Categories:    parseListFlag(config.GetString(FlagCategories)),
+		Controls:      parseListFlag(config.GetString(FlagControls)),
+	}
+}
+
+func (s *ruleSelection) isEmpty() bool {
+	return len(s.ExcludeRules) == 0 && !s.hasInclusions()
+}
+
+func (s *ruleSelection) hasInclusions() bool {
+	return len(s.IncludeRules) > 0 || len(s.IncludeLabels) > 0 || len(s.Categories) > 0 || len(s.Controls) > 0
+}
+
+// selectRules resolves the selection against the metadata of the loaded rules
+// and returns the IDs of the selected rules, in lexical order. Its error is
+// reported by the engine.
+func (s *ruleSelection) selectRules(metadata []engine.MetadataResult) ([]string, error) {
+	if err := validateRuleSelection(s, metadata); err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[string]bool)
+
+	for _, id := range s.ExcludeRules {
+		excluded[strings.ToUpper(id)] = true
+	}
+
+	selected := make(map[string]bool)
+
+	for _, m := range metadata {
+		if m.Error != "" || m.Metadata.ID == "" || excluded[strings.ToUpper(m.Metadata.ID)] {
+			continue
+		}
+
+		if !s.hasInclusions() || s.includes(m) {
+			selected[m.Metadata.ID] = true
+		}
+	}
+
+	var ruleIDs []string
+
+	for id := range selected {
+		ruleIDs = append(ruleIDs, id)
+	}
+
+	sort.Strings(ruleIDs)
+
+	if len(ruleIDs) == 0 {
+		return nil, errNoRuleSelected()
+	}
+
+	return ruleIDs, nil
+}
+
+func (s *ruleSelection) includes(m engine.MetadataResult) bool {
+	if len(s.IncludeRules) > 0 && !containsFold(s.IncludeRules, m.Metadata.ID) {
+		return false
+	}
+
+	if len(s.Categories) > 0 && !containsFold(s.Categories, m.Metadata.Category) {
+		return false
+	}
+
+	if len(s.IncludeLabels) > 0 && !containsAnyFold(s.IncludeLabels, m.Metadata.Labels) {
+		return false
+	}
+
+	return len(s.Controls) == 0 || controls.MatchesAny(m.Metadata.Controls, s.Controls)
+}
+
+func containsAnyFold(values []string, vs []string) bool {
+	for _, v := range vs {
+		if containsFold(values, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsFold(values []string, v string) bool {
+	if v == "" {
+		return false
+	}
+
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+
+	return false
+}
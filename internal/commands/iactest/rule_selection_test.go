@@ -0,0 +1,109 @@
+package iactest
+
+import (
+	"testing"
+
+	"github.com/snyk/policy-engine/pkg/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/engine"
+)
+
+var testRulesMetadata = []engine.MetadataResult{
+	{Metadata: policy.Metadata{ID: "SNYK-CC-1", Category: "Logging", Controls: []string{"CIS-AWS_v1.4.0_3.1"}}},
+	{Metadata: policy.Metadata{ID: "SNYK-CC-2", Category: "Network", Labels: []string{"pci"}, Controls: []string{"CIS-AWS_v1.3.0_4.1"}}},
+	{Metadata: policy.Metadata{ID: "SNYK-CC-3", Category: "Network", Controls: []string{"CIS-AWS_v1.4.0_5.2", "NIST-800-53_vRev4_sc-7"}}},
+	{Package: "data.rules.broken", Error: "unable to read metadata"},
+}
+
+func TestRuleSelection(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    map[string]any
+		expected  []string
+		expectErr bool
+	}{
+		{
+			name:     "no selection",
+			config:   map[string]any{},
+			expected: []string{"SNYK-CC-1", "SNYK-CC-2", "SNYK-CC-3"},
+		},
+		{
+			name:     "include rules",
+			config:   map[string]any{FlagIncludeRules: "snyk-cc-3,SNYK-CC-1"},
+			expected: []string{"SNYK-CC-1", "SNYK-CC-3"},
+		},
+		{
+			name:     "exclude rules",
+			config:   map[string]any{FlagExcludeRules: "SNYK-CC-2"},
+			expected: []string{"SNYK-CC-1", "SNYK-CC-3"},
+		},
+		{
+			name:     "include labels",
+			config:   map[string]any{FlagIncludeLabels: "PCI"},
+			expected: []string{"SNYK-CC-2"},
+		},
+		{
+			name:     "categories",
+			config:   map[string]any{FlagCategories: "network"},
+			expected: []string{"SNYK-CC-2", "SNYK-CC-3"},
+		},
+		{
+			name:     "controls",
+			config:   map[string]any{FlagControls: "CIS-AWS_v1.4.0"},
+			expected: []string{"SNYK-CC-1", "SNYK-CC-3"},
+		},
+		{
+			name:     "values of a flag are combined",
+			config:   map[string]any{FlagCategories: "logging,network"},
+			expected: []string{"SNYK-CC-1", "SNYK-CC-2", "SNYK-CC-3"},
+		},
+		{
+			name:     "flags are intersected",
+			config:   map[string]any{FlagCategories: "network", FlagControls: "CIS-AWS_v1.4.0"},
+			expected: []string{"SNYK-CC-3"},
+		},
+		{
+			name:     "exclusions win",
+			config:   map[string]any{FlagCategories: "network", FlagExcludeRules: "SNYK-CC-3"},
+			expected: []string{"SNYK-CC-2"},
+		},
+		{
+			name:      "no rule matches every flag",
+			config:    map[string]any{FlagControls: "NIST-800-53", FlagIncludeLabels: "pci"},
+			expectErr: true,
+		},
+		{
+			name:      "unknown included rule",
+			config:    map[string]any{FlagIncludeRules: "SNYK-CC-1,SNYK-CC-9"},
+			expectErr: true,
+		},
+		{
+			name:      "unknown excluded rule",
+			config:    map[string]any{FlagExcludeRules: "SNYK-CC-9"},
+			expectErr: true,
+		},
+		{
+			name:      "no matching rule",
+			config:    map[string]any{FlagControls: "CIS-AZURE"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection := newRuleSelection(setupMockConfig(tt.config))
+
+			ruleIDs, err := selection.selectRules(testRulesMetadata)
+
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ruleIDs)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package iactest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/snyk/error-catalog-golang-public/cli"
+	"github.com/snyk/go-application-framework/pkg/configuration"
+
+	"github.com/snyk/cli-extension-iac/internal/rules"
+)
+
+// NewRulesClient returns the client that downloads the rules bundle from the
+// rules URL and its mirrors, and caches it in the cache directory. The rules
+// URL defaults to the one set when the CLI is built.
+func NewRulesClient(config configuration.Configuration, httpClient rules.HTTPClient, logger *zerolog.Logger) (*rules.CachedClient, error) {
+	rulesClientURL := config.GetString(RulesClientURL)
+	if rulesClientURL == "" {
+		rulesClientURL = internalRulesClientURL
+	}
+
+	rulesPublicKeys, err := rules.ParsePublicKeys(internalRulesPublicKeys)
+	if err != nil {
+		return nil, cli.NewGeneralIACFailureError(fmt.Sprintf("The public keys of the rules bundle are invalid: %v", err))
+	}
+
+	// The signature of the rules bundle is only verified once the CLI is
+	// built with public keys.
+	skipSignatureVerification := config.GetBool(SkipRulesSignatureVerification)
+
+	if skipSignatureVerification {
+		logger.Warn().Msg("the signature of the rules bundle is not verified")
+	} else if len(rulesPublicKeys) == 0 {
+		logger.Warn().Msg("no public key is configured for the rules bundle, its signature is not verified")
+	}
+
+	rulesClient := rules.Client{
+		HTTPClient:                httpClient,
+		URL:                       rulesClientURL,
+		Mirrors:                   rules.ParseMirrors(config.GetString(RulesMirrorURLs)),
+		PublicKeys:                rulesPublicKeys,
+		SkipSignatureVerification: skipSignatureVerification,
+		Backoff:                   time.Second,
+		Logger:                    logger,
+	}
+
+	manifestTTL := rules.DefaultManifestTTL
+	if config.IsSet(RulesManifestTTL) {
+		// The value was already validated.
+		manifestTTL, _ = time.ParseDuration(config.GetString(RulesManifestTTL))
+	}
+
+	return &rules.CachedClient{
+		Client:      &rulesClient,
+		Dir:         RulesCacheDir(config, &rulesClient),
+		ManifestTTL: manifestTTL,
+		Logger:      logger,
+	}, nil
+}
+
+// RulesCacheDir returns the directory where the rule bundles downloaded by
+// the client are cached. Every combination of rules URL, mirrors and public
+// keys has its own directory, so that a bundle downloaded from other mirrors,
+// or accepted with other keys, is never served from the cache. RulesCacheDir
+// returns an empty string if the cache directory is not configured.
+func RulesCacheDir(config configuration.Configuration, client *rules.Client) string {
+	cacheDir := config.GetString(configuration.CACHE_PATH)
+	if cacheDir == "" {
+		return ""
+	}
+
+	hash := sha256.New()
+
+	fmt.Fprintf(hash, "url=%s\n", client.URL)
+
+	for _, mirror := range client.Mirrors {
+		fmt.Fprintf(hash, "mirror=%s\n", mirror)
+	}
+
+	for _, key := range client.PublicKeys {
+		fmt.Fprintf(hash, "key=%s\n", base64.StdEncoding.EncodeToString(key))
+	}
+
+	return filepath.Join(cacheDir, "snyk-iac", "rules", hex.EncodeToString(hash.Sum(nil)[:8]))
+}
@@ -0,0 +1,36 @@
+package iactest
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/snyk/go-application-framework/pkg/configuration"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/rules"
+)
+
+func TestRulesCacheDir(t *testing.T) {
+	config := configuration.NewWithOpts()
+
+	client := &rules.Client{URL: "https://rules.example.com"}
+
+	require.Empty(t, RulesCacheDir(config, client))
+
+	config.Set(configuration.CACHE_PATH, t.TempDir())
+
+	dir := RulesCacheDir(config, client)
+	require.NotEmpty(t, dir)
+	require.Equal(t, dir, RulesCacheDir(config, &rules.Client{URL: "https://rules.example.com"}))
+
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	for _, other := range []*rules.Client{
+		{URL: "https://internal.example.com"},
+		{URL: "https://rules.example.com", Mirrors: []string{"https://mirror.example.com"}},
+		{URL: "https://rules.example.com", PublicKeys: []ed25519.PublicKey{publicKey}},
+	} {
+		require.NotEqual(t, dir, RulesCacheDir(config, other))
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/rs/zerolog"
 	"github.com/snyk/go-application-framework/pkg/ui"
+
+	"github.com/snyk/cli-extension-iac/internal/command"
+	"github.com/snyk/cli-extension-iac/internal/results"
 )
 
 const (
@@ -52,6 +55,25 @@ func (u *iacTestUI) DisplayCompleted() {
 	u.backend.Output(fmt.Sprintf("%s %s", renderGreen("✔"), CompletionText))
 }
 
+// DisplayResults renders a report of the scan results, including a summary of
+// the findings.
+func (u *iacTestUI) DisplayResults(r *results.Results) {
+	if u.disabled || r == nil {
+		return
+	}
+
+	u.backend.Output(renderReport(r))
+}
+
+// DisplayScanMessages renders the scan errors and the scan warnings, if any.
+func (u *iacTestUI) DisplayScanMessages(errors, warnings []command.ScanMessage) {
+	if u.disabled || len(errors) == 0 && len(warnings) == 0 {
+		return
+	}
+
+	u.backend.Output(renderScanMessages(errors, warnings))
+}
+
 func (u *iacTestUI) StartProgressBar() {
 	if u.disabled {
 		return
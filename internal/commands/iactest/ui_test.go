@@ -2,9 +2,12 @@ package iactest_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/rs/zerolog"
+	"github.com/snyk/cli-extension-iac/internal/command"
 	"github.com/snyk/cli-extension-iac/internal/commands/iactest"
+	"github.com/snyk/cli-extension-iac/internal/results"
 	"github.com/snyk/go-application-framework/pkg/ui"
 	"github.com/stretchr/testify/mock"
 )
@@ -83,6 +86,193 @@ func TestDisplayCompleted(t *testing.T) {
 	backend.AssertCalled(t, "Output", expectedOutput)
 }
 
+func TestDisplayResults(t *testing.T) {
+	backend := new(MockUserInterface)
+	backend.On("NewProgressBar").Return(new(MockProgressBar))
+	logger := zerolog.Nop()
+	u := iactest.NewUI(iactest.UIConfig{
+		Backend: backend,
+		Logger:  &logger,
+	})
+
+	scanResults := &results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			{
+				Rule:        results.Rule{Title: "Low issue"},
+				Severity:    "low",
+				Remediation: "Fix the low issue",
+				Resource: results.Resource{
+					File:          "b.tf",
+					FormattedPath: "resource.aws_s3_bucket[b]",
+					Line:          3,
+					Column:        1,
+				},
+			},
+			{
+				Rule: results.Rule{
+					Title:         "High issue",
+					Documentation: "https://security.snyk.io/rules/cloud/SNYK-CC-TF-1",
+				},
+				Severity: "high",
+				Resource: results.Resource{
+					File: "b.tf",
+					Line: 7,
+				},
+			},
+			{
+				Rule:     results.Rule{Title: "Critical issue"},
+				Severity: "critical",
+				Resource: results.Resource{
+					File:          "a.yaml",
+					Stack:         "App",
+					ConstructPath: "App/Bucket/Resource",
+				},
+			},
+		},
+		PassedVulnerabilities: []results.Vulnerability{{}, {}},
+		Metadata: results.Metadata{
+			IgnoredCount: 4,
+		},
+	}
+
+	expectedOutput := `
+Infrastructure As Code issues:
+
+File: a.yaml
+
+  ✗ [CRITICAL] Critical issue
+    Construct: App/Bucket/Resource
+
+File: b.tf
+
+  ✗ [HIGH] High issue
+    Line: 7
+    Info: https://security.snyk.io/rules/cloud/SNYK-CC-TF-1
+
+  ✗ [LOW] Low issue
+    Path: resource.aws_s3_bucket[b]
+    Line: 3, Column: 1
+    Remediation: Fix the low issue
+
+Test Summary
+
+  Total issues: 3 [ 1 critical, 1 high, 0 medium, 1 low ]
+  Ignored issues: 4
+  Passed checks: 2
+`
+	backend.On("Output", expectedOutput).Return(nil)
+	u.DisplayResults(scanResults)
+
+	backend.AssertCalled(t, "Output", expectedOutput)
+}
+
+func TestDisplayResultsNoIssues(t *testing.T) {
+	backend := new(MockUserInterface)
+	backend.On("NewProgressBar").Return(new(MockProgressBar))
+	logger := zerolog.Nop()
+	u := iactest.NewUI(iactest.UIConfig{
+		Backend: backend,
+		Logger:  &logger,
+	})
+
+	expectedOutput := `
+✔ No issues found.
+
+Test Summary
+
+  Total issues: 0 [ 0 critical, 0 high, 0 medium, 0 low ]
+  Ignored issues: 0
+  Passed checks: 0
+`
+	backend.On("Output", expectedOutput).Return(nil)
+	u.DisplayResults(&results.Results{})
+
+	backend.AssertCalled(t, "Output", expectedOutput)
+}
+
+func TestDisplayResultsIgnoredIssues(t *testing.T) {
+	backend := new(MockUserInterface)
+	backend.On("NewProgressBar").Return(new(MockProgressBar))
+	logger := zerolog.Nop()
+	u := iactest.NewUI(iactest.UIConfig{
+		Backend: backend,
+		Logger:  &logger,
+	})
+
+	expires := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	scanResults := &results.Results{
+		IgnoredVulnerabilities: []results.Vulnerability{
+			{
+				Rule:     results.Rule{Title: "Public bucket"},
+				Severity: "medium",
+				Resource: results.Resource{
+					File: "main.tf",
+				},
+				Ignore: &results.Ignore{
+					Source:  results.IgnoreSourceInline,
+					Reason:  "Public by design",
+					Expires: &expires,
+				},
+			},
+		},
+		Metadata: results.Metadata{
+			IgnoredCount: 1,
+		},
+	}
+
+	expectedOutput := `
+✔ No issues found.
+
+Ignored issues:
+
+  ✗ [MEDIUM] Public bucket
+    File: main.tf
+    Reason: Public by design
+    Expires: 2027-01-01
+
+Test Summary
+
+  Total issues: 0 [ 0 critical, 0 high, 0 medium, 0 low ]
+  Ignored issues: 1
+  Passed checks: 0
+`
+	backend.On("Output", expectedOutput).Return(nil)
+	u.DisplayResults(scanResults)
+
+	backend.AssertCalled(t, "Output", expectedOutput)
+}
+
+func TestDisplayScanMessages(t *testing.T) {
+	backend := new(MockUserInterface)
+	backend.On("NewProgressBar").Return(new(MockProgressBar))
+	logger := zerolog.Nop()
+	u := iactest.NewUI(iactest.UIConfig{
+		Backend: backend,
+		Logger:  &logger,
+	})
+
+	expectedOutput := `
+Scan errors:
+
+  no IaC files found
+    Path: empty
+
+Scan warnings:
+
+  unsupported feature
+`
+	backend.On("Output", expectedOutput).Return(nil)
+	u.DisplayScanMessages(
+		[]command.ScanMessage{{Message: "no IaC files found", Code: 2114, Path: "empty"}},
+		[]command.ScanMessage{{Message: "unsupported feature", Code: 3005}},
+	)
+	u.DisplayScanMessages(nil, nil)
+
+	backend.AssertCalled(t, "Output", expectedOutput)
+	backend.AssertNumberOfCalls(t, "Output", 1)
+}
+
 func TestProgressBar(t *testing.T) {
 	backend := new(MockUserInterface)
 	progressBar := new(MockProgressBar)
@@ -122,6 +312,8 @@ func TestDisabled(t *testing.T) {
 	u.DisplayTitle()
 	u.StartProgressBar()
 	u.ClearProgressBar()
+	u.DisplayResults(&results.Results{})
+	u.DisplayScanMessages([]command.ScanMessage{{Message: "no IaC files found"}}, nil)
 	u.DisplayCompleted()
 
 	backend.AssertNotCalled(t, "Output")
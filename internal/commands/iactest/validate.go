@@ -2,12 +2,20 @@ package iactest
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/snyk/error-catalog-golang-public/cli"
 	"github.com/snyk/go-application-framework/pkg/configuration"
+
+	"github.com/snyk/cli-extension-iac/internal/engine"
+	"github.com/snyk/cli-extension-iac/internal/exitcode"
+	"github.com/snyk/cli-extension-iac/internal/rules"
+	"github.com/snyk/cli-extension-iac/internal/severity"
+	"github.com/snyk/cli-extension-iac/internal/tfvars"
 )
 
 var (
@@ -20,19 +28,19 @@ var (
 		"frontend": {}, "backend": {}, "internal": {}, "external": {}, "mobile": {}, "saas": {}, "onprem": {}, "hosted": {}, "distributed": {}}
 	validOptionsProjectLifecycle = map[string]struct{}{
 		"production": {}, "development": {}, "sandbox": {}}
+	validOptionsFailOn = map[string]struct{}{
+		exitcode.FailOnNew: {}, exitcode.FailOnAll: {}, exitcode.FailOnUpgradable: {}}
 )
 
-const tfVarExt = ".tfvars"
-
 func validateConfig(config configuration.Configuration) error {
-	if config.GetBool(FeatureFlagNewEngine) {
+	if newEngineEnabled(config) {
 		err := validateIacV2Config(config)
 		if err != nil {
 			return err
 		}
 	}
 
-	if config.GetBool(FeatureFlagIntegratedExperience) {
+	if integratedExperienceEnabled(config) {
 		err := validateIacPlusConfig(config)
 		if err != nil {
 			return err
@@ -73,6 +81,45 @@ func validateCommonConfig(config configuration.Configuration) error {
 		}
 	}
 
+	if config.IsSet(FlagVar) {
+		err := validateVar(config)
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.IsSet(FlagHelmValues) {
+		err := validateHelmValues(config)
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.IsSet(FlagBaseline) {
+		err := validateBaseline(config)
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.IsSet(FlagBaselineWrite) && config.IsSet(FlagChangedSince) {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("The --%s and --%s flags can't be used together. A baseline must record the issues of every file, not only of the changed files", FlagBaselineWrite, FlagChangedSince))
+	}
+
+	if config.IsSet(FlagRules) {
+		err := validateRules(config)
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.IsSet(FlagSeverityOverrides) {
+		err := validateSeverityOverrides(config)
+		if err != nil {
+			return err
+		}
+	}
+
 	if config.IsSet(FlagSeverityThreshold) {
 		flag := flagWithOptions{
 			name:         FlagSeverityThreshold,
@@ -86,8 +133,45 @@ func validateCommonConfig(config configuration.Configuration) error {
 		}
 	}
 
-	if config.GetString(RulesClientURL) == "" {
-		return cli.NewGeneralIACFailureError("A rule bundle must be provided using the IAC_RULES_URL env var in the CLI build command. Example: IAC_RULES_URL=<url> make build")
+	if config.IsSet(FlagFailOn) {
+		flag := flagWithOptions{
+			name:         FlagFailOn,
+			allowEmpty:   false,
+			singleChoice: true,
+			validOptions: validOptionsFailOn,
+		}
+		err := validateFlagValue(config, flag)
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.IsSet(FlagMaxIssues) {
+		if _, err := exitcode.ParseLimits(config.GetString(FlagMaxIssues)); err != nil {
+			return cli.NewInvalidFlagOptionError(fmt.Sprintf("Invalid value provided to --%s: %v", FlagMaxIssues, err))
+		}
+	}
+
+	if config.IsSet(RulesManifestTTL) {
+		if _, err := time.ParseDuration(config.GetString(RulesManifestTTL)); err != nil {
+			return cli.NewInvalidFlagOptionError(fmt.Sprintf("Invalid duration %q provided to %s. Example: 1h30m", config.GetString(RulesManifestTTL), strings.ToUpper(RulesManifestTTL)))
+		}
+	}
+
+	if config.GetBool(FlagOffline) {
+		return validateOfflineConfig(config)
+	}
+
+	mirrors := rules.ParseMirrors(config.GetString(RulesMirrorURLs))
+
+	for _, mirror := range mirrors {
+		if u, err := url.Parse(mirror); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return cli.NewInvalidFlagOptionError(fmt.Sprintf("Invalid rules mirror URL %q provided to %s. Mirror URLs must be absolute HTTP or HTTPS URLs", mirror, strings.ToUpper(RulesMirrorURLs)))
+		}
+	}
+
+	if config.GetString(RulesClientURL) == "" && len(mirrors) == 0 {
+		return cli.NewGeneralIACFailureError(fmt.Sprintf("A rule bundle must be provided using the IAC_RULES_URL env var in the CLI build command, or using the %s env var. Example: IAC_RULES_URL=<url> make build", strings.ToUpper(RulesMirrorURLs)))
 	}
 
 	return nil
@@ -223,17 +307,180 @@ func validateTags(config configuration.Configuration) error {
 }
 
 func validateVarFile(config configuration.Configuration) error {
-	varFile := config.GetString(FlagVarFile)
-	_, err := os.Stat(varFile)
+	for _, varFile := range getStringArray(config, FlagVarFile) {
+		_, err := os.Stat(varFile)
 
-	if os.IsNotExist(err) {
-		return cli.NewInvalidFlagOptionError(fmt.Sprintf("We were unable to locate a variable definitions file at: %s. The file at the provided path does not exist", varFile))
+		if os.IsNotExist(err) {
+			return cli.NewInvalidFlagOptionError(fmt.Sprintf("We were unable to locate a variable definitions file at: %s. The file at the provided path does not exist", varFile))
+		}
+
+		if !tfvars.IsVarFile(varFile) {
+			errMsg := fmt.Sprintf("Unsupported value %s provided to --%s. Supported values are: .tfvars, .tfvars.json", varFile, FlagVarFile)
+			return cli.NewInvalidFlagOptionError(errMsg)
+		}
 	}
 
-	ext := filepath.Ext(varFile)
-	if ext != tfVarExt {
-		errMsg := fmt.Sprintf("Unsupported value %s provided to --%s. Supported values are: %s", varFile, FlagVarFile, tfVarExt)
-		return cli.NewInvalidFlagOptionError(errMsg)
+	return nil
+}
+
+func validateVar(config configuration.Configuration) error {
+	for _, v := range getStringArray(config, FlagVar) {
+		if _, _, err := tfvars.ParseVar(v); err != nil {
+			return cli.NewInvalidFlagOptionError(fmt.Sprintf("Unsupported value %s provided to --%s. Variables must be in the key=value format", v, FlagVar))
+		}
+	}
+
+	return nil
+}
+
+func validateHelmValues(config configuration.Configuration) error {
+	for _, valuesFile := range parseListFlag(config.GetString(FlagHelmValues)) {
+		if _, err := os.Stat(valuesFile); err != nil {
+			return cli.NewInvalidFlagOptionError(fmt.Sprintf("We were unable to locate a Helm values file at: %s. The file at the provided path does not exist", valuesFile))
+		}
+
+		if ext := filepath.Ext(valuesFile); ext != ".yaml" && ext != ".yml" {
+			return cli.NewInvalidFlagOptionError(fmt.Sprintf("Unsupported value %s provided to --%s. Supported values are .yaml and .yml files", valuesFile, FlagHelmValues))
+		}
+	}
+
+	return nil
+}
+
+func validateRules(config configuration.Configuration) error {
+	rulesPath := config.GetString(FlagRules)
+
+	info, err := os.Stat(rulesPath)
+	if err != nil {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("We were unable to locate the custom rules at: %s. The path provided to --%s does not exist", rulesPath, FlagRules))
+	}
+
+	if !info.IsDir() && !strings.HasSuffix(rulesPath, ".tar.gz") {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("Unsupported value %s provided to --%s. Supported values are a directory of Rego files or a .tar.gz bundle", rulesPath, FlagRules))
+	}
+
+	return nil
+}
+
+func validateSeverityOverrides(config configuration.Configuration) error {
+	path := config.GetString(FlagSeverityOverrides)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("We were unable to read a severity overrides file at: %s", path))
+	}
+
+	if _, err := severity.ParseOverrides(data); err != nil {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("Invalid severity overrides file provided to --%s: %v", FlagSeverityOverrides, err))
+	}
+
+	return nil
+}
+
+// validateRuleSelection checks that the rule IDs provided to --include-rules
+// and --exclude-rules are the IDs of loaded rules. It can only run once the
+// rules are loaded, which is why it's not part of validateConfig. Its error is
+// reported by the engine as a scan error, and then as an invalid flag option.
+func validateRuleSelection(s *ruleSelection, metadata []engine.MetadataResult) error {
+	known := make(map[string]bool)
+
+	for _, m := range metadata {
+		if m.Error == "" && m.Metadata.ID != "" {
+			known[strings.ToUpper(m.Metadata.ID)] = true
+		}
+	}
+
+	flags := []struct {
+		name string
+		ids  []string
+	}{
+		{name: FlagIncludeRules, ids: s.IncludeRules},
+		{name: FlagExcludeRules, ids: s.ExcludeRules},
+	}
+
+	for _, flag := range flags {
+		var unknown []string
+
+		for _, id := range flag.ids {
+			if !known[strings.ToUpper(id)] {
+				unknown = append(unknown, id)
+			}
+		}
+
+		if len(unknown) > 0 {
+			return fmt.Errorf("Unknown rule IDs provided to --%s: %s. Use snyk iac rules list to list the available rules", flag.name, strings.Join(unknown, ", "))
+		}
+	}
+
+	return nil
+}
+
+func errNoRuleSelected() error {
+	return fmt.Errorf("No rule matches the values provided to --%s, --%s, --%s, --%s and --%s", FlagIncludeRules, FlagExcludeRules, FlagIncludeLabels, FlagCategories, FlagControls)
+}
+
+// validateOfflineConfig validates the configuration of the offline mode, where
+// the rule bundle, the organization settings and the custom rules are read
+// from local files.
+func validateOfflineConfig(config configuration.Configuration) error {
+	if config.GetBool(FlagReport) {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("The flag --%s is not supported together with --%s", FlagReport, FlagOffline))
+	}
+
+	if config.IsSet(FlagSnykCloudEnvironment) {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("The flag --%s is not supported together with --%s", FlagSnykCloudEnvironment, FlagOffline))
+	}
+
+	if config.GetString(FlagRulesBundleVersion) != "" {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("The flag --%s is not supported together with --%s", FlagRulesBundleVersion, FlagOffline))
+	}
+
+	// The report of an offline test is rendered by the extension, which
+	// doesn't render the JSON and SARIF outputs of the legacy CLI.
+	for _, flag := range []string{FlagJson, FlagSarif} {
+		if config.GetBool(flag) {
+			return cli.NewInvalidFlagOptionError(fmt.Sprintf("The flag --%s is not supported together with --%s", flag, FlagOffline))
+		}
+	}
+
+	if config.GetString(FlagJsonFileOutput) != "" {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("The flag --%s is not supported together with --%s", FlagJsonFileOutput, FlagOffline))
+	}
+
+	files := []struct {
+		key         string
+		description string
+	}{
+		{key: RulesBundlePath, description: "rule bundle"},
+		{key: OrgSettingsPath, description: "organization settings file"},
+	}
+
+	for _, file := range files {
+		path := config.GetString(file.key)
+
+		if path == "" {
+			return cli.NewInvalidFlagOptionError(fmt.Sprintf("The --%s flag requires a local %s. Set its path with the %s environment variable", FlagOffline, file.description, strings.ToUpper(file.key)))
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			return cli.NewInvalidFlagOptionError(fmt.Sprintf("We were unable to locate the %s at: %s", file.description, path))
+		}
+	}
+
+	if dir := config.GetString(CustomRulesPath); dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return cli.NewInvalidFlagOptionError(fmt.Sprintf("We were unable to locate the custom rules directory at: %s", dir))
+		}
+	}
+
+	return nil
+}
+
+func validateBaseline(config configuration.Configuration) error {
+	baselineFile := config.GetString(FlagBaseline)
+
+	if _, err := os.Stat(baselineFile); os.IsNotExist(err) {
+		return cli.NewInvalidFlagOptionError(fmt.Sprintf("We were unable to locate a baseline file at: %s. The file at the provided path does not exist", baselineFile))
 	}
 
 	return nil
@@ -2,10 +2,12 @@ package iactest
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/snyk/go-application-framework/pkg/configuration"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestValidateFlagValue(t *testing.T) {
@@ -469,6 +471,11 @@ func TestValidateVarFile(t *testing.T) {
 			hasErr: false,
 			desc:   "valid --var-file",
 		},
+		{
+			in:     input{"test.tfvars.json", true},
+			hasErr: false,
+			desc:   "valid JSON --var-file",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -492,6 +499,215 @@ func TestValidateVarFile(t *testing.T) {
 	}
 }
 
+func TestValidateMultipleVarFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	first := filepath.Join(dir, "first.tfvars")
+	second := filepath.Join(dir, "second.tfvars.json")
+
+	assert.NoError(t, os.WriteFile(first, nil, 0644))
+	assert.NoError(t, os.WriteFile(second, nil, 0644))
+
+	config := setupMockConfig(map[string]any{FlagVarFile: []string{first, second}})
+	assert.Nil(t, validateVarFile(config))
+
+	config = setupMockConfig(map[string]any{FlagVarFile: []string{first, filepath.Join(dir, "missing.tfvars")}})
+	assert.NotNil(t, validateVarFile(config))
+}
+
+func TestValidateVar(t *testing.T) {
+	testCases := []struct {
+		in     []string
+		hasErr bool
+		desc   string
+	}{
+		{
+			in:     []string{"region=eu-west-1", `cidrs=["0.0.0.0/0"]`, "empty="},
+			hasErr: false,
+			desc:   "valid --var",
+		},
+		{
+			in:     []string{"region=eu-west-1", "region"},
+			hasErr: true,
+			desc:   "--var without a value",
+		},
+		{
+			in:     []string{"=eu-west-1"},
+			hasErr: true,
+			desc:   "--var without a name",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := setupMockConfig(map[string]any{FlagVar: tc.in})
+
+			if tc.hasErr {
+				assert.NotNil(t, validateVar(config))
+			} else {
+				assert.Nil(t, validateVar(config))
+			}
+		})
+	}
+}
+
+func TestValidateBaseline(t *testing.T) {
+	baselineFile := filepath.Join(t.TempDir(), ".snyk-iac-baseline.json")
+	assert.NoError(t, os.WriteFile(baselineFile, []byte(`{"version": 1}`), 0644))
+
+	assert.Nil(t, validateBaseline(setupMockConfig(map[string]any{FlagBaseline: baselineFile})))
+	assert.NotNil(t, validateBaseline(setupMockConfig(map[string]any{FlagBaseline: "missing.json"})))
+}
+
+func TestValidateBaselineWriteWithChangedSince(t *testing.T) {
+	assert.Nil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagBaselineWrite: "baseline.json"})))
+	assert.Nil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagChangedSince: "main"})))
+	assert.NotNil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagBaselineWrite: "baseline.json", FlagChangedSince: "main"})))
+}
+
+func TestValidateOfflineConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	bundleFile := filepath.Join(dir, "bundle.tar.gz")
+	assert.NoError(t, os.WriteFile(bundleFile, nil, 0644))
+
+	settingsFile := filepath.Join(dir, "settings.json")
+	assert.NoError(t, os.WriteFile(settingsFile, []byte(`{}`), 0644))
+
+	valid := map[string]any{
+		FlagOffline:     true,
+		RulesBundlePath: bundleFile,
+		OrgSettingsPath: settingsFile,
+		CustomRulesPath: dir,
+	}
+
+	with := func(key string, value any) map[string]any {
+		config := map[string]any{}
+		for k, v := range valid {
+			config[k] = v
+		}
+		config[key] = value
+		return config
+	}
+
+	testCases := []struct {
+		desc   string
+		config map[string]any
+		hasErr bool
+	}{
+		{desc: "valid offline config", config: valid},
+		{desc: "missing bundle", config: with(RulesBundlePath, ""), hasErr: true},
+		{desc: "bundle not found", config: with(RulesBundlePath, filepath.Join(dir, "missing.tar.gz")), hasErr: true},
+		{desc: "missing settings", config: with(OrgSettingsPath, ""), hasErr: true},
+		{desc: "custom rules not a directory", config: with(CustomRulesPath, bundleFile), hasErr: true},
+		{desc: "report", config: with(FlagReport, true), hasErr: true},
+		{desc: "snyk cloud environment", config: with(FlagSnykCloudEnvironment, "env"), hasErr: true},
+		{desc: "pinned bundle version", config: with(FlagRulesBundleVersion, "v1.0.0"), hasErr: true},
+		{desc: "json", config: with(FlagJson, true), hasErr: true},
+		{desc: "sarif", config: with(FlagSarif, true), hasErr: true},
+		{desc: "json file output", config: with(FlagJsonFileOutput, "results.json"), hasErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := setupMockConfig(tc.config)
+			config.Set(RulesClientURL, "")
+
+			err := validateCommonConfig(config)
+			if tc.hasErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateRulesManifestTTL(t *testing.T) {
+	assert.Nil(t, validateCommonConfig(setupMockConfig(map[string]any{RulesManifestTTL: "30m"})))
+	assert.Nil(t, validateCommonConfig(setupMockConfig(map[string]any{RulesManifestTTL: "0s"})))
+	assert.NotNil(t, validateCommonConfig(setupMockConfig(map[string]any{RulesManifestTTL: "one hour"})))
+}
+
+func TestValidateRulesMirrorURLs(t *testing.T) {
+	config := setupMockConfig(map[string]any{RulesMirrorURLs: "https://mirror.example.com, http://fallback.example.com/rules"})
+	config.Set(RulesClientURL, "")
+	assert.Nil(t, validateCommonConfig(config))
+
+	config = setupMockConfig(map[string]any{RulesMirrorURLs: ""})
+	config.Set(RulesClientURL, "")
+	assert.NotNil(t, validateCommonConfig(config))
+
+	assert.NotNil(t, validateCommonConfig(setupMockConfig(map[string]any{RulesMirrorURLs: "mirror.example.com"})))
+	assert.NotNil(t, validateCommonConfig(setupMockConfig(map[string]any{RulesMirrorURLs: "ftp://mirror.example.com"})))
+}
+
+func TestValidateRules(t *testing.T) {
+	dir := t.TempDir()
+
+	bundleFile := filepath.Join(dir, "rules.tar.gz")
+	assert.NoError(t, os.WriteFile(bundleFile, nil, 0644))
+
+	regoFile := filepath.Join(dir, "rule.rego")
+	assert.NoError(t, os.WriteFile(regoFile, nil, 0644))
+
+	assert.Nil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagRules: dir})))
+	assert.Nil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagRules: bundleFile})))
+	assert.NotNil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagRules: regoFile})))
+	assert.NotNil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagRules: filepath.Join(dir, "missing")})))
+}
+
+func TestValidateSeverityOverrides(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.yaml")
+	assert.NoError(t, os.WriteFile(valid, []byte("severities:\n  SNYK-CC-TF-1: low\n"), 0644))
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	assert.NoError(t, os.WriteFile(invalid, []byte("severities:\n  SNYK-CC-TF-1: urgent\n"), 0644))
+
+	assert.Nil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagSeverityOverrides: valid})))
+	assert.NotNil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagSeverityOverrides: invalid})))
+	assert.NotNil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagSeverityOverrides: filepath.Join(dir, "missing.yaml")})))
+}
+
+func TestValidateHelmValues(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "values.yaml")
+	assert.NoError(t, os.WriteFile(valid, []byte("replicaCount: 2\n"), 0644))
+
+	invalid := filepath.Join(dir, "values.json")
+	assert.NoError(t, os.WriteFile(invalid, []byte("{}"), 0644))
+
+	assert.Nil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagHelmValues: valid})))
+	assert.NotNil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagHelmValues: valid + "," + invalid})))
+	assert.NotNil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagHelmValues: filepath.Join(dir, "missing.yaml")})))
+}
+
+func TestValidateExitCodePolicy(t *testing.T) {
+	assert.Nil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagFailOn: "upgradable"})))
+	assert.NotNil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagFailOn: "patchable"})))
+	assert.Nil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagMaxIssues: "critical=0,high=5"})))
+	assert.NotNil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagMaxIssues: "critical"})))
+	assert.NotNil(t, validateCommonConfig(setupMockConfig(map[string]any{FlagMaxIssues: "urgent=1"})))
+}
+
+func TestValidateRuleSelection(t *testing.T) {
+	selection := &ruleSelection{
+		IncludeRules: []string{"SNYK-CC-1"},
+		ExcludeRules: []string{"snyk-cc-2"},
+	}
+	assert.Nil(t, validateRuleSelection(selection, testRulesMetadata))
+
+	selection = &ruleSelection{
+		IncludeRules: []string{"SNYK-CC-1", "SNYK-CC-9", "SNYK-CC-10"},
+	}
+	err := validateRuleSelection(selection, testRulesMetadata)
+
+	require.EqualError(t, err, "Unknown rule IDs provided to --include-rules: SNYK-CC-9, SNYK-CC-10. Use snyk iac rules list to list the available rules")
+}
+
 func setupMockConfig(flagValues map[string]any) configuration.Configuration {
 	config := configuration.New()
 	config.Set(RulesClientURL, "url")
@@ -0,0 +1,278 @@
+// Package compliance summarizes scan results by compliance framework and
+// control, and renders the summary as JSON or Markdown.
+package compliance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/snyk/cli-extension-iac/internal/controls"
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+// Summary is the compliance posture of the scanned resources, by framework.
+type Summary struct {
+	Frameworks []Framework `json:"frameworks"`
+}
+
+// Framework summarizes the controls of a framework. A control passes if none
+// of its checks failed.
+type Framework struct {
+	Name           string    `json:"name"`
+	PassedControls int       `json:"passedControls"`
+	FailedControls int       `json:"failedControls"`
+	Percentage     float64   `json:"percentage"`
+	Controls       []Control `json:"controls"`
+}
+
+// Control summarizes the checks of a control. A check is the evaluation of a
+// rule mapped to the control against a resource.
+type Control struct {
+	ID               string     `json:"id"`
+	Passed           int        `json:"passed"`
+	Failed           int        `json:"failed"`
+	Percentage       float64    `json:"percentage"`
+	FailingResources []Resource `json:"failingResources,omitempty"`
+}
+
+// Resource is a resource that failed a rule mapped to a control.
+type Resource struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	RuleID string `json:"ruleId"`
+}
+
+// FromResults groups the failed and passed vulnerabilities by the controls of
+// their rules. Ignored and baseline vulnerabilities are not part of the
+// summary. Vulnerabilities of rules without controls are skipped.
+func FromResults(r *results.Results) *Summary {
+	summary := Summary{
+		Frameworks: []Framework{},
+	}
+
+	if r == nil {
+		return &summary
+	}
+
+	builders := make(map[string]*controlBuilder)
+
+	add := func(v results.Vulnerability, passed bool) {
+		for _, control := range ruleControls(r, v.Rule) {
+			b := builders[control]
+			if b == nil {
+				b = &controlBuilder{
+					control: Control{ID: control},
+					seen:    make(map[Resource]bool),
+				}
+				builders[control] = b
+			}
+
+			b.add(v, passed)
+		}
+	}
+
+	for _, v := range r.Vulnerabilities {
+		add(v, false)
+	}
+
+	for _, v := range r.PassedVulnerabilities {
+		add(v, true)
+	}
+
+	frameworks := make(map[string]*Framework)
+
+	var names []string
+
+	for _, b := range builders {
+		name := controls.Framework(b.control.ID)
+
+		f := frameworks[name]
+		if f == nil {
+			f = &Framework{Name: name}
+			frameworks[name] = f
+			names = append(names, name)
+		}
+
+		control := b.build()
+
+		if control.Failed > 0 {
+			f.FailedControls++
+		} else {
+			f.PassedControls++
+		}
+
+		f.Controls = append(f.Controls, control)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := frameworks[name]
+
+		sort.Slice(f.Controls, func(i, j int) bool {
+			return f.Controls[i].ID < f.Controls[j].ID
+		})
+
+		f.Percentage = percentage(f.PassedControls, f.FailedControls)
+
+		summary.Frameworks = append(summary.Frameworks, *f)
+	}
+
+	return &summary
+}
+
+// ruleControls returns the controls of a rule. The controls of the Snyk rules
+// are not part of the vulnerabilities, and are read from the results instead.
+func ruleControls(r *results.Results, rule results.Rule) []string {
+	if len(rule.Controls) > 0 {
+		return rule.Controls
+	}
+
+	return r.RuleControls[rule.ID]
+}
+
+type controlBuilder struct {
+	control Control
+	seen    map[Resource]bool
+}
+
+func (b *controlBuilder) add(v results.Vulnerability, passed bool) {
+	if passed {
+		b.control.Passed++
+		return
+	}
+
+	b.control.Failed++
+
+	resource := Resource{
+		ID:     v.Resource.ID,
+		Type:   v.Resource.Type,
+		File:   filepath.ToSlash(v.Resource.File),
+		Line:   v.Resource.Line,
+		RuleID: v.Rule.ID,
+	}
+
+	// A rule can report more than one vulnerability for the same resource.
+	if !b.seen[resource] {
+		b.seen[resource] = true
+		b.control.FailingResources = append(b.control.FailingResources, resource)
+	}
+}
+
+func (b *controlBuilder) build() Control {
+	control := b.control
+
+	control.Percentage = percentage(control.Passed, control.Failed)
+
+	sort.SliceStable(control.FailingResources, func(i, j int) bool {
+		x, y := control.FailingResources[i], control.FailingResources[j]
+		if x.File != y.File {
+			return x.File < y.File
+		}
+		if x.ID != y.ID {
+			return x.ID < y.ID
+		}
+		return x.RuleID < y.RuleID
+	})
+
+	return control
+}
+
+// percentage returns the percentage of passed items, rounded to two decimals.
+// Without any item, the percentage is 100.
+func percentage(passed, failed int) float64 {
+	total := passed + failed
+	if total == 0 {
+		return 100
+	}
+
+	return math.Round(float64(passed)/float64(total)*10000) / 100
+}
+
+// WriteJSON renders the compliance summary of the scan results as an indented
+// JSON document.
+func WriteJSON(w io.Writer, r *results.Results) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(FromResults(r)); err != nil {
+		return fmt.Errorf("encode compliance summary: %v", err)
+	}
+
+	return nil
+}
+
+// WriteMarkdown renders the compliance summary of the scan results as a
+// Markdown document, with a table of the frameworks followed by a section for
+// every framework.
+func WriteMarkdown(w io.Writer, r *results.Results) error {
+	summary := FromResults(r)
+
+	var b strings.Builder
+
+	b.WriteString("# Compliance summary\n\n")
+
+	if len(summary.Frameworks) == 0 {
+		b.WriteString("No rule mapped to a compliance control was evaluated.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	b.WriteString("| Framework | Passed controls | Failed controls | Compliance |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+
+	for _, f := range summary.Frameworks {
+		fmt.Fprintf(&b, "| %s | %d | %d | %s |\n", escape(f.Name), f.PassedControls, f.FailedControls, formatPercentage(f.Percentage))
+	}
+
+	for _, f := range summary.Frameworks {
+		fmt.Fprintf(&b, "\n## %s\n\n", f.Name)
+		b.WriteString("| Control | Passed | Failed | Compliance |\n")
+		b.WriteString("| --- | --- | --- | --- |\n")
+
+		for _, c := range f.Controls {
+			fmt.Fprintf(&b, "| %s | %d | %d | %s |\n", escape(c.ID), c.Passed, c.Failed, formatPercentage(c.Percentage))
+		}
+
+		for _, c := range f.Controls {
+			if len(c.FailingResources) == 0 {
+				continue
+			}
+
+			fmt.Fprintf(&b, "\n### %s\n\n", c.ID)
+
+			for _, resource := range c.FailingResources {
+				fmt.Fprintf(&b, "- `%s` (%s)", resource.ID, resource.RuleID)
+
+				if resource.File != "" {
+					fmt.Fprintf(&b, " in %s", resource.File)
+
+					if resource.Line > 0 {
+						fmt.Fprintf(&b, ":%d", resource.Line)
+					}
+				}
+
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatPercentage(p float64) string {
+	return fmt.Sprintf("%.2f%%", p)
+}
+
+// escape escapes the pipes in the cell of a Markdown table.
+func escape(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
@@ -0,0 +1,175 @@
+package compliance_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/compliance"
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+func testResults() *results.Results {
+	return &results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			{
+				Rule:     results.Rule{ID: "SNYK-CC-TF-1"},
+				Resource: results.Resource{ID: "aws_s3_bucket.public", Type: "aws_s3_bucket", File: "main.tf", Line: 3},
+			},
+			{
+				// The same resource is reported once per control.
+				Rule:     results.Rule{ID: "SNYK-CC-TF-1"},
+				Resource: results.Resource{ID: "aws_s3_bucket.public", Type: "aws_s3_bucket", File: "main.tf", Line: 3},
+			},
+			{
+				Rule:     results.Rule{ID: "CUSTOM-1", Controls: []string{"SOC2_CC6.1"}},
+				Resource: results.Resource{ID: "aws_s3_bucket.public", Type: "aws_s3_bucket", File: "main.tf", Line: 3},
+			},
+			{
+				Rule:     results.Rule{ID: "SNYK-CC-TF-3"},
+				Resource: results.Resource{ID: "aws_vpc.main", Type: "aws_vpc", File: "vpc.tf", Line: 1},
+			},
+		},
+		PassedVulnerabilities: []results.Vulnerability{
+			{
+				Rule:     results.Rule{ID: "SNYK-CC-TF-1"},
+				Resource: results.Resource{ID: "aws_s3_bucket.private", Type: "aws_s3_bucket", File: "main.tf", Line: 7},
+			},
+			{
+				Rule:     results.Rule{ID: "SNYK-CC-TF-2"},
+				Resource: results.Resource{ID: "aws_s3_bucket.private", Type: "aws_s3_bucket", File: "main.tf", Line: 7},
+			},
+		},
+		RuleControls: map[string][]string{
+			"SNYK-CC-TF-1": {"CIS-AWS_v1.4.0_2.1.5", "PCI-DSS_v3.2.1_1.2"},
+			"SNYK-CC-TF-2": {"CIS-AWS_v1.4.0_2.1.1"},
+		},
+	}
+}
+
+func TestFromNilResults(t *testing.T) {
+	require.Equal(t, &compliance.Summary{Frameworks: []compliance.Framework{}}, compliance.FromResults(nil))
+}
+
+func TestFromResults(t *testing.T) {
+	summary := compliance.FromResults(testResults())
+
+	failingBucket := compliance.Resource{
+		ID:     "aws_s3_bucket.public",
+		Type:   "aws_s3_bucket",
+		File:   "main.tf",
+		Line:   3,
+		RuleID: "SNYK-CC-TF-1",
+	}
+
+	expected := &compliance.Summary{
+		Frameworks: []compliance.Framework{
+			{
+				Name:           "CIS-AWS_v1.4.0",
+				PassedControls: 1,
+				FailedControls: 1,
+				Percentage:     50,
+				Controls: []compliance.Control{
+					{
+						ID:         "CIS-AWS_v1.4.0_2.1.1",
+						Passed:     1,
+						Percentage: 100,
+					},
+					{
+						ID:               "CIS-AWS_v1.4.0_2.1.5",
+						Passed:           1,
+						Failed:           2,
+						Percentage:       33.33,
+						FailingResources: []compliance.Resource{failingBucket},
+					},
+				},
+			},
+			{
+				Name:           "PCI-DSS_v3.2.1",
+				FailedControls: 1,
+				Percentage:     0,
+				Controls: []compliance.Control{
+					{
+						ID:               "PCI-DSS_v3.2.1_1.2",
+						Passed:           1,
+						Failed:           2,
+						Percentage:       33.33,
+						FailingResources: []compliance.Resource{failingBucket},
+					},
+				},
+			},
+			{
+				Name:           "SOC2",
+				FailedControls: 1,
+				Percentage:     0,
+				Controls: []compliance.Control{
+					{
+						ID:     "SOC2_CC6.1",
+						Failed: 1,
+						FailingResources: []compliance.Resource{
+							{ID: "aws_s3_bucket.public", Type: "aws_s3_bucket", File: "main.tf", Line: 3, RuleID: "CUSTOM-1"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	require.Equal(t, expected, summary)
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, compliance.WriteMarkdown(&buf, testResults()))
+
+	expected := "# Compliance summary\n" +
+		"\n" +
+		"| Framework | Passed controls | Failed controls | Compliance |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| CIS-AWS_v1.4.0 | 1 | 1 | 50.00% |\n" +
+		"| PCI-DSS_v3.2.1 | 0 | 1 | 0.00% |\n" +
+		"| SOC2 | 0 | 1 | 0.00% |\n" +
+		"\n" +
+		"## CIS-AWS_v1.4.0\n" +
+		"\n" +
+		"| Control | Passed | Failed | Compliance |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| CIS-AWS_v1.4.0_2.1.1 | 1 | 0 | 100.00% |\n" +
+		"| CIS-AWS_v1.4.0_2.1.5 | 1 | 2 | 33.33% |\n" +
+		"\n" +
+		"### CIS-AWS_v1.4.0_2.1.5\n" +
+		"\n" +
+		"- `aws_s3_bucket.public` (SNYK-CC-TF-1) in main.tf:3\n" +
+		"\n" +
+		"## PCI-DSS_v3.2.1\n" +
+		"\n" +
+		"| Control | Passed | Failed | Compliance |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| PCI-DSS_v3.2.1_1.2 | 1 | 2 | 33.33% |\n" +
+		"\n" +
+		"### PCI-DSS_v3.2.1_1.2\n" +
+		"\n" +
+		"- `aws_s3_bucket.public` (SNYK-CC-TF-1) in main.tf:3\n" +
+		"\n" +
+		"## SOC2\n" +
+		"\n" +
+		"| Control | Passed | Failed | Compliance |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| SOC2_CC6.1 | 0 | 1 | 0.00% |\n" +
+		"\n" +
+		"### SOC2_CC6.1\n" +
+		"\n" +
+		"- `aws_s3_bucket.public` (CUSTOM-1) in main.tf:3\n"
+
+	require.Equal(t, expected, buf.String())
+}
+
+func TestWriteMarkdownWithoutControls(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, compliance.WriteMarkdown(&buf, &results.Results{}))
+
+	require.Equal(t, "# Compliance summary\n\nNo rule mapped to a compliance control was evaluated.\n", buf.String())
+}
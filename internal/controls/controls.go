@@ -0,0 +1,41 @@
+// Package controls matches the compliance controls that rules are mapped to.
+// Controls have the form "<framework>_<version>_<section>", for example
+// "CIS-AWS_v1.4.0_1.2".
+package controls
+
+import "strings"
+
+// Matches returns true if the control is selected by the selector. A selector
+// is a framework, with or without its version, or a full control. Matching is
+// case-insensitive.
+func Matches(control, selector string) bool {
+	control = strings.ToLower(control)
+	selector = strings.ToLower(selector)
+
+	return control == selector || strings.HasPrefix(control, selector+"_")
+}
+
+// Framework returns the framework of a control, including its version. A
+// control that doesn't have the expected form is its own framework.
+func Framework(control string) string {
+	parts := strings.SplitN(control, "_", 3)
+	if len(parts) < 3 {
+		return parts[0]
+	}
+
+	return parts[0] + "_" + parts[1]
+}
+
+// MatchesAny returns true if one of the controls is selected by one of the
+// selectors.
+func MatchesAny(controls, selectors []string) bool {
+	for _, selector := range selectors {
+		for _, control := range controls {
+			if Matches(control, selector) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
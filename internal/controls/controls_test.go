@@ -0,0 +1,35 @@
+package controls
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		control  string
+		selector string
+		expected bool
+	}{
+		{control: "CIS-AWS_v1.4.0_1.2", selector: "CIS-AWS", expected: true},
+		{control: "CIS-AWS_v1.4.0_1.2", selector: "cis-aws_v1.4.0", expected: true},
+		{control: "CIS-AWS_v1.4.0_1.2", selector: "CIS-AWS_v1.4.0_1.2", expected: true},
+		{control: "CIS-AWS_v1.4.0_1.2", selector: "CIS-AWS_v1.3.0", expected: false},
+		{control: "CIS-AWS_v1.4.0_1.2", selector: "CIS", expected: false},
+		{control: "CIS-AWS_v1.4.0_1.20", selector: "CIS-AWS_v1.4.0_1.2", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.control+" "+tt.selector, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Matches(tt.control, tt.selector))
+		})
+	}
+}
+
+func TestFramework(t *testing.T) {
+	assert.Equal(t, "CIS-AWS_v1.4.0", Framework("CIS-AWS_v1.4.0_1.2"))
+	assert.Equal(t, "CIS-AWS_v1.4.0", Framework("CIS-AWS_v1.4.0_1.2_a"))
+	assert.Equal(t, "SOC2", Framework("SOC2_CC6.1"))
+	assert.Equal(t, "PCI-DSS", Framework("PCI-DSS"))
+}
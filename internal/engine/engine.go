@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 
@@ -13,10 +14,13 @@ import (
 	"github.com/snyk/cli-extension-iac/internal/cloudapi"
 	engine "github.com/snyk/cli-extension-iac/internal/policyengine"
 	resultspkg "github.com/snyk/cli-extension-iac/internal/results"
+	"github.com/snyk/cli-extension-iac/internal/tfvars"
 )
 
 type Results = engine.Results
 
+type MetadataResult = engine.MetadataResult
+
 type Engine struct {
 	FS afero.Fs
 }
@@ -30,12 +34,25 @@ type RunOptions struct {
 	SnykClient           cloudapi.Client
 	Scan                 string
 	DetectionDepth       int
-	VarFile              string
-	Logger               *zerolog.Logger
+	// TerraformVars are the Terraform var files and variables set on the
+	// command line, in order.
+	TerraformVars []tfvars.Arg
+	// Env is the environment. Its TF_VAR_ variables set Terraform variables.
+	Env []string
+	// HelmValues are the values files used to render the Helm charts, in
+	// addition to the values of the charts.
+	HelmValues []string
+	// LocalRuleBundles are the custom rules passed with --rules. They are
+	// loaded after CustomRuleBundles, and failing to load them is fatal.
+	LocalRuleBundles []bundle.Reader
+	// SelectRules resolves the IDs of the rules to evaluate from the metadata
+	// of the loaded rules. Every rule is evaluated if SelectRules is nil.
+	SelectRules func(metadata []MetadataResult) ([]string, error)
+	Logger      *zerolog.Logger
 }
 
 func (e *Engine) Run(ctx context.Context, options RunOptions) (*Results, resultspkg.ScanAnalytics, []error, []error) {
-	var errs []error
+	var errs, warnings []error
 
 	resolver, resolverErrCh, err := newResourcesResolvers(ctx, options)
 	if err != nil {
@@ -47,25 +64,47 @@ func (e *Engine) Run(ctx context.Context, options RunOptions) (*Results, results
 
 	wrapped := engine.NewEngine(ctx, engine.EngineOptions{
 		SnykBundle:        options.SnykBundle,
-		CustomRuleBundles: options.CustomRuleBundles,
+		CustomRuleBundles: append(append([]bundle.Reader{}, options.CustomRuleBundles...), options.LocalRuleBundles...),
 		Logger:            options.Logger,
 	})
-	// Initialization errors are considered non-fatal. The engine is able to
-	// continue running bundles whichever bundles did successfully initialize.
-	errs = append(errs, wrapped.InitializationErrors()...)
+	// Initialization errors are considered non-fatal and are reported as
+	// warnings. The engine is able to continue running whichever bundles did
+	// successfully initialize. The errors of the local custom rules are
+	// fatal, because they were explicitly requested.
+	for _, err := range wrapped.InitializationErrors() {
+		if isLocalRuleBundleError(err, options.LocalRuleBundles) {
+			errs = append(errs, err)
+		} else {
+			warnings = append(warnings, err)
+		}
+	}
+
+	ruleIDs, err := selectRules(ctx, wrapped, options.SelectRules)
+	if err != nil {
+		return nil, resultspkg.ScanAnalytics{}, append(errs, Error{
+			Message: err.Error(),
+			Code:    ErrorCodeInvalidRuleSelection,
+		}), warnings
+	}
 
 	runOptions := engine.RunOptions{
 		FS:                e.FS,
 		Paths:             options.Paths,
 		Scan:              options.Scan,
 		DetectionDepth:    options.DetectionDepth,
-		VarFile:           options.VarFile,
+		TerraformVars:     options.TerraformVars,
+		Env:               options.Env,
+		HelmValues:        options.HelmValues,
+		RuleIDs:           ruleIDs,
 		ResourcesResolver: resolver,
 	}
 	loader, configLoaderErrs, configLoaderWarnings := wrapped.LoadInput(runOptions)
+	errs = append(errs, configLoaderErrs...)
+	warnings = append(warnings, configLoaderWarnings...)
+
 	inputs := loader.ToStates()
 	if len(inputs) == 0 {
-		return nil, resultspkg.ScanAnalytics{}, append(errs, configLoaderErrs...), configLoaderWarnings
+		return nil, resultspkg.ScanAnalytics{}, errs, warnings
 	}
 
 	// Evaluate policies in another goroutine in case
@@ -96,12 +135,54 @@ func (e *Engine) Run(ctx context.Context, options RunOptions) (*Results, results
 	select {
 	case err := <-resolverErrCh:
 		if err != nil {
-			return nil, resultspkg.ScanAnalytics{}, append(errs, err), configLoaderWarnings
+			return nil, resultspkg.ScanAnalytics{}, append(errs, err), warnings
 		}
 	default:
 	}
 
-	return results, resultspkg.ScanAnalytics{SuppressedResults: suppressedResults}, configLoaderErrs, configLoaderWarnings
+	return results, resultspkg.ScanAnalytics{SuppressedResults: suppressedResults}, errs, warnings
+}
+
+// isLocalRuleBundleError returns true if err is an initialization error of one
+// of the local custom rule bundles.
+func isLocalRuleBundleError(err error, localRuleBundles []bundle.Reader) bool {
+	var engineErr Error
+
+	if !errors.As(err, &engineErr) || engineErr.Path == "" {
+		return false
+	}
+
+	for _, b := range localRuleBundles {
+		if b.Info().FileInfo.Path == engineErr.Path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// selectRules returns the IDs of the rules to evaluate. An empty list means
+// that every rule is evaluated, so a selection matching no rule is an error.
+func selectRules(ctx context.Context, eng *engine.Engine, selectFn func([]MetadataResult) ([]string, error)) ([]string, error) {
+	if selectFn == nil {
+		return nil, nil
+	}
+
+	metadata, err := eng.Metadata(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read rules metadata: %v", err)
+	}
+
+	ruleIDs, err := selectFn(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ruleIDs) == 0 {
+		return nil, fmt.Errorf("no rule matches the rule selection")
+	}
+
+	return ruleIDs, nil
 }
 
 func evalInBackground(eng *engine.Engine, ctx context.Context, options engine.RunOptions, inputs []models.State) <-chan *engine.Results {
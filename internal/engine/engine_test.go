@@ -1,10 +1,18 @@
 package engine
 
 import (
+	"context"
+	"fmt"
+	"sort"
 	"testing"
 
+	"github.com/snyk/policy-engine/pkg/bundle"
 	"github.com/snyk/policy-engine/pkg/models"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/rules"
 )
 
 func Test_calculateSuppressionInfo(t *testing.T) {
@@ -137,3 +145,118 @@ func Test_calculateSuppressionInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestRunSelectRules(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	rule := func(pkg, id string) string {
+		return fmt.Sprintf(`package rules.%s
+
+metadata := {"id": %q, "title": "S3 bucket is public", "severity": "high"}
+
+input_type := "tf"
+
+resource_type := "aws_s3_bucket"
+
+deny[info] {
+	input.acl == "public-read"
+	info := {"message": "The bucket is public"}
+}
+`, pkg, id)
+	}
+
+	require.NoError(t, afero.WriteFile(fs, "rules/first.rego", []byte(rule("first", "CUSTOM-1")), 0644))
+	require.NoError(t, afero.WriteFile(fs, "rules/second.rego", []byte(rule("second", "CUSTOM-2")), 0644))
+	require.NoError(t, afero.WriteFile(fs, "src/main.tf", []byte(`resource "aws_s3_bucket" "public" { acl = "public-read" }`), 0644))
+
+	run := func(selectRules func([]MetadataResult) ([]string, error)) (*Results, []error) {
+		customRules, err := rules.ReadLocalBundle(fs, "rules")
+		require.NoError(t, err)
+
+		e := Engine{FS: fs}
+
+		results, _, errs, _ := e.Run(context.Background(), RunOptions{
+			Paths:             []string{"src"},
+			CustomRuleBundles: []bundle.Reader{customRules},
+			SelectRules:       selectRules,
+		})
+
+		return results, errs
+	}
+
+	ruleIDs := func(results *Results) []string {
+		var ids []string
+		for _, result := range results.Results {
+			for _, ruleResults := range result.RuleResults {
+				ids = append(ids, ruleResults.Id)
+			}
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	t.Run("every rule", func(t *testing.T) {
+		results, errs := run(nil)
+		require.Empty(t, errs)
+		assert.Equal(t, []string{"CUSTOM-1", "CUSTOM-2"}, ruleIDs(results))
+	})
+
+	t.Run("selected rules", func(t *testing.T) {
+		results, errs := run(func(metadata []MetadataResult) ([]string, error) {
+			assert.Len(t, metadata, 2)
+			return []string{"CUSTOM-2"}, nil
+		})
+		require.Empty(t, errs)
+		assert.Equal(t, []string{"CUSTOM-2"}, ruleIDs(results))
+	})
+
+	t.Run("invalid selection", func(t *testing.T) {
+		results, errs := run(func([]MetadataResult) ([]string, error) {
+			return nil, fmt.Errorf("unknown rule")
+		})
+		assert.Nil(t, results)
+		assert.Equal(t, []error{Error{Message: "unknown rule", Code: ErrorCodeInvalidRuleSelection}}, errs)
+	})
+
+	t.Run("empty selection", func(t *testing.T) {
+		_, errs := run(func([]MetadataResult) ([]string, error) {
+			return nil, nil
+		})
+		assert.Equal(t, []error{Error{Message: "no rule matches the rule selection", Code: ErrorCodeInvalidRuleSelection}}, errs)
+	})
+}
+
+func TestRunInitializationErrors(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "custom/invalid.rego", []byte("package rules.invalid\n\ndeny[info] {\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "src/main.tf", []byte(`resource "aws_s3_bucket" "public" { acl = "public-read" }`), 0644))
+
+	invalidRules, err := rules.ReadLocalBundle(fs, "custom")
+	require.NoError(t, err)
+
+	e := Engine{FS: fs}
+
+	t.Run("custom rules of the organization", func(t *testing.T) {
+		results, _, errs, warnings := e.Run(context.Background(), RunOptions{
+			Paths:             []string{"src"},
+			CustomRuleBundles: []bundle.Reader{invalidRules},
+		})
+		assert.NotNil(t, results)
+		assert.Empty(t, errs)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, ErrorCodeFailedToLoadRules, warnings[0].(Error).Code)
+		assert.Equal(t, "custom", warnings[0].(Error).Path)
+	})
+
+	t.Run("local custom rules", func(t *testing.T) {
+		_, _, errs, warnings := e.Run(context.Background(), RunOptions{
+			Paths:            []string{"src"},
+			LocalRuleBundles: []bundle.Reader{invalidRules},
+		})
+		assert.Empty(t, warnings)
+		require.Len(t, errs, 1)
+		assert.Equal(t, ErrorCodeFailedToLoadRules, errs[0].(Error).Code)
+		assert.Equal(t, "custom", errs[0].(Error).Path)
+	})
+}
@@ -25,6 +25,8 @@ const (
 	ErrorCodeMissingRemoteSubmodulesError   = engine.ErrorCodeMissingRemoteSubmodulesError
 	ErrorCodeEvaluationError                = engine.ErrorCodeEvaluationError
 	ErrorCodeMissingTermError               = engine.ErrorCodeMissingTermError
+	ErrorCodeInvalidRuleSelection           = engine.ErrorCodeInvalidRuleSelection
+	ErrorCodeUnsupportedFeature             = engine.ErrorCodeUnsupportedFeature
 )
 
 type Error = engine.Error
@@ -32,3 +34,4 @@ type SubmoduleLoadingError = engine.SubmoduleLoadingError
 type MissingRemoteSubmodulesError = engine.MissingRemoteSubmodulesError
 type EvaluationError = engine.EvaluationError
 type MissingTermError = engine.MissingTermError
+type UnsupportedFeatureError = engine.UnsupportedFeatureError
@@ -0,0 +1,221 @@
+// Package exitcode decides the exit code of a test from its processed results,
+// its scan errors and its scan warnings.
+package exitcode
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+// Exit codes of a test. 3 is not used, because the Snyk CLI uses it when no
+// supported file is found.
+const (
+	Success     = 0
+	Findings    = 1
+	ScanErrors  = 2
+	PartialScan = 4
+)
+
+// Values of --fail-on.
+const (
+	// FailOnNew counts the issues that are not ignored and not part of the
+	// baseline. This is the default.
+	FailOnNew = "new"
+
+	// FailOnAll counts the issues that are not ignored, including the ones
+	// that are part of the baseline.
+	FailOnAll = "all"
+
+	// FailOnUpgradable counts the new issues that have a remediation.
+	FailOnUpgradable = "upgradable"
+)
+
+// FailOnValues lists the valid values of --fail-on.
+var FailOnValues = []string{FailOnNew, FailOnAll, FailOnUpgradable}
+
+// Policy decides when a test fails. Without limits, a single issue counted by
+// FailOn fails the test. With limits, the test fails if the number of issues
+// of a severity is greater than its limit. Severities without a limit don't
+// fail the test. Scan warnings only fail the test if FailOnWarnings is true,
+// since a partial scan is common, e.g. with remote Terraform modules.
+type Policy struct {
+	FailOn         string
+	Limits         map[string]int
+	FailOnWarnings bool
+}
+
+// Outcome is the outcome of a test.
+type Outcome struct {
+	Results      *results.Results
+	ScanErrors   int
+	ScanWarnings int
+}
+
+// Decision is the exit code of a test, and the reason for it.
+type Decision struct {
+	Code   int
+	Reason string
+}
+
+// Evaluate returns the exit code of a test. Scan errors take precedence over
+// findings, and findings take precedence over scan warnings.
+func (p Policy) Evaluate(o Outcome) Decision {
+	if o.ScanErrors > 0 {
+		return Decision{
+			Code:   ScanErrors,
+			Reason: fmt.Sprintf("the test encountered %d %s", o.ScanErrors, plural(o.ScanErrors, "error", "errors")),
+		}
+	}
+
+	if reason := p.findings(o.Results); reason != "" {
+		return Decision{
+			Code:   Findings,
+			Reason: reason,
+		}
+	}
+
+	if p.FailOnWarnings && o.ScanWarnings > 0 {
+		return Decision{
+			Code:   PartialScan,
+			Reason: fmt.Sprintf("the test was partial, with %d %s", o.ScanWarnings, plural(o.ScanWarnings, "warning", "warnings")),
+		}
+	}
+
+	return Decision{Code: Success}
+}
+
+// findings returns why the issues fail the test, or an empty string if they
+// don't.
+func (p Policy) findings(r *results.Results) string {
+	counts := make(map[string]int)
+
+	var total int
+
+	for _, v := range p.counted(r) {
+		counts[v.Severity]++
+		total++
+	}
+
+	if len(p.Limits) == 0 {
+		if total == 0 {
+			return ""
+		}
+
+		return fmt.Sprintf("%d %s found", total, plural(total, "issue", "issues"))
+	}
+
+	var exceeded []string
+
+	for _, severity := range sortedSeverities(p.Limits) {
+		if count, limit := counts[severity], p.Limits[severity]; count > limit {
+			exceeded = append(exceeded, fmt.Sprintf("%d %s (limit %d)", count, severity, limit))
+		}
+	}
+
+	if len(exceeded) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("issues above the limits: %s", strings.Join(exceeded, ", "))
+}
+
+func (p Policy) counted(r *results.Results) []results.Vulnerability {
+	if r == nil {
+		return nil
+	}
+
+	switch p.FailOn {
+	case FailOnAll:
+		return append(append([]results.Vulnerability(nil), r.Vulnerabilities...), r.BaselineVulnerabilities...)
+	case FailOnUpgradable:
+		var upgradable []results.Vulnerability
+
+		for _, v := range r.Vulnerabilities {
+			if strings.TrimSpace(v.Remediation) != "" {
+				upgradable = append(upgradable, v)
+			}
+		}
+
+		return upgradable
+	default:
+		return r.Vulnerabilities
+	}
+}
+
+var severityOrder = map[string]int{
+	"critical": 0,
+	"high":     1,
+	"medium":   2,
+	"low":      3,
+}
+
+func sortedSeverities(limits map[string]int) []string {
+	var severities []string
+
+	for severity := range limits {
+		severities = append(severities, severity)
+	}
+
+	sort.Slice(severities, func(i, j int) bool {
+		return severityOrder[severities[i]] < severityOrder[severities[j]]
+	})
+
+	return severities
+}
+
+// ParseLimits parses per-severity limits in the form "critical=0,high=5".
+func ParseLimits(v string) (map[string]int, error) {
+	limits := make(map[string]int)
+
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		severity, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid limit %q, limits must have the form <severity>=<count>", part)
+		}
+
+		severity = strings.ToLower(strings.TrimSpace(severity))
+		if _, ok := severityOrder[severity]; !ok {
+			return nil, fmt.Errorf("invalid severity %q, valid severities are: low, medium, high, critical", severity)
+		}
+
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid limit %q for severity %s, limits must be zero or a positive number", value, severity)
+		}
+
+		limits[severity] = limit
+	}
+
+	return limits, nil
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+
+	return plural
+}
+
+// Error is returned when the exit code policy fails a test.
+type Error struct {
+	Decision Decision
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("test failed: %s", e.Decision.Reason)
+}
+
+// ExitCode returns the exit code of the failed test.
+func (e *Error) ExitCode() int {
+	return e.Decision.Code
+}
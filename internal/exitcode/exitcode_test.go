@@ -0,0 +1,123 @@
+package exitcode_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/exitcode"
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+func vulnerability(severity, remediation string) results.Vulnerability {
+	return results.Vulnerability{
+		Severity:    severity,
+		Remediation: remediation,
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	scanResults := &results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			vulnerability("critical", ""),
+			vulnerability("high", "fix it"),
+			vulnerability("high", ""),
+		},
+		BaselineVulnerabilities: []results.Vulnerability{
+			vulnerability("medium", "fix it"),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		policy   exitcode.Policy
+		outcome  exitcode.Outcome
+		expected exitcode.Decision
+	}{
+		{
+			name:     "no issues",
+			outcome:  exitcode.Outcome{Results: &results.Results{}},
+			expected: exitcode.Decision{Code: exitcode.Success},
+		},
+		{
+			name:     "nil results",
+			outcome:  exitcode.Outcome{},
+			expected: exitcode.Decision{Code: exitcode.Success},
+		},
+		{
+			name:     "new issues",
+			outcome:  exitcode.Outcome{Results: scanResults},
+			expected: exitcode.Decision{Code: exitcode.Findings, Reason: "3 issues found"},
+		},
+		{
+			name:     "all issues",
+			policy:   exitcode.Policy{FailOn: exitcode.FailOnAll},
+			outcome:  exitcode.Outcome{Results: scanResults},
+			expected: exitcode.Decision{Code: exitcode.Findings, Reason: "4 issues found"},
+		},
+		{
+			name:     "upgradable issues",
+			policy:   exitcode.Policy{FailOn: exitcode.FailOnUpgradable},
+			outcome:  exitcode.Outcome{Results: scanResults},
+			expected: exitcode.Decision{Code: exitcode.Findings, Reason: "1 issue found"},
+		},
+		{
+			name:     "issues within the limits",
+			policy:   exitcode.Policy{Limits: map[string]int{"critical": 1, "high": 2}},
+			outcome:  exitcode.Outcome{Results: scanResults},
+			expected: exitcode.Decision{Code: exitcode.Success},
+		},
+		{
+			name:     "issues above the limits",
+			policy:   exitcode.Policy{Limits: map[string]int{"high": 1, "critical": 0, "low": 0}},
+			outcome:  exitcode.Outcome{Results: scanResults},
+			expected: exitcode.Decision{Code: exitcode.Findings, Reason: "issues above the limits: 1 critical (limit 0), 2 high (limit 1)"},
+		},
+		{
+			name:     "scan errors",
+			outcome:  exitcode.Outcome{Results: scanResults, ScanErrors: 1, ScanWarnings: 1},
+			expected: exitcode.Decision{Code: exitcode.ScanErrors, Reason: "the test encountered 1 error"},
+		},
+		{
+			name:     "scan warnings are ignored by default",
+			outcome:  exitcode.Outcome{Results: &results.Results{}, ScanWarnings: 2},
+			expected: exitcode.Decision{Code: exitcode.Success},
+		},
+		{
+			name:     "partial scan",
+			policy:   exitcode.Policy{FailOnWarnings: true},
+			outcome:  exitcode.Outcome{Results: &results.Results{}, ScanWarnings: 2},
+			expected: exitcode.Decision{Code: exitcode.PartialScan, Reason: "the test was partial, with 2 warnings"},
+		},
+		{
+			name:     "findings take precedence over warnings",
+			policy:   exitcode.Policy{FailOnWarnings: true},
+			outcome:  exitcode.Outcome{Results: scanResults, ScanWarnings: 2},
+			expected: exitcode.Decision{Code: exitcode.Findings, Reason: "3 issues found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.policy.Evaluate(tt.outcome))
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := exitcode.ParseLimits("Critical=0, high=5,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"critical": 0, "high": 5}, limits)
+
+	for _, invalid := range []string{"high", "urgent=1", "high=-1", "high=many"} {
+		_, err := exitcode.ParseLimits(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestError(t *testing.T) {
+	err := &exitcode.Error{Decision: exitcode.Decision{Code: exitcode.Findings, Reason: "3 issues found"}}
+
+	require.Equal(t, "test failed: 3 issues found", err.Error())
+	require.Equal(t, exitcode.Findings, err.ExitCode())
+}
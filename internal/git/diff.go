@@ -0,0 +1,111 @@
+package git
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ChangedFiles computes the files that changed between a Git reference and
+// the working tree. path is a path inside the Git repository. ref is any
+// revision understood by Git (e.g. a branch name, a tag or a commit hash). The
+// result includes files changed in the commits of HEAD since its merge base
+// with ref, like `git diff ref...HEAD`, and any uncommitted change in the
+// working tree, including untracked files. The commits added to ref since
+// HEAD diverged from it are not changes of HEAD, and are left out. Deleted
+// and renamed files are reported using both their old and new names.
+// ChangedFiles returns absolute paths, sorted alphabetically.
+func ChangedFiles(path string, ref string) ([]string, error) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{
+		DetectDotGit: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open repository: %v", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("read worktree: %v", err)
+	}
+
+	refCommit, err := resolveCommit(repo, plumbing.Revision(ref))
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %v", ref, err)
+	}
+
+	headCommit, err := resolveCommit(repo, plumbing.Revision(plumbing.HEAD))
+	if err != nil {
+		return nil, fmt.Errorf("resolve HEAD: %v", err)
+	}
+
+	bases, err := refCommit.MergeBase(headCommit)
+	if err != nil {
+		return nil, fmt.Errorf("find merge base of %s and HEAD: %v", ref, err)
+	}
+
+	if len(bases) == 0 {
+		return nil, fmt.Errorf("find merge base of %s and HEAD: no common ancestor", ref)
+	}
+
+	baseTree, err := bases[0].Tree()
+	if err != nil {
+		return nil, fmt.Errorf("read merge base: %v", err)
+	}
+
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("read HEAD: %v", err)
+	}
+
+	changes, err := object.DiffTree(baseTree, headTree)
+	if err != nil {
+		return nil, fmt.Errorf("diff trees: %v", err)
+	}
+
+	changed := make(map[string]bool)
+
+	for _, change := range changes {
+		if change.From.Name != "" {
+			changed[change.From.Name] = true
+		}
+		if change.To.Name != "" {
+			changed[change.To.Name] = true
+		}
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("read worktree status: %v", err)
+	}
+
+	for name, fileStatus := range status {
+		if fileStatus.Staging != git.Unmodified || fileStatus.Worktree != git.Unmodified {
+			changed[name] = true
+		}
+	}
+
+	root := worktree.Filesystem.Root()
+
+	files := make([]string, 0, len(changed))
+
+	for name := range changed {
+		files = append(files, filepath.Join(root, filepath.FromSlash(name)))
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func resolveCommit(repo *git.Repository, revision plumbing.Revision) (*object.Commit, error) {
+	hash, err := repo.ResolveRevision(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.CommitObject(*hash)
+}
@@ -0,0 +1,122 @@
+package git_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	goGit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/git"
+)
+
+func TestChangedFiles(t *testing.T) {
+	root, repo := initRepository(t)
+
+	writeFile(t, root, "unchanged.tf", "a")
+	writeFile(t, root, "committed.tf", "a")
+	writeFile(t, root, "deleted.tf", "a")
+	writeFile(t, root, "modified.tf", "a")
+	base := commitAll(t, repo)
+
+	writeFile(t, root, "committed.tf", "b")
+	require.NoError(t, os.Remove(filepath.Join(root, "deleted.tf")))
+	writeFile(t, root, "module/added.tf", "a")
+	commitAll(t, repo)
+
+	writeFile(t, root, "modified.tf", "b")
+	writeFile(t, root, "untracked.yaml", "a")
+
+	files, err := git.ChangedFiles(root, base.String())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		filepath.Join(root, "committed.tf"),
+		filepath.Join(root, "deleted.tf"),
+		filepath.Join(root, "modified.tf"),
+		filepath.Join(root, "module", "added.tf"),
+		filepath.Join(root, "untracked.yaml"),
+	}, files)
+}
+
+func TestChangedFilesSinceMergeBase(t *testing.T) {
+	root, repo := initRepository(t)
+
+	writeFile(t, root, "main.tf", "a")
+	base := commitAll(t, repo)
+
+	workTree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	// The reference moves on after HEAD diverged from it.
+	writeFile(t, root, "upstream.tf", "a")
+	upstream := commitAll(t, repo)
+
+	require.NoError(t, workTree.Checkout(&goGit.CheckoutOptions{Hash: base, Branch: plumbing.NewBranchReferenceName("feature"), Create: true}))
+
+	writeFile(t, root, "feature.tf", "a")
+	commitAll(t, repo)
+
+	files, err := git.ChangedFiles(root, upstream.String())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{filepath.Join(root, "feature.tf")}, files)
+}
+
+func TestChangedFilesNoChanges(t *testing.T) {
+	root, repo := initRepository(t)
+
+	writeFile(t, root, "main.tf", "a")
+	commitAll(t, repo)
+
+	files, err := git.ChangedFiles(root, "HEAD")
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestChangedFilesInvalidRef(t *testing.T) {
+	root, repo := initRepository(t)
+
+	writeFile(t, root, "main.tf", "a")
+	commitAll(t, repo)
+
+	_, err := git.ChangedFiles(root, "does-not-exist")
+	require.ErrorContains(t, err, "resolve does-not-exist")
+}
+
+func TestChangedFilesNoRepository(t *testing.T) {
+	_, err := git.ChangedFiles(t.TempDir(), "HEAD")
+	require.ErrorContains(t, err, "open repository")
+}
+
+func writeFile(t *testing.T, root, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(root, name)
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func commitAll(t *testing.T, repo *goGit.Repository) plumbing.Hash {
+	t.Helper()
+
+	workTree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, workTree.AddWithOptions(&goGit.AddOptions{All: true}))
+
+	hash, err := workTree.Commit("commit", &goGit.CommitOptions{
+		Author: &object.Signature{
+			Email: "author@example.com",
+			When:  time.Now(),
+		},
+	})
+	require.NoError(t, err)
+
+	return hash
+}
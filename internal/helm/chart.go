@@ -0,0 +1,207 @@
+// Package helm renders Helm charts locally, without access to a cluster, and
+// maps every line of the rendered manifests back to the line of the template
+// that produced it.
+package helm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/ignore"
+)
+
+// ChartFile is the name of the file that identifies a chart directory.
+const ChartFile = "Chart.yaml"
+
+const subchartsDir = "charts"
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// IsChart returns true if the directory contains a Chart.yaml file.
+func IsChart(fsys afero.Fs, dir string) bool {
+	info, err := fsys.Stat(filepath.Join(dir, ChartFile))
+	return err == nil && !info.IsDir()
+}
+
+// chartLoader loads the charts of a file system with the loader of Helm, and
+// remembers the path of every template it loads.
+type chartLoader struct {
+	fsys afero.Fs
+
+	// paths are the paths of the templates in the file system.
+	paths map[*chart.File]string
+
+	// warnings are the parts of the charts that are not loaded, i.e. the
+	// packaged subcharts.
+	warnings []error
+}
+
+// load loads the chart in a directory, with its unpacked subcharts. The files
+// ignored by the .helmignore file of the chart are not loaded, like with
+// `helm template`.
+func (l *chartLoader) load(dir string) (*chart.Chart, error) {
+	rules, err := l.ignoreRules(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*loader.BufferedFile
+
+	err = afero.Walk(l.fsys, dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		if rel == "." {
+			return nil
+		}
+
+		name := filepath.ToSlash(rel)
+
+		// The subcharts are loaded separately, to know where their
+		// templates are.
+		if name == subchartsDir && info.IsDir() {
+			return filepath.SkipDir
+		}
+
+		if rules.Ignore(name, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		data, err := afero.ReadFile(l.fsys, path)
+		if err != nil {
+			return fmt.Errorf("read %s: %v", name, err)
+		}
+
+		files = append(files, &loader.BufferedFile{Name: name, Data: bytes.TrimPrefix(data, utf8BOM)})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read chart directory: %v", err)
+	}
+
+	c, err := loader.LoadFiles(files)
+	if err != nil {
+		return nil, fmt.Errorf("load chart %s: %v", dir, err)
+	}
+
+	for _, template := range c.Templates {
+		l.paths[template] = filepath.Join(dir, filepath.FromSlash(template.Name))
+	}
+
+	if err := l.loadSubcharts(c, filepath.Join(dir, subchartsDir)); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (l *chartLoader) loadSubcharts(c *chart.Chart, dir string) error {
+	entries, err := afero.ReadDir(l.fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("read subcharts: %v", err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+
+		if !entry.IsDir() {
+			if strings.HasSuffix(entry.Name(), ".tgz") || strings.HasSuffix(entry.Name(), ".tar.gz") {
+				l.warnings = append(l.warnings, fmt.Errorf("packaged subchart %s is not rendered, unpack it to scan it", path))
+			}
+			continue
+		}
+
+		if !IsChart(l.fsys, path) {
+			continue
+		}
+
+		subchart, err := l.load(path)
+		if err != nil {
+			return fmt.Errorf("load subchart %s: %v", entry.Name(), err)
+		}
+
+		c.AddDependency(subchart)
+	}
+
+	return nil
+}
+
+func (l *chartLoader) ignoreRules(dir string) (*ignore.Rules, error) {
+	rules := ignore.Empty()
+
+	data, err := afero.ReadFile(l.fsys, filepath.Join(dir, ignore.HelmIgnore))
+	if err == nil {
+		if rules, err = ignore.Parse(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("parse %s: %v", ignore.HelmIgnore, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("read %s: %v", ignore.HelmIgnore, err)
+	}
+
+	rules.AddDefaults()
+
+	return rules, nil
+}
+
+// readValues reads a values file.
+func readValues(fsys afero.Fs, path string) (map[string]any, error) {
+	data, err := afero.ReadFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := chartutil.ReadValues(data)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %v", filepath.Base(path), err)
+	}
+
+	return values, nil
+}
+
+// mergeValues merges the values of src into dst, recursively, like Helm does
+// with the values files passed to `helm template`. The values of src take
+// precedence.
+func mergeValues(dst, src map[string]any) map[string]any {
+	merged := make(map[string]any, len(dst))
+
+	for key, value := range dst {
+		merged[key] = value
+	}
+
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := merged[key].(map[string]any)
+
+		if srcIsMap && dstIsMap {
+			merged[key] = mergeValues(dstMap, srcMap)
+		} else {
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,171 @@
+package helm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template/parse"
+)
+
+// The lines of a rendered template are mapped back to the lines of the
+// template from the positions of the nodes of its parse tree. Every line of
+// the template is turned into a pattern: its text matches itself, and the
+// output of its actions matches anything. A rendered line is produced by the
+// next line of the template whose pattern matches it or, in a loop, by the
+// closest previous one. A rendered line that matches no pattern is part of the
+// output of an action spanning several lines, e.g. toYaml or include.
+
+// templateLine is the pattern of a line of a template.
+type templateLine struct {
+	pieces []string
+	// text is true if the line contains text other than whitespace.
+	text bool
+	// action is true if the line contains an action producing output.
+	action  bool
+	pattern *regexp.Regexp
+}
+
+func (l *templateLine) addText(s string) {
+	if s == "" {
+		return
+	}
+
+	l.pieces = append(l.pieces, regexp.QuoteMeta(s))
+	l.text = l.text || strings.TrimSpace(s) != ""
+}
+
+// addAction adds the output of an action to the line. The output of an action
+// following the indentation of the line doesn't start with whitespace, so that
+// the more indented output of the actions of the previous lines doesn't match.
+func (l *templateLine) addAction() {
+	if l.text {
+		l.pieces = append(l.pieces, ".*")
+	} else {
+		l.pieces = append(l.pieces, `(\S.*)?`)
+	}
+
+	l.action = true
+}
+
+// mapLines returns the line of the template that produced every line of the
+// output, starting from one.
+func mapLines(name, source, output string) ([]int, error) {
+	lines, err := templateLines(name, source)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		result []int
+		cursor = 0
+	)
+
+	for _, rendered := range strings.Split(output, "\n") {
+		if line := matchLine(lines, rendered, cursor); line > 0 {
+			cursor = line
+			result = append(result, line)
+		} else {
+			result = append(result, actionLine(lines, cursor))
+		}
+	}
+
+	return result, nil
+}
+
+// matchLine returns the next line of the template after the cursor whose
+// pattern matches the rendered line or, if there is none, the closest line
+// before it. It returns zero if no line matches.
+func matchLine(lines []templateLine, rendered string, cursor int) int {
+	matches := func(i int) bool {
+		return lines[i].text && lines[i].pattern.MatchString(rendered)
+	}
+
+	for i := cursor + 1; i < len(lines); i++ {
+		if matches(i) {
+			return i
+		}
+	}
+
+	for i := cursor; i > 0; i-- {
+		if matches(i) {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// actionLine returns the line of the action that produced a rendered line
+// that doesn't match any line of the template: the next line after the cursor
+// that only contains actions, or the line of the cursor.
+func actionLine(lines []templateLine, cursor int) int {
+	for i := cursor + 1; i < len(lines) && !lines[i].text; i++ {
+		if lines[i].action {
+			return i
+		}
+	}
+
+	return max(cursor, 1)
+}
+
+// templateLines returns the patterns of the lines of a template, indexed by
+// line number. The templates it defines are not part of its output, so they
+// are ignored.
+func templateLines(name, source string) ([]templateLine, error) {
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+
+	if _, err := tree.Parse(source, "", "", map[string]*parse.Tree{}); err != nil {
+		return nil, fmt.Errorf("parse template %s: %v", name, err)
+	}
+
+	lines := make([]templateLine, strings.Count(source, "\n")+2)
+
+	addNodes(lines, source, tree.Root)
+
+	for i := range lines {
+		lines[i].pattern = regexp.MustCompile("^" + strings.Join(lines[i].pieces, "") + "$")
+	}
+
+	return lines, nil
+}
+
+func addNodes(lines []templateLine, source string, list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.TextNode:
+			line := lineAt(source, n.Pos)
+			for i, s := range strings.Split(string(n.Text), "\n") {
+				lines[line+i].addText(s)
+			}
+		case *parse.ActionNode:
+			// An action setting a variable doesn't produce any output.
+			if len(n.Pipe.Decl) == 0 {
+				lines[lineAt(source, n.Pos)].addAction()
+			}
+		case *parse.TemplateNode:
+			lines[lineAt(source, n.Pos)].addAction()
+		case *parse.IfNode:
+			addNodes(lines, source, n.List)
+			addNodes(lines, source, n.ElseList)
+		case *parse.RangeNode:
+			addNodes(lines, source, n.List)
+			addNodes(lines, source, n.ElseList)
+		case *parse.WithNode:
+			addNodes(lines, source, n.List)
+			addNodes(lines, source, n.ElseList)
+		}
+	}
+}
+
+func lineAt(source string, pos parse.Pos) int {
+	if int(pos) > len(source) {
+		pos = parse.Pos(len(source))
+	}
+
+	return 1 + strings.Count(source[:pos], "\n")
+}
@@ -0,0 +1,206 @@
+package helm
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/afero"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+)
+
+// releaseOptions are the options of the release the charts are rendered for.
+// They are the ones used by `helm template`.
+var releaseOptions = chartutil.ReleaseOptions{
+	Name:      "release-name",
+	Namespace: "default",
+	Revision:  1,
+	IsInstall: true,
+}
+
+// Manifest is the output of a template of a chart.
+type Manifest struct {
+	// Path is the path of the template that produced the manifest.
+	Path string
+
+	// Content is the rendered template, without its empty documents.
+	Content []byte
+
+	// Lines maps every line of Content, starting from zero, to the line of
+	// the template that produced it, starting from one.
+	Lines []int
+}
+
+// Rendering is the output of a chart.
+type Rendering struct {
+	Manifests []Manifest
+
+	// Warnings are the parts of the chart that were not rendered, e.g. the
+	// packaged subcharts.
+	Warnings []error
+}
+
+// Render renders the chart in a directory with the template engine of Helm,
+// like `helm template` does without access to a cluster. The values of the
+// chart are overridden by the values files in order. The templates of the
+// enabled subcharts are rendered too.
+func Render(fsys afero.Fs, dir string, valuesFiles []string) (*Rendering, error) {
+	l := chartLoader{
+		fsys:  fsys,
+		paths: make(map[*chart.File]string),
+	}
+
+	c, err := l.load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]any{}
+
+	for _, valuesFile := range valuesFiles {
+		override, err := readValues(fsys, valuesFile)
+		if err != nil {
+			return nil, fmt.Errorf("read values file %s: %v", valuesFile, err)
+		}
+
+		values = mergeValues(values, override)
+	}
+
+	if err := chartutil.ProcessDependenciesWithMerge(c, values); err != nil {
+		return nil, fmt.Errorf("process dependencies: %v", err)
+	}
+
+	renderValues, err := chartutil.ToRenderValues(c, values, releaseOptions, chartutil.DefaultCapabilities)
+	if err != nil {
+		return nil, fmt.Errorf("compute values: %v", err)
+	}
+
+	rendered, err := engine.Engine{}.Render(c, renderValues)
+	if err != nil {
+		return nil, err
+	}
+
+	rendering := Rendering{
+		Warnings: l.warnings,
+	}
+
+	for _, t := range templates(c) {
+		output, ok := rendered[t.name]
+		if !ok || isPartial(t.name) {
+			continue
+		}
+
+		lines, err := mapLines(t.name, string(t.file.Data), output)
+		if err != nil {
+			return nil, err
+		}
+
+		content, sources := documents(strings.Split(output, "\n"), lines)
+		if len(sources) == 0 {
+			continue
+		}
+
+		rendering.Manifests = append(rendering.Manifests, Manifest{
+			Path:    l.paths[t.file],
+			Content: []byte(content),
+			Lines:   sources,
+		})
+	}
+
+	return &rendering, nil
+}
+
+// namedTemplate is a template of a chart, with the name Helm renders it with.
+type namedTemplate struct {
+	name string
+	file *chart.File
+}
+
+// templates returns the templates of a chart and of its subcharts, in order.
+func templates(c *chart.Chart) []namedTemplate {
+	var result []namedTemplate
+
+	for _, file := range c.Templates {
+		result = append(result, namedTemplate{
+			name: path.Join(c.ChartFullPath(), file.Name),
+			file: file,
+		})
+	}
+
+	for _, dependency := range c.Dependencies() {
+		result = append(result, templates(dependency)...)
+	}
+
+	return result
+}
+
+// isPartial returns true for the templates that don't produce a manifest:
+// the files starting with an underscore, which only define named templates,
+// and the notes displayed after an installation.
+func isPartial(name string) bool {
+	base := path.Base(name)
+	return strings.HasPrefix(base, "_") || strings.EqualFold(base, "NOTES.txt")
+}
+
+// documents removes the YAML documents that are empty or only contain
+// comments, since they don't describe any resource. The lines of the
+// separators are attributed to the first line of the following document.
+func documents(lines []string, sources []int) (string, []int) {
+	type document struct {
+		lines   []string
+		sources []int
+		empty   bool
+	}
+
+	current := &document{empty: true}
+	docs := []*document{current}
+
+	for i, line := range lines {
+		if isSeparator(line) {
+			current = &document{empty: true}
+			docs = append(docs, current)
+			continue
+		}
+
+		current.lines = append(current.lines, line)
+		current.sources = append(current.sources, sources[i])
+
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			current.empty = false
+		}
+	}
+
+	var (
+		b      strings.Builder
+		result []int
+	)
+
+	for _, doc := range docs {
+		if doc.empty {
+			continue
+		}
+
+		for n := len(doc.lines); n > 0 && strings.TrimSpace(doc.lines[n-1]) == ""; n-- {
+			doc.lines = doc.lines[:n-1]
+		}
+
+		if len(result) > 0 {
+			b.WriteString("---\n")
+			result = append(result, doc.sources[0])
+		}
+
+		for i, line := range doc.lines {
+			b.WriteString(line)
+			b.WriteString("\n")
+			result = append(result, doc.sources[i])
+		}
+	}
+
+	return b.String(), result
+}
+
+func isSeparator(line string) bool {
+	return line == "---" || strings.HasPrefix(line, "--- ") || strings.TrimRight(line, " \t\r") == "---"
+}
@@ -0,0 +1,231 @@
+package helm_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/helm"
+)
+
+var chartDir = filepath.Join("testdata", "chart")
+
+func TestRender(t *testing.T) {
+	rendering, err := helm.Render(afero.NewOsFs(), chartDir, nil)
+	require.NoError(t, err)
+	require.Empty(t, rendering.Warnings)
+
+	// The service and the subchart are disabled, and the notes don't produce
+	// a manifest.
+	require.Len(t, rendering.Manifests, 1)
+
+	manifest := rendering.Manifests[0]
+
+	require.Equal(t, filepath.Join(chartDir, "templates", "deployment.yaml"), manifest.Path)
+	require.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: release-name-app
+  labels:
+    app.kubernetes.io/name: app
+    app.kubernetes.io/instance: release-name
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: app
+          image: "nginx:1.0"
+          securityContext:
+            privileged: true
+`, string(manifest.Content))
+
+	// The labels are produced by the include on line 6, and the security
+	// context by the toYaml on line 15.
+	require.Equal(t, []int{1, 2, 3, 4, 5, 6, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}, manifest.Lines)
+}
+
+func TestRenderWithValuesFiles(t *testing.T) {
+	rendering, err := helm.Render(afero.NewOsFs(), chartDir, []string{filepath.Join("testdata", "values-prod.yaml")})
+	require.NoError(t, err)
+	require.Len(t, rendering.Manifests, 3)
+
+	deployment, service, configMap := rendering.Manifests[0], rendering.Manifests[1], rendering.Manifests[2]
+
+	require.Contains(t, string(deployment.Content), "            privileged: false\n            runAsNonRoot: true\n")
+
+	require.Equal(t, filepath.Join(chartDir, "templates", "service.yaml"), service.Path)
+	require.Contains(t, string(service.Content), "    - port: 80\n")
+
+	// The subchart is rendered with its values overridden by the parent, and
+	// with the global values of the parent.
+	require.Equal(t, filepath.Join(chartDir, "charts", "cache", "templates", "configmap.yaml"), configMap.Path)
+	require.Contains(t, string(configMap.Content), "  environment: \"prod\"\n  size: \"3\"\n")
+}
+
+func TestRenderRemovesEmptyDocuments(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "chart/Chart.yaml", []byte("apiVersion: v2\nname: chart\nversion: 0.1.0\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "chart/templates/resources.yaml", []byte(`---
+# Comment
+{{- range .Values.names }}
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: {{ . }}
+{{- end }}
+`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "values.yaml", []byte("names: [a, b]\n"), 0644))
+
+	rendering, err := helm.Render(fs, "chart", []string{"values.yaml"})
+	require.NoError(t, err)
+	require.Len(t, rendering.Manifests, 1)
+
+	require.Equal(t, `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: a
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: b
+`, string(rendering.Manifests[0].Content))
+	require.Equal(t, []int{5, 6, 7, 8, 5, 5, 6, 7, 8}, rendering.Manifests[0].Lines)
+}
+
+func TestRenderLines(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "chart/Chart.yaml", []byte("apiVersion: v2\nname: chart\nversion: 0.1.0\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "chart/values.yaml", []byte("labels: {a: b, c: d}\ndata: {first: 1, second: 2}\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "chart/templates/configmap.yaml", []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  labels: {{- toYaml .Values.labels | nindent 4 }}
+data:
+  {{- range $key, $value := .Values.data }}
+  {{ $key }}: {{ $value | quote }}
+  {{- end }}
+`), 0644))
+
+	rendering, err := helm.Render(fs, "chart", nil)
+	require.NoError(t, err)
+	require.Len(t, rendering.Manifests, 1)
+
+	require.Equal(t, `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  labels:
+    a: b
+    c: d
+data:
+  first: "1"
+  second: "2"
+`, string(rendering.Manifests[0].Content))
+
+	// The labels are produced by the toYaml on line 5, and every item of the
+	// data by the body of the range on line 8.
+	require.Equal(t, []int{1, 2, 3, 4, 5, 5, 5, 6, 8, 8}, rendering.Manifests[0].Lines)
+}
+
+func TestRenderIgnoredFiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "chart/Chart.yaml", []byte("apiVersion: v2\nname: chart\nversion: 0.1.0\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "chart/.helmignore", []byte("templates/ignored.yaml\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "chart/templates/ignored.yaml", []byte("kind: ConfigMap\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "chart/templates/service-account.yaml", []byte("kind: ServiceAccount\n"), 0644))
+
+	rendering, err := helm.Render(fs, "chart", nil)
+	require.NoError(t, err)
+	require.Len(t, rendering.Manifests, 1)
+	require.Equal(t, filepath.Join("chart", "templates", "service-account.yaml"), rendering.Manifests[0].Path)
+}
+
+func TestRenderSprigFunctions(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "chart/Chart.yaml", []byte("apiVersion: v2\nname: chart\nversion: 0.1.0\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "chart/templates/configmap.yaml", []byte(`{{- $config := mustMergeOverwrite (dict "port" 80) .Values.config -}}
+{{- $ca := genCA "ca" 365 -}}
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  config.toml: {{ toToml $config | quote }}
+  ca.crt: {{ hasPrefix "-----BEGIN CERTIFICATE-----" $ca.Cert | quote }}
+  host: {{ getHostByName "example.com" | quote }}
+`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "values.yaml", []byte("config:\n  port: 8080\n"), 0644))
+
+	rendering, err := helm.Render(fs, "chart", []string{"values.yaml"})
+	require.NoError(t, err)
+	require.Len(t, rendering.Manifests, 1)
+
+	content := string(rendering.Manifests[0].Content)
+	require.Contains(t, content, `config.toml: "port = 8080.0\n"`)
+	require.Contains(t, content, `ca.crt: "true"`)
+	require.Contains(t, content, `host: ""`)
+
+	require.NoError(t, afero.WriteFile(fs, "chart/templates/configmap.yaml", []byte(`region: {{ env "AWS_REGION" }}`), 0644))
+
+	_, err = helm.Render(fs, "chart", nil)
+	require.ErrorContains(t, err, `function "env" not defined`)
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		message  string
+	}{
+		{
+			name:     "required value",
+			template: `name: {{ required "a name is required" .Values.name }}`,
+			message:  "a name is required",
+		},
+		{
+			name:     "failure",
+			template: `{{ fail "unsupported configuration" }}`,
+			message:  "unsupported configuration",
+		},
+		{
+			name:     "invalid template",
+			template: `name: {{ .Values.name `,
+			message:  "unclosed action",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+
+			require.NoError(t, afero.WriteFile(fs, "chart/Chart.yaml", []byte("apiVersion: v2\nname: chart\nversion: 0.1.0\n"), 0644))
+			require.NoError(t, afero.WriteFile(fs, "chart/templates/template.yaml", []byte(tt.template), 0644))
+
+			_, err := helm.Render(fs, "chart", nil)
+			require.ErrorContains(t, err, tt.message)
+		})
+	}
+}
+
+func TestRenderPackagedSubcharts(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "chart/Chart.yaml", []byte("apiVersion: v2\nname: chart\nversion: 0.1.0\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "chart/charts/redis-1.0.0.tgz", nil, 0644))
+
+	rendering, err := helm.Render(fs, "chart", nil)
+	require.NoError(t, err)
+	require.Empty(t, rendering.Manifests)
+	require.Len(t, rendering.Warnings, 1)
+	require.ErrorContains(t, rendering.Warnings[0], filepath.Join("chart", "charts", "redis-1.0.0.tgz"))
+}
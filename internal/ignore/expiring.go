@@ -3,18 +3,20 @@ package ignore
 import "time"
 
 type expiringMatcher struct {
-	expires time.Time
+	rule    Rule
 	matcher pathMatcher
 }
 
-func (m *expiringMatcher) setExpires(expires time.Time) {
-	m.expires = expires
-}
+func (m *expiringMatcher) setRule(rule Rule) error {
+	if err := m.matcher.setPattern(rule.Pattern); err != nil {
+		return err
+	}
+
+	m.rule = rule
 
-func (m *expiringMatcher) setPattern(pattern string) error {
-	return m.matcher.setPattern(pattern)
+	return nil
 }
 
 func (m *expiringMatcher) match(now time.Time, parts ...string) bool {
-	return (m.expires.IsZero() || now.Before(m.expires)) && m.matcher.match(parts...)
+	return (m.rule.Expires.IsZero() || now.Before(m.rule.Expires)) && m.matcher.match(parts...)
 }
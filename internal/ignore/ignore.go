@@ -14,17 +14,27 @@ type Matcher struct {
 // AddIgnore adds a new ignore to the matcher. It returns an error if the
 // vulnerability ID or the pattern is invalid.
 func (m *Matcher) AddIgnore(id string, expires time.Time, pattern string) error {
-	if len(id) == 0 {
+	return m.AddRule(Rule{
+		ID:      id,
+		Expires: expires,
+		Pattern: pattern,
+	})
+}
+
+// AddRule adds a new ignore rule to the matcher. It returns an error if the
+// vulnerability ID or the pattern of the rule is invalid.
+func (m *Matcher) AddRule(rule Rule) error {
+	if len(rule.ID) == 0 {
 		return fmt.Errorf("empty vulnerability ID")
 	}
 
 	var matcher vulnerabilityMatcher
 
 	if m.matchers != nil {
-		matcher = m.matchers[id]
+		matcher = m.matchers[rule.ID]
 	}
 
-	if err := matcher.addIgnore(expires, pattern); err != nil {
+	if err := matcher.addRule(rule); err != nil {
 		return err
 	}
 
@@ -32,7 +42,7 @@ func (m *Matcher) AddIgnore(id string, expires time.Time, pattern string) error
 		m.matchers = make(map[string]vulnerabilityMatcher)
 	}
 
-	m.matchers[id] = matcher
+	m.matchers[rule.ID] = matcher
 
 	return nil
 }
@@ -42,13 +52,20 @@ func (m *Matcher) AddIgnore(id string, expires time.Time, pattern string) error
 // a slice of path components. Match returns true if at least one of the ignores
 // in this Matcher matches the provided argument, false otherwise.
 func (m *Matcher) Match(id string, now time.Time, parts ...string) bool {
+	_, ok := m.MatchRule(id, now, parts...)
+	return ok
+}
+
+// MatchRule is like Match, but it also returns the first ignore rule that
+// matches the provided arguments.
+func (m *Matcher) MatchRule(id string, now time.Time, parts ...string) (Rule, bool) {
 	if m.matchers == nil {
-		return false
+		return Rule{}, false
 	}
 
 	if matcher, ok := m.matchers[id]; ok {
 		return matcher.match(now, parts...)
 	}
 
-	return false
+	return Rule{}, false
 }
@@ -0,0 +1,242 @@
+package ignore
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// InlineIgnore is an ignore declared by a comment in a source file, like
+//
+//	# snyk:ignore SNYK-CC-TF-1 reason="Public by design" expires=2027-01-01
+//
+// The comment can be placed at the end of a line, in which case it applies to
+// that line, or on its own line, in which case it applies to the next line
+// that is neither blank nor a comment. More than one rule ID can be specified
+// by separating them with commas.
+type InlineIgnore struct {
+	RuleIDs    []string
+	Reason     string
+	Expires    time.Time
+	File       string
+	Line       int
+	TargetLine int
+}
+
+// Location is a position in a source file.
+type Location struct {
+	File string
+	Line int
+}
+
+var (
+	inlineIgnoreRegexp = regexp.MustCompile(`^snyk:ignore(?:\s+|$)(.*)$`)
+	attributeRegexp    = regexp.MustCompile(`^([A-Za-z]+)=(?:"((?:[^"\\]|\\.)*)"|(\S*))\s*`)
+)
+
+// commentMarkers returns the tokens that start a line comment in a file,
+// based on the file extension.
+func commentMarkers(path string) []string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tf", ".hcl", ".tfvars":
+		return []string{"#", "//"}
+	case ".json":
+		return []string{"//"}
+	default:
+		return []string{"#"}
+	}
+}
+
+// ParseInlineIgnores parses the ignores declared by comments in the content
+// of a source file. The path of the file determines which comment syntax is
+// recognized. ParseInlineIgnores returns an error if a comment starting with
+// "snyk:ignore" is malformed.
+func ParseInlineIgnores(path string, data []byte) ([]InlineIgnore, error) {
+	var (
+		ignores []InlineIgnore
+		pending []int
+	)
+
+	markers := commentMarkers(path)
+
+	for i, text := range strings.Split(string(data), "\n") {
+		line := i + 1
+		code, comment, hasComment := splitComment(strings.TrimSuffix(text, "\r"), markers)
+
+		if hasComment {
+			ignore, ok, err := parseInlineIgnore(comment)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+			}
+
+			if ok {
+				ignore.File = path
+				ignore.Line = line
+
+				if strings.TrimSpace(code) != "" {
+					ignore.TargetLine = line
+				} else {
+					pending = append(pending, len(ignores))
+				}
+
+				ignores = append(ignores, ignore)
+			}
+		}
+
+		if strings.TrimSpace(code) == "" {
+			continue
+		}
+
+		for _, i := range pending {
+			ignores[i].TargetLine = line
+		}
+
+		pending = nil
+	}
+
+	return ignores, nil
+}
+
+// splitComment splits a line in the code preceding a comment and the text of
+// the comment. Comment markers inside double-quoted strings are ignored.
+func splitComment(line string, markers []string) (string, string, bool) {
+	var quoted, escaped bool
+
+	for i := 0; i < len(line); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case line[i] == '\\':
+			escaped = quoted
+		case line[i] == '"':
+			quoted = !quoted
+		case !quoted:
+			for _, marker := range markers {
+				if strings.HasPrefix(line[i:], marker) {
+					return line[:i], strings.TrimSpace(line[i+len(marker):]), true
+				}
+			}
+		}
+	}
+
+	return line, "", false
+}
+
+func parseInlineIgnore(comment string) (InlineIgnore, bool, error) {
+	var ignore InlineIgnore
+
+	match := inlineIgnoreRegexp.FindStringSubmatch(comment)
+	if match == nil {
+		return ignore, false, nil
+	}
+
+	rest := strings.TrimSpace(match[1])
+
+	ids, rest, _ := strings.Cut(rest, " ")
+	if ids == "" || strings.Contains(ids, "=") {
+		return ignore, false, fmt.Errorf("missing rule ID")
+	}
+
+	for _, id := range strings.Split(ids, ",") {
+		if id == "" {
+			return ignore, false, fmt.Errorf("empty rule ID in %q", ids)
+		}
+
+		ignore.RuleIDs = append(ignore.RuleIDs, id)
+	}
+
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		attribute := attributeRegexp.FindStringSubmatch(rest)
+		if attribute == nil {
+			return ignore, false, fmt.Errorf("invalid attribute: %s", rest)
+		}
+
+		rest = rest[len(attribute[0]):]
+
+		value := attribute[3]
+		if strings.HasPrefix(attribute[0], attribute[1]+`="`) {
+			value = strings.ReplaceAll(attribute[2], `\"`, `"`)
+		}
+
+		switch attribute[1] {
+		case "reason":
+			ignore.Reason = value
+		case "expires":
+			expires, err := ParseExpires(value)
+			if err != nil {
+				return ignore, false, err
+			}
+
+			ignore.Expires = expires
+		default:
+			return ignore, false, fmt.Errorf("unknown attribute: %s", attribute[1])
+		}
+	}
+
+	return ignore, true, nil
+}
+
+// ParseExpires parses an expiration date, written either as a date (e.g.
+// 2027-01-01) or as an RFC 3339 timestamp.
+func ParseExpires(value string) (time.Time, error) {
+	if expires, err := time.Parse(time.DateOnly, value); err == nil {
+		return expires, nil
+	}
+
+	expires, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiration date: %s", value)
+	}
+
+	return expires, nil
+}
+
+// Expired returns true if the ignore has an expiration date and the date is
+// not after now.
+func (i InlineIgnore) Expired(now time.Time) bool {
+	return !i.Expires.IsZero() && !now.Before(i.Expires)
+}
+
+// InlineMatcher matches vulnerabilities to the ignores declared in the source
+// files where the vulnerable resources are defined.
+type InlineMatcher struct {
+	ignores map[string][]InlineIgnore
+}
+
+// AddIgnores adds the ignores parsed from a source file to the matcher.
+func (m *InlineMatcher) AddIgnores(ignores ...InlineIgnore) {
+	if m.ignores == nil {
+		m.ignores = make(map[string][]InlineIgnore)
+	}
+
+	for _, ignore := range ignores {
+		file := filepath.Clean(ignore.File)
+		m.ignores[file] = append(m.ignores[file], ignore)
+	}
+}
+
+// Match returns the first ignore for the rule ID that applies to one of the
+// locations and is not expired at the provided evaluation time.
+func (m *InlineMatcher) Match(id string, now time.Time, locations ...Location) (InlineIgnore, bool) {
+	for _, location := range locations {
+		if location.File == "" || location.Line == 0 {
+			continue
+		}
+
+		for _, ignore := range m.ignores[filepath.Clean(location.File)] {
+			if ignore.TargetLine != location.Line || ignore.Expired(now) {
+				continue
+			}
+
+			for _, ruleID := range ignore.RuleIDs {
+				if ruleID == id {
+					return ignore, true
+				}
+			}
+		}
+	}
+
+	return InlineIgnore{}, false
+}
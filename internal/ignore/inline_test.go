@@ -0,0 +1,187 @@
+package ignore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/ignore"
+)
+
+func TestParseInlineIgnores(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		data    string
+		ignores []ignore.InlineIgnore
+	}{
+		{
+			name: "terraform above resource",
+			path: "main.tf",
+			data: `# snyk:ignore SNYK-CC-TF-1 reason="Public by design" expires=2027-01-01
+
+resource "aws_s3_bucket" "public" {
+  bucket = "public"
+}
+`,
+			ignores: []ignore.InlineIgnore{
+				{
+					RuleIDs:    []string{"SNYK-CC-TF-1"},
+					Reason:     "Public by design",
+					Expires:    time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+					File:       "main.tf",
+					Line:       1,
+					TargetLine: 3,
+				},
+			},
+		},
+		{
+			name: "terraform trailing comment",
+			path: "main.tf",
+			data: `resource "aws_s3_bucket" "public" {
+  acl = "public-read" // snyk:ignore SNYK-CC-TF-1,SNYK-CC-TF-2
+}
+`,
+			ignores: []ignore.InlineIgnore{
+				{
+					RuleIDs:    []string{"SNYK-CC-TF-1", "SNYK-CC-TF-2"},
+					File:       "main.tf",
+					Line:       2,
+					TargetLine: 2,
+				},
+			},
+		},
+		{
+			name: "yaml stacked comments",
+			path: "pod.yaml",
+			data: `# snyk:ignore SNYK-CC-K8S-1 reason="Needs \"root\""
+# some other comment
+# snyk:ignore SNYK-CC-K8S-2 reason=privileged
+apiVersion: v1
+`,
+			ignores: []ignore.InlineIgnore{
+				{
+					RuleIDs:    []string{"SNYK-CC-K8S-1"},
+					Reason:     `Needs "root"`,
+					File:       "pod.yaml",
+					Line:       1,
+					TargetLine: 4,
+				},
+				{
+					RuleIDs:    []string{"SNYK-CC-K8S-2"},
+					Reason:     "privileged",
+					File:       "pod.yaml",
+					Line:       3,
+					TargetLine: 4,
+				},
+			},
+		},
+		{
+			name: "json with comments",
+			path: "template.json",
+			data: `{
+  // snyk:ignore SNYK-CC-AWS-1
+  "Resources": {}
+}
+`,
+			ignores: []ignore.InlineIgnore{
+				{
+					RuleIDs:    []string{"SNYK-CC-AWS-1"},
+					File:       "template.json",
+					Line:       2,
+					TargetLine: 3,
+				},
+			},
+		},
+		{
+			name: "comment marker in string",
+			path: "main.tf",
+			data: `description = "# snyk:ignore SNYK-CC-TF-1"`,
+		},
+		{
+			name: "unrelated comments",
+			path: "pod.yaml",
+			data: "# snyk:ignored\n# ignore SNYK-CC-K8S-1\napiVersion: v1\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ignores, err := ignore.ParseInlineIgnores(test.path, []byte(test.data))
+			require.NoError(t, err)
+			require.Equal(t, test.ignores, ignores)
+		})
+	}
+}
+
+func TestParseInlineIgnoresInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		error string
+	}{
+		{
+			name:  "missing rule ID",
+			data:  "# snyk:ignore",
+			error: "main.tf:1: missing rule ID",
+		},
+		{
+			name:  "missing rule ID with attributes",
+			data:  `# snyk:ignore reason="no"`,
+			error: "main.tf:1: missing rule ID",
+		},
+		{
+			name:  "invalid expiration date",
+			data:  "\n# snyk:ignore SNYK-CC-TF-1 expires=tomorrow",
+			error: "main.tf:2: invalid expiration date: tomorrow",
+		},
+		{
+			name:  "unknown attribute",
+			data:  "# snyk:ignore SNYK-CC-TF-1 owner=me",
+			error: "main.tf:1: unknown attribute: owner",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ignore.ParseInlineIgnores("main.tf", []byte(test.data))
+			require.EqualError(t, err, test.error)
+		})
+	}
+}
+
+func TestInlineMatcher(t *testing.T) {
+	now := time.Now()
+
+	var m ignore.InlineMatcher
+
+	m.AddIgnores(
+		ignore.InlineIgnore{
+			RuleIDs:    []string{"RULE-1"},
+			File:       "main.tf",
+			TargetLine: 3,
+		},
+		ignore.InlineIgnore{
+			RuleIDs:    []string{"RULE-2"},
+			Expires:    now.Add(-time.Hour),
+			File:       "main.tf",
+			TargetLine: 3,
+		},
+	)
+
+	_, ok := m.Match("RULE-1", now, ignore.Location{File: "./main.tf", Line: 3})
+	require.True(t, ok)
+
+	_, ok = m.Match("RULE-1", now, ignore.Location{File: "main.tf", Line: 10}, ignore.Location{File: "main.tf", Line: 3})
+	require.True(t, ok)
+
+	_, ok = m.Match("RULE-1", now, ignore.Location{File: "main.tf", Line: 4})
+	require.False(t, ok)
+
+	_, ok = m.Match("RULE-1", now, ignore.Location{File: "other.tf", Line: 3})
+	require.False(t, ok)
+
+	_, ok = m.Match("RULE-2", now, ignore.Location{File: "main.tf", Line: 3})
+	require.False(t, ok, "expired ignores should not match")
+}
@@ -0,0 +1,23 @@
+package ignore
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// PathParts returns the path components matched against the patterns of the
+// ignore rules for a vulnerability, given the file where the vulnerable
+// resource is defined and the formatted path of the vulnerability.
+func PathParts(file string, formattedPath string) []string {
+	var parts []string
+
+	if len(file) > 0 {
+		parts = append(parts, filepath.ToSlash(file))
+	}
+
+	if len(formattedPath) > 0 {
+		parts = append(parts, strings.Split(formattedPath, ".")...)
+	}
+
+	return parts
+}
@@ -0,0 +1,322 @@
+package ignore
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPolicyVersion is the version written in new .snyk policy files.
+const DefaultPolicyVersion = "v1.25.0"
+
+// policyTimeFormat is the format used by the Snyk CLI for dates in .snyk
+// policy files.
+const policyTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// PolicyEditor edits the ignore rules of a .snyk policy file. The editor works
+// on the YAML document tree, so that comments and unrelated sections of the
+// policy file are preserved.
+type PolicyEditor struct {
+	document yaml.Node
+}
+
+// NewPolicyEditor creates an editor for the content of a .snyk policy file.
+// If the content is empty, the editor starts from an empty policy.
+func NewPolicyEditor(config []byte) (*PolicyEditor, error) {
+	var e PolicyEditor
+
+	if len(bytes.TrimSpace(config)) > 0 {
+		if err := yaml.Unmarshal(config, &e.document); err != nil {
+			return nil, fmt.Errorf("unmarshal policy: %v", err)
+		}
+	}
+
+	if e.document.Kind == 0 || len(e.document.Content) == 0 {
+		e.document = yaml.Node{
+			Kind: yaml.DocumentNode,
+			Content: []*yaml.Node{
+				{
+					Kind: yaml.MappingNode,
+					Content: []*yaml.Node{
+						scalarNode("version"),
+						scalarNode(DefaultPolicyVersion),
+					},
+				},
+			},
+		}
+	}
+
+	if root := e.document.Content[0]; root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid policy: the root must be a mapping")
+	}
+
+	return &e, nil
+}
+
+// Rules returns the ignore rules currently in the policy.
+func (e *PolicyEditor) Rules() ([]Rule, error) {
+	data, err := e.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePolicy(data)
+}
+
+// Add adds an ignore rule to the policy. It returns an error if the rule ID
+// or pattern is invalid.
+func (e *PolicyEditor) Add(rule Rule) error {
+	if rule.ID == "" {
+		return fmt.Errorf("empty vulnerability ID")
+	}
+
+	var matcher pathMatcher
+
+	if err := matcher.setPattern(rule.Pattern); err != nil {
+		return err
+	}
+
+	ignores, err := e.ignoreNode(true)
+	if err != nil {
+		return err
+	}
+
+	rules := mappingValue(ignores, rule.ID)
+	if rules == nil || rules.Kind != yaml.SequenceNode {
+		rules = &yaml.Node{Kind: yaml.SequenceNode}
+		setMappingValue(ignores, rule.ID, rules)
+	}
+
+	meta := &yaml.Node{Kind: yaml.MappingNode}
+
+	if rule.Reason != "" {
+		setMappingValue(meta, "reason", scalarNode(rule.Reason))
+	}
+
+	if !rule.Expires.IsZero() {
+		setMappingValue(meta, "expires", timeNode(rule.Expires))
+	}
+
+	if !rule.Created.IsZero() {
+		setMappingValue(meta, "created", timeNode(rule.Created))
+	}
+
+	if rule.IgnoredBy != nil {
+		ignoredBy := &yaml.Node{Kind: yaml.MappingNode}
+
+		for _, field := range [][2]string{{"id", rule.IgnoredBy.ID}, {"name", rule.IgnoredBy.Name}, {"email", rule.IgnoredBy.Email}} {
+			if field[1] != "" {
+				setMappingValue(ignoredBy, field[0], scalarNode(field[1]))
+			}
+		}
+
+		setMappingValue(meta, "ignoredBy", ignoredBy)
+	}
+
+	rules.Content = append(rules.Content, &yaml.Node{
+		Kind: yaml.MappingNode,
+		Content: []*yaml.Node{
+			quotedNode(rule.Pattern),
+			meta,
+		},
+	})
+
+	return nil
+}
+
+// Expire sets the expiration date of the ignore rules for the vulnerability
+// ID to now. If pattern is not empty, only the rules with that pattern are
+// expired. Expire returns the number of expired rules.
+func (e *PolicyEditor) Expire(id string, pattern string, now time.Time) (int, error) {
+	return e.edit(id, pattern, func(rules *yaml.Node, i int) {
+		meta := rules.Content[i].Content[1]
+
+		if meta.Kind != yaml.MappingNode {
+			*meta = yaml.Node{Kind: yaml.MappingNode}
+		}
+
+		setMappingValue(meta, "expires", timeNode(now))
+	})
+}
+
+// Remove removes the ignore rules for the vulnerability ID. If pattern is not
+// empty, only the rules with that pattern are removed. Remove returns the
+// number of removed rules.
+func (e *PolicyEditor) Remove(id string, pattern string) (int, error) {
+	ignores, rules, err := e.rulesNode(id)
+	if err != nil || rules == nil {
+		return 0, err
+	}
+
+	var (
+		kept    []*yaml.Node
+		removed int
+	)
+
+	for _, item := range rules.Content {
+		if matchesPattern(item, pattern) {
+			removed++
+		} else {
+			kept = append(kept, item)
+		}
+	}
+
+	rules.Content = kept
+
+	if len(kept) == 0 {
+		deleteMappingValue(ignores, id)
+	}
+
+	return removed, nil
+}
+
+func (e *PolicyEditor) edit(id string, pattern string, f func(rules *yaml.Node, i int)) (int, error) {
+	_, rules, err := e.rulesNode(id)
+	if err != nil || rules == nil {
+		return 0, err
+	}
+
+	var edited int
+
+	for i, item := range rules.Content {
+		if matchesPattern(item, pattern) {
+			f(rules, i)
+			edited++
+		}
+	}
+
+	return edited, nil
+}
+
+// Bytes serializes the edited policy.
+func (e *PolicyEditor) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(&e.document); err != nil {
+		return nil, fmt.Errorf("encode policy: %v", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("encode policy: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// rulesNode returns the mapping node of the "ignore" section and the sequence
+// node of the rules for the vulnerability ID, if they exist.
+func (e *PolicyEditor) rulesNode(id string) (*yaml.Node, *yaml.Node, error) {
+	ignores, err := e.ignoreNode(false)
+	if err != nil || ignores == nil {
+		return nil, nil, err
+	}
+
+	rules := mappingValue(ignores, id)
+	if rules == nil || rules.Kind != yaml.SequenceNode {
+		return ignores, nil, nil
+	}
+
+	return ignores, rules, nil
+}
+
+// ignoreNode returns the mapping node of the "ignore" section. If create is
+// true, the section is created when it doesn't exist.
+func (e *PolicyEditor) ignoreNode(create bool) (*yaml.Node, error) {
+	root := e.document.Content[0]
+
+	ignores := mappingValue(root, "ignore")
+
+	if ignores != nil && ignores.Kind == yaml.ScalarNode && ignores.Tag == "!!null" {
+		ignores.Kind = yaml.MappingNode
+		ignores.Tag = ""
+		ignores.Value = ""
+	}
+
+	if ignores == nil {
+		if !create {
+			return nil, nil
+		}
+
+		ignores = &yaml.Node{Kind: yaml.MappingNode}
+		setMappingValue(root, "ignore", ignores)
+	}
+
+	if ignores.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid policy: the ignore section must be a mapping")
+	}
+
+	// An empty section is usually written as "ignore: {}". Switch to the block
+	// style, so that new rules are written like the existing ones.
+	if create {
+		ignores.Style &^= yaml.FlowStyle
+	}
+
+	return ignores, nil
+}
+
+// matchesPattern returns true if the sequence item is a rule for pattern. An
+// empty pattern matches every rule.
+func matchesPattern(item *yaml.Node, pattern string) bool {
+	if item.Kind != yaml.MappingNode || len(item.Content) != 2 {
+		return false
+	}
+
+	return pattern == "" || item.Content[0].Value == pattern
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+
+	mapping.Content = append(mapping.Content, scalarNode(key), value)
+}
+
+func deleteMappingValue(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Tag:   "!!str",
+		Value: value,
+	}
+}
+
+func quotedNode(value string) *yaml.Node {
+	node := scalarNode(value)
+	node.Style = yaml.SingleQuotedStyle
+	return node
+}
+
+func timeNode(t time.Time) *yaml.Node {
+	return &yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Tag:   "!!timestamp",
+		Value: t.UTC().Format(policyTimeFormat),
+	}
+}
@@ -0,0 +1,136 @@
+package ignore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/ignore"
+)
+
+const editedPolicy = `# Snyk (https://snyk.io) policy file, patches or ignores known vulnerabilities.
+version: v1.25.0
+# ignores vulnerabilities until expiry date; change duration by modifying expiry date
+ignore:
+  SNYK-CC-K8S-4:
+    # privileged pods are reviewed by the platform team
+    - test/fixtures/kubernetes/pod-privileged.yaml:
+        reason: None Given
+        expires: 2022-09-16T16:45:04.439Z
+        created: 2022-08-17T16:45:04.445Z
+    - 'foo > bar > baz':
+        reason: no fix available
+patch: {}
+`
+
+func TestPolicyEditorAdd(t *testing.T) {
+	editor, err := ignore.NewPolicyEditor([]byte(editedPolicy))
+	require.NoError(t, err)
+
+	require.NoError(t, editor.Add(ignore.Rule{
+		ID:      "SNYK-CC-TF-1",
+		Pattern: "main.tf > resource > aws_s3_bucket[public]",
+		Reason:  "public by design",
+		Expires: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		Created: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+	}))
+
+	require.NoError(t, editor.Add(ignore.Rule{
+		ID:      "SNYK-CC-K8S-4",
+		Pattern: "*",
+	}))
+
+	data, err := editor.Bytes()
+	require.NoError(t, err)
+
+	require.Equal(t, `# Snyk (https://snyk.io) policy file, patches or ignores known vulnerabilities.
+version: v1.25.0
+# ignores vulnerabilities until expiry date; change duration by modifying expiry date
+ignore:
+  SNYK-CC-K8S-4:
+    # privileged pods are reviewed by the platform team
+    - test/fixtures/kubernetes/pod-privileged.yaml:
+        reason: None Given
+        expires: 2022-09-16T16:45:04.439Z
+        created: 2022-08-17T16:45:04.445Z
+    - 'foo > bar > baz':
+        reason: no fix available
+    - '*': {}
+  SNYK-CC-TF-1:
+    - 'main.tf > resource > aws_s3_bucket[public]':
+        reason: public by design
+        expires: 2027-01-01T00:00:00.000Z
+        created: 2026-01-01T12:00:00.000Z
+patch: {}
+`, string(data))
+}
+
+func TestPolicyEditorAddEmptyPolicy(t *testing.T) {
+	for _, policy := range []string{"", "version: v1.25.0\nignore: {}\n", "ignore:\n"} {
+		editor, err := ignore.NewPolicyEditor([]byte(policy))
+		require.NoError(t, err)
+
+		require.NoError(t, editor.Add(ignore.Rule{ID: "SNYK-CC-TF-1", Pattern: "*", Reason: "r"}))
+
+		rules, err := editor.Rules()
+		require.NoError(t, err)
+		require.Equal(t, []ignore.Rule{{ID: "SNYK-CC-TF-1", Pattern: "*", Reason: "r"}}, rules)
+	}
+}
+
+func TestPolicyEditorAddInvalid(t *testing.T) {
+	editor, err := ignore.NewPolicyEditor(nil)
+	require.NoError(t, err)
+
+	require.EqualError(t, editor.Add(ignore.Rule{Pattern: "*"}), "empty vulnerability ID")
+	require.EqualError(t, editor.Add(ignore.Rule{ID: "SNYK-CC-TF-1"}), "empty pattern")
+}
+
+func TestPolicyEditorExpire(t *testing.T) {
+	editor, err := ignore.NewPolicyEditor([]byte(editedPolicy))
+	require.NoError(t, err)
+
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	n, err := editor.Expire("SNYK-CC-K8S-4", "foo > bar > baz", now)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	n, err = editor.Expire("SNYK-CC-TF-1", "", now)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	rules, err := editor.Rules()
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Equal(t, time.Date(2022, 9, 16, 16, 45, 4, 439000000, time.UTC), rules[0].Expires)
+	require.Equal(t, now, rules[1].Expires)
+}
+
+func TestPolicyEditorRemove(t *testing.T) {
+	editor, err := ignore.NewPolicyEditor([]byte(editedPolicy))
+	require.NoError(t, err)
+
+	n, err := editor.Remove("SNYK-CC-K8S-4", "foo > bar > baz")
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	rules, err := editor.Rules()
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+
+	n, err = editor.Remove("SNYK-CC-K8S-4", "")
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	data, err := editor.Bytes()
+	require.NoError(t, err)
+
+	require.Equal(t, `# Snyk (https://snyk.io) policy file, patches or ignores known vulnerabilities.
+version: v1.25.0
+# ignores vulnerabilities until expiry date; change duration by modifying expiry date
+ignore: {}
+patch: {}
+`, string(data))
+}
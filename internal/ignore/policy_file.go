@@ -2,6 +2,7 @@ package ignore
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -12,9 +13,31 @@ import (
 // of the rules within it, an error is returned. If the file is empty or doesn't
 // contain any rules, a non-nil, empty matcher is still returned.
 func NewMatcherFromPolicy(config []byte) (*Matcher, error) {
+	rules, err := ParsePolicy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var matcher Matcher
+
+	for _, rule := range rules {
+		if err := matcher.AddRule(rule); err != nil {
+			return nil, fmt.Errorf("add policy rule: %v", err)
+		}
+	}
+
+	return &matcher, nil
+}
+
+// ParsePolicy parses the ignore rules in the content of a .snyk policy file.
+// Rules are sorted by vulnerability ID, and then by order of appearance.
+func ParsePolicy(config []byte) ([]Rule, error) {
 	var policy struct {
 		Ignore map[string][]map[string]struct {
-			Expires time.Time
+			Reason    string
+			Expires   time.Time
+			Created   time.Time
+			IgnoredBy *IgnoredBy `yaml:"ignoredBy"`
 		}
 	}
 
@@ -22,17 +45,40 @@ func NewMatcherFromPolicy(config []byte) (*Matcher, error) {
 		return nil, fmt.Errorf("unmarshal policy: %v", err)
 	}
 
-	var matcher Matcher
+	var ids []string
 
-	for policyID, policyRules := range policy.Ignore {
-		for _, p := range policyRules {
-			for pattern, ruleMeta := range p {
-				if err := matcher.AddIgnore(policyID, ruleMeta.Expires, pattern); err != nil {
-					return nil, fmt.Errorf("add policy rule: %v", err)
-				}
+	for id := range policy.Ignore {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	var rules []Rule
+
+	for _, policyID := range ids {
+		for _, p := range policy.Ignore[policyID] {
+			var patterns []string
+
+			for pattern := range p {
+				patterns = append(patterns, pattern)
+			}
+
+			sort.Strings(patterns)
+
+			for _, pattern := range patterns {
+				ruleMeta := p[pattern]
+
+				rules = append(rules, Rule{
+					ID:        policyID,
+					Pattern:   pattern,
+					Reason:    ruleMeta.Reason,
+					Expires:   ruleMeta.Expires,
+					Created:   ruleMeta.Created,
+					IgnoredBy: ruleMeta.IgnoredBy,
+				})
 			}
 		}
 	}
 
-	return &matcher, nil
+	return rules, nil
 }
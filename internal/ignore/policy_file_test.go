@@ -71,3 +71,58 @@ func readFile(t *testing.T, name string) []byte {
 
 	return data
 }
+
+func TestParsePolicy(t *testing.T) {
+	policy := `
+ignore:
+  RULE-2:
+    - 'b.tf':
+        reason: no fix available
+        expires: 2022-02-01T00:00:00.000Z
+        created: 2021-11-29T17:25:19.200Z
+        ignoredBy:
+          id: 00000000-0000-0000-0000-000000000000
+          name: Jane Doe
+          email: jane@example.com
+  RULE-1:
+    - '*':
+        reason: accepted risk
+`
+
+	rules, err := ignore.ParsePolicy([]byte(policy))
+	require.NoError(t, err)
+
+	require.Equal(t, []ignore.Rule{
+		{
+			ID:      "RULE-1",
+			Pattern: "*",
+			Reason:  "accepted risk",
+		},
+		{
+			ID:      "RULE-2",
+			Pattern: "b.tf",
+			Reason:  "no fix available",
+			Expires: newTime(t, "2022-02-01T00:00:00.000Z"),
+			Created: newTime(t, "2021-11-29T17:25:19.200Z"),
+			IgnoredBy: &ignore.IgnoredBy{
+				ID:    "00000000-0000-0000-0000-000000000000",
+				Name:  "Jane Doe",
+				Email: "jane@example.com",
+			},
+		},
+	}, rules)
+}
+
+func TestMatchRule(t *testing.T) {
+	matcher, err := ignore.NewMatcherFromPolicy(readFile(t, "multiple-rules.yaml"))
+	require.NoError(t, err)
+
+	rule, ok := matcher.MatchRule("SNYK-CC-K8S-4", newTime(t, "2022-01-01T00:00:00.000Z"), "foo", "bar", "baz")
+	require.True(t, ok)
+	require.Equal(t, "foo > bar > baz", rule.Pattern)
+	require.Equal(t, "no fix available", rule.Reason)
+	require.Equal(t, newTime(t, "2021-11-29T17:25:19.200Z"), rule.Created)
+
+	_, ok = matcher.MatchRule("SNYK-CC-K8S-4", newTime(t, "2022-03-01T00:00:00.000Z"), "foo", "bar", "baz")
+	require.False(t, ok)
+}
@@ -0,0 +1,21 @@
+package ignore
+
+import "time"
+
+// Rule is an ignore rule. It ignores the vulnerabilities with the given ID
+// whose path matches the pattern, until the rule expires.
+type Rule struct {
+	ID        string
+	Pattern   string
+	Reason    string
+	Expires   time.Time
+	Created   time.Time
+	IgnoredBy *IgnoredBy
+}
+
+// IgnoredBy identifies the user who created an ignore rule.
+type IgnoredBy struct {
+	ID    string `yaml:"id"`
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
+}
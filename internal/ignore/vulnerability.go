@@ -6,12 +6,10 @@ type vulnerabilityMatcher struct {
 	matchers []expiringMatcher
 }
 
-func (m *vulnerabilityMatcher) addIgnore(expires time.Time, pattern string) error {
+func (m *vulnerabilityMatcher) addRule(rule Rule) error {
 	var matcher expiringMatcher
 
-	matcher.setExpires(expires)
-
-	if err := matcher.setPattern(pattern); err != nil {
+	if err := matcher.setRule(rule); err != nil {
 		return err
 	}
 
@@ -20,12 +18,12 @@ func (m *vulnerabilityMatcher) addIgnore(expires time.Time, pattern string) erro
 	return nil
 }
 
-func (m *vulnerabilityMatcher) match(now time.Time, parts ...string) bool {
+func (m *vulnerabilityMatcher) match(now time.Time, parts ...string) (Rule, bool) {
 	for _, matcher := range m.matchers {
 		if matcher.match(now, parts...) {
-			return true
+			return matcher.rule, true
 		}
 	}
 
-	return false
+	return Rule{}, false
 }
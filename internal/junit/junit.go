@@ -0,0 +1,284 @@
+// Package junit renders scan results as JUnit XML reports, so that they can
+// be displayed by CI test reporters.
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+const (
+	reportName  = "Snyk IaC"
+	unknownFile = "unknown"
+)
+
+type TestSuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Suites   []TestSuite `xml:"testsuite"`
+}
+
+type TestSuite struct {
+	Name      string     `xml:"name,attr"`
+	Tests     int        `xml:"tests,attr"`
+	Failures  int        `xml:"failures,attr"`
+	Skipped   int        `xml:"skipped,attr"`
+	TestCases []TestCase `xml:"testcase"`
+}
+
+type TestCase struct {
+	Name      string   `xml:"name,attr"`
+	Classname string   `xml:"classname,attr"`
+	Failure   *Failure `xml:"failure,omitempty"`
+	Skipped   *Skipped `xml:"skipped,omitempty"`
+}
+
+type Failure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type Skipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// FromResults converts the scan results to JUnit test suites. Every file is a
+// test suite, and every rule evaluated against a resource in that file is a
+// test case. A test case fails if at least one of its vulnerabilities is not
+// ignored, and is skipped if all of its vulnerabilities are ignored.
+func FromResults(r *results.Results) *TestSuites {
+	suites := TestSuites{
+		Name: reportName,
+	}
+
+	if r == nil {
+		return &suites
+	}
+
+	builders := make(map[string]*suiteBuilder)
+
+	getBuilder := func(file string) *suiteBuilder {
+		if file == "" {
+			file = unknownFile
+		}
+
+		file = filepath.ToSlash(file)
+
+		if builders[file] == nil {
+			builders[file] = newSuiteBuilder(file)
+		}
+
+		return builders[file]
+	}
+
+	for _, v := range r.Vulnerabilities {
+		getBuilder(v.Resource.File).addFailed(v)
+	}
+
+	for _, v := range r.IgnoredVulnerabilities {
+		getBuilder(v.Resource.File).addFailed(v)
+	}
+
+	for _, v := range r.PassedVulnerabilities {
+		getBuilder(v.Resource.File).addPassed(v)
+	}
+
+	var names []string
+
+	for name := range builders {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		suite := builders[name].build()
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	return &suites
+}
+
+// Write renders the scan results as an indented JUnit XML document.
+func Write(w io.Writer, r *results.Results) error {
+	return Encode(w, FromResults(r))
+}
+
+// Encode renders test suites as an indented JUnit XML document.
+func Encode(w io.Writer, suites *TestSuites) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("write XML header: %v", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	if err := encoder.Encode(suites); err != nil {
+		return fmt.Errorf("encode JUnit report: %v", err)
+	}
+
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("write XML trailer: %v", err)
+	}
+
+	return nil
+}
+
+type testCaseKey struct {
+	ruleID     string
+	resourceID string
+}
+
+type testCaseBuilder struct {
+	ruleID     string
+	resourceID string
+	failed     []results.Vulnerability
+	ignored    []results.Vulnerability
+}
+
+type suiteBuilder struct {
+	name      string
+	testCases map[testCaseKey]*testCaseBuilder
+	order     []testCaseKey
+}
+
+func newSuiteBuilder(name string) *suiteBuilder {
+	return &suiteBuilder{
+		name:      name,
+		testCases: make(map[testCaseKey]*testCaseBuilder),
+	}
+}
+
+func (b *suiteBuilder) testCase(v results.Vulnerability) *testCaseBuilder {
+	key := testCaseKey{
+		ruleID:     v.Rule.ID,
+		resourceID: v.Resource.ID,
+	}
+
+	if b.testCases[key] == nil {
+		b.testCases[key] = &testCaseBuilder{
+			ruleID:     v.Rule.ID,
+			resourceID: v.Resource.ID,
+		}
+		b.order = append(b.order, key)
+	}
+
+	return b.testCases[key]
+}
+
+func (b *suiteBuilder) addFailed(v results.Vulnerability) {
+	tc := b.testCase(v)
+
+	if v.Ignored {
+		tc.ignored = append(tc.ignored, v)
+	} else {
+		tc.failed = append(tc.failed, v)
+	}
+}
+
+func (b *suiteBuilder) addPassed(v results.Vulnerability) {
+	b.testCase(v)
+}
+
+func (b *suiteBuilder) build() TestSuite {
+	suite := TestSuite{
+		Name: b.name,
+	}
+
+	for _, key := range b.order {
+		tc := b.testCases[key].build()
+
+		suite.Tests++
+
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+
+		if tc.Skipped != nil {
+			suite.Skipped++
+		}
+
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	return suite
+}
+
+func (b *testCaseBuilder) build() TestCase {
+	tc := TestCase{
+		Name:      b.ruleID,
+		Classname: b.resourceID,
+	}
+
+	switch {
+	case len(b.failed) > 0:
+		v := b.failed[0]
+
+		message := v.Message
+		if message == "" {
+			message = v.Rule.Title
+		}
+
+		tc.Failure = &Failure{
+			Message: message,
+			Type:    v.Severity,
+			Text:    failureText(b.failed),
+		}
+	case len(b.ignored) > 0:
+		message := "ignored"
+		if i := b.ignored[0].Ignore; i != nil && i.Reason != "" {
+			message = fmt.Sprintf("ignored: %s", i.Reason)
+		}
+
+		tc.Skipped = &Skipped{
+			Message: message,
+		}
+	}
+
+	return tc
+}
+
+func failureText(vulnerabilities []results.Vulnerability) string {
+	var b strings.Builder
+
+	for i, v := range vulnerabilities {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "%s\n", v.Rule.Title)
+		fmt.Fprintf(&b, "Severity: %s\n", v.Severity)
+
+		if v.Resource.FormattedPath != "" {
+			fmt.Fprintf(&b, "Path: %s\n", v.Resource.FormattedPath)
+		}
+
+		if v.Resource.Line > 0 {
+			fmt.Fprintf(&b, "Line: %d\n", v.Resource.Line)
+		}
+
+		if v.Remediation != "" {
+			fmt.Fprintf(&b, "Remediation: %s\n", v.Remediation)
+		}
+
+		if v.Rule.Documentation != "" {
+			fmt.Fprintf(&b, "Info: %s\n", v.Rule.Documentation)
+		}
+	}
+
+	return b.String()
+}
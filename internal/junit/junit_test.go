@@ -0,0 +1,159 @@
+package junit_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/junit"
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+func TestFromNilResults(t *testing.T) {
+	suites := junit.FromResults(nil)
+
+	require.Equal(t, "Snyk IaC", suites.Name)
+	require.Empty(t, suites.Suites)
+}
+
+func TestFromResults(t *testing.T) {
+	input := &results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			{
+				Rule:        results.Rule{ID: "SNYK-CC-TF-1", Title: "rule-title"},
+				Message:     "result-message",
+				Remediation: "result-remediation",
+				Severity:    "high",
+				Resource: results.Resource{
+					ID:            "aws_s3_bucket.a",
+					File:          "main.tf",
+					FormattedPath: "resource.aws_s3_bucket[a].acl",
+					Line:          3,
+				},
+			},
+			{
+				Rule:     results.Rule{ID: "SNYK-CC-TF-1", Title: "rule-title"},
+				Severity: "high",
+				Resource: results.Resource{
+					ID:   "aws_s3_bucket.a",
+					File: "main.tf",
+				},
+			},
+			{
+				Rule:     results.Rule{ID: "SNYK-CC-K8S-1", Title: "k8s-title"},
+				Severity: "low",
+				Ignored:  true,
+				Resource: results.Resource{
+					ID:   "Pod.default.pod",
+					File: "pod.yaml",
+				},
+			},
+		},
+		PassedVulnerabilities: []results.Vulnerability{
+			{
+				Rule: results.Rule{ID: "SNYK-CC-TF-2"},
+				Resource: results.Resource{
+					ID:   "aws_s3_bucket.a",
+					File: "main.tf",
+				},
+			},
+		},
+	}
+
+	expected := &junit.TestSuites{
+		Name:     "Snyk IaC",
+		Tests:    3,
+		Failures: 1,
+		Skipped:  1,
+		Suites: []junit.TestSuite{
+			{
+				Name:     "main.tf",
+				Tests:    2,
+				Failures: 1,
+				TestCases: []junit.TestCase{
+					{
+						Name:      "SNYK-CC-TF-1",
+						Classname: "aws_s3_bucket.a",
+						Failure: &junit.Failure{
+							Message: "result-message",
+							Type:    "high",
+							Text:    "rule-title\nSeverity: high\nPath: resource.aws_s3_bucket[a].acl\nLine: 3\nRemediation: result-remediation\n\nrule-title\nSeverity: high\n",
+						},
+					},
+					{
+						Name:      "SNYK-CC-TF-2",
+						Classname: "aws_s3_bucket.a",
+					},
+				},
+			},
+			{
+				Name:    "pod.yaml",
+				Tests:   1,
+				Skipped: 1,
+				TestCases: []junit.TestCase{
+					{
+						Name:      "SNYK-CC-K8S-1",
+						Classname: "Pod.default.pod",
+						Skipped:   &junit.Skipped{Message: "ignored"},
+					},
+				},
+			},
+		},
+	}
+
+	actual := junit.FromResults(input)
+	actual.XMLName = expected.XMLName
+
+	require.Equal(t, expected, actual)
+}
+
+func TestFromResultsIgnoredVulnerabilities(t *testing.T) {
+	input := &results.Results{
+		IgnoredVulnerabilities: []results.Vulnerability{
+			{
+				Rule:     results.Rule{ID: "SNYK-CC-TF-1"},
+				Ignored:  true,
+				Resource: results.Resource{ID: "aws_s3_bucket.a", File: "main.tf"},
+				Ignore: &results.Ignore{
+					Source: results.IgnoreSourcePolicy,
+					Reason: "accepted risk",
+				},
+			},
+		},
+	}
+
+	actual := junit.FromResults(input)
+
+	require.Equal(t, 1, actual.Skipped)
+	require.Equal(t, &junit.Skipped{Message: "ignored: accepted risk"}, actual.Suites[0].TestCases[0].Skipped)
+}
+
+func TestWrite(t *testing.T) {
+	input := &results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			{
+				Rule:     results.Rule{ID: "SNYK-CC-TF-1", Title: "rule-title"},
+				Message:  "result-message",
+				Severity: "high",
+				Resource: results.Resource{ID: "aws_s3_bucket.a", File: "main.tf"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	require.NoError(t, junit.Write(&buf, input))
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="Snyk IaC" tests="1" failures="1" skipped="0">
+  <testsuite name="main.tf" tests="1" failures="1" skipped="0">
+    <testcase name="SNYK-CC-TF-1" classname="aws_s3_bucket.a">
+      <failure message="result-message" type="high">rule-title&#xA;Severity: high&#xA;</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+
+	require.Equal(t, expected, buf.String())
+}
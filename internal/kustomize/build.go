@@ -0,0 +1,470 @@
+package kustomize
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// Location is a position in a file.
+type Location struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// source is a YAML document that defines or patches a resource.
+type source struct {
+	file string
+	node *yaml.Node
+}
+
+func (s source) location(node *yaml.Node) Location {
+	if node == nil {
+		return Location{File: s.file}
+	}
+
+	return Location{File: s.file, Line: node.Line, Column: node.Column}
+}
+
+// Resource is a resource built by a kustomization.
+type Resource struct {
+	Object map[string]any
+
+	// origin is the document that defines the resource, or the entry of the
+	// generator that generated it.
+	origin source
+
+	// patches are the strategic merge patches applied to the resource, in
+	// order. The patches that are not read from a file are not tracked.
+	patches []source
+
+	// includes are the entries of the kustomizations that included the
+	// resource, from the innermost kustomization to the overlay.
+	includes []Location
+}
+
+// Kind returns the kind of the resource.
+func (r *Resource) Kind() string {
+	kind, _ := r.Object["kind"].(string)
+	return kind
+}
+
+// Name returns the name of the resource.
+func (r *Resource) Name() string {
+	name, _ := r.metadata()["name"].(string)
+	return name
+}
+
+// Namespace returns the namespace of the resource.
+func (r *Resource) Namespace() string {
+	namespace, _ := r.metadata()["namespace"].(string)
+	return namespace
+}
+
+func (r *Resource) metadata() map[string]any {
+	metadata, _ := r.Object["metadata"].(map[string]any)
+	return metadata
+}
+
+// Locate returns the location of an attribute of the resource, followed by
+// the locations of the kustomizations that included the resource, up to the
+// overlay. The attribute is located in the last patch that sets it, or in the
+// document that defines the resource. An empty path locates the resource.
+func (r *Resource) Locate(path []any) []Location {
+	var location *Location
+
+	if len(path) > 0 {
+		for i := len(r.patches) - 1; i >= 0; i-- {
+			if node, exact := find(r.patches[i].node, path); exact {
+				l := r.patches[i].location(node)
+				location = &l
+				break
+			}
+		}
+	}
+
+	if location == nil {
+		node, _ := find(r.origin.node, path)
+		l := r.origin.location(node)
+		location = &l
+	}
+
+	return append([]Location{*location}, r.includes...)
+}
+
+// find returns the node of a path, or the deepest node of the path that
+// exists. It returns true if the whole path exists. Keys are returned instead
+// of values, since they point to the attribute being set.
+func find(node *yaml.Node, path []any) (*yaml.Node, bool) {
+	if node == nil {
+		return nil, false
+	}
+
+	current := node
+
+	for _, element := range path {
+		switch current.Kind {
+		case yaml.MappingNode:
+			key, ok := element.(string)
+			if !ok {
+				return current, false
+			}
+
+			found := false
+
+			for i := 0; i+1 < len(current.Content); i += 2 {
+				if current.Content[i].Value == key {
+					node, current = current.Content[i], current.Content[i+1]
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return node, false
+			}
+		case yaml.SequenceNode:
+			index, ok := toIndex(element)
+			if !ok || index < 0 || index >= len(current.Content) {
+				return node, false
+			}
+
+			node, current = current.Content[index], current.Content[index]
+		default:
+			return node, false
+		}
+	}
+
+	return node, true
+}
+
+func toIndex(v any) (int, bool) {
+	switch v := v.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
+// Result is the output of a kustomization.
+type Result struct {
+	Resources []*Resource
+
+	// Files are the files read to build the kustomization.
+	Files []string
+
+	// Warnings are the parts of the kustomization that were not built, e.g.
+	// the remote resources and the unsupported fields.
+	Warnings []error
+}
+
+// unsupportedFields are the fields of a kustomization file that are not
+// built, because Kustomize runs the helm binary to build them.
+var unsupportedFields = []string{"helmCharts", "helmChartInflationGenerator"}
+
+// Build builds the kustomization in a directory, with its bases and
+// components, like `kustomize build` does. The kustomizations are copied to
+// an in-memory file system without their remote resources, which can't be
+// built without network access, and without their unsupported fields.
+func Build(fsys afero.Fs, dir string) (*Result, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = string(filepath.Separator)
+	}
+
+	b := builder{
+		fs:             fsys,
+		mem:            filesys.MakeFsInMemory(),
+		cwd:            cwd,
+		relative:       !filepath.IsAbs(dir),
+		kustomizations: make(map[string]*kustomization),
+		documents:      make(map[string][]*yaml.Node),
+		read:           make(map[string]bool),
+	}
+
+	dir = filepath.Clean(dir)
+
+	if err := b.load(dir, true); err != nil {
+		return nil, err
+	}
+
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(recordingFs{b.mem, b.addFile}, b.memPath(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	result := Result{
+		Files:    b.files,
+		Warnings: b.warnings,
+	}
+
+	for _, res := range resMap.Resources() {
+		r, err := b.resource(dir, res)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Resources = append(result.Resources, r)
+	}
+
+	return &result, nil
+}
+
+type builder struct {
+	fs  afero.Fs
+	mem filesys.FileSystem
+
+	// cwd is the directory the relative paths of fs are relative to in mem,
+	// and relative is true if the paths of fs are relative.
+	cwd      string
+	relative bool
+
+	// copied are the directories copied to mem.
+	copied []string
+
+	// kustomizations are the kustomizations that are built, by directory.
+	kustomizations map[string]*kustomization
+
+	// documents are the documents of the files that define or patch the
+	// resources, by path.
+	documents map[string][]*yaml.Node
+
+	read     map[string]bool
+	files    []string
+	warnings []error
+}
+
+// load copies the directory of a kustomization to the in-memory file system,
+// and loads the kustomizations it includes. The origin of the resources is
+// only annotated by Kustomize if the built kustomization asks for it.
+func (b *builder) load(dir string, root bool) error {
+	if _, ok := b.kustomizations[dir]; ok {
+		return nil
+	}
+
+	k, err := loadKustomization(b.fs, dir)
+	if err != nil {
+		return err
+	}
+
+	b.kustomizations[dir] = k
+
+	if err := b.copyDir(dir); err != nil {
+		return fmt.Errorf("copy %s: %v", dir, err)
+	}
+
+	data, err := b.rewrite(k, root)
+	if err != nil {
+		return fmt.Errorf("rewrite %s: %v", k.path, err)
+	}
+
+	if err := b.mem.WriteFile(b.memPath(k.path), data); err != nil {
+		return fmt.Errorf("copy %s: %v", k.path, err)
+	}
+
+	for _, entry := range k.includes() {
+		if path := filepath.Join(dir, entry.path); IsKustomization(b.fs, path) {
+			if err := b.load(path, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyDir copies a directory to the in-memory file system, unless one of its
+// parents was already copied. The files that were already copied are kept,
+// since they may have been rewritten.
+func (b *builder) copyDir(dir string) error {
+	for _, copied := range b.copied {
+		if rel, err := filepath.Rel(copied, dir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+
+	b.copied = append(b.copied, dir)
+
+	return afero.Walk(b.fs, dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != dir && info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+
+			return b.mem.MkdirAll(b.memPath(path))
+		}
+
+		if b.mem.Exists(b.memPath(path)) {
+			return nil
+		}
+
+		data, err := afero.ReadFile(b.fs, path)
+		if err != nil {
+			return err
+		}
+
+		return b.mem.WriteFile(b.memPath(path), data)
+	})
+}
+
+// rewrite returns the content of a kustomization file without its remote
+// entries and its unsupported fields. The kustomization that is built also
+// asks for the origin of its resources to be annotated.
+func (b *builder) rewrite(k *kustomization, root bool) ([]byte, error) {
+	data, err := afero.ReadFile(b.fs, k.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	node := mapping(&document)
+	if node == nil {
+		return data, nil
+	}
+
+	var content []*yaml.Node
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		if slices.Contains(unsupportedFields, key.Value) {
+			b.warnings = append(b.warnings, fmt.Errorf("field %s of %s is not supported and is not built", key.Value, k.path))
+			continue
+		}
+
+		switch key.Value {
+		case "resources", "bases":
+			value.Content = b.removeRemote(value.Content, "resource")
+		case "components":
+			value.Content = b.removeRemote(value.Content, "component")
+		case buildMetadataField:
+			if root {
+				value.Content = append(value.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: types.OriginAnnotations})
+				root = false
+			}
+		}
+
+		content = append(content, key, value)
+	}
+
+	if root {
+		content = append(content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: buildMetadataField},
+			&yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: types.OriginAnnotations}}},
+		)
+	}
+
+	node.Content = content
+
+	return yaml.Marshal(&document)
+}
+
+const buildMetadataField = "buildMetadata"
+
+func (b *builder) removeRemote(entries []*yaml.Node, kind string) []*yaml.Node {
+	var result []*yaml.Node
+
+	for _, entry := range entries {
+		if isRemote(entry.Value) {
+			b.warnings = append(b.warnings, fmt.Errorf("remote %s %s is not built", kind, entry.Value))
+			continue
+		}
+
+		result = append(result, entry)
+	}
+
+	return result
+}
+
+// resource converts a resource built by Kustomize, and locates it from the
+// origin annotated by Kustomize, which is removed.
+func (b *builder) resource(dir string, res *resource.Resource) (*Resource, error) {
+	origin, err := res.GetOrigin()
+	if err != nil {
+		return nil, fmt.Errorf("origin of %s: %v", res.CurId(), err)
+	}
+
+	if err := res.SetOrigin(nil); err != nil {
+		return nil, fmt.Errorf("origin of %s: %v", res.CurId(), err)
+	}
+
+	object, err := res.Map()
+	if err != nil {
+		return nil, fmt.Errorf("convert %s: %v", res.CurId(), err)
+	}
+
+	r := Resource{Object: object}
+
+	if origin != nil {
+		b.locate(&r, dir, res, origin)
+	}
+
+	return &r, nil
+}
+
+func (b *builder) memPath(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+
+	return filepath.Join(b.cwd, path)
+}
+
+func (b *builder) fsPath(path string) string {
+	if !b.relative {
+		return path
+	}
+
+	if rel, err := filepath.Rel(b.cwd, path); err == nil {
+		return rel
+	}
+
+	return path
+}
+
+func (b *builder) addFile(memPath string) {
+	if path := b.fsPath(memPath); !b.read[path] {
+		b.read[path] = true
+		b.files = append(b.files, path)
+	}
+}
+
+// recordingFs is a file system that records the files read by Kustomize.
+type recordingFs struct {
+	filesys.FileSystem
+	read func(path string)
+}
+
+func (f recordingFs) ReadFile(path string) ([]byte, error) {
+	data, err := f.FileSystem.ReadFile(path)
+	if err == nil {
+		f.read(filepath.Clean(path))
+	}
+
+	return data, err
+}
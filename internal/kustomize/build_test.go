@@ -0,0 +1,377 @@
+package kustomize_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/kustomize"
+)
+
+func newFs(t *testing.T, files map[string]string) afero.Fs {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0644))
+	}
+
+	return fs
+}
+
+const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  selector:
+    matchLabels:
+      app: app
+  template:
+    metadata:
+      labels:
+        app: app
+    spec:
+      containers:
+        - name: app
+          image: registry:5000/app:1.0.0
+          envFrom:
+            - configMapRef:
+                name: config
+        - name: sidecar
+          image: sidecar
+`
+
+func find(t *testing.T, result *kustomize.Result, kind, name string) *kustomize.Resource {
+	t.Helper()
+
+	for _, r := range result.Resources {
+		if r.Kind() == kind && r.Name() == name {
+			return r
+		}
+	}
+
+	require.Failf(t, "resource not found", "%s %s", kind, name)
+
+	return nil
+}
+
+func TestBuild(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"base/kustomization.yaml": `resources:
+  - deployment.yaml
+configMapGenerator:
+  - name: config
+    literals:
+      - LOG_LEVEL=info
+`,
+		"base/deployment.yaml": deployment,
+		"overlay/kustomization.yaml": `namespace: prod
+namePrefix: prod-
+commonLabels:
+  env: prod
+commonAnnotations:
+  team: platform
+resources:
+  - ../base
+  - https://github.com/example/remote
+patchesStrategicMerge:
+  - patch.yaml
+patchesJson6902:
+  - target:
+      kind: Deployment
+      name: prod-.*
+    patch: |-
+      - op: remove
+        path: /spec/template/spec/containers/1
+replicas:
+  - name: app
+    count: 3
+images:
+  - name: registry:5000/app
+    newTag: 2.0.0
+`,
+		"overlay/patch.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          securityContext:
+            privileged: true
+`,
+	})
+
+	result, err := kustomize.Build(fs, "overlay")
+	require.NoError(t, err)
+	require.Len(t, result.Resources, 2)
+	require.Len(t, result.Warnings, 1)
+	require.ElementsMatch(t, []string{
+		"overlay/kustomization.yaml",
+		"base/kustomization.yaml",
+		"base/deployment.yaml",
+		"overlay/patch.yaml",
+	}, result.Files)
+
+	d := find(t, result, "Deployment", "prod-app")
+	require.Equal(t, "prod", d.Namespace())
+
+	require.Equal(t, map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]any{
+			"name":        "prod-app",
+			"namespace":   "prod",
+			"labels":      map[string]any{"env": "prod"},
+			"annotations": map[string]any{"team": "platform"},
+		},
+		"spec": map[string]any{
+			"replicas": 3,
+			"selector": map[string]any{
+				"matchLabels": map[string]any{"app": "app", "env": "prod"},
+			},
+			"template": map[string]any{
+				"metadata": map[string]any{
+					"labels":      map[string]any{"app": "app", "env": "prod"},
+					"annotations": map[string]any{"team": "platform"},
+				},
+				"spec": map[string]any{
+					"containers": []any{
+						map[string]any{
+							"name":  "app",
+							"image": "registry:5000/app:2.0.0",
+							"envFrom": []any{
+								map[string]any{
+									"configMapRef": map[string]any{"name": "prod-config-hf678c7m2b"},
+								},
+							},
+							"securityContext": map[string]any{"privileged": true},
+						},
+					},
+				},
+			},
+		},
+	}, d.Object)
+
+	c := find(t, result, "ConfigMap", "prod-config-hf678c7m2b")
+	require.Equal(t, map[string]any{"LOG_LEVEL": "info"}, c.Object["data"])
+}
+
+func TestLocate(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"base/kustomization.yaml": `resources:
+  - deployment.yaml
+`,
+		"base/deployment.yaml": deployment,
+		"overlay/kustomization.yaml": `resources:
+  - ../base
+configMapGenerator:
+  - name: config
+    literals:
+      - LOG_LEVEL=info
+patches:
+  - path: patch.yaml
+`,
+		"overlay/patch.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          securityContext:
+            privileged: true
+`,
+	})
+
+	result, err := kustomize.Build(fs, "overlay")
+	require.NoError(t, err)
+
+	d := find(t, result, "Deployment", "app")
+
+	includes := []kustomize.Location{
+		{File: "base/kustomization.yaml", Line: 2, Column: 5},
+		{File: "overlay/kustomization.yaml", Line: 2, Column: 5},
+	}
+
+	// The resource is located in the document that defines it.
+	require.Equal(t, append([]kustomize.Location{
+		{File: "base/deployment.yaml", Line: 1, Column: 1},
+	}, includes...), d.Locate(nil))
+
+	// An attribute set by a patch is located in the patch.
+	require.Equal(t, append([]kustomize.Location{
+		{File: "overlay/patch.yaml", Line: 11, Column: 13},
+	}, includes...), d.Locate([]any{"spec", "template", "spec", "containers", 0, "securityContext", "privileged"}))
+
+	// An attribute that is not patched is located in the base.
+	require.Equal(t, append([]kustomize.Location{
+		{File: "base/deployment.yaml", Line: 16, Column: 11},
+	}, includes...), d.Locate([]any{"spec", "template", "spec", "containers", 0, "image"}))
+
+	// A missing attribute is located at its closest parent.
+	require.Equal(t, "base/deployment.yaml", d.Locate([]any{"spec", "replicas"})[0].File)
+	require.Equal(t, 5, d.Locate([]any{"spec", "replicas"})[0].Line)
+
+	// A generated resource is located at its generator. Its name ends with the
+	// hash of its content, like with `kustomize build`.
+	c := find(t, result, "ConfigMap", "config-hf678c7m2b")
+	require.Equal(t, []kustomize.Location{
+		{File: "overlay/kustomization.yaml", Line: 4, Column: 5},
+	}, c.Locate([]any{"data", "LOG_LEVEL"}))
+}
+
+func TestBuildComponents(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"app/kustomization.yaml": `resources:
+  - deployment.yaml
+components:
+  - ../hardening
+`,
+		"app/deployment.yaml": deployment,
+		"hardening/kustomization.yaml": `kind: Component
+patches:
+  - target:
+      kind: Deployment
+    patch: |-
+      apiVersion: apps/v1
+      kind: Deployment
+      metadata:
+        name: any
+      spec:
+        template:
+          spec:
+            automountServiceAccountToken: false
+            containers:
+              - name: sidecar
+                $patch: delete
+`,
+	})
+
+	result, err := kustomize.Build(fs, "app")
+	require.NoError(t, err)
+
+	d := find(t, result, "Deployment", "app")
+	spec := d.Object["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)
+
+	require.Equal(t, false, spec["automountServiceAccountToken"])
+	require.Len(t, spec["containers"], 1)
+	require.Contains(t, result.Files, "hardening/kustomization.yaml")
+}
+
+func TestBuildUnsupportedFields(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"app/kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+  - deployment.yaml
+helmCharts:
+  - name: chart
+helmChartInflationGenerator:
+  - chartName: chart
+`,
+		"app/deployment.yaml": deployment,
+	})
+
+	result, err := kustomize.Build(fs, "app")
+	require.NoError(t, err)
+	require.Len(t, result.Resources, 1)
+
+	var warnings []string
+
+	for _, warning := range result.Warnings {
+		warnings = append(warnings, warning.Error())
+	}
+
+	path := filepath.Join("app", "kustomization.yaml")
+
+	require.Equal(t, []string{
+		"field helmCharts of " + path + " is not supported and is not built",
+		"field helmChartInflationGenerator of " + path + " is not supported and is not built",
+	}, warnings)
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{
+			name: "missing resource",
+			files: map[string]string{
+				"app/kustomization.yaml": "resources:\n  - missing.yaml\n",
+			},
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"app/kustomization.yaml":   "resources:\n  - ../other\n",
+				"other/kustomization.yaml": "resources:\n  - ../app\n",
+			},
+		},
+		{
+			name: "patch of an undefined resource",
+			files: map[string]string{
+				"app/kustomization.yaml": "patchesStrategicMerge:\n  - |-\n    kind: Deployment\n    metadata:\n      name: app\n",
+			},
+		},
+		{
+			name: "invalid kustomization",
+			files: map[string]string{
+				"app/kustomization.yaml": "resources: [",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := kustomize.Build(newFs(t, test.files), "app")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestIncluded(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"base/kustomization.yaml":           "resources:\n  - deployment.yaml\n",
+		"base/deployment.yaml":              deployment,
+		"overlays/prod/kustomization.yaml":  "resources:\n  - ../../base\n  - https://github.com/example/remote\n",
+		"overlays/stage/kustomization.yaml": "resources:\n  - ../../base\n",
+	})
+
+	require.Equal(t, map[string]bool{
+		"base/deployment.yaml": true,
+		"base":                 true,
+	}, kustomize.Included(fs, ".", 0))
+}
+
+func TestIncludedSkipsHiddenAndDeepDirectories(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"base/kustomization.yaml":          "resources:\n  - deployment.yaml\n",
+		"base/deployment.yaml":             deployment,
+		"app/kustomization.yaml":           "resources:\n  - service.yaml\n",
+		"app/service.yaml":                 deployment,
+		".cache/app/kustomization.yaml":    "resources:\n  - ../../base\n",
+		"overlays/prod/kustomization.yaml": "resources:\n  - ../../base\n",
+	})
+
+	// The overlay is too deep, so the base is not included.
+	require.Equal(t, map[string]bool{
+		"app/service.yaml":     true,
+		"base/deployment.yaml": true,
+	}, kustomize.Included(fs, ".", 1))
+
+	require.Equal(t, map[string]bool{
+		"app/service.yaml":     true,
+		"base/deployment.yaml": true,
+		"base":                 true,
+	}, kustomize.Included(fs, ".", 2))
+}
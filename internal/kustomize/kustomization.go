@@ -0,0 +1,225 @@
+// Package kustomize builds Kustomize overlays in-process with the API of
+// Kustomize, and keeps track of the files that define and patch every
+// resource, so that the resources can be mapped back to their bases and to the
+// overlays that include them.
+package kustomize
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kustomize/api/types"
+)
+
+// Files are the names of a kustomization file, in the order Kustomize looks
+// for them.
+var Files = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// File returns the path of the kustomization file of a directory.
+func File(fsys afero.Fs, dir string) (string, bool) {
+	for _, name := range Files {
+		path := filepath.Join(dir, name)
+
+		if info, err := fsys.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+// IsKustomization returns true if the directory contains a kustomization file.
+func IsKustomization(fsys afero.Fs, dir string) bool {
+	_, ok := File(fsys, dir)
+	return ok
+}
+
+// kustomization is a kustomization file, with its fields as Kustomize reads
+// them and its content, used to locate its entries.
+type kustomization struct {
+	types.Kustomization
+
+	// path is the path of the kustomization file.
+	path string
+
+	// root is the content of the kustomization file.
+	root *yaml.Node
+}
+
+func loadKustomization(fsys afero.Fs, dir string) (*kustomization, error) {
+	path, ok := File(fsys, dir)
+	if !ok {
+		return nil, fmt.Errorf("no kustomization file found in %s", dir)
+	}
+
+	data, err := afero.ReadFile(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("read kustomization: %v", err)
+	}
+
+	var root yaml.Node
+
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %v", path, err)
+	}
+
+	k := kustomization{
+		path: path,
+		root: &root,
+	}
+
+	if err := root.Decode(&k.Kustomization); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %v", path, err)
+	}
+
+	return &k, nil
+}
+
+// mapping returns the mapping node of a YAML document, or nil if the document
+// is not a mapping.
+func mapping(node *yaml.Node) *yaml.Node {
+
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	return node
+}
+
+// entry returns the node of an entry of a list of the kustomization, or nil
+// if it can't be found.
+func (k *kustomization) entry(key string, index int) *yaml.Node {
+	node := mapping(k.root)
+	if node == nil {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key {
+			continue
+		}
+
+		if list := node.Content[i+1]; list.Kind == yaml.SequenceNode && index < len(list.Content) {
+			return list.Content[index]
+		}
+	}
+
+	return nil
+}
+
+// location returns the location of an entry of a list of the kustomization,
+// or the location of the kustomization file if it can't be found.
+func (k *kustomization) location(key string, index int) Location {
+	if node := k.entry(key, index); node != nil {
+		return Location{File: k.path, Line: node.Line, Column: node.Column}
+	}
+
+	return Location{File: k.path}
+}
+
+// include is an entry of the resources, bases or components of a
+// kustomization that refers to a local file or directory.
+type include struct {
+	key   string
+	index int
+	path  string
+}
+
+// includes returns the entries of the kustomization that are not remote.
+func (k *kustomization) includes() []include {
+	var result []include
+
+	for _, list := range []struct {
+		key     string
+		entries []string
+	}{
+		{"resources", k.Resources},
+		{"bases", k.Bases},
+		{"components", k.Components},
+	} {
+		for i, entry := range list.entries {
+			if !isRemote(entry) {
+				result = append(result, include{key: list.key, index: i, path: entry})
+			}
+		}
+	}
+
+	return result
+}
+
+// depth returns the number of directories of a relative path.
+func depth(rel string) int {
+	return strings.Count(filepath.ToSlash(rel), "/") + 1
+}
+
+// isRemote returns true if a resource of a kustomization is a remote URL,
+// which can't be built without network access.
+func isRemote(resource string) bool {
+	return strings.Contains(resource, "://") ||
+		strings.HasPrefix(resource, "git@") ||
+		strings.HasPrefix(resource, "github.com/") ||
+		strings.HasPrefix(resource, "gitlab.com/") ||
+		strings.HasPrefix(resource, "bitbucket.org/")
+}
+
+// Included returns the paths of the files and directories that are included
+// by the kustomizations under a directory. These paths are scanned as part of
+// the kustomizations that include them, and shouldn't be scanned on their own.
+// Hidden directories are skipped, and so are the directories more than
+// maxDepth levels below root, unless maxDepth is 0.
+func Included(fsys afero.Fs, root string, maxDepth int) map[string]bool {
+	included := make(map[string]bool)
+
+	_ = afero.Walk(fsys, root, func(path string, info fs.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+
+		if path != root {
+			if strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			if rel, err := filepath.Rel(root, path); err == nil && maxDepth > 0 && depth(rel) > maxDepth {
+				return filepath.SkipDir
+			}
+		}
+
+		if !IsKustomization(fsys, path) {
+			return nil
+		}
+
+		for _, include := range Includes(fsys, path) {
+			included[include] = true
+		}
+
+		return nil
+	})
+
+	return included
+}
+
+// Includes returns the paths of the local files and directories included by
+// the resources, bases and components of the kustomization in a directory.
+func Includes(fsys afero.Fs, dir string) []string {
+	k, err := loadKustomization(fsys, dir)
+	if err != nil {
+		return nil
+	}
+
+	var result []string
+
+	for _, entry := range k.includes() {
+		result = append(result, filepath.Join(dir, entry.path))
+	}
+
+	return result
+}
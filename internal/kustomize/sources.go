@@ -0,0 +1,328 @@
+package kustomize
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/kustomize/api/types"
+)
+
+// The resources built by Kustomize only know the path of the file that
+// defines them, or of the kustomization that generates them. The document
+// that defines a resource, the patches applied to it and the entries of the
+// kustomizations that include it are found from the kustomization files.
+
+// link is an entry of a kustomization that includes a resource.
+type link struct {
+	k     *kustomization
+	entry include
+}
+
+// locate sets the sources of a resource from its origin, whose paths are
+// relative to the directory of the built kustomization.
+func (b *builder) locate(r *Resource, dir string, res *resource.Resource, origin *resource.Origin) {
+	var (
+		target string
+		inner  []*kustomization
+		name   string
+	)
+
+	if origin.ConfiguredIn != "" {
+		path := filepath.Join(dir, origin.ConfiguredIn)
+		target = filepath.Dir(path)
+
+		if k, ok := b.kustomizations[target]; ok {
+			r.origin, name = k.generator(origin.ConfiguredBy.Kind, res)
+			inner = append(inner, k)
+		}
+	} else {
+		target = filepath.Join(dir, origin.Path)
+		r.origin = b.document(target, res)
+		name = documentName(r.origin.node)
+	}
+
+	chain, _ := b.chain(dir, target, make(map[string]bool))
+
+	for _, l := range chain {
+		r.includes = append(r.includes, l.k.location(l.entry.key, l.entry.index))
+		inner = append(inner, l.k)
+	}
+
+	seen := make(map[*kustomization]bool)
+
+	for _, k := range inner {
+		for _, k := range b.withComponents(k, seen) {
+			for _, p := range b.patches(k) {
+				if p.appliesTo(res, name) {
+					r.patches = append(r.patches, p.source)
+				}
+			}
+		}
+	}
+}
+
+// chain returns the entries that include a file or a kustomization directory,
+// from the innermost kustomization to the kustomization in dir.
+func (b *builder) chain(dir, target string, visited map[string]bool) ([]link, bool) {
+	if dir == target {
+		return nil, true
+	}
+
+	k, ok := b.kustomizations[dir]
+	if !ok || visited[dir] {
+		return nil, false
+	}
+
+	visited[dir] = true
+
+	for _, entry := range k.includes() {
+		path := filepath.Join(dir, entry.path)
+
+		if path == target {
+			return []link{{k: k, entry: entry}}, true
+		}
+
+		if chain, ok := b.chain(path, target, visited); ok {
+			return append(chain, link{k: k, entry: entry}), true
+		}
+	}
+
+	return nil, false
+}
+
+// withComponents returns the components of a kustomization, recursively,
+// followed by the kustomization. The components transform the resources
+// before the kustomization that uses them.
+func (b *builder) withComponents(k *kustomization, seen map[*kustomization]bool) []*kustomization {
+	if seen[k] {
+		return nil
+	}
+
+	seen[k] = true
+
+	var result []*kustomization
+
+	for _, component := range k.Components {
+		if c, ok := b.kustomizations[filepath.Join(filepath.Dir(k.path), component)]; ok {
+			result = append(result, b.withComponents(c, seen)...)
+		}
+	}
+
+	return append(result, k)
+}
+
+// document returns the document of a file that defines a resource. Since the
+// name of the resource may have been changed by the kustomizations, the
+// document with the same name or with the longest name contained in the name
+// of the resource is chosen.
+func (b *builder) document(path string, res *resource.Resource) source {
+	var (
+		found  *yaml.Node
+		length = -1
+	)
+
+	for _, node := range b.readDocuments(path) {
+		if documentKind(node) != res.GetKind() {
+			continue
+		}
+
+		name := documentName(node)
+
+		if name == res.GetName() {
+			found = node
+			break
+		}
+
+		if strings.Contains(res.GetName(), name) && len(name) > length {
+			found, length = node, len(name)
+		}
+	}
+
+	return source{file: path, node: found}
+}
+
+func (b *builder) readDocuments(path string) []*yaml.Node {
+	if nodes, ok := b.documents[path]; ok {
+		return nodes
+	}
+
+	var nodes []*yaml.Node
+
+	if data, err := afero.ReadFile(b.fs, path); err == nil {
+		nodes, _ = parseDocuments(data)
+	}
+
+	b.documents[path] = nodes
+
+	return nodes
+}
+
+// patch is a strategic merge patch of a kustomization, read from a file.
+type patch struct {
+	source
+	target *types.Selector
+}
+
+// patches returns the strategic merge patches of a kustomization that are
+// read from a file. The inline patches and the JSON 6902 patches are not
+// tracked.
+func (b *builder) patches(k *kustomization) []patch {
+	var result []patch
+
+	add := func(path string, target *types.Selector) {
+		file := filepath.Join(filepath.Dir(k.path), path)
+
+		for _, node := range b.readDocuments(file) {
+			if node.Kind == yaml.MappingNode {
+				result = append(result, patch{source: source{file: file, node: node}, target: target})
+			}
+		}
+	}
+
+	for _, entry := range k.PatchesStrategicMerge {
+		if !isInline(string(entry)) {
+			add(string(entry), nil)
+		}
+	}
+
+	for _, p := range k.Patches {
+		if p.Path != "" {
+			add(p.Path, p.Target)
+		}
+	}
+
+	return result
+}
+
+// appliesTo returns true if the patch applies to a resource, whose name was
+// name in the document that defines it. A patch without a target applies to
+// the resource with its kind and its name, which may have been changed since.
+func (p patch) appliesTo(res *resource.Resource, name string) bool {
+	current := res.GetName()
+
+	if p.target == nil {
+		patchName := documentName(p.node)
+
+		if documentKind(p.node) != res.GetKind() {
+			return false
+		}
+
+		return patchName == current || patchName == name ||
+			name != "" && strings.Contains(current, patchName) && strings.Contains(patchName, name)
+	}
+
+	selector, err := types.NewSelectorRegex(p.target)
+	if err != nil {
+		return false
+	}
+
+	if !selector.MatchGvk(res.GetGvk()) || !selector.MatchName(current) && !selector.MatchName(name) {
+		return false
+	}
+
+	if matches, err := res.MatchesLabelSelector(p.target.LabelSelector); err != nil || !matches {
+		return false
+	}
+
+	matches, err := res.MatchesAnnotationSelector(p.target.AnnotationSelector)
+
+	return err == nil && matches
+}
+
+// generator returns the entry of the generator of a kustomization that
+// generated a resource, and the name it gave to the resource. Since the name
+// of the resource may have been changed by the kustomizations, and ends with
+// the hash of its content, the generator with the longest name contained in
+// the name of the resource is chosen.
+func (k *kustomization) generator(kind string, res *resource.Resource) (source, string) {
+	key, names := "configMapGenerator", []string{}
+
+	for _, generator := range k.ConfigMapGenerator {
+		names = append(names, generator.Name)
+	}
+
+	if kind == "SecretGenerator" {
+		key, names = "secretGenerator", nil
+
+		for _, generator := range k.SecretGenerator {
+			names = append(names, generator.Name)
+		}
+	}
+
+	found := -1
+
+	for i, name := range names {
+		if strings.Contains(res.GetName(), name) && (found < 0 || len(name) > len(names[found])) {
+			found = i
+		}
+	}
+
+	if found < 0 {
+		return source{file: k.path}, ""
+	}
+
+	return source{file: k.path, node: k.entry(key, found)}, names[found]
+}
+
+// isInline returns true if an entry of patchesStrategicMerge is a patch
+// instead of the path of a patch.
+func isInline(entry string) bool {
+	return strings.Contains(entry, "\n") || strings.Contains(entry, ": ")
+}
+
+func documentKind(node *yaml.Node) string {
+	var document struct {
+		Kind string `yaml:"kind"`
+	}
+
+	if node != nil {
+		_ = node.Decode(&document)
+	}
+
+	return document.Kind
+}
+
+func documentName(node *yaml.Node) string {
+	var document struct {
+		Metadata struct {
+			Name string `yaml:"name"`
+		} `yaml:"metadata"`
+	}
+
+	if node != nil {
+		_ = node.Decode(&document)
+	}
+
+	return document.Metadata.Name
+}
+
+// parseDocuments returns the root node of every YAML document.
+func parseDocuments(data []byte) ([]*yaml.Node, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	var nodes []*yaml.Node
+
+	for {
+		var document yaml.Node
+
+		if err := decoder.Decode(&document); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if document.Kind == yaml.DocumentNode {
+			nodes = append(nodes, document.Content...)
+		} else {
+			nodes = append(nodes, &document)
+		}
+	}
+
+	return nodes, nil
+}
@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/snyk/policy-engine/pkg/input"
+	"github.com/snyk/policy-engine/pkg/models"
+	"github.com/spf13/afero"
+
+	"github.com/snyk/cli-extension-iac/internal/cdk"
+)
+
+// errCloudAssembly is wrapped by the errors of the cloud assemblies that can't
+// be read.
+var errCloudAssembly = errors.New("unable to read CDK cloud assembly")
+
+// cdkMetaKey is the key of the metadata of a resource that holds its stack
+// and construct path.
+const cdkMetaKey = "cdk"
+
+// CDKConstruct returns the stack and the construct path of a resource loaded
+// from a CDK cloud assembly. The construct path is empty if the assembly
+// doesn't record it.
+func CDKConstruct(resource models.ResourceState) (stack string, path string, ok bool) {
+	meta, ok := resource.Meta[cdkMetaKey].(map[string]interface{})
+	if !ok {
+		return "", "", false
+	}
+
+	stack, _ = meta["stack"].(string)
+	path, _ = meta["path"].(string)
+
+	return stack, path, true
+}
+
+// cdkDetector detects the cloud assemblies synthesized by the CDK, usually in
+// a cdk.out directory. The templates of the stacks are loaded by the
+// CloudFormation detector.
+type cdkDetector struct{}
+
+func (d *cdkDetector) DetectDirectory(i *input.Directory, opts input.DetectOptions) (input.IACConfiguration, error) {
+	if !cdk.IsAssembly(i.Fs, i.Path) {
+		return nil, nil
+	}
+
+	return d.detect(i.Fs, i.Path)
+}
+
+func (d *cdkDetector) DetectFile(i *input.File, opts input.DetectOptions) (input.IACConfiguration, error) {
+	dir := filepath.Dir(i.Path)
+
+	if filepath.Base(i.Path) != cdk.ManifestFile || !cdk.IsAssembly(i.Fs, dir) {
+		return nil, nil
+	}
+
+	return d.detect(i.Fs, dir)
+}
+
+func (d *cdkDetector) detect(fs afero.Fs, dir string) (input.IACConfiguration, error) {
+	assembly, err := cdk.ReadAssembly(fs, dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", input.FailedToParseInput, errCloudAssembly, err)
+	}
+
+	config := cdkConfiguration{
+		path:        dir,
+		loadedFiles: assembly.Files,
+	}
+
+	cloudFormation := input.CfnDetector{}
+
+	for _, stack := range assembly.Stacks {
+		data, err := afero.ReadFile(fs, stack.Template)
+		if err != nil {
+			config.errors = append(config.errors, fmt.Errorf("%w: %s: %v", input.UnableToReadFile, stack.Template, err))
+			continue
+		}
+
+		if err := stack.ReadConstructs(data); err != nil {
+			config.errors = append(config.errors, fmt.Errorf("%w: %v", input.FailedToParseInput, err))
+			continue
+		}
+
+		loaded, err := cloudFormation.DetectFile(&input.File{Path: stack.Template, Fs: fs}, input.DetectOptions{IgnoreExt: true})
+		if err != nil {
+			config.errors = append(config.errors, fmt.Errorf("%s: %w", stack.Template, err))
+			continue
+		}
+
+		config.stacks = append(config.stacks, cdkStack{
+			Stack:         stack,
+			configuration: loaded,
+		})
+		config.loadedFiles = append(config.loadedFiles, stack.Template)
+	}
+
+	return &config, nil
+}
+
+// cdkStack is the CloudFormation configuration of a stack.
+type cdkStack struct {
+	cdk.Stack
+	configuration input.IACConfiguration
+}
+
+// cdkConfiguration is a cloud assembly. Its resources are the resources of
+// every stack, labelled with their stack and construct path.
+type cdkConfiguration struct {
+	path        string
+	stacks      []cdkStack
+	loadedFiles []string
+	errors      []error
+}
+
+func (c *cdkConfiguration) ToState() models.State {
+	resources := map[string]map[string]models.ResourceState{}
+
+	for _, s := range c.stacks {
+		for resourceType, byKey := range s.configuration.ToState().Resources {
+			if _, ok := resources[resourceType]; !ok {
+				resources[resourceType] = map[string]models.ResourceState{}
+			}
+
+			for key, resource := range byKey {
+				meta := map[string]interface{}{}
+
+				for k, v := range resource.Meta {
+					meta[k] = v
+				}
+
+				label := map[string]interface{}{
+					"stack": s.Name,
+				}
+
+				if path, ok := s.Constructs[resource.Id]; ok {
+					label["path"] = path
+				}
+
+				meta[cdkMetaKey] = label
+				resource.Meta = meta
+
+				// Logical IDs are only unique in a stack. The resources of
+				// the other stacks with the same ID are keyed by template.
+
+				if _, ok := resources[resourceType][key]; ok {
+					key = resource.Namespace + ":" + key
+				}
+
+				resources[resourceType][key] = resource
+			}
+		}
+	}
+
+	return models.State{
+		InputType:           input.CloudFormation.Name,
+		EnvironmentProvider: "iac",
+		Meta: map[string]interface{}{
+			"filepath": c.path,
+		},
+		Resources: resources,
+		Scope: map[string]interface{}{
+			"filepath": c.path,
+		},
+	}
+}
+
+func (c *cdkConfiguration) Location(path []interface{}) (input.LocationStack, error) {
+	// The path is {template, type, logical ID, attribute path...}, since the
+	// namespace of a CloudFormation resource is the path of its template.
+
+	if len(path) < 3 {
+		return nil, nil
+	}
+
+	template, ok := path[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: expected a string template path in path: %v", input.UnableToResolveLocation, path)
+	}
+
+	for _, s := range c.stacks {
+		if s.Template == template {
+			return s.configuration.Location(path)
+		}
+	}
+
+	return nil, nil
+}
+
+func (c *cdkConfiguration) LoadedFiles() []string {
+	return c.loadedFiles
+}
+
+func (c *cdkConfiguration) Errors() []error {
+	return c.errors
+}
+
+func (c *cdkConfiguration) Type() *input.Type {
+	return input.CloudFormation
+}
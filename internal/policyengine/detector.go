@@ -9,6 +9,8 @@ import (
 
 	"github.com/snyk/policy-engine/pkg/input"
 	"gopkg.in/yaml.v3"
+
+	"github.com/snyk/cli-extension-iac/internal/terragrunt"
 )
 
 type syntaxValidator func(input *input.File) error
@@ -101,18 +103,42 @@ func newErrorsHandlingDetector(wrapped input.Detector) errorsHandlingDetector {
 	}
 }
 
-func newDetector() *detector {
+// detectorOptions configures the detectors that need more than the
+// input.DetectOptions of the Policy Engine.
+type detectorOptions struct {
+	helmValuesFiles    []string
+	terraformVariables terraformVariables
+
+	// terragrunt is the cache of the Terragrunt configurations, shared with
+	// the scanner.
+	terragrunt *terragrunt.Cache
+}
+
+func newDetector(options detectorOptions) *detector {
+	if options.terragrunt == nil {
+		options.terragrunt = &terragrunt.Cache{}
+	}
+
 	var (
 		cloudFormation       = newErrorsHandlingDetector(&input.CfnDetector{})
 		terraformPlan        = newErrorsHandlingDetector(&input.TfPlanDetector{})
-		terraform            = newErrorsHandlingDetector(&input.TfDetector{})
+		terraformModule      = &terraformDetector{variables: options.terraformVariables}
+		terraform            = newErrorsHandlingDetector(terraformModule)
 		terraformState       = newErrorsHandlingDetector(&input.TfStateDetector{})
 		kubernetes           = newErrorsHandlingDetector(&input.KubernetesDetector{})
 		azureResourceManager = newErrorsHandlingDetector(&input.ArmDetector{})
+		helmChart            = newErrorsHandlingDetector(&helmDetector{valuesFiles: options.helmValuesFiles})
+		kustomization        = newErrorsHandlingDetector(&kustomizeDetector{})
+		cloudAssembly        = newErrorsHandlingDetector(&cdkDetector{})
+		terragruntUnit       = newErrorsHandlingDetector(&terragruntDetector{terraform: terraformModule, cache: options.terragrunt})
 	)
 
 	return &detector{
 		directoryDelegates: []input.Detector{
+			helmChart,
+			kustomization,
+			cloudAssembly,
+			terragruntUnit,
 			terraform,
 		},
 		fileDelegates: map[string][]input.Detector{
@@ -125,6 +151,7 @@ func newDetector() *detector {
 				kubernetes,
 			},
 			".json": {
+				cloudAssembly,
 				cloudFormation,
 				terraformPlan,
 				terraformState,
@@ -12,6 +12,8 @@ import (
 	"github.com/snyk/policy-engine/pkg/models"
 	"github.com/snyk/policy-engine/pkg/policy"
 	"github.com/spf13/afero"
+
+	"github.com/snyk/cli-extension-iac/internal/tfvars"
 )
 
 type Results = models.Results
@@ -53,11 +55,17 @@ func NewEngine(ctx context.Context, options EngineOptions) *Engine {
 }
 
 type RunOptions struct {
-	FS                afero.Fs
-	Paths             []string
-	RuleIDs           []string
-	Scan              string
-	VarFile           string
+	FS      afero.Fs
+	Paths   []string
+	RuleIDs []string
+	Scan    string
+	// TerraformVars are the Terraform var files and variables set on the
+	// command line, in order. Later ones take precedence.
+	TerraformVars []tfvars.Arg
+	// Env is the environment, in the format of os.Environ. Its TF_VAR_
+	// variables set Terraform variables.
+	Env               []string
+	HelmValues        []string
 	DetectionDepth    int
 	ResourcesResolver policy.ResourcesResolver
 }
@@ -80,10 +88,19 @@ func (e *Engine) Run(ctx context.Context, options RunOptions) (*Results, []error
 }
 
 func (e *Engine) LoadInput(options RunOptions) (input.Loader, []error, []error) {
+	variables, err := newTerraformVariables(options.TerraformVars, options.Env)
+	if err != nil {
+		return input.NewLoader(newDetector(detectorOptions{})), []error{Error{
+			Message: err.Error(),
+			Code:    ErrorCodeFailedToParseInput,
+		}}, nil
+	}
+
 	scanner := scanner{
 		fs:             options.FS,
 		detectionDepth: options.DetectionDepth,
-		varFile:        options.VarFile,
+		variables:      variables,
+		helmValues:     options.HelmValues,
 		logger:         e.logger,
 	}
 
@@ -114,7 +131,9 @@ func (e *Engine) Metadata(ctx context.Context) ([]MetadataResult, error) {
 func (e *Engine) InitializationErrors() []error {
 	var errs []error
 	for _, err := range e.wrapped.InitializationErrors {
-		errs = append(errs, unwrapEngineError(err, ""))
+		if err := unwrapInitializationError(err); err != nil {
+			errs = append(errs, err)
+		}
 	}
 	return errs
 }
@@ -9,6 +9,9 @@ import (
 
 	"github.com/rs/zerolog"
 	engine "github.com/snyk/cli-extension-iac/internal/policyengine"
+	"github.com/snyk/cli-extension-iac/internal/tfvars"
+	"github.com/snyk/policy-engine/pkg/bundle"
+	"github.com/snyk/policy-engine/pkg/models"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/require"
 )
@@ -219,14 +222,424 @@ func TestTfVars(t *testing.T) {
 
 	t.Run("scans the file when a var file is provided", func(t *testing.T) {
 		results, errs := runEngine(t, engine.RunOptions{
-			FS:      afero.NewOsFs(),
-			Paths:   []string{filePath},
-			VarFile: varPath,
+			FS:            afero.NewOsFs(),
+			Paths:         []string{filePath},
+			TerraformVars: []tfvars.Arg{{VarFile: varPath}},
 		})
 
 		require.Nil(t, errs)
 		require.Equal(t, results.Results[0].Input.Resources["aws_security_group"]["aws_security_group.vars"].Attributes["ingress"].([]interface{})[0].(map[string]interface{})["cidr_blocks"].([]interface{})[0], "0.0.0.0/0")
 	})
+
+	autoDir := filepath.Join("testdata", "terraform-auto-vars")
+
+	securityGroup := func(t *testing.T, results *engine.Results) (name, description, cidr interface{}) {
+		t.Helper()
+
+		require.Len(t, results.Results, 1)
+
+		attributes := results.Results[0].Input.Resources["aws_security_group"]["aws_security_group.vars"].Attributes
+		ingress := attributes["ingress"].([]interface{})[0].(map[string]interface{})
+
+		return attributes["name"], attributes["description"], ingress["cidr_blocks"].([]interface{})[0]
+	}
+
+	t.Run("loads the var files of the module when scanning a file", func(t *testing.T) {
+		results, errs := runEngine(t, engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{filepath.Join(autoDir, "main.tf")},
+		})
+
+		require.Nil(t, errs)
+
+		name, description, cidr := securityGroup(t, results)
+		require.Equal(t, "tfvars", name)
+		require.Equal(t, "us-east-1", description)
+		require.Equal(t, "10.0.0.0/8", cidr)
+	})
+
+	t.Run("environment variables have the lowest precedence", func(t *testing.T) {
+		results, errs := runEngine(t, engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{autoDir},
+			Env:   []string{"TF_VAR_name=env", "TF_VAR_region=eu-west-1", "HOME=/root"},
+		})
+
+		require.Nil(t, errs)
+
+		name, description, cidr := securityGroup(t, results)
+		require.Equal(t, "tfvars", name)
+		require.Equal(t, "eu-west-1", description)
+		require.Equal(t, "10.0.0.0/8", cidr)
+	})
+
+	t.Run("var files and variables apply in order", func(t *testing.T) {
+		dir := t.TempDir()
+
+		first := filepath.Join(dir, "first.tfvars")
+		require.NoError(t, os.WriteFile(first, []byte(`name = "first"`), 0644))
+
+		second := filepath.Join(dir, "second.tfvars.json")
+		require.NoError(t, os.WriteFile(second, []byte(`{"name": "second", "region": "second"}`), 0644))
+
+		results, errs := runEngine(t, engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{autoDir},
+			TerraformVars: []tfvars.Arg{
+				{VarFile: first},
+				{VarFile: second},
+				{Var: "region=cli"},
+				{Var: `cidrs=["0.0.0.0/0"]`},
+			},
+			Env: []string{"TF_VAR_region=eu-west-1"},
+		})
+
+		require.Nil(t, errs)
+
+		name, description, cidr := securityGroup(t, results)
+		require.Equal(t, "second", name)
+		require.Equal(t, "cli", description)
+		require.Equal(t, "0.0.0.0/0", cidr)
+	})
+
+	t.Run("var files and variables apply in the order of the command line", func(t *testing.T) {
+		varFile := filepath.Join(t.TempDir(), "override.tfvars")
+		require.NoError(t, os.WriteFile(varFile, []byte(`name = "file"`+"\n"+`region = "file"`), 0644))
+
+		results, errs := runEngine(t, engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{autoDir},
+			TerraformVars: []tfvars.Arg{
+				{Var: "name=cli"},
+				{VarFile: varFile},
+				{Var: "region=cli"},
+			},
+		})
+
+		require.Nil(t, errs)
+
+		name, description, _ := securityGroup(t, results)
+		require.Equal(t, "file", name)
+		require.Equal(t, "cli", description)
+	})
+
+	t.Run("reports invalid variables", func(t *testing.T) {
+		_, errs := runEngine(t, engine.RunOptions{
+			FS:            afero.NewOsFs(),
+			Paths:         []string{autoDir},
+			TerraformVars: []tfvars.Arg{{Var: "region"}},
+		})
+
+		require.Len(t, errs, 1)
+
+		var engineError engine.Error
+		require.ErrorAs(t, errs[0], &engineError)
+		require.Equal(t, engine.ErrorCodeFailedToParseInput, engineError.Code)
+	})
+}
+
+func TestHelm(t *testing.T) {
+	chartPath := filepath.Join("testdata", "helm", "chart")
+	valuesPath := filepath.Join("testdata", "helm", "values.yaml")
+	templatePath := filepath.Join(chartPath, "templates", "pod.yaml")
+
+	t.Run("renders the chart with its values", func(t *testing.T) {
+		results, errs := runEngine(t, engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{chartPath},
+		})
+
+		require.Nil(t, errs)
+		require.Len(t, results.Results, 1)
+		require.Equal(t, "k8s", results.Results[0].Input.InputType)
+		require.Contains(t, results.Results[0].Input.Resources["Pod"], "apps.app")
+	})
+
+	t.Run("renders the chart with the values files", func(t *testing.T) {
+		results, errs := runEngine(t, engine.RunOptions{
+			FS:         afero.NewOsFs(),
+			Paths:      []string{chartPath},
+			HelmValues: []string{valuesPath},
+		})
+
+		require.Nil(t, errs)
+		require.Contains(t, results.Results[0].Input.Resources["Pod"], "prod.app")
+	})
+
+	t.Run("maps the resources to the lines of the templates", func(t *testing.T) {
+		logger := zerolog.Nop()
+
+		e := engine.NewEngine(context.Background(), engine.EngineOptions{
+			Logger: &logger,
+		})
+
+		loader, errs, _ := e.LoadInput(engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{chartPath},
+		})
+		require.Nil(t, errs)
+
+		location, err := loader.Location(chartPath, []interface{}{"apps", "Pod", "app"})
+		require.NoError(t, err)
+		require.Equal(t, templatePath, location[0].Path)
+		require.Equal(t, 1, location[0].Line)
+
+		location, err = loader.Location(chartPath, []interface{}{"apps", "Pod", "app", "metadata", "labels", "app.kubernetes.io/name"})
+		require.NoError(t, err)
+		require.Equal(t, 7, location[0].Line)
+
+		location, err = loader.Location(chartPath, []interface{}{"apps", "Pod", "app", "spec", "containers", 0, "securityContext", "privileged"})
+		require.NoError(t, err)
+		require.Equal(t, templatePath, location[0].Path)
+		require.Equal(t, 13, location[0].Line)
+	})
+}
+
+func TestKustomize(t *testing.T) {
+	dir := filepath.Join("testdata", "kustomize")
+	basePath := filepath.Join(dir, "base")
+	overlayPath := filepath.Join(dir, "overlays", "prod")
+
+	t.Run("builds the overlays and skips the bases they include", func(t *testing.T) {
+		results, errs := runEngine(t, engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{dir},
+		})
+
+		require.Nil(t, errs)
+		require.Len(t, results.Results, 1)
+		require.Equal(t, "k8s", results.Results[0].Input.InputType)
+		require.Equal(t, overlayPath, results.Results[0].Input.Meta["filepath"])
+
+		resource := results.Results[0].Input.Resources["Deployment"]["prod.prod-app"]
+		require.Equal(t, "prod", resource.Namespace)
+	})
+
+	t.Run("maps the resources to their bases and overlays", func(t *testing.T) {
+		logger := zerolog.Nop()
+
+		e := engine.NewEngine(context.Background(), engine.EngineOptions{
+			Logger: &logger,
+		})
+
+		loader, errs, _ := e.LoadInput(engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{overlayPath},
+		})
+		require.Nil(t, errs)
+
+		location, err := loader.Location(overlayPath, []interface{}{"prod", "Deployment", "prod-app", "spec", "template", "spec", "containers", 0, "image"})
+		require.NoError(t, err)
+		require.Len(t, location, 3)
+		require.Equal(t, filepath.Join(basePath, "deployment.yaml"), location[0].Path)
+		require.Equal(t, 10, location[0].Line)
+		require.Equal(t, filepath.Join(basePath, "kustomization.yaml"), location[1].Path)
+		require.Equal(t, 2, location[1].Line)
+		require.Equal(t, filepath.Join(overlayPath, "kustomization.yaml"), location[2].Path)
+		require.Equal(t, 4, location[2].Line)
+
+		location, err = loader.Location(overlayPath, []interface{}{"prod", "Deployment", "prod-app", "spec", "template", "spec", "containers", 0, "securityContext", "privileged"})
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(overlayPath, "privileged.yaml"), location[0].Path)
+		require.Equal(t, 11, location[0].Line)
+	})
+
+	t.Run("reports the unsupported fields as warnings", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte("resources:\n  - deployment.yaml\nhelmCharts:\n  - name: chart\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "deployment.yaml"), []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n"), 0644))
+
+		logger := zerolog.Nop()
+
+		e := engine.NewEngine(context.Background(), engine.EngineOptions{
+			Logger: &logger,
+		})
+
+		_, errs, warnings := e.LoadInput(engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{dir},
+		})
+		require.Nil(t, errs)
+		require.Len(t, warnings, 1)
+
+		var unsupported engine.UnsupportedFeatureError
+		require.ErrorAs(t, warnings[0], &unsupported)
+		require.Equal(t, engine.ErrorCodeUnsupportedFeature, unsupported.Code)
+		require.Equal(t, dir, unsupported.Path)
+		require.Contains(t, unsupported.Message, "field helmCharts")
+	})
+}
+
+func TestCDK(t *testing.T) {
+	assemblyPath := filepath.Join("testdata", "cdk", "cdk.out")
+	appTemplate := filepath.Join(assemblyPath, "App.template.json")
+	apiTemplate := filepath.Join(assemblyPath, "assembly-Prod", "ProdApi1A2B3C4D.template.json")
+
+	t.Run("loads the stacks of the cloud assembly", func(t *testing.T) {
+		results, errs := runEngine(t, engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{filepath.Join("testdata", "cdk")},
+		})
+
+		require.Nil(t, errs)
+		require.Len(t, results.Results, 1)
+		require.Equal(t, "cfn", results.Results[0].Input.InputType)
+
+		resources := results.Results[0].Input.Resources
+
+		stack, path, ok := engine.CDKConstruct(resources["AWS::S3::Bucket"]["Bucket83908E77"])
+		require.True(t, ok)
+		require.Equal(t, "App", stack)
+		require.Equal(t, "App/Bucket/Resource", path)
+
+		stack, path, ok = engine.CDKConstruct(resources["AWS::SQS::Queue"]["Queue4A7E3555"])
+		require.True(t, ok)
+		require.Equal(t, "Prod/Api", stack)
+		require.Equal(t, "Prod/Api/Queue/Resource", path)
+
+		// Both stacks have a CDKMetadata resource.
+		require.Len(t, resources["AWS::CDK::Metadata"], 2)
+	})
+
+	t.Run("loads the cloud assembly from its manifest", func(t *testing.T) {
+		results, errs := runEngine(t, engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{filepath.Join(assemblyPath, "manifest.json")},
+		})
+
+		require.Nil(t, errs)
+		require.Len(t, results.Results, 1)
+		require.Len(t, results.Results[0].Input.Resources["AWS::SQS::Queue"], 1)
+	})
+
+	t.Run("maps the resources to their templates", func(t *testing.T) {
+		logger := zerolog.Nop()
+
+		e := engine.NewEngine(context.Background(), engine.EngineOptions{
+			Logger: &logger,
+		})
+
+		loader, errs, _ := e.LoadInput(engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{assemblyPath},
+		})
+		require.Nil(t, errs)
+
+		location, err := loader.Location(assemblyPath, []interface{}{appTemplate, "AWS::S3::Bucket", "Bucket83908E77", "BucketName"})
+		require.NoError(t, err)
+		require.Equal(t, appTemplate, location[0].Path)
+		require.Equal(t, 6, location[0].Line)
+
+		location, err = loader.Location(assemblyPath, []interface{}{apiTemplate, "AWS::SQS::Queue", "Queue4A7E3555"})
+		require.NoError(t, err)
+		require.Equal(t, apiTemplate, location[0].Path)
+		require.Equal(t, 3, location[0].Line)
+	})
+}
+
+func TestTerragrunt(t *testing.T) {
+	rootPath := filepath.Join("testdata", "terragrunt")
+	unitPath := filepath.Join(rootPath, "live", "prod", "network")
+	remotePath := filepath.Join(rootPath, "live", "prod", "remote")
+
+	t.Run("loads the module of the unit with its inputs", func(t *testing.T) {
+		results, errs := runEngine(t, engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{rootPath},
+		})
+
+		require.Nil(t, errs)
+		require.Len(t, results.Results, 2)
+
+		var unit *models.Result
+
+		for i := range results.Results {
+			if results.Results[i].Input.Meta["filepath"] == unitPath {
+				unit = &results.Results[i]
+			}
+		}
+
+		require.NotNil(t, unit)
+		require.Equal(t, "tf_hcl", unit.Input.InputType)
+
+		vpc := unit.Input.Resources["aws_vpc"]["aws_vpc.main"]
+		require.Equal(t, "10.0.0.0/16", vpc.Attributes["cidr_block"])
+		require.Equal(t, "prod", vpc.Attributes["tags"].(map[string]interface{})["Environment"])
+
+		ssh := unit.Input.Resources["aws_security_group"]["aws_security_group.ssh"]
+		require.Equal(t, "prod-ssh", ssh.Attributes["name"])
+		require.Equal(t, "0.0.0.0/0", ssh.Attributes["ingress"].([]interface{})[0].(map[string]interface{})["cidr_blocks"].([]interface{})[0])
+	})
+
+	t.Run("reports the units with a remote source as warnings", func(t *testing.T) {
+		logger := zerolog.Nop()
+
+		e := engine.NewEngine(context.Background(), engine.EngineOptions{
+			Logger: &logger,
+		})
+
+		_, errs, warnings := e.LoadInput(engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{remotePath},
+		})
+
+		require.Nil(t, errs)
+		require.Len(t, warnings, 1)
+
+		var missing engine.MissingRemoteSubmodulesError
+		require.ErrorAs(t, warnings[0], &missing)
+		require.Equal(t, remotePath, missing.Dir)
+		require.Equal(t, []string{"git::https://github.com/example/modules.git//network?ref=v1.0.0"}, missing.MissingModules)
+	})
+
+	t.Run("reports the inputs that can't be evaluated as warnings", func(t *testing.T) {
+		logger := zerolog.Nop()
+
+		e := engine.NewEngine(context.Background(), engine.EngineOptions{
+			Logger: &logger,
+		})
+
+		_, errs, warnings := e.LoadInput(engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{unitPath},
+		})
+
+		require.Nil(t, errs)
+		require.Len(t, warnings, 1)
+
+		var evaluation engine.EvaluationError
+		require.ErrorAs(t, warnings[0], &evaluation)
+		require.Equal(t, unitPath, evaluation.Path)
+		require.Len(t, evaluation.Expressions, 1)
+		require.Contains(t, evaluation.Expressions[0], "terragrunt.hcl:14")
+	})
+
+	t.Run("environment variables take precedence over the inputs", func(t *testing.T) {
+		results, errs := runEngine(t, engine.RunOptions{
+			FS:    afero.NewOsFs(),
+			Paths: []string{unitPath},
+			Env:   []string{"TF_VAR_name=env"},
+		})
+
+		require.Nil(t, errs)
+		require.Len(t, results.Results, 1)
+		require.Equal(t, "env", results.Results[0].Input.Resources["aws_security_group"]["aws_security_group.ssh"].Attributes["name"])
+	})
+
+	t.Run("var files take precedence over the inputs", func(t *testing.T) {
+		varFile := filepath.Join(t.TempDir(), "override.tfvars")
+		require.NoError(t, os.WriteFile(varFile, []byte(`name = "override"`), 0644))
+
+		results, errs := runEngine(t, engine.RunOptions{
+			FS:            afero.NewOsFs(),
+			Paths:         []string{unitPath},
+			TerraformVars: []tfvars.Arg{{VarFile: varFile}},
+		})
+
+		require.Nil(t, errs)
+		require.Len(t, results.Results, 1)
+		require.Equal(t, "override", results.Results[0].Input.Resources["aws_security_group"]["aws_security_group.ssh"].Attributes["name"])
+	})
 }
 
 func readDir(t *testing.T, dir string) []string {
@@ -265,3 +678,40 @@ func setHidden(t *testing.T, p string) {
 	// engine_hidden_windows_test.go will be built and used by tests invoking
 	// setHidden prior to scanning.
 }
+
+func TestCustomRuleBundleErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		code engine.ErrorCode
+	}{
+		{name: "unparseable rule", rule: "package rules.custom\n\ndeny {", code: engine.ErrorCodeFailedToLoadRules},
+		{name: "uncompilable rule", rule: "package rules.custom\n\ndeny { undefined_function(input) }\n", code: engine.ErrorCodeFailedToCompile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{"bundle_format_version":"v1"}`), 0644))
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "rules"), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "rules", "rule.rego"), []byte(tt.rule), 0644))
+
+			logger := zerolog.Nop()
+
+			e := engine.NewEngine(context.Background(), engine.EngineOptions{
+				CustomRuleBundles: []bundle.Reader{bundle.NewDirReader(dir)},
+				Logger:            &logger,
+			})
+
+			errs := e.InitializationErrors()
+			require.Len(t, errs, 1)
+
+			var engineError engine.Error
+
+			require.ErrorAs(t, errs[0], &engineError)
+			require.Equal(t, tt.code, engineError.Code)
+			require.Equal(t, dir, engineError.Path)
+		})
+	}
+}
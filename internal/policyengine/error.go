@@ -35,6 +35,8 @@ const (
 	ErrorCodeMissingRemoteSubmodulesError
 	ErrorCodeEvaluationError
 	ErrorCodeMissingTermError
+	ErrorCodeInvalidRuleSelection
+	ErrorCodeUnsupportedFeature
 )
 
 // Error represents a known error condition that might occur when running the
@@ -103,6 +105,32 @@ func (e MissingTermError) Error() string {
 	return e.Message
 }
 
+// UnsupportedFeatureError is a warning about a part of an input that is not
+// supported, and is left out of the scan.
+type UnsupportedFeatureError struct {
+	Message string
+	Code    ErrorCode
+	Path    string
+}
+
+func (e UnsupportedFeatureError) Error() string {
+	return e.Message
+}
+
+// unsupportedFeatureError wraps the errors of the detectors about the parts of
+// an input that are not supported, so that they are reported as warnings.
+type unsupportedFeatureError struct {
+	err error
+}
+
+func (e unsupportedFeatureError) Error() string {
+	return e.err.Error()
+}
+
+func (e unsupportedFeatureError) Unwrap() error {
+	return e.err
+}
+
 func unwrapEngineError(err error, path string) error {
 	if shouldIgnoreError(err) {
 		return nil
@@ -119,6 +147,39 @@ func unwrapEngineError(err error, path string) error {
 	return err
 }
 
+// unwrapInitializationError converts an error that occurred while loading a
+// rule bundle. The policy-engine wraps these errors in a RuleBundleError that
+// can't be unwrapped, so the cause is recognized by the message and the path of
+// the bundle is taken from the error.
+func unwrapInitializationError(err error) error {
+	var ruleBundleError *engine.RuleBundleError
+
+	if !errors.As(err, &ruleBundleError) {
+		return unwrapEngineError(err, "")
+	}
+
+	var path string
+
+	if info := ruleBundleError.ToModel(); info.RuleBundle != nil {
+		path = info.RuleBundle.Name
+	}
+
+	message := ruleBundleError.Error()
+
+	switch {
+	case strings.HasPrefix(message, engine.ErrFailedToReadBundle.Error()):
+		return Error{Message: message, Code: ErrorCodeFailedToLoadRules, Path: path}
+	case strings.HasPrefix(message, engine.FailedToLoadRegoAPI.Error()):
+		return Error{Message: message, Code: ErrorCodeFailedToLoadRegoAPI, Path: path}
+	case strings.HasPrefix(message, engine.FailedToLoadRules.Error()):
+		return Error{Message: message, Code: ErrorCodeFailedToLoadRules, Path: path}
+	case strings.HasPrefix(message, engine.FailedToCompile.Error()):
+		return Error{Message: message, Code: ErrorCodeFailedToCompile, Path: path}
+	default:
+		return unwrapEngineError(err, path)
+	}
+}
+
 func errorCode(err error) (ErrorCode, error) {
 	switch {
 	case errors.Is(err, input.UnsupportedInputType):
@@ -158,6 +219,7 @@ func unwrapEngineWarning(err error, path string) error {
 	var missingRemoteSubmodulesError hcl_interpreter.MissingRemoteSubmodulesError
 	var evaluationError hcl_interpreter.EvaluationError
 	var missingTermError hcl_interpreter.MissingTermError
+	var unsupportedFeature unsupportedFeatureError
 
 	switch {
 	case errors.As(err, &submoduleLoadingError):
@@ -196,6 +258,12 @@ func unwrapEngineWarning(err error, path string) error {
 			Path:    path,
 			Term:    missingTermError.Term,
 		}
+	case errors.As(err, &unsupportedFeature):
+		return UnsupportedFeatureError{
+			Message: err.Error(),
+			Code:    ErrorCodeUnsupportedFeature,
+			Path:    path,
+		}
 	default:
 		return nil
 	}
@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/snyk/policy-engine/pkg/input"
+	"github.com/snyk/policy-engine/pkg/models"
+	"github.com/spf13/afero"
+
+	"github.com/snyk/cli-extension-iac/internal/helm"
+)
+
+// errHelmChart is wrapped by the errors of the charts that can't be rendered.
+var errHelmChart = errors.New("unable to render Helm chart")
+
+// helmDetector detects the directories containing a Helm chart. The chart is
+// rendered locally, and the rendered manifests are loaded by the Kubernetes
+// detector.
+type helmDetector struct {
+	valuesFiles []string
+}
+
+func (d *helmDetector) DetectDirectory(i *input.Directory, opts input.DetectOptions) (input.IACConfiguration, error) {
+	if !helm.IsChart(i.Fs, i.Path) {
+		return nil, nil
+	}
+
+	rendering, err := helm.Render(i.Fs, i.Path, d.valuesFiles)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", input.FailedToParseInput, errHelmChart, err)
+	}
+
+	config := helmConfiguration{
+		path:        i.Path,
+		loadedFiles: []string{filepath.Join(i.Path, helm.ChartFile)},
+		errors:      rendering.Warnings,
+	}
+
+	// The Kubernetes detector reads the manifests from a file system, so the
+	// rendered manifests are stored in memory at the path of their template.
+
+	fs := afero.NewMemMapFs()
+	kubernetes := input.KubernetesDetector{}
+
+	for _, manifest := range rendering.Manifests {
+		if err := afero.WriteFile(fs, manifest.Path, manifest.Content, 0644); err != nil {
+			return nil, fmt.Errorf("%w: %w: %v", input.FailedToParseInput, errHelmChart, err)
+		}
+
+		loaded, err := kubernetes.DetectFile(&input.File{Path: manifest.Path, Fs: fs}, input.DetectOptions{IgnoreExt: true})
+		if errors.Is(err, input.InvalidInput) {
+			continue
+		} else if err != nil {
+			config.errors = append(config.errors, fmt.Errorf("%s: %w", manifest.Path, err))
+			continue
+		}
+
+		config.templates = append(config.templates, helmTemplate{
+			configuration: loaded,
+			lines:         manifest.Lines,
+		})
+		config.loadedFiles = append(config.loadedFiles, manifest.Path)
+	}
+
+	return &config, nil
+}
+
+func (d *helmDetector) DetectFile(i *input.File, opts input.DetectOptions) (input.IACConfiguration, error) {
+	return nil, nil
+}
+
+// helmTemplate is the Kubernetes configuration rendered by a template, with
+// the line of the template that produced every rendered line.
+type helmTemplate struct {
+	configuration input.IACConfiguration
+	lines         []int
+}
+
+func (t helmTemplate) line(rendered int) int {
+	if rendered < 1 || rendered > len(t.lines) {
+		return rendered
+	}
+
+	return t.lines[rendered-1]
+}
+
+// helmConfiguration is a rendered Helm chart. Its resources are the resources
+// of every rendered template, and their locations are the lines of the
+// templates that produced them.
+type helmConfiguration struct {
+	path        string
+	templates   []helmTemplate
+	loadedFiles []string
+	errors      []error
+}
+
+func (c *helmConfiguration) ToState() models.State {
+	resources := map[string]map[string]models.ResourceState{}
+
+	for _, t := range c.templates {
+		for resourceType, byKey := range t.configuration.ToState().Resources {
+			if _, ok := resources[resourceType]; !ok {
+				resources[resourceType] = map[string]models.ResourceState{}
+			}
+
+			for key, resource := range byKey {
+				resources[resourceType][key] = resource
+			}
+		}
+	}
+
+	return models.State{
+		InputType:           input.Kubernetes.Name,
+		EnvironmentProvider: "iac",
+		Meta: map[string]interface{}{
+			"filepath": c.path,
+		},
+		Resources: resources,
+		Scope: map[string]interface{}{
+			"filepath": c.path,
+		},
+	}
+}
+
+func (c *helmConfiguration) Location(path []interface{}) (input.LocationStack, error) {
+	for _, t := range c.templates {
+		locations, err := t.configuration.Location(path)
+		if len(locations) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// The column of a rendered line doesn't match the column of its
+		// template, so only the line is reported.
+
+		mapped := make(input.LocationStack, 0, len(locations))
+
+		for _, location := range locations {
+			mapped = append(mapped, input.Location{
+				Path: location.Path,
+				Line: t.line(location.Line),
+			})
+		}
+
+		return mapped, err
+	}
+
+	return nil, nil
+}
+
+func (c *helmConfiguration) LoadedFiles() []string {
+	return c.loadedFiles
+}
+
+func (c *helmConfiguration) Errors() []error {
+	return c.errors
+}
+
+func (c *helmConfiguration) Type() *input.Type {
+	return input.Kubernetes
+}
@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/snyk/policy-engine/pkg/input"
+	"github.com/snyk/policy-engine/pkg/models"
+
+	"github.com/snyk/cli-extension-iac/internal/kustomize"
+)
+
+// errKustomization is wrapped by the errors of the kustomizations that can't
+// be built.
+var errKustomization = errors.New("unable to build kustomization")
+
+// kustomizeDetector detects the directories containing a kustomization. The
+// kustomization is built in-process, and its resources are scanned as
+// Kubernetes resources.
+type kustomizeDetector struct{}
+
+func (d *kustomizeDetector) DetectDirectory(i *input.Directory, opts input.DetectOptions) (input.IACConfiguration, error) {
+	if !kustomize.IsKustomization(i.Fs, i.Path) {
+		return nil, nil
+	}
+
+	result, err := kustomize.Build(i.Fs, i.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", input.FailedToParseInput, errKustomization, err)
+	}
+
+	config := kustomizeConfiguration{
+		path:        i.Path,
+		resources:   map[kubernetesKey]*kustomize.Resource{},
+		loadedFiles: result.Files,
+	}
+
+	for _, warning := range result.Warnings {
+		config.errors = append(config.errors, unsupportedFeatureError{warning})
+	}
+
+	for _, r := range result.Resources {
+		if r.Kind() == "" || r.Name() == "" {
+			config.errors = append(config.errors, fmt.Errorf("%w: resource without a kind or a name", input.InvalidInput))
+			continue
+		}
+
+		config.resources[newKubernetesKey(r)] = r
+	}
+
+	return &config, nil
+}
+
+func (d *kustomizeDetector) DetectFile(i *input.File, opts input.DetectOptions) (input.IACConfiguration, error) {
+	return nil, nil
+}
+
+// kubernetesKey identifies a Kubernetes resource the same way the Kubernetes
+// detector of the Policy Engine does.
+type kubernetesKey struct {
+	namespace string
+	kind      string
+	name      string
+}
+
+func newKubernetesKey(r *kustomize.Resource) kubernetesKey {
+	key := kubernetesKey{
+		namespace: r.Namespace(),
+		kind:      r.Kind(),
+		name:      r.Name(),
+	}
+
+	if key.namespace == "" {
+		key.namespace = "default"
+	}
+
+	return key
+}
+
+// kustomizeConfiguration is a built kustomization. The location of a resource
+// is the file that defines or patches it, followed by the entries of the
+// kustomizations that include it, up to the overlay that was built.
+type kustomizeConfiguration struct {
+	path        string
+	resources   map[kubernetesKey]*kustomize.Resource
+	loadedFiles []string
+	errors      []error
+}
+
+func (c *kustomizeConfiguration) ToState() models.State {
+	resources := map[string]map[string]models.ResourceState{}
+
+	for key, r := range c.resources {
+		if _, ok := resources[key.kind]; !ok {
+			resources[key.kind] = map[string]models.ResourceState{}
+		}
+
+		resources[key.kind][fmt.Sprintf("%s.%s", key.namespace, key.name)] = models.ResourceState{
+			Id:           key.name,
+			Namespace:    key.namespace,
+			ResourceType: key.kind,
+			Meta:         map[string]interface{}{},
+			Attributes:   r.Object,
+		}
+	}
+
+	return models.State{
+		InputType:           input.Kubernetes.Name,
+		EnvironmentProvider: "iac",
+		Meta: map[string]interface{}{
+			"filepath": c.path,
+		},
+		Resources: resources,
+		Scope: map[string]interface{}{
+			"filepath": c.path,
+		},
+	}
+}
+
+func (c *kustomizeConfiguration) Location(path []interface{}) (input.LocationStack, error) {
+	// The path is {namespace, kind, name, attribute path...}, like the path of
+	// the Kubernetes configurations of the Policy Engine.
+
+	if len(path) < 3 {
+		return nil, nil
+	}
+
+	namespace, namespaceOK := path[0].(string)
+	kind, kindOK := path[1].(string)
+	name, nameOK := path[2].(string)
+
+	if !namespaceOK || !kindOK || !nameOK {
+		return nil, fmt.Errorf("%w: expected a namespace, a kind and a name in path: %v", input.UnableToResolveLocation, path)
+	}
+
+	r, ok := c.resources[kubernetesKey{namespace: namespace, kind: kind, name: name}]
+	if !ok {
+		return nil, nil
+	}
+
+	var stack input.LocationStack
+
+	for _, location := range r.Locate(path[3:]) {
+		stack = append(stack, input.Location{
+			Path: location.File,
+			Line: location.Line,
+			Col:  location.Column,
+		})
+	}
+
+	return stack, nil
+}
+
+func (c *kustomizeConfiguration) LoadedFiles() []string {
+	return c.loadedFiles
+}
+
+func (c *kustomizeConfiguration) Errors() []error {
+	return c.errors
+}
+
+func (c *kustomizeConfiguration) Type() *input.Type {
+	return input.Kubernetes
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"context"
+	"io"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/snyk/policy-engine/pkg/bundle"
+	"github.com/snyk/policy-engine/pkg/data"
+	"github.com/snyk/policy-engine/pkg/policy"
+)
+
+// PolicyInputTypes returns the input types of the policies of the Snyk bundle
+// and of the custom rule bundles, by package. The bundles that can't be read
+// are skipped, because the engine reports them as initialization errors.
+func PolicyInputTypes(ctx context.Context, snykBundle io.Reader, customRuleBundles []bundle.Reader) map[string]string {
+	inputTypes := map[string]string{}
+
+	if snykBundle != nil {
+		modules := modulesConsumer{}
+
+		if err := data.TarGzProvider(snykBundle)(ctx, modules); err == nil {
+			addPolicyInputTypes(inputTypes, modules)
+		}
+	}
+
+	for _, reader := range customRuleBundles {
+		b, err := bundle.ReadBundle(reader)
+		if err != nil {
+			continue
+		}
+
+		addPolicyInputTypes(inputTypes, b.Modules())
+	}
+
+	return inputTypes
+}
+
+func addPolicyInputTypes(inputTypes map[string]string, modules map[string]*ast.Module) {
+	for _, moduleSet := range policy.ExtractModuleSets(ast.NewModuleTree(modules)) {
+		p, err := policy.PolicyFactory(moduleSet)
+		if err != nil || p == nil {
+			continue
+		}
+
+		inputTypes[p.Package()] = p.InputType()
+	}
+}
+
+// modulesConsumer collects the Rego modules of a data provider by path.
+type modulesConsumer map[string]*ast.Module
+
+func (c modulesConsumer) Module(_ context.Context, path string, module *ast.Module) error {
+	c[path] = module
+	return nil
+}
+
+func (c modulesConsumer) DataDocument(context.Context, string, map[string]interface{}) error {
+	return nil
+}
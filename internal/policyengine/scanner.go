@@ -1,26 +1,39 @@
 package engine
 
 import (
+	"errors"
+	"path/filepath"
+
 	"github.com/rs/zerolog"
 	"github.com/snyk/policy-engine/pkg/input"
 	"github.com/snyk/policy-engine/pkg/models"
 	"github.com/snyk/policy-engine/pkg/postprocess"
 	"github.com/spf13/afero"
+
+	"github.com/snyk/cli-extension-iac/internal/kustomize"
+	"github.com/snyk/cli-extension-iac/internal/terragrunt"
 )
 
 type scanner struct {
 	fs             afero.Fs
-	varFile        string
+	variables      terraformVariables
+	helmValues     []string
 	logger         *zerolog.Logger
 	loader         input.Loader
 	detectionDepth int
+	terragrunt     *terragrunt.Cache
 	errors         []error
 	warnings       []error
 }
 
 func (e *scanner) scan(paths []string) (input.Loader, []error, []error) {
 	e.errors = nil
-	e.loader = input.NewLoader(newDetector())
+	e.terragrunt = &terragrunt.Cache{}
+	e.loader = input.NewLoader(newDetector(detectorOptions{
+		helmValuesFiles:    e.helmValues,
+		terraformVariables: e.variables,
+		terragrunt:         e.terragrunt,
+	}))
 
 	e.loadPaths(paths)
 
@@ -118,6 +131,15 @@ func (e *scanner) walkDirectory(path string) {
 	// The directory was not loaded by the Policy Engine, which means it was not
 	// recognized to be a collection of IaC files. Iterate over its content and
 	// check whether we can load any of the files or subdirectories under it.
+	// The files and directories included by a kustomization, and the local
+	// modules of the Terragrunt units, are scanned as part of the
+	// kustomization or of the unit, and are skipped.
+
+	included := kustomize.Included(e.fs, path, e.maxDepth())
+
+	for source := range e.terragrunt.Sources(e.fs, path, e.maxDepth()) {
+		included[source] = true
+	}
 
 	walkFunc := func(d input.Detectable, depth int) (skip bool, err error) {
 		if e.detectionDepth > 0 && depth-1 > e.detectionDepth {
@@ -128,6 +150,10 @@ func (e *scanner) walkDirectory(path string) {
 			return true, err
 		}
 
+		if included[filepath.Clean(d.GetPath())] {
+			return true, nil
+		}
+
 		return e.load(d), nil
 	}
 
@@ -138,6 +164,17 @@ func (e *scanner) walkDirectory(path string) {
 	}
 }
 
+// maxDepth returns the number of levels of directories below an input path
+// that are loaded, or 0 if there is no limit. A directory at the detection
+// depth is still loaded as a whole, e.g. as a kustomization.
+func (e *scanner) maxDepth() int {
+	if e.detectionDepth <= 0 {
+		return 0
+	}
+
+	return e.detectionDepth + 1
+}
+
 func (e *scanner) loadFile(path string) {
 	e.load(e.newFile(path))
 }
@@ -157,17 +194,19 @@ func (e *scanner) newFile(p string) *input.File {
 }
 
 func (e *scanner) load(d input.Detectable) bool {
-	var varFiles []string
-
-	if e.varFile != "" {
-		varFiles = []string{e.varFile}
-	}
-
-	loaded, err := e.loader.Load(d, input.DetectOptions{VarFiles: varFiles})
+	loaded, err := e.loader.Load(d, input.DetectOptions{})
 	if err != nil {
 		if unwrapped := unwrapEngineError(err, d.GetPath()); unwrapped != nil {
 			e.errors = append(e.errors, unwrapped)
 		}
+
+		// A chart that can't be rendered, a kustomization that can't be
+		// built, a cloud assembly that can't be read or a Terragrunt unit
+		// that can't be loaded is reported once. Its files must not be
+		// loaded on their own.
+		if errors.Is(err, errHelmChart) || errors.Is(err, errKustomization) || errors.Is(err, errCloudAssembly) || errors.Is(err, errTerragrunt) {
+			return true
+		}
 	}
 
 	return loaded
@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/snyk/policy-engine/pkg/input"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/snyk/cli-extension-iac/internal/tfvars"
+)
+
+// The var files that set the variables of the environment and of the command
+// line are written next to the module, in an in-memory layer over the file
+// system. Their names don't match the var files Terraform loads automatically.
+// The variables of the command line are written to one file for every group of
+// consecutive variables, numbered in order.
+const (
+	envVarFile = "snyk-iac-env.tfvars.json"
+	cliVarFile = "snyk-iac-vars-%d.tfvars.json"
+)
+
+// terraformVariables are the values of the Terraform variables set outside of
+// the scanned modules.
+type terraformVariables struct {
+	// env are the variables set by the TF_VAR_ environment variables.
+	env map[string]cty.Value
+
+	// args are the var files and the variables of the command line, in order.
+	args []terraformArg
+}
+
+// terraformArg is a var file passed to the scan, or a group of consecutive
+// variables set on the command line.
+type terraformArg struct {
+	file string
+	vars map[string]cty.Value
+}
+
+func newTerraformVariables(args []tfvars.Arg, env []string) (terraformVariables, error) {
+	var (
+		parsed []terraformArg
+		vars   []string
+	)
+
+	flush := func() error {
+		if len(vars) == 0 {
+			return nil
+		}
+
+		values, err := tfvars.ParseVars(vars)
+		if err != nil {
+			return err
+		}
+
+		parsed = append(parsed, terraformArg{vars: values})
+		vars = nil
+
+		return nil
+	}
+
+	for _, arg := range args {
+		if arg.VarFile == "" {
+			vars = append(vars, arg.Var)
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return terraformVariables{}, err
+		}
+
+		parsed = append(parsed, terraformArg{file: arg.VarFile})
+	}
+
+	if err := flush(); err != nil {
+		return terraformVariables{}, err
+	}
+
+	return terraformVariables{
+		env:  tfvars.ParseEnv(env),
+		args: parsed,
+	}, nil
+}
+
+// load returns the var files that set the variables of the module in a
+// directory, and the file system they can be read from. The var files are in
+// the order of precedence of Terraform: the environment, the var files of the
+// module, then the var files passed to the scan and the variables of the
+// command line, in the order of the command line. The defaults have a lower
+// precedence than the environment.
+//
+// The Policy Engine loads the var files of a module before the var files it
+// is passed, so the variables of the environment that are set by the var
+// files of the module are left out. The var files of the module are only
+// returned if withAutoFiles is true, for the modules loaded from a single
+// file.
+func (v terraformVariables) load(fsys afero.Fs, dir string, defaults map[string]cty.Value, withAutoFiles bool) (afero.Fs, []string, error) {
+	env := map[string]cty.Value{}
+
+	for name, value := range defaults {
+		env[name] = value
+	}
+
+	for name, value := range v.env {
+		env[name] = value
+	}
+
+	autoFiles, err := tfvars.AutoFiles(fsys, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(env) > 0 {
+		set, err := tfvars.Names(fsys, autoFiles)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for name := range set {
+			delete(env, name)
+		}
+	}
+
+	var (
+		overlay  afero.Fs
+		varFiles []string
+	)
+
+	write := func(name string, values map[string]cty.Value) error {
+		data, err := tfvars.Encode(values)
+		if err != nil {
+			return err
+		}
+
+		if overlay == nil {
+			overlay = afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(fsys), afero.NewMemMapFs())
+		}
+
+		path := filepath.Join(dir, name)
+
+		if err := afero.WriteFile(overlay, path, data, 0644); err != nil {
+			return err
+		}
+
+		varFiles = append(varFiles, path)
+
+		return nil
+	}
+
+	if len(env) > 0 {
+		if err := write(envVarFile, env); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if withAutoFiles {
+		varFiles = append(varFiles, autoFiles...)
+	}
+
+	for i, arg := range v.args {
+		if arg.file != "" {
+			varFiles = append(varFiles, arg.file)
+			continue
+		}
+
+		if err := write(fmt.Sprintf(cliVarFile, i), arg.vars); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if overlay == nil {
+		overlay = fsys
+	}
+
+	return overlay, varFiles, nil
+}
+
+// terraformDetector detects Terraform modules, and loads them with the
+// variables set outside of the module.
+type terraformDetector struct {
+	variables terraformVariables
+}
+
+func (d *terraformDetector) DetectDirectory(i *input.Directory, opts input.DetectOptions) (input.IACConfiguration, error) {
+	return d.detectDirectory(i.Fs, i.Path, nil)
+}
+
+func (d *terraformDetector) DetectFile(i *input.File, opts input.DetectOptions) (input.IACConfiguration, error) {
+	fs, varFiles, err := d.variables.load(i.Fs, filepath.Dir(i.Path), nil, true)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", input.FailedToParseInput, err)
+	}
+
+	return (&input.TfDetector{}).DetectFile(&input.File{Path: i.Path, Fs: fs}, input.DetectOptions{
+		IgnoreExt: opts.IgnoreExt,
+		VarFiles:  varFiles,
+	})
+}
+
+// detectDirectory loads the module in a directory. The defaults are the
+// values of the variables with a lower precedence than the environment.
+func (d *terraformDetector) detectDirectory(fsys afero.Fs, dir string, defaults map[string]cty.Value) (input.IACConfiguration, error) {
+	fs, varFiles, err := d.variables.load(fsys, dir, defaults, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", input.FailedToParseInput, err)
+	}
+
+	return (&input.TfDetector{}).DetectDirectory(&input.Directory{Path: dir, Fs: fs}, input.DetectOptions{
+		VarFiles: varFiles,
+	})
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/snyk/policy-engine/pkg/hcl_interpreter"
+	"github.com/snyk/policy-engine/pkg/input"
+	"github.com/snyk/policy-engine/pkg/models"
+
+	"github.com/snyk/cli-extension-iac/internal/terragrunt"
+)
+
+// errTerragrunt is wrapped by the errors of the Terragrunt units that can't be
+// loaded.
+var errTerragrunt = errors.New("unable to load Terragrunt unit")
+
+// terragruntDetector detects the directories containing a terragrunt.hcl file.
+// The Terraform module of the unit is loaded by the Terraform detector, with
+// the inputs of the unit as variables.
+type terragruntDetector struct {
+	terraform *terraformDetector
+	cache     *terragrunt.Cache
+}
+
+func (d *terragruntDetector) DetectDirectory(i *input.Directory, opts input.DetectOptions) (input.IACConfiguration, error) {
+	if !terragrunt.IsTerragrunt(i.Fs, i.Path) {
+		return nil, nil
+	}
+
+	config, err := d.cache.Load(i.Fs, i.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", input.FailedToParseInput, errTerragrunt, err)
+	}
+
+	// The configuration is shared with the cache and must not be modified.
+	result := terragruntConfiguration{
+		path:        i.Path,
+		loadedFiles: append([]string(nil), config.Files...),
+	}
+
+	// The expressions that can't be evaluated are reported the same way as
+	// the ones of a Terraform module.
+
+	for _, diags := range config.Warnings {
+		result.errors = append(result.errors, hcl_interpreter.EvaluationError{Diags: diags})
+	}
+
+	// Remote sources are not downloaded. The unit is reported the same way as
+	// a Terraform module with remote submodules.
+
+	if config.Remote {
+		result.errors = append(result.errors, hcl_interpreter.MissingRemoteSubmodulesError{
+			Dir:            i.Path,
+			MissingModules: []string{config.Source},
+		})
+
+		return &result, nil
+	}
+
+	if config.ModuleDir == "" {
+		return &result, nil
+	}
+
+	// Terragrunt sets the inputs as TF_VAR_ environment variables, unless
+	// they are already set in the environment.
+
+	module, err := d.terraform.detectDirectory(i.Fs, config.ModuleDir, config.Inputs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", input.FailedToParseInput, errTerragrunt, err)
+	}
+
+	// A configuration without a source and without Terraform files, like the
+	// root configuration included by the units, is not a unit.
+
+	if module == nil {
+		if config.Source == "" {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("%w: %w: terraform source %s has no Terraform files", input.FailedToParseInput, errTerragrunt, config.Source)
+	}
+
+	result.module = module
+	result.errors = append(result.errors, module.Errors()...)
+
+	result.loadedFiles = append(result.loadedFiles, module.LoadedFiles()...)
+
+	return &result, nil
+}
+
+func (d *terragruntDetector) DetectFile(i *input.File, opts input.DetectOptions) (input.IACConfiguration, error) {
+	return nil, nil
+}
+
+// terragruntConfiguration is a Terragrunt unit. Its resources are the
+// resources of its Terraform module, and it has no resources if the module
+// can't be loaded.
+type terragruntConfiguration struct {
+	path        string
+	module      input.IACConfiguration
+	loadedFiles []string
+	errors      []error
+}
+
+func (c *terragruntConfiguration) ToState() models.State {
+	state := models.State{
+		InputType:           input.TerraformHCL.Name,
+		EnvironmentProvider: "iac",
+		Resources:           map[string]map[string]models.ResourceState{},
+	}
+
+	if c.module != nil {
+		state = c.module.ToState()
+	}
+
+	state.Meta = map[string]interface{}{
+		"filepath": c.path,
+	}
+	state.Scope = map[string]interface{}{
+		"filepath": c.path,
+	}
+
+	return state
+}
+
+func (c *terragruntConfiguration) Location(path []interface{}) (input.LocationStack, error) {
+	if c.module == nil {
+		return nil, nil
+	}
+
+	return c.module.Location(path)
+}
+
+func (c *terragruntConfiguration) LoadedFiles() []string {
+	return c.loadedFiles
+}
+
+func (c *terragruntConfiguration) Errors() []error {
+	return c.errors
+}
+
+func (c *terragruntConfiguration) Type() *input.Type {
+	return input.TerraformHCL
+}
@@ -0,0 +1,26 @@
+variable "name" {
+  type    = string
+  default = "default"
+}
+
+variable "region" {
+  type    = string
+  default = "us-east-1"
+}
+
+variable "cidrs" {
+  type    = list(string)
+  default = ["1.1.1.1/1"]
+}
+
+resource "aws_security_group" "vars" {
+  name        = var.name
+  description = var.region
+
+  ingress {
+    from_port   = 22
+    to_port     = 22
+    protocol    = "tcp"
+    cidr_blocks = var.cidrs
+  }
+}
@@ -0,0 +1,15 @@
+include "root" {
+  path = find_in_parent_folders()
+}
+
+terraform {
+  source = "../../../modules//network"
+}
+
+inputs = {
+  cidr_block       = "10.0.0.0/16"
+  environment      = "prod"
+  remote_user_addr = ["0.0.0.0/0"]
+  name             = "prod-ssh"
+  account          = get_aws_account_id()
+}
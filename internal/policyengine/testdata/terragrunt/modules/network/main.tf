@@ -0,0 +1,36 @@
+variable "cidr_block" {
+  type = string
+}
+
+variable "name" {
+  type = string
+}
+
+variable "environment" {
+  type = string
+}
+
+variable "remote_user_addr" {
+  type    = list(string)
+  default = ["1.1.1.1/1"]
+}
+
+resource "aws_vpc" "main" {
+  cidr_block = var.cidr_block
+
+  tags = {
+    Environment = var.environment
+  }
+}
+
+resource "aws_security_group" "ssh" {
+  name   = var.name
+  vpc_id = aws_vpc.main.id
+
+  ingress {
+    from_port   = 22
+    to_port     = 22
+    protocol    = "tcp"
+    cidr_blocks = var.remote_user_addr
+  }
+}
@@ -0,0 +1,5 @@
+inputs = {
+  tags = {
+    owner = "platform"
+  }
+}
@@ -0,0 +1,38 @@
+package processor
+
+import (
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+type baselineMatcher interface {
+	Contains(v results.Vulnerability) bool
+}
+
+// filterVulnerabilitiesByBaseline moves the vulnerabilities that are part of
+// the baseline out of the reported vulnerabilities, so that only new
+// vulnerabilities affect the outcome of the scan.
+func filterVulnerabilitiesByBaseline(r *results.Results, baseline baselineMatcher) *results.Results {
+	if r == nil {
+		return nil
+	}
+
+	var (
+		kept      []results.Vulnerability
+		baselined []results.Vulnerability
+	)
+
+	for _, v := range r.Vulnerabilities {
+		if baseline.Contains(v) {
+			baselined = append(baselined, v)
+		} else {
+			kept = append(kept, v)
+		}
+	}
+
+	result := *r
+
+	result.Vulnerabilities = kept
+	result.BaselineVulnerabilities = baselined
+
+	return &result
+}
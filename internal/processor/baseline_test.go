@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+type mockBaseline func(v results.Vulnerability) bool
+
+func (m mockBaseline) Contains(v results.Vulnerability) bool {
+	return m(v)
+}
+
+func TestFilterVulnerabilitiesByBaseline(t *testing.T) {
+	known := results.Vulnerability{Rule: results.Rule{ID: "known"}}
+	unknown := results.Vulnerability{Rule: results.Rule{ID: "unknown"}}
+
+	baseline := mockBaseline(func(v results.Vulnerability) bool {
+		return v.Rule.ID == "known"
+	})
+
+	tests := []struct {
+		name   string
+		input  *results.Results
+		output *results.Results
+	}{
+		{
+			name:   "nil",
+			input:  nil,
+			output: nil,
+		},
+		{
+			name: "known and new vulnerabilities",
+			input: &results.Results{
+				Vulnerabilities: []results.Vulnerability{known, unknown},
+			},
+			output: &results.Results{
+				Vulnerabilities:         []results.Vulnerability{unknown},
+				BaselineVulnerabilities: []results.Vulnerability{known},
+			},
+		},
+		{
+			name: "only new vulnerabilities",
+			input: &results.Results{
+				Vulnerabilities: []results.Vulnerability{unknown},
+			},
+			output: &results.Results{
+				Vulnerabilities: []results.Vulnerability{unknown},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.output, filterVulnerabilitiesByBaseline(test.input, baseline))
+		})
+	}
+}
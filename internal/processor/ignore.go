@@ -1,15 +1,14 @@
 package processor
 
 import (
-	"path/filepath"
-	"strings"
 	"time"
 
+	"github.com/snyk/cli-extension-iac/internal/ignore"
 	"github.com/snyk/cli-extension-iac/internal/results"
 )
 
 type matcher interface {
-	Match(id string, now time.Time, parts ...string) bool
+	MatchRule(id string, now time.Time, parts ...string) (ignore.Rule, bool)
 }
 
 func filterVulnerabilitiesByIgnores(r *results.Results, matcher matcher, now time.Time) *results.Results {
@@ -23,17 +22,11 @@ func filterVulnerabilitiesByIgnores(r *results.Results, matcher matcher, now tim
 	)
 
 	for _, v := range r.Vulnerabilities {
-		var parts []string
+		parts := ignore.PathParts(v.Resource.File, v.Resource.FormattedPath)
 
-		if file := v.Resource.File; len(file) > 0 {
-			parts = append(parts, filepath.ToSlash(file))
-		}
-
-		if path := v.Resource.FormattedPath; len(path) > 0 {
-			parts = append(parts, strings.Split(path, ".")...)
-		}
-
-		if matcher.Match(v.Rule.ID, now, parts...) {
+		if rule, ok := matcher.MatchRule(v.Rule.ID, now, parts...); ok {
+			v.Ignored = true
+			v.Ignore = ignoreFromRule(rule)
 			ignored = append(ignored, v)
 		} else {
 			kept = append(kept, v)
@@ -43,7 +36,36 @@ func filterVulnerabilitiesByIgnores(r *results.Results, matcher matcher, now tim
 	result := *r
 
 	result.Vulnerabilities = kept
+	result.IgnoredVulnerabilities = ignored
 	result.Metadata.IgnoredCount = len(ignored)
 
 	return &result
 }
+
+func ignoreFromRule(rule ignore.Rule) *results.Ignore {
+	i := results.Ignore{
+		Source: results.IgnoreSourcePolicy,
+		Path:   rule.Pattern,
+		Reason: rule.Reason,
+	}
+
+	if !rule.Expires.IsZero() {
+		expires := rule.Expires
+		i.Expires = &expires
+	}
+
+	if !rule.Created.IsZero() {
+		created := rule.Created
+		i.Created = &created
+	}
+
+	if rule.IgnoredBy != nil {
+		i.IgnoredBy = &results.IgnoredBy{
+			ID:    rule.IgnoredBy.ID,
+			Name:  rule.IgnoredBy.Name,
+			Email: rule.IgnoredBy.Email,
+		}
+	}
+
+	return &i
+}
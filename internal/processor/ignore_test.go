@@ -1,20 +1,28 @@
 package processor
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/rs/zerolog"
+
+	"github.com/snyk/cli-extension-iac/internal/ignore"
 	"github.com/snyk/cli-extension-iac/internal/results"
 	"github.com/stretchr/testify/require"
 )
 
-type mockMatcher func(id string, now time.Time, parts ...string) bool
+type mockMatcher func(id string, now time.Time, parts ...string) (ignore.Rule, bool)
 
-func (m mockMatcher) Match(id string, now time.Time, parts ...string) bool {
+func (m mockMatcher) MatchRule(id string, now time.Time, parts ...string) (ignore.Rule, bool) {
 	return m(id, now, parts...)
 }
 
 func TestFilterVulnerabilitiesByIgnores(t *testing.T) {
+	expires := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
 	tests := []struct {
 		name    string
 		input   *results.Results
@@ -26,7 +34,7 @@ func TestFilterVulnerabilitiesByIgnores(t *testing.T) {
 			input:  nil,
 			output: nil,
 			matcher: func(t *testing.T) matcher {
-				return mockMatcher(func(id string, now time.Time, parts ...string) bool {
+				return mockMatcher(func(id string, now time.Time, parts ...string) (ignore.Rule, bool) {
 					panic("matcher should not be used")
 				})
 			},
@@ -48,15 +56,42 @@ func TestFilterVulnerabilitiesByIgnores(t *testing.T) {
 			},
 			output: &results.Results{
 				Vulnerabilities: nil,
+				IgnoredVulnerabilities: []results.Vulnerability{
+					{
+						Rule: results.Rule{
+							ID: "id",
+						},
+						Ignored: true,
+						Ignore: &results.Ignore{
+							Source:    results.IgnoreSourcePolicy,
+							Path:      "*",
+							Reason:    "reason",
+							Expires:   &expires,
+							Created:   &created,
+							IgnoredBy: &results.IgnoredBy{Name: "name", Email: "email"},
+						},
+						Resource: results.Resource{
+							File:          "file",
+							FormattedPath: "a.b.c",
+						},
+					},
+				},
 				Metadata: results.Metadata{
 					IgnoredCount: 1,
 				},
 			},
 			matcher: func(t *testing.T) matcher {
-				return mockMatcher(func(id string, now time.Time, parts ...string) bool {
+				return mockMatcher(func(id string, now time.Time, parts ...string) (ignore.Rule, bool) {
 					require.Equal(t, id, "id")
 					require.Equal(t, parts, []string{"file", "a", "b", "c"})
-					return true
+					return ignore.Rule{
+						ID:        "id",
+						Pattern:   "*",
+						Reason:    "reason",
+						Expires:   expires,
+						Created:   created,
+						IgnoredBy: &ignore.IgnoredBy{Name: "name", Email: "email"},
+					}, true
 				})
 			},
 		},
@@ -89,10 +124,10 @@ func TestFilterVulnerabilitiesByIgnores(t *testing.T) {
 				},
 			},
 			matcher: func(t *testing.T) matcher {
-				return mockMatcher(func(id string, now time.Time, parts ...string) bool {
+				return mockMatcher(func(id string, now time.Time, parts ...string) (ignore.Rule, bool) {
 					require.Equal(t, id, "id")
 					require.Equal(t, parts, []string{"file", "a", "b", "c"})
-					return false
+					return ignore.Rule{}, false
 				})
 			},
 		},
@@ -105,3 +140,39 @@ func TestFilterVulnerabilitiesByIgnores(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateIgnoreMatcherReasonRequired(t *testing.T) {
+	policy := `
+ignore:
+  RULE-1:
+    - '*':
+        reason: accepted risk
+  RULE-2:
+    - '*':
+        created: 2022-01-01T00:00:00.000Z
+`
+
+	policyPath := filepath.Join(t.TempDir(), ".snyk")
+	require.NoError(t, os.WriteFile(policyPath, []byte(policy), 0644))
+
+	logger := zerolog.Nop()
+
+	p := ResultsProcessor{
+		PolicyPath: policyPath,
+		Logger:     &logger,
+	}
+
+	now := time.Now()
+
+	m, rules, err := p.createIgnoreMatcher(false)
+	require.Len(t, rules, 2)
+	require.NoError(t, err)
+	require.True(t, m.Match("RULE-1", now, "file"))
+	require.True(t, m.Match("RULE-2", now, "file"))
+
+	m, rules, err = p.createIgnoreMatcher(true)
+	require.Len(t, rules, 1)
+	require.NoError(t, err)
+	require.True(t, m.Match("RULE-1", now, "file"))
+	require.False(t, m.Match("RULE-2", now, "file"))
+}
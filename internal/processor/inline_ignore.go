@@ -0,0 +1,138 @@
+package processor
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/snyk/cli-extension-iac/internal/ignore"
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+// readInlineIgnores reads the ignores declared by comments in the source files
+// of the resources and the vulnerabilities. Source files are read with
+// readFile, at most once. A file that can't be read or that contains a
+// malformed ignore is logged and doesn't declare any ignore. If
+// reasonRequired is true, ignores without a reason are rejected.
+func readInlineIgnores(r *results.Results, readFile func(string) ([]byte, error), reasonRequired bool, logger *zerolog.Logger) []ignore.InlineIgnore {
+	if r == nil {
+		return nil
+	}
+
+	var (
+		files   []string
+		parsed  = make(map[string]bool)
+		ignores []ignore.InlineIgnore
+	)
+
+	for _, vulnerabilities := range [][]results.Vulnerability{r.Vulnerabilities, r.PassedVulnerabilities} {
+		for _, v := range vulnerabilities {
+			for _, location := range vulnerabilityLocations(v) {
+				files = append(files, location.File)
+			}
+		}
+	}
+
+	for _, resource := range r.Resources {
+		files = append(files, resource.File)
+	}
+
+	for _, file := range files {
+		if file == "" || parsed[file] {
+			continue
+		}
+
+		parsed[file] = true
+
+		data, err := readFile(file)
+		if err != nil {
+			logger.Warn().Err(err).Msgf("read inline ignores from %s", file)
+			continue
+		}
+
+		fileIgnores, err := ignore.ParseInlineIgnores(file, data)
+		if err != nil {
+			logger.Warn().Err(err).Msg("parse inline ignores")
+			continue
+		}
+
+		for _, i := range fileIgnores {
+			if reasonRequired && strings.TrimSpace(i.Reason) == "" {
+				logger.Warn().Msgf("reject inline ignore at %s:%d: a reason is required", i.File, i.Line)
+				continue
+			}
+
+			ignores = append(ignores, i)
+		}
+	}
+
+	return ignores
+}
+
+// filterVulnerabilitiesByInlineIgnores moves the vulnerabilities ignored by
+// one of the inline ignores to the ignored vulnerabilities.
+func filterVulnerabilitiesByInlineIgnores(r *results.Results, ignores []ignore.InlineIgnore, now time.Time) *results.Results {
+	if r == nil {
+		return nil
+	}
+
+	var (
+		matcher ignore.InlineMatcher
+		kept    []results.Vulnerability
+		ignored []results.Vulnerability
+	)
+
+	matcher.AddIgnores(ignores...)
+
+	for _, v := range r.Vulnerabilities {
+		inlineIgnore, ok := matcher.Match(v.Rule.ID, now, vulnerabilityLocations(v)...)
+		if !ok {
+			kept = append(kept, v)
+			continue
+		}
+
+		v.Ignored = true
+		v.Ignore = &results.Ignore{
+			Source: results.IgnoreSourceInline,
+			Reason: inlineIgnore.Reason,
+			Location: &results.Location{
+				File: inlineIgnore.File,
+				Line: inlineIgnore.Line,
+			},
+		}
+
+		if !inlineIgnore.Expires.IsZero() {
+			expires := inlineIgnore.Expires
+			v.Ignore.Expires = &expires
+		}
+
+		ignored = append(ignored, v)
+	}
+
+	result := *r
+
+	result.Vulnerabilities = kept
+	result.IgnoredVulnerabilities = append(append([]results.Vulnerability(nil), r.IgnoredVulnerabilities...), ignored...)
+	result.Metadata.IgnoredCount += len(ignored)
+
+	return &result
+}
+
+// vulnerabilityLocations returns the locations of the vulnerable resource,
+// starting from the most specific one.
+func vulnerabilityLocations(v results.Vulnerability) []ignore.Location {
+	var locations []ignore.Location
+
+	for _, location := range v.Resource.SourceLocation {
+		locations = append(locations, ignore.Location{
+			File: location.File,
+			Line: location.Line,
+		})
+	}
+
+	return append(locations, ignore.Location{
+		File: v.Resource.File,
+		Line: v.Resource.Line,
+	})
+}
@@ -0,0 +1,150 @@
+package processor
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+func TestFilterVulnerabilitiesByInlineIgnores(t *testing.T) {
+	logger := zerolog.Nop()
+
+	files := map[string]string{
+		"main.tf": `# snyk:ignore RULE-1 reason="Public by design" expires=2100-01-01
+resource "aws_s3_bucket" "public" {
+  acl = "public-read" # snyk:ignore RULE-3
+}
+`,
+	}
+
+	var reads []string
+
+	readFile := func(path string) ([]byte, error) {
+		reads = append(reads, path)
+
+		if data, ok := files[path]; ok {
+			return []byte(data), nil
+		}
+
+		return nil, os.ErrNotExist
+	}
+
+	vulnerability := func(id string, file string, lines ...int) results.Vulnerability {
+		v := results.Vulnerability{
+			Rule: results.Rule{ID: id},
+			Resource: results.Resource{
+				File: file,
+				Line: lines[len(lines)-1],
+			},
+		}
+
+		for _, line := range lines {
+			v.Resource.SourceLocation = append(v.Resource.SourceLocation, results.Location{File: file, Line: line})
+		}
+
+		return v
+	}
+
+	input := &results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			vulnerability("RULE-1", "main.tf", 2),
+			vulnerability("RULE-2", "main.tf", 2),
+			vulnerability("RULE-3", "main.tf", 3, 2),
+			vulnerability("RULE-1", "missing.tf", 2),
+		},
+		Metadata: results.Metadata{
+			IgnoredCount: 1,
+		},
+	}
+
+	ignores := readInlineIgnores(input, readFile, false, &logger)
+
+	require.Equal(t, []string{"main.tf", "missing.tf"}, reads)
+
+	output := filterVulnerabilitiesByInlineIgnores(input, ignores, time.Now())
+
+	require.Equal(t, []results.Vulnerability{
+		vulnerability("RULE-2", "main.tf", 2),
+		vulnerability("RULE-1", "missing.tf", 2),
+	}, output.Vulnerabilities)
+
+	expires := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first := vulnerability("RULE-1", "main.tf", 2)
+	first.Ignored = true
+	first.Ignore = &results.Ignore{
+		Source:   results.IgnoreSourceInline,
+		Reason:   "Public by design",
+		Expires:  &expires,
+		Location: &results.Location{File: "main.tf", Line: 1},
+	}
+
+	second := vulnerability("RULE-3", "main.tf", 3, 2)
+	second.Ignored = true
+	second.Ignore = &results.Ignore{
+		Source:   results.IgnoreSourceInline,
+		Location: &results.Location{File: "main.tf", Line: 3},
+	}
+
+	require.Equal(t, []results.Vulnerability{first, second}, output.IgnoredVulnerabilities)
+	require.Equal(t, 3, output.Metadata.IgnoredCount)
+}
+
+func TestFilterVulnerabilitiesByInlineIgnoresNil(t *testing.T) {
+	logger := zerolog.Nop()
+
+	readFile := func(path string) ([]byte, error) {
+		panic("no file should be read")
+	}
+
+	require.Nil(t, readInlineIgnores(nil, readFile, false, &logger))
+	require.Nil(t, filterVulnerabilitiesByInlineIgnores(nil, nil, time.Now()))
+}
+
+func TestFilterVulnerabilitiesByInlineIgnoresReasonRequired(t *testing.T) {
+	logger := zerolog.Nop()
+
+	readFile := func(path string) ([]byte, error) {
+		return []byte("# snyk:ignore RULE-1\n# snyk:ignore RULE-2 reason=accepted\nresource \"a\" \"b\" {}\n"), nil
+	}
+
+	input := &results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			{Rule: results.Rule{ID: "RULE-1"}, Resource: results.Resource{File: "main.tf", Line: 3}},
+			{Rule: results.Rule{ID: "RULE-2"}, Resource: results.Resource{File: "main.tf", Line: 3}},
+		},
+	}
+
+	output := filterVulnerabilitiesByInlineIgnores(input, readInlineIgnores(input, readFile, true, &logger), time.Now())
+
+	require.Len(t, output.Vulnerabilities, 1)
+	require.Equal(t, "RULE-1", output.Vulnerabilities[0].Rule.ID)
+	require.Len(t, output.IgnoredVulnerabilities, 1)
+	require.Equal(t, "RULE-2", output.IgnoredVulnerabilities[0].Rule.ID)
+	require.Equal(t, 1, output.Metadata.IgnoredCount)
+}
+
+func TestReadInlineIgnoresFromResources(t *testing.T) {
+	logger := zerolog.Nop()
+
+	readFile := func(path string) ([]byte, error) {
+		return []byte("# snyk:ignore RULE-1\nresource \"a\" \"b\" {}\n"), nil
+	}
+
+	input := &results.Results{
+		Resources: []results.Resource{
+			{File: "main.tf", Line: 2},
+		},
+	}
+
+	ignores := readInlineIgnores(input, readFile, false, &logger)
+
+	require.Len(t, ignores, 1)
+	require.Equal(t, []string{"RULE-1"}, ignores[0].RuleIDs)
+	require.Equal(t, 2, ignores[0].TargetLine)
+}
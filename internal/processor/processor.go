@@ -4,13 +4,16 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
+	"github.com/snyk/cli-extension-iac/internal/baseline"
 	"github.com/snyk/cli-extension-iac/internal/ignore"
 	"github.com/snyk/cli-extension-iac/internal/platform"
 	engine "github.com/snyk/cli-extension-iac/internal/policyengine"
 	"github.com/snyk/cli-extension-iac/internal/results"
+	"github.com/snyk/cli-extension-iac/internal/severity"
 
 	"github.com/snyk/cli-extension-iac/internal/settings"
 )
@@ -41,6 +44,11 @@ type ResultsProcessor struct {
 	SerializeEngineResults       func(results *engine.Results) (string, error)
 	SettingsReader               SettingsReader
 	PolicyPath                   string
+	BaselinePath                 string
+	BaselineWritePath            string
+	SeverityOverridesPath        string
+	DisableInlineIgnores         bool
+	ReadFile                     func(string) ([]byte, error)
 	IncludePassedVulnerabilities bool
 	IacNewEngine                 bool
 	AllowAnalytics               bool
@@ -69,25 +77,78 @@ func (p *ResultsProcessor) ProcessResults(rawResults *engine.Results, scanAnalyt
 		return nil, fmt.Errorf("compute project URI: %v", err)
 	}
 
-	matcher, err := p.createIgnoreMatcher()
+	reasonRequired := userSettings.IgnoreSettings.ReasonRequired
+
+	matcher, ignoreRules, err := p.createIgnoreMatcher(reasonRequired)
 	if err != nil {
 		return nil, fmt.Errorf("create ignore policy matcher: %v", err)
 	}
 
-	if len(userSettings.CustomSeverities) != 0 {
-		rawResults = applyCustomSeverities(rawResults, userSettings.CustomSeverities)
+	localSeverities, err := p.readSeverityOverrides()
+	if err != nil {
+		return nil, fmt.Errorf("read severity overrides: %v", err)
+	}
+
+	severityPolicy := severity.Policy{
+		Org:   userSettings.CustomSeverities,
+		Local: localSeverities,
 	}
 
 	rawResults = filterMissingResources(rawResults)
-	scanResults := results.FromEngineResults(rawResults, p.IncludePassedVulnerabilities)
+	scanResults := severityPolicy.Apply(results.FromEngineResults(rawResults, p.IncludePassedVulnerabilities))
 	scanResults.Metadata.ProjectName = projectName
 
+	// The raw results are shared by the legacy engine and can be part of the
+	// output, so they must have the same severities as the scan results.
+	if customSeverities := severityPolicy.CustomSeverities(); len(customSeverities) != 0 {
+		rawResults = applyCustomSeverities(rawResults, customSeverities)
+	}
+
+	now := time.Now()
+
+	inlineIgnores := p.readInlineIgnores(scanResults, userSettings)
+
+	// Stale ignores are detected before the severity threshold is applied, so
+	// that ignores for vulnerabilities below the threshold are not reported.
+	staleIgnores := findStaleIgnores(ignoreRules, inlineIgnores, scanResults.Vulnerabilities, now)
+
+	// The shared results are taken before the severity threshold and the
+	// ignores are applied. The threshold only affects the local output, and
+	// the ignores of the policy file are shared separately.
+	var shareResults *results.Results
+
+	if p.Report && p.IacNewEngine {
+		shareResults = &results.Results{
+			Resources:       scanResults.Resources,
+			Vulnerabilities: append([]results.Vulnerability(nil), scanResults.Vulnerabilities...),
+		}
+	}
+
+	scanResults = filterVulnerabilitiesByIgnores(scanResults, matcher, now)
+	scanResults = filterVulnerabilitiesByInlineIgnores(scanResults, inlineIgnores, now)
+	scanResults.ScanAnalytics = scanAnalytics
+	scanResults.StaleIgnores = staleIgnores
+
+	// The baseline is written before the severity threshold is applied, so
+	// that it records the vulnerabilities below the threshold too.
+	if p.BaselineWritePath != "" {
+		if err := p.writeBaseline(scanResults.Vulnerabilities); err != nil {
+			return nil, fmt.Errorf("write baseline: %v", err)
+		}
+	}
+
 	if p.SeverityThreshold != "" {
 		scanResults = filterBySeverityThreshold(scanResults, p.SeverityThreshold)
 	}
 
-	scanResults = filterVulnerabilitiesByIgnores(scanResults, matcher, time.Now())
-	scanResults.ScanAnalytics = scanAnalytics
+	if p.BaselinePath != "" {
+		knownVulnerabilities, err := p.readBaseline()
+		if err != nil {
+			return nil, fmt.Errorf("read baseline: %v", err)
+		}
+
+		scanResults = filterVulnerabilitiesByBaseline(scanResults, knownVulnerabilities)
+	}
 
 	if p.Report {
 		p.Logger.Info().Msgf("share results: project name = %v", projectName)
@@ -113,8 +174,6 @@ func (p *ResultsProcessor) ProcessResults(rawResults *engine.Results, scanAnalyt
 				return nil, fmt.Errorf("share results to Registry failed on reading the policy file: %v", err)
 			}
 
-			shareResults := results.FromEngineResults(rawResults, false)
-
 			output, err := p.SnykPlatform.ShareResultsRegistry(context.Background(), shareResults, opts, string(policyFile))
 			if err != nil {
 				return nil, fmt.Errorf("share results: %v", err)
@@ -134,13 +193,61 @@ func (p *ResultsProcessor) ProcessResults(rawResults *engine.Results, scanAnalyt
 	return scanResults, nil
 }
 
-func (p *ResultsProcessor) createIgnoreMatcher() (*ignore.Matcher, error) {
+// readInlineIgnores reads the inline ignores of the source files. Anyone who
+// can edit the source files can add an inline ignore, so they are not read
+// when only administrators can ignore issues. Like the policy file, they are
+// not read when DisableInlineIgnores is set by --ignore-policy.
+func (p *ResultsProcessor) readInlineIgnores(r *results.Results, userSettings *settings.Settings) []ignore.InlineIgnore {
+	if p.DisableInlineIgnores {
+		return nil
+	}
+
+	if userSettings.IgnoreSettings.AdminOnly {
+		p.Logger.Info().Msg("skip inline ignores: only administrators can ignore issues")
+		return nil
+	}
+
+	readFile := p.ReadFile
+	if readFile == nil {
+		readFile = os.ReadFile
+	}
+
+	return readInlineIgnores(r, readFile, userSettings.IgnoreSettings.ReasonRequired, p.Logger)
+}
+
+// createIgnoreMatcher creates a matcher from the rules in the policy file. If
+// reasonRequired is true, rules without a reason are rejected.
+// createIgnoreMatcher also returns the rules added to the matcher.
+func (p *ResultsProcessor) createIgnoreMatcher(reasonRequired bool) (*ignore.Matcher, []ignore.Rule, error) {
 	data, err := p.readPolicyFile()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return ignore.NewMatcherFromPolicy(data)
+	rules, err := ignore.ParsePolicy(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		matcher  ignore.Matcher
+		accepted []ignore.Rule
+	)
+
+	for _, rule := range rules {
+		if reasonRequired && strings.TrimSpace(rule.Reason) == "" {
+			p.Logger.Warn().Msgf("reject ignore rule for %s with path %q: a reason is required", rule.ID, rule.Pattern)
+			continue
+		}
+
+		if err := matcher.AddRule(rule); err != nil {
+			return nil, nil, fmt.Errorf("add policy rule: %v", err)
+		}
+
+		accepted = append(accepted, rule)
+	}
+
+	return &matcher, accepted, nil
 }
 
 func (p *ResultsProcessor) readPolicyFile() ([]byte, error) {
@@ -155,3 +262,40 @@ func (p *ResultsProcessor) readPolicyFile() ([]byte, error) {
 
 	return data, err
 }
+
+func (p *ResultsProcessor) readSeverityOverrides() (map[string]string, error) {
+	if p.SeverityOverridesPath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(p.SeverityOverridesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return severity.ParseOverrides(data)
+}
+
+func (p *ResultsProcessor) readBaseline() (*baseline.Baseline, error) {
+	data, err := os.ReadFile(p.BaselinePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return baseline.Read(data)
+}
+
+func (p *ResultsProcessor) writeBaseline(vulnerabilities []results.Vulnerability) (e error) {
+	f, err := os.Create(p.BaselineWritePath)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil && e == nil {
+			e = fmt.Errorf("close baseline file: %v", err)
+		}
+	}()
+
+	return baseline.New(vulnerabilities).Write(f)
+}
@@ -3,6 +3,8 @@ package processor_test
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/rs/zerolog"
@@ -235,3 +237,246 @@ func TestWithShareResultsRegistryFailure(t *testing.T) {
 	require.Error(t, err)
 	require.Nil(t, results)
 }
+
+func TestSeverityPolicyAppliedToDisplayedAndSharedResults(t *testing.T) {
+	logger := zerolog.Nop()
+
+	overridesPath := filepath.Join(t.TempDir(), "severities.yaml")
+	require.NoError(t, os.WriteFile(overridesPath, []byte("severities:\n  SNYK-CC-TF-2: critical\n"), 0644))
+
+	var shared *results.Results
+
+	snykPlatform := mockSnykPlatform{
+		shareResultsRegistry: func(ctx context.Context, engineResults *results.Results, opts platform.ShareResultsOptions, policyFile string) (*platform.ShareResultsOutput, error) {
+			shared = engineResults
+			return &platform.ShareResultsOutput{ProjectIds: map[string]string{}}, nil
+		},
+	}
+
+	settingsReader := mockSettingsReader{
+		readSettings: func(ctx context.Context) (*settings.Settings, error) {
+			return &settings.Settings{
+				CustomSeverities: map[string]string{
+					"SNYK-CC-TF-1": "low",
+					"SNYK-CC-TF-2": "low",
+				},
+			}, nil
+		},
+	}
+
+	resultsProcessor := processor.ResultsProcessor{
+		SnykPlatform:          snykPlatform,
+		Report:                true,
+		SeverityThreshold:     "high",
+		SeverityOverridesPath: overridesPath,
+		GetWd:                 func() (string, error) { return "dir", nil },
+		GetRepoRootDir:        func(string) (string, error) { return "dir", nil },
+		GetOriginUrl:          func(string) (string, error) { return "", nil },
+		SettingsReader:        settingsReader,
+		IacNewEngine:          true,
+		Logger:                &logger,
+	}
+
+	ruleResults := func(id string) models.RuleResults {
+		return models.RuleResults{
+			Id: id,
+			Results: []models.RuleResult{
+				{
+					Passed:       false,
+					Severity:     "high",
+					ResourceId:   "aws_s3_bucket.bucket",
+					ResourceType: "aws_s3_bucket",
+					Resources: []*models.RuleResultResource{
+						{Id: "aws_s3_bucket.bucket", Type: "aws_s3_bucket"},
+					},
+				},
+			},
+		}
+	}
+
+	rawResults := &engine.Results{
+		Results: []models.Result{
+			{
+				Input: models.State{
+					Resources: map[string]map[string]models.ResourceState{
+						"aws_s3_bucket": {
+							"aws_s3_bucket.bucket": {Id: "aws_s3_bucket.bucket", ResourceType: "aws_s3_bucket"},
+						},
+					},
+				},
+				RuleResults: []models.RuleResults{
+					ruleResults("SNYK-CC-TF-1"),
+					ruleResults("SNYK-CC-TF-2"),
+				},
+			},
+		},
+	}
+
+	scanResults, err := resultsProcessor.ProcessResults(rawResults, results.ScanAnalytics{})
+	require.NoError(t, err)
+
+	// SNYK-CC-TF-1 is lowered by the organization and filtered by the
+	// threshold, SNYK-CC-TF-2 is raised by the local overrides.
+	require.Len(t, scanResults.Vulnerabilities, 1)
+	require.Equal(t, "SNYK-CC-TF-2", scanResults.Vulnerabilities[0].Rule.ID)
+	require.Equal(t, "critical", scanResults.Vulnerabilities[0].Severity)
+	require.Equal(t, "high", scanResults.Vulnerabilities[0].OriginalSeverity)
+	require.Equal(t, "local", scanResults.Vulnerabilities[0].SeveritySource)
+
+	// The shared results have the same severities, and are not filtered by
+	// the threshold.
+	require.NotNil(t, shared)
+	require.Len(t, shared.Vulnerabilities, 2)
+	require.Equal(t, "SNYK-CC-TF-1", shared.Vulnerabilities[0].Rule.ID)
+	require.Equal(t, "low", shared.Vulnerabilities[0].Severity)
+	require.Equal(t, scanResults.Vulnerabilities[0], shared.Vulnerabilities[1])
+
+	// The raw results have the same severities.
+	require.Equal(t, "low", rawResults.Results[0].RuleResults[0].Results[0].Severity)
+	require.Equal(t, "critical", rawResults.Results[0].RuleResults[1].Results[0].Severity)
+}
+
+func TestBaselineWrittenBeforeSeverityThreshold(t *testing.T) {
+	logger := zerolog.Nop()
+
+	baselinePath := filepath.Join(t.TempDir(), "baseline.json")
+
+	resultsProcessor := processor.ResultsProcessor{
+		SeverityThreshold: "high",
+		BaselineWritePath: baselinePath,
+		GetWd:             func() (string, error) { return "dir", nil },
+		GetRepoRootDir:    func(string) (string, error) { return "dir", nil },
+		GetOriginUrl:      func(string) (string, error) { return "", nil },
+		SettingsReader: mockSettingsReader{
+			readSettings: func(ctx context.Context) (*settings.Settings, error) {
+				return &settings.Settings{}, nil
+			},
+		},
+		Logger: &logger,
+	}
+
+	ruleResults := func(id string, severity string) models.RuleResults {
+		return models.RuleResults{
+			Id: id,
+			Results: []models.RuleResult{
+				{
+					Severity:     severity,
+					ResourceId:   "aws_s3_bucket.bucket",
+					ResourceType: "aws_s3_bucket",
+					Resources: []*models.RuleResultResource{
+						{Id: "aws_s3_bucket.bucket", Type: "aws_s3_bucket"},
+					},
+				},
+			},
+		}
+	}
+
+	scanResults, err := resultsProcessor.ProcessResults(&engine.Results{
+		Results: []models.Result{
+			{
+				Input: models.State{
+					Resources: map[string]map[string]models.ResourceState{
+						"aws_s3_bucket": {
+							"aws_s3_bucket.bucket": {Id: "aws_s3_bucket.bucket", ResourceType: "aws_s3_bucket"},
+						},
+					},
+				},
+				RuleResults: []models.RuleResults{
+					ruleResults("SNYK-CC-TF-1", "medium"),
+					ruleResults("SNYK-CC-TF-2", "high"),
+				},
+			},
+		},
+	}, results.ScanAnalytics{})
+	require.NoError(t, err)
+
+	require.Len(t, scanResults.Vulnerabilities, 1)
+	require.Equal(t, "SNYK-CC-TF-2", scanResults.Vulnerabilities[0].Rule.ID)
+
+	// The baseline records the vulnerability below the threshold too.
+	data, err := os.ReadFile(baselinePath)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"ruleId": "SNYK-CC-TF-1"`)
+	require.Contains(t, string(data), `"ruleId": "SNYK-CC-TF-2"`)
+}
+
+func TestInlineIgnores(t *testing.T) {
+	logger := zerolog.Nop()
+
+	rawResults := &engine.Results{
+		Results: []models.Result{
+			{
+				Input: models.State{
+					Resources: map[string]map[string]models.ResourceState{
+						"aws_s3_bucket": {
+							"aws_s3_bucket.bucket": {Id: "aws_s3_bucket.bucket", ResourceType: "aws_s3_bucket"},
+						},
+					},
+				},
+				RuleResults: []models.RuleResults{
+					{
+						Id: "SNYK-CC-TF-1",
+						Results: []models.RuleResult{
+							{
+								Severity:     "high",
+								ResourceId:   "aws_s3_bucket.bucket",
+								ResourceType: "aws_s3_bucket",
+								Resources: []*models.RuleResultResource{
+									{
+										Id:       "aws_s3_bucket.bucket",
+										Type:     "aws_s3_bucket",
+										Location: []models.SourceLocation{{Filepath: "main.tf", Line: 2}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	process := func(adminOnly bool, disableInlineIgnores bool) *results.Results {
+		resultsProcessor := processor.ResultsProcessor{
+			GetWd:          func() (string, error) { return "dir", nil },
+			GetRepoRootDir: func(string) (string, error) { return "dir", nil },
+			GetOriginUrl:   func(string) (string, error) { return "", nil },
+			SettingsReader: mockSettingsReader{
+				readSettings: func(ctx context.Context) (*settings.Settings, error) {
+					return &settings.Settings{
+						IgnoreSettings: settings.IgnoreSettings{AdminOnly: adminOnly},
+					}, nil
+				},
+			},
+			DisableInlineIgnores: disableInlineIgnores,
+			ReadFile: func(string) ([]byte, error) {
+				return []byte("# snyk:ignore SNYK-CC-TF-1 reason=accepted\nresource \"aws_s3_bucket\" \"bucket\" {}\n"), nil
+			},
+			Logger: &logger,
+		}
+
+		scanResults, err := resultsProcessor.ProcessResults(rawResults, results.ScanAnalytics{})
+		require.NoError(t, err)
+
+		return scanResults
+	}
+
+	t.Run("applied", func(t *testing.T) {
+		scanResults := process(false, false)
+		require.Empty(t, scanResults.Vulnerabilities)
+		require.Len(t, scanResults.IgnoredVulnerabilities, 1)
+		require.Empty(t, scanResults.StaleIgnores)
+	})
+
+	t.Run("only administrators can ignore issues", func(t *testing.T) {
+		scanResults := process(true, false)
+		require.Len(t, scanResults.Vulnerabilities, 1)
+		require.Empty(t, scanResults.IgnoredVulnerabilities)
+	})
+
+	t.Run("ignore policy", func(t *testing.T) {
+		scanResults := process(false, true)
+		require.Len(t, scanResults.Vulnerabilities, 1)
+		require.Empty(t, scanResults.IgnoredVulnerabilities)
+	})
+}
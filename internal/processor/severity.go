@@ -20,6 +20,8 @@ var severityLevelByName = map[string]int{
 	"critical": severityLevelCritical,
 }
 
+// filterBySeverityThreshold removes the vulnerabilities, and the ignored
+// vulnerabilities, whose severity is below the threshold.
 func filterBySeverityThreshold(scanResults *results.Results, severityThreshold string) *results.Results {
 	if scanResults == nil {
 		return nil
@@ -27,19 +29,28 @@ func filterBySeverityThreshold(scanResults *results.Results, severityThreshold s
 
 	severityThresholdLevel := severityLevelByName[severityThreshold]
 
-	var filteredVulnerabilities []results.Vulnerability
+	filter := func(vulnerabilities []results.Vulnerability) []results.Vulnerability {
+		var filteredVulnerabilities []results.Vulnerability
 
-	for _, vulnerability := range scanResults.Vulnerabilities {
-		severityLevel, ok := severityLevelByName[vulnerability.Severity]
-		if !ok {
-			continue
-		}
-		if severityLevel >= severityThresholdLevel {
-			filteredVulnerabilities = append(filteredVulnerabilities, vulnerability)
+		for _, vulnerability := range vulnerabilities {
+			severityLevel, ok := severityLevelByName[vulnerability.Severity]
+			if !ok {
+				continue
+			}
+			if severityLevel >= severityThresholdLevel {
+				filteredVulnerabilities = append(filteredVulnerabilities, vulnerability)
+			}
 		}
+
+		return filteredVulnerabilities
 	}
 
-	scanResults.Vulnerabilities = filteredVulnerabilities
+	scanResults.Vulnerabilities = filter(scanResults.Vulnerabilities)
+
+	if scanResults.IgnoredVulnerabilities != nil {
+		scanResults.IgnoredVulnerabilities = filter(scanResults.IgnoredVulnerabilities)
+		scanResults.Metadata.IgnoredCount = len(scanResults.IgnoredVulnerabilities)
+	}
 
 	return scanResults
 }
@@ -428,3 +428,24 @@ func TestApplyCustomSeveritiesRawResults(t *testing.T) {
 		})
 	}
 }
+
+func TestApplySeverityThresholdToIgnoredVulnerabilities(t *testing.T) {
+	output := filterBySeverityThreshold(&results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			{Rule: results.Rule{ID: "rule-1"}, Severity: "high"},
+		},
+		IgnoredVulnerabilities: []results.Vulnerability{
+			{Rule: results.Rule{ID: "rule-2"}, Severity: "low", Ignored: true},
+			{Rule: results.Rule{ID: "rule-3"}, Severity: "critical", Ignored: true},
+		},
+		Metadata: results.Metadata{IgnoredCount: 2},
+	}, "high")
+
+	require.Equal(t, []results.Vulnerability{
+		{Rule: results.Rule{ID: "rule-1"}, Severity: "high"},
+	}, output.Vulnerabilities)
+	require.Equal(t, []results.Vulnerability{
+		{Rule: results.Rule{ID: "rule-3"}, Severity: "critical", Ignored: true},
+	}, output.IgnoredVulnerabilities)
+	require.Equal(t, 1, output.Metadata.IgnoredCount)
+}
@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/snyk/cli-extension-iac/internal/ignore"
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+// findStaleIgnores returns the ignore rules and the inline ignores that are
+// expired, and the ones that don't match any of the vulnerabilities. An inline
+// ignore is reported once for every rule ID, with the file and the line of its
+// comment as path.
+func findStaleIgnores(rules []ignore.Rule, inlineIgnores []ignore.InlineIgnore, vulnerabilities []results.Vulnerability, now time.Time) []results.StaleIgnore {
+	var stale []results.StaleIgnore
+
+	for _, rule := range rules {
+		staleIgnore := results.StaleIgnore{
+			RuleID: rule.ID,
+			Path:   rule.Pattern,
+			Reason: rule.Reason,
+		}
+
+		if !rule.Expires.IsZero() {
+			expires := rule.Expires
+			staleIgnore.Expires = &expires
+		}
+
+		if !rule.Expires.IsZero() && !now.Before(rule.Expires) {
+			staleIgnore.Expired = true
+			stale = append(stale, staleIgnore)
+			continue
+		}
+
+		if !matchesAny(rule, vulnerabilities, now) {
+			stale = append(stale, staleIgnore)
+		}
+	}
+
+	for _, inlineIgnore := range inlineIgnores {
+		for _, id := range inlineIgnore.RuleIDs {
+			staleIgnore := results.StaleIgnore{
+				RuleID: id,
+				Path:   fmt.Sprintf("%s:%d", inlineIgnore.File, inlineIgnore.Line),
+				Reason: inlineIgnore.Reason,
+			}
+
+			if !inlineIgnore.Expires.IsZero() {
+				expires := inlineIgnore.Expires
+				staleIgnore.Expires = &expires
+			}
+
+			if inlineIgnore.Expired(now) {
+				staleIgnore.Expired = true
+				stale = append(stale, staleIgnore)
+				continue
+			}
+
+			if !inlineMatchesAny(id, inlineIgnore, vulnerabilities, now) {
+				stale = append(stale, staleIgnore)
+			}
+		}
+	}
+
+	return stale
+}
+
+func inlineMatchesAny(id string, inlineIgnore ignore.InlineIgnore, vulnerabilities []results.Vulnerability, now time.Time) bool {
+	var matcher ignore.InlineMatcher
+
+	matcher.AddIgnores(inlineIgnore)
+
+	for _, v := range vulnerabilities {
+		if v.Rule.ID != id {
+			continue
+		}
+
+		if _, ok := matcher.Match(id, now, vulnerabilityLocations(v)...); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesAny(rule ignore.Rule, vulnerabilities []results.Vulnerability, now time.Time) bool {
+	var matcher ignore.Matcher
+
+	if err := matcher.AddRule(rule); err != nil {
+		return false
+	}
+
+	for _, v := range vulnerabilities {
+		if matcher.Match(v.Rule.ID, now, ignore.PathParts(v.Resource.File, v.Resource.FormattedPath)...) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/ignore"
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+func TestFindStaleIgnores(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	vulnerabilities := []results.Vulnerability{
+		{
+			Rule: results.Rule{ID: "RULE-1"},
+			Resource: results.Resource{
+				File:          "main.tf",
+				FormattedPath: "resource.aws_s3_bucket[public]",
+			},
+		},
+	}
+
+	rules := []ignore.Rule{
+		{ID: "RULE-1", Pattern: "main.tf > *", Reason: "matched"},
+		{ID: "RULE-1", Pattern: "other.tf > *", Reason: "other file"},
+		{ID: "RULE-2", Pattern: "*", Reason: "unknown rule", Expires: future},
+		{ID: "RULE-1", Pattern: "*", Reason: "expired", Expires: expired},
+	}
+
+	require.Equal(t, []results.StaleIgnore{
+		{RuleID: "RULE-1", Path: "other.tf > *", Reason: "other file"},
+		{RuleID: "RULE-2", Path: "*", Reason: "unknown rule", Expires: &future},
+		{RuleID: "RULE-1", Path: "*", Reason: "expired", Expires: &expired, Expired: true},
+	}, findStaleIgnores(rules, nil, vulnerabilities, now))
+}
+
+func TestFindStaleInlineIgnores(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+
+	vulnerabilities := []results.Vulnerability{
+		{
+			Rule: results.Rule{ID: "RULE-1"},
+			Resource: results.Resource{
+				File: "main.tf",
+				Line: 2,
+			},
+		},
+	}
+
+	inlineIgnores := []ignore.InlineIgnore{
+		{RuleIDs: []string{"RULE-1", "RULE-2"}, Reason: "matched", File: "main.tf", Line: 1, TargetLine: 2},
+		{RuleIDs: []string{"RULE-1"}, Reason: "other line", File: "main.tf", Line: 5, TargetLine: 6},
+		{RuleIDs: []string{"RULE-1"}, Reason: "expired", Expires: expired, File: "main.tf", Line: 2, TargetLine: 2},
+	}
+
+	require.Equal(t, []results.StaleIgnore{
+		{RuleID: "RULE-2", Path: "main.tf:1", Reason: "matched"},
+		{RuleID: "RULE-1", Path: "main.tf:5", Reason: "other line"},
+		{RuleID: "RULE-1", Path: "main.tf:2", Reason: "expired", Expires: &expired, Expired: true},
+	}, findStaleIgnores(nil, inlineIgnores, vulnerabilities, now))
+}
+
+func TestFindStaleIgnoresWithoutRules(t *testing.T) {
+	require.Nil(t, findStaleIgnores(nil, nil, nil, time.Now()))
+}
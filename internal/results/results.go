@@ -6,16 +6,27 @@ import (
 	"github.com/snyk/policy-engine/pkg/models"
 	"regexp"
 	"strings"
+	"time"
 
 	engine "github.com/snyk/cli-extension-iac/internal/policyengine"
 )
 
 type Results struct {
-	Resources             []Resource      `json:"resources,omitempty"`
-	Vulnerabilities       []Vulnerability `json:"vulnerabilities,omitempty"`
-	PassedVulnerabilities []Vulnerability `json:"passedVulnerabilities,omitempty"`
-	Metadata              Metadata        `json:"metadata"`
-	ScanAnalytics         ScanAnalytics   `json:"scanAnalytics"`
+	Resources               []Resource      `json:"resources,omitempty"`
+	Vulnerabilities         []Vulnerability `json:"vulnerabilities,omitempty"`
+	PassedVulnerabilities   []Vulnerability `json:"passedVulnerabilities,omitempty"`
+	BaselineVulnerabilities []Vulnerability `json:"baselineVulnerabilities,omitempty"`
+	IgnoredVulnerabilities  []Vulnerability `json:"ignoredVulnerabilities,omitempty"`
+	Metadata                Metadata        `json:"metadata"`
+	ScanAnalytics           ScanAnalytics   `json:"scanAnalytics"`
+
+	// StaleIgnores are reported as warnings instead of being part of the
+	// results.
+	StaleIgnores []StaleIgnore `json:"-"`
+
+	// RuleControls maps the ID of every evaluated rule to its controls. Unlike
+	// Rule.Controls, it also contains the controls of the Snyk rules.
+	RuleControls map[string][]string `json:"-"`
 }
 
 type Metadata struct {
@@ -32,6 +43,12 @@ type Vulnerability struct {
 	Ignored     bool                   `json:"ignored"`
 	Resource    Resource               `json:"resource"`
 	Context     map[string]interface{} `json:"context,omitempty"`
+	Ignore      *Ignore                `json:"ignore,omitempty"`
+
+	// OriginalSeverity is the severity of the rule, and SeveritySource the
+	// source of the override, if the severity was overridden.
+	OriginalSeverity string `json:"originalSeverity,omitempty"`
+	SeveritySource   string `json:"severitySource,omitempty"`
 }
 
 type Rule struct {
@@ -57,6 +74,11 @@ type Resource struct {
 	Column         int               `json:"column,omitempty"`
 	Tags           map[string]string `json:"tags,omitempty"`
 	SourceLocation []Location        `json:"sourceLocation,omitempty"`
+
+	// Stack and ConstructPath identify the CDK construct that defines a
+	// resource loaded from a cloud assembly.
+	Stack         string `json:"stack,omitempty"`
+	ConstructPath string `json:"constructPath,omitempty"`
 }
 
 type Location struct {
@@ -65,6 +87,44 @@ type Location struct {
 	Column int    `json:"column,omitempty"`
 }
 
+// Ignore describes the ignore that caused a vulnerability to be ignored.
+type Ignore struct {
+	Source    string     `json:"source"`
+	Path      string     `json:"path,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	Expires   *time.Time `json:"expires,omitempty"`
+	Created   *time.Time `json:"created,omitempty"`
+	IgnoredBy *IgnoredBy `json:"ignoredBy,omitempty"`
+	Location  *Location  `json:"location,omitempty"`
+}
+
+// IgnoredBy identifies the user who ignored a vulnerability.
+type IgnoredBy struct {
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+const (
+	// IgnoreSourcePolicy identifies ignores declared in the .snyk policy file.
+	IgnoreSourcePolicy = "policy"
+
+	// IgnoreSourceInline identifies ignores declared by comments in source
+	// files.
+	IgnoreSourceInline = "inline"
+)
+
+// StaleIgnore is an ignore rule of the policy file, or an inline ignore, that
+// is expired or that didn't match any vulnerability. The Path of an inline
+// ignore is the file and the line of its comment.
+type StaleIgnore struct {
+	RuleID  string
+	Path    string
+	Reason  string
+	Expires *time.Time
+	Expired bool
+}
+
 type ScanAnalytics struct {
 	SuppressedResults map[string][]string `json:"suppressedResults,omitempty"`
 }
@@ -82,7 +142,22 @@ func FromEngineResults(results *engine.Results, includePassed bool) *Results {
 		Resources:             resourcesFromEngineResults(results),
 		Vulnerabilities:       failedVulnerabilities,
 		PassedVulnerabilities: passedVulnerabilities,
+		RuleControls:          ruleControlsFromEngineResults(results),
+	}
+}
+
+func ruleControlsFromEngineResults(results *engine.Results) map[string][]string {
+	controls := make(map[string][]string)
+
+	for _, result := range results.Results {
+		for _, ruleResults := range result.RuleResults {
+			if len(ruleResults.Controls) > 0 {
+				controls[ruleResults.Id] = ruleResults.Controls
+			}
+		}
 	}
+
+	return controls
 }
 
 var inputKindByInputType = map[string]string{
@@ -208,11 +283,12 @@ func vulnerabilitiesFromEngineResults(results *engine.Results, includePassed boo
 					}
 					vulnerability.Resource.SourceLocation = sourceLocation
 
-					if result.Input.Resources != nil {
-						if resourcesByType, typeExists := result.Input.Resources[resource.Type]; typeExists {
-							if resourceDetails, idExists := resourcesByType[resource.Id]; idExists {
-								vulnerability.Resource.Tags = resourceDetails.Tags
-							}
+					if resourceDetails, ok := inputResource(result.Input.Resources, resource); ok {
+						vulnerability.Resource.Tags = resourceDetails.Tags
+
+						if stack, path, ok := engine.CDKConstruct(resourceDetails); ok {
+							vulnerability.Resource.Stack = stack
+							vulnerability.Resource.ConstructPath = path
 						}
 					}
 
@@ -258,6 +334,25 @@ func vulnerabilitiesFromEngineResults(results *engine.Results, includePassed boo
 	return
 }
 
+// inputResource returns the input resource of a rule result. Resources are
+// usually keyed by their ID, but the keys of the resources that share an ID
+// with another resource of the same input are qualified by their namespace.
+func inputResource(resources map[string]map[string]models.ResourceState, resource *models.RuleResultResource) (models.ResourceState, bool) {
+	byKey := resources[resource.Type]
+
+	if r, ok := byKey[resource.Id]; ok && r.Namespace == resource.Namespace {
+		return r, true
+	}
+
+	for _, r := range byKey {
+		if r.Id == resource.Id && r.Namespace == resource.Namespace {
+			return r, true
+		}
+	}
+
+	return models.ResourceState{}, false
+}
+
 func resourcesFromEngineResults(results *engine.Results) []Resource {
 	var resources []Resource
 
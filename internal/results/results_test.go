@@ -497,6 +497,73 @@ func TestResultsVulnerabilities(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "vulnerable result of a CDK construct",
+			input: &engine.Results{
+				Results: []models.Result{
+					{
+						Input: models.State{
+							InputType: "cfn",
+							Resources: map[string]map[string]models.ResourceState{
+								"AWS::S3::Bucket": {
+									"Bucket": {
+										Id:           "Bucket",
+										Namespace:    "cdk.out/App.template.json",
+										ResourceType: "AWS::S3::Bucket",
+										Meta: map[string]any{
+											"cdk": map[string]any{"stack": "App", "path": "App/Bucket/Resource"},
+										},
+									},
+									"cdk.out/Api.template.json:Bucket": {
+										Id:           "Bucket",
+										Namespace:    "cdk.out/Api.template.json",
+										ResourceType: "AWS::S3::Bucket",
+										Meta: map[string]any{
+											"cdk": map[string]any{"stack": "Api", "path": "Api/Bucket/Resource"},
+										},
+									},
+								},
+							},
+						},
+						RuleResults: []models.RuleResults{
+							{
+								Id: "SNYK-RULE-ID",
+								Results: []models.RuleResult{
+									{
+										ResourceId:        "Bucket",
+										ResourceType:      "AWS::S3::Bucket",
+										ResourceNamespace: "cdk.out/Api.template.json",
+										Resources: []*models.RuleResultResource{
+											{
+												Id:        "Bucket",
+												Type:      "AWS::S3::Bucket",
+												Namespace: "cdk.out/Api.template.json",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			output: []results.Vulnerability{
+				{
+					Rule: results.Rule{
+						ID:            "SNYK-RULE-ID",
+						Documentation: "https://security.snyk.io/rules/cloud/SNYK-RULE-ID",
+					},
+					Resource: results.Resource{
+						ID:            "Bucket",
+						Type:          "AWS::S3::Bucket",
+						FormattedPath: "resource.Bucket",
+						Kind:          "cloudformationconfig",
+						Stack:         "Api",
+						ConstructPath: "Api/Bucket/Resource",
+					},
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -867,3 +934,32 @@ func TestResultsResource(t *testing.T) {
 		})
 	}
 }
+
+func TestResultsRuleControls(t *testing.T) {
+	input := &engine.Results{
+		Results: []models.Result{
+			{
+				RuleResults: []models.RuleResults{
+					{
+						Id:       "SNYK-CC-TF-1",
+						Controls: []string{"CIS-AWS_v1.4.0_2.1.5"},
+					},
+					{
+						Id:       "CUSTOM-1",
+						Controls: []string{"SOC2_CC6.1"},
+					},
+					{
+						Id: "SNYK-CC-TF-2",
+					},
+				},
+			},
+		},
+	}
+
+	expected := map[string][]string{
+		"SNYK-CC-TF-1": {"CIS-AWS_v1.4.0_2.1.5"},
+		"CUSTOM-1":     {"SOC2_CC6.1"},
+	}
+
+	require.Equal(t, expected, results.FromEngineResults(input, false).RuleControls)
+}
@@ -0,0 +1,295 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultManifestTTL is how long a cached manifest is used before it is
+// downloaded again.
+const DefaultManifestTTL = time.Hour
+
+// CachedClient downloads rule bundles with a Client and stores them in a cache
+// directory, keyed by bundle version. The checksum and the signature of a
+// cached bundle are verified every time the bundle is read. The manifest
+// is downloaded again when it is older than ManifestTTL. If the manifest or
+// the bundle can't be downloaded, DownloadLatestBundle falls back to the last
+// bundle that was successfully downloaded. If Dir is empty, nothing is cached.
+type CachedClient struct {
+	Client      *Client
+	Dir         string
+	ManifestTTL time.Duration
+	Now         func() time.Time
+	Logger      *zerolog.Logger
+}
+
+type cachedManifest struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Manifest  manifest  `json:"manifest"`
+}
+
+type lastGoodBundle struct {
+	Version string         `json:"version"`
+	Bundle  bundleMetadata `json:"bundle"`
+}
+
+func (c *CachedClient) DownloadLatestBundle(currentEngineVersion string, w io.Writer) error {
+	if c.Dir == "" {
+		return c.Client.DownloadLatestBundle(currentEngineVersion, w)
+	}
+
+	manifest, err := c.manifest(compatibleWith(currentEngineVersion))
+	if err != nil {
+		return c.fallback(fmt.Errorf("download manifest: %v", err), w)
+	}
+
+	bundleVersion, err := determineBundleVersion(currentEngineVersion, manifest)
+	if err != nil {
+		return fmt.Errorf("determine bundle version: %v", err)
+	}
+
+	bundle := manifest.Versions[bundleVersion]
+
+	data, err := c.bundle(bundleVersion, bundle)
+	if errors.Is(err, ErrInvalidSignature) {
+		// A bundle with an invalid signature might have been tampered with,
+		// and must be reported instead of silently replaced.
+		return fmt.Errorf("download bundle: %w", err)
+	}
+	if err != nil {
+		return c.fallback(fmt.Errorf("download bundle: %v", err), w)
+	}
+
+	if err := c.writeLastGoodBundle(lastGoodBundle{Version: bundleVersion, Bundle: bundle}); err != nil {
+		c.Logger.Warn().Err(err).Msg("write last good bundle")
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write bundle: %v", err)
+	}
+
+	return nil
+}
+
+func (c *CachedClient) DownloadPinnedBundle(pinnedBundleVersion string, currentEngineVersion string, w io.Writer) error {
+	if c.Dir == "" {
+		return c.Client.DownloadPinnedBundle(pinnedBundleVersion, currentEngineVersion, w)
+	}
+
+	manifest, err := c.manifest(func(m manifest) error {
+		_, err := pinnedBundleMetadata(pinnedBundleVersion, currentEngineVersion, m)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("download manifest: %v", err)
+	}
+
+	bundle, err := pinnedBundleMetadata(pinnedBundleVersion, currentEngineVersion, manifest)
+	if err != nil {
+		return err
+	}
+
+	data, err := c.bundle(pinnedBundleVersion, bundle)
+	if err != nil {
+		return fmt.Errorf("download bundle: %w", err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write bundle: %v", err)
+	}
+
+	return nil
+}
+
+// manifest returns the cached manifest if it is fresh and accepted by
+// validate, and downloads it otherwise. A stale manifest is used if the
+// download fails.
+func (c *CachedClient) manifest(validate func(manifest) error) (manifest, error) {
+	cached, cacheErr := c.readCachedManifest()
+	if cacheErr == nil {
+		cacheErr = validate(cached.Manifest)
+	}
+
+	if cacheErr == nil && c.now().Sub(cached.FetchedAt) < c.ManifestTTL {
+		return cached.Manifest, nil
+	}
+
+	downloaded, err := c.Client.downloadManifest(validate)
+	if err != nil {
+		if cacheErr == nil {
+			c.Logger.Warn().Err(err).Msgf("using the manifest cached at %s", cached.FetchedAt.Format(time.RFC3339))
+			return cached.Manifest, nil
+		}
+
+		return manifest{}, err
+	}
+
+	if err := c.writeCachedManifest(cachedManifest{FetchedAt: c.now(), Manifest: downloaded}); err != nil {
+		c.Logger.Warn().Err(err).Msg("write cached manifest")
+	}
+
+	return downloaded, nil
+}
+
+// bundle returns the content of the bundle from the cache if its checksum and
+// signature are valid, and downloads it from the mirrors otherwise.
+func (c *CachedClient) bundle(version string, bundle bundleMetadata) ([]byte, error) {
+	path, err := c.bundlePath(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		err := c.Client.verify(version, bundle, data)
+		if err == nil {
+			c.Logger.Info().Msgf("using the cached bundle %s", version)
+			return data, nil
+		}
+
+		c.Logger.Warn().Err(err).Msgf("invalid cached bundle %s", version)
+	}
+
+	var buffer bytes.Buffer
+
+	if err := c.Client.downloadBundleFromMirrors(version, bundle, &buffer); err != nil {
+		return nil, err
+	}
+
+	if err := writeFile(path, buffer.Bytes()); err != nil {
+		c.Logger.Warn().Err(err).Msgf("write cached bundle %s", version)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// fallback writes the last good bundle to w. If there is no valid last good
+// bundle, fallback returns cause.
+func (c *CachedClient) fallback(cause error, w io.Writer) error {
+	lastGood, err := c.readLastGoodBundle()
+	if err != nil {
+		return cause
+	}
+
+	path, err := c.bundlePath(lastGood.Version)
+	if err != nil {
+		return cause
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cause
+	}
+
+	if err := c.Client.verify(lastGood.Version, lastGood.Bundle, data); err != nil {
+		return cause
+	}
+
+	c.Logger.Warn().Err(cause).Msgf("using the last good bundle %s", lastGood.Version)
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write bundle: %v", err)
+	}
+
+	return nil
+}
+
+func (c *CachedClient) bundlePath(version string) (string, error) {
+	if version == "" || version != filepath.Base(version) || strings.HasPrefix(version, ".") {
+		return "", fmt.Errorf("invalid bundle version: %q", version)
+	}
+
+	return filepath.Join(c.Dir, "bundles", version+".tar.gz"), nil
+}
+
+func (c *CachedClient) readCachedManifest() (cachedManifest, error) {
+	var cached cachedManifest
+
+	if err := readJSON(filepath.Join(c.Dir, "versions.json"), &cached); err != nil {
+		return cachedManifest{}, err
+	}
+
+	return cached, nil
+}
+
+func (c *CachedClient) writeCachedManifest(cached cachedManifest) error {
+	return writeJSON(filepath.Join(c.Dir, "versions.json"), cached)
+}
+
+func (c *CachedClient) readLastGoodBundle() (lastGoodBundle, error) {
+	var lastGood lastGoodBundle
+
+	if err := readJSON(filepath.Join(c.Dir, "last-good.json"), &lastGood); err != nil {
+		return lastGoodBundle{}, err
+	}
+
+	return lastGood, nil
+}
+
+func (c *CachedClient) writeLastGoodBundle(lastGood lastGoodBundle) error {
+	return writeJSON(filepath.Join(c.Dir, "last-good.json"), lastGood)
+}
+
+func (c *CachedClient) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+
+	return time.Now()
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal: %v", err)
+	}
+
+	return writeFile(path, data)
+}
+
+// writeFile writes data to a temporary file that is then renamed to path, so
+// that a concurrent reader never sees a partially written file.
+func writeFile(path string, data []byte) (e error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("create directory: %v", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %v", err)
+	}
+
+	defer func() {
+		if e != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write temporary file: %v", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close temporary file: %v", err)
+	}
+
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,254 @@
+package rules
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+type cacheTestServer struct {
+	server           *httptest.Server
+	preferredVersion string
+	fail             atomic.Bool
+	failBundles      atomic.Bool
+	manifestRequests atomic.Int32
+	bundleRequests   atomic.Int32
+}
+
+func newCacheTestServer(t *testing.T, preferredVersion string) *cacheTestServer {
+	s := cacheTestServer{
+		preferredVersion: preferredVersion,
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if r.URL.Path == "/versions.json" {
+			s.manifestRequests.Add(1)
+		} else {
+			s.bundleRequests.Add(1)
+
+			if s.failBundles.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		writeVersionsMock(w, r, s.server, s.preferredVersion)
+
+		if filepath.Base(r.URL.Path) == "bundle.tar.gz" {
+			_, _ = w.Write(bundle)
+		}
+	}))
+
+	t.Cleanup(s.server.Close)
+
+	return &s
+}
+
+func newCachedClient(s *cacheTestServer, dir string, now *time.Time) *CachedClient {
+	logger := zerolog.Nop()
+
+	return &CachedClient{
+		Client: &Client{
+			HTTPClient:                s.server.Client(),
+			SkipSignatureVerification: true,
+			URL:                       s.server.URL,
+		},
+		Dir:         dir,
+		ManifestTTL: time.Hour,
+		Now:         func() time.Time { return *now },
+		Logger:      &logger,
+	}
+}
+
+func TestCachedClient_DownloadLatestBundle(t *testing.T) {
+	s := newCacheTestServer(t, "v0.3.5")
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := newCachedClient(s, dir, &now)
+
+	var b bytes.Buffer
+	require.NoError(t, client.DownloadLatestBundle("v0.4.1", &b))
+	require.Equal(t, bundle, b.Bytes())
+	require.Equal(t, int32(1), s.manifestRequests.Load())
+	require.Equal(t, int32(1), s.bundleRequests.Load())
+	require.FileExists(t, filepath.Join(dir, "bundles", "v0.3.5.tar.gz"))
+
+	// The manifest and the bundle are read from the cache.
+	b.Reset()
+	require.NoError(t, client.DownloadLatestBundle("v0.4.1", &b))
+	require.Equal(t, bundle, b.Bytes())
+	require.Equal(t, int32(1), s.manifestRequests.Load())
+	require.Equal(t, int32(1), s.bundleRequests.Load())
+
+	// The manifest is refreshed after the TTL, the bundle is still cached.
+	now = now.Add(2 * time.Hour)
+	b.Reset()
+	require.NoError(t, client.DownloadLatestBundle("v0.4.1", &b))
+	require.Equal(t, bundle, b.Bytes())
+	require.Equal(t, int32(2), s.manifestRequests.Load())
+	require.Equal(t, int32(1), s.bundleRequests.Load())
+}
+
+func TestCachedClient_InvalidCachedBundle(t *testing.T) {
+	s := newCacheTestServer(t, "v0.3.5")
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := newCachedClient(s, dir, &now)
+
+	var b bytes.Buffer
+	require.NoError(t, client.DownloadLatestBundle("v0.4.1", &b))
+
+	path := filepath.Join(dir, "bundles", "v0.3.5.tar.gz")
+	require.NoError(t, os.WriteFile(path, []byte("corrupted"), 0600))
+
+	b.Reset()
+	require.NoError(t, client.DownloadLatestBundle("v0.4.1", &b))
+	require.Equal(t, bundle, b.Bytes())
+	require.Equal(t, int32(2), s.bundleRequests.Load())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, bundle, data)
+}
+
+func TestCachedClient_StaleManifest(t *testing.T) {
+	s := newCacheTestServer(t, "v0.3.5")
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := newCachedClient(s, dir, &now)
+
+	var b bytes.Buffer
+	require.NoError(t, client.DownloadLatestBundle("v0.4.1", &b))
+
+	s.fail.Store(true)
+	now = now.Add(2 * time.Hour)
+
+	b.Reset()
+	require.NoError(t, client.DownloadLatestBundle("v0.4.1", &b))
+	require.Equal(t, bundle, b.Bytes())
+}
+
+func TestCachedClient_LastGoodBundle(t *testing.T) {
+	s := newCacheTestServer(t, "v0.3.5")
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := newCachedClient(s, dir, &now)
+
+	var b bytes.Buffer
+	require.NoError(t, client.DownloadLatestBundle("v0.4.1", &b))
+
+	// A new preferred version is published, but its bundle can't be
+	// downloaded.
+	s.preferredVersion = "v0.31.0"
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, os.Remove(filepath.Join(dir, "versions.json")))
+
+	client.Client.HTTPClient = failingBundleClient{s.server.Client()}
+
+	b.Reset()
+	require.NoError(t, client.DownloadLatestBundle("v0.31.0", &b))
+	require.Equal(t, bundle, b.Bytes())
+
+	// The network is down and there is no cached manifest.
+	s.fail.Store(true)
+	require.NoError(t, os.Remove(filepath.Join(dir, "versions.json")))
+
+	b.Reset()
+	require.NoError(t, client.DownloadLatestBundle("v0.31.0", &b))
+	require.Equal(t, bundle, b.Bytes())
+}
+
+func TestCachedClient_BundleFromMirror(t *testing.T) {
+	primary := newCacheTestServer(t, "v0.3.5")
+	mirror := newCacheTestServer(t, "v0.3.5")
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := newCachedClient(primary, dir, &now)
+	client.Client.HTTPClient = http.DefaultClient
+	client.Client.Mirrors = []string{primary.server.URL, mirror.server.URL}
+
+	var b bytes.Buffer
+	require.NoError(t, client.DownloadLatestBundle("v0.4.1", &b))
+	require.Equal(t, int32(1), primary.bundleRequests.Load())
+
+	// The cached manifest points to the bundle of the primary, which is no
+	// longer available.
+	require.NoError(t, os.Remove(filepath.Join(dir, "bundles", "v0.3.5.tar.gz")))
+	primary.failBundles.Store(true)
+
+	b.Reset()
+	require.NoError(t, client.DownloadLatestBundle("v0.4.1", &b))
+	require.Equal(t, bundle, b.Bytes())
+	require.Equal(t, int32(2), primary.bundleRequests.Load())
+	require.Equal(t, int32(1), mirror.manifestRequests.Load())
+	require.Equal(t, int32(1), mirror.bundleRequests.Load())
+	require.FileExists(t, filepath.Join(dir, "bundles", "v0.3.5.tar.gz"))
+}
+
+func TestCachedClient_NoCache(t *testing.T) {
+	s := newCacheTestServer(t, "v0.3.5")
+	s.fail.Store(true)
+
+	now := time.Now()
+	client := newCachedClient(s, t.TempDir(), &now)
+
+	var b bytes.Buffer
+	require.Error(t, client.DownloadLatestBundle("v0.4.1", &b))
+	require.Zero(t, b.Len())
+}
+
+func TestCachedClient_DownloadPinnedBundle(t *testing.T) {
+	s := newCacheTestServer(t, "v0.3.5")
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := newCachedClient(s, dir, &now)
+
+	var b bytes.Buffer
+	require.NoError(t, client.DownloadPinnedBundle("v0.2.0-dev.20221007", "v0.4.0", &b))
+	require.Equal(t, bundle, b.Bytes())
+	require.FileExists(t, filepath.Join(dir, "bundles", "v0.2.0-dev.20221007.tar.gz"))
+
+	b.Reset()
+	require.NoError(t, client.DownloadPinnedBundle("v0.2.0-dev.20221007", "v0.4.0", &b))
+	require.Equal(t, int32(1), s.bundleRequests.Load())
+
+	require.Error(t, client.DownloadPinnedBundle("v9.9.9", "v0.4.0", &b))
+	require.Error(t, client.DownloadPinnedBundle("v0.31.0", "v0.4.0", &b))
+}
+
+func TestCachedClient_WithoutDir(t *testing.T) {
+	s := newCacheTestServer(t, "v0.3.5")
+
+	now := time.Now()
+	client := newCachedClient(s, "", &now)
+
+	var b bytes.Buffer
+	require.NoError(t, client.DownloadLatestBundle("v0.4.1", &b))
+	require.Equal(t, bundle, b.Bytes())
+}
+
+// failingBundleClient fails every request except the ones for the manifest.
+type failingBundleClient struct {
+	client HTTPClient
+}
+
+func (c failingBundleClient) Do(req *http.Request) (*http.Response, error) {
+	if req.URL.Path != "/versions.json" {
+		return &http.Response{StatusCode: http.StatusBadGateway, Body: http.NoBody}, nil
+	}
+
+	return c.client.Do(req)
+}
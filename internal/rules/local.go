@@ -0,0 +1,209 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pebundle "github.com/snyk/policy-engine/pkg/bundle"
+	"github.com/snyk/policy-engine/pkg/bundle/base"
+	v1 "github.com/snyk/policy-engine/pkg/bundle/v1"
+	"github.com/spf13/afero"
+)
+
+// ReadLocalBundle opens the custom rules at path, which is either a
+// directory of Rego files or a .tar.gz bundle. Errors in the content of the
+// rules are not reported here, but by the engine when the bundle is loaded.
+func ReadLocalBundle(fs afero.Fs, path string) (pebundle.Reader, error) {
+	info, err := fs.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat custom rules: %v", err)
+	}
+
+	if info.IsDir() {
+		return &dirRuleBundle{fs: fs, path: path}, nil
+	}
+
+	if !strings.HasSuffix(path, ".tar.gz") {
+		return nil, fmt.Errorf("custom rules must be a directory or a .tar.gz bundle: %s", path)
+	}
+
+	return readTarGzBundle(fs, path)
+}
+
+// ReadLocalBundles opens the custom rules at path, which is either a .tar.gz
+// bundle, a directory of .tar.gz bundles or a directory of Rego files. A
+// directory containing .tar.gz files is read as a directory of bundles, in the
+// lexical order of their file names.
+func ReadLocalBundles(fs afero.Fs, path string) ([]pebundle.Reader, error) {
+	info, err := fs.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat custom rules: %v", err)
+	}
+
+	if !info.IsDir() {
+		b, err := ReadLocalBundle(fs, path)
+		if err != nil {
+			return nil, err
+		}
+
+		return []pebundle.Reader{b}, nil
+	}
+
+	entries, err := afero.ReadDir(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("read custom rules directory: %v", err)
+	}
+
+	var bundles []pebundle.Reader
+
+	// ReadDir returns the entries sorted by name.
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() || !strings.HasSuffix(entry.Name(), ".tar.gz") {
+			continue
+		}
+
+		b, err := readTarGzBundle(fs, filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		bundles = append(bundles, b)
+	}
+
+	if len(bundles) == 0 {
+		return []pebundle.Reader{&dirRuleBundle{fs: fs, path: path}}, nil
+	}
+
+	return bundles, nil
+}
+
+func readTarGzBundle(fs afero.Fs, path string) (pebundle.Reader, error) {
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("read custom rule bundle: %v", err)
+	}
+
+	b, err := pebundle.NewTarGzReader(path, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("open custom rule bundle %s: %v", path, err)
+	}
+
+	return b, nil
+}
+
+const (
+	defaultManifestVersion = v1.VERSION
+	defaultManifest        = `{"bundle_format_version":"` + defaultManifestVersion + `"}`
+)
+
+// dirRuleBundle reads a bundle from a directory. Unlike the directory reader
+// of the policy-engine, it doesn't require a manifest: the directory might
+// just contain Rego files, in which case the files outside of the rules/ and
+// lib/ directories of the bundle layout are read as rules.
+type dirRuleBundle struct {
+	fs   afero.Fs
+	path string
+}
+
+func (r *dirRuleBundle) Info() base.SourceInfo {
+	return base.SourceInfo{
+		SourceType: pebundle.DIRECTORY,
+		FileInfo: base.FileInfo{
+			Path: r.path,
+		},
+	}
+}
+
+func (r *dirRuleBundle) WalkFiles(handler base.WalkFilesFunc) error {
+	hasManifest, err := r.hasManifest()
+	if err != nil {
+		return err
+	}
+
+	// The manifest of the bundle is read while walking the files, so the
+	// default manifest must be walked, too.
+	if !hasManifest {
+		if err := handler("manifest.json", strings.NewReader(defaultManifest)); err != nil {
+			return err
+		}
+	}
+
+	return afero.Walk(r.fs, r.path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(r.path, path)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+
+		if !hasManifest && strings.HasSuffix(rel, ".rego") && !strings.HasPrefix(rel, "rules/") && !strings.HasPrefix(rel, "lib/") {
+			rel = "rules/" + rel
+		}
+
+		f, err := r.fs.Open(path)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = f.Close() }()
+
+		return handler(rel, f)
+	})
+}
+
+func (r *dirRuleBundle) Manifest() (*base.Manifest, error) {
+	hasManifest, err := r.hasManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasManifest {
+		return &base.Manifest{BundleFormatVersion: defaultManifestVersion}, nil
+	}
+
+	f, err := r.fs.Open(r.manifestPath())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", r.path, pebundle.ErrUnableToReadManifest, err)
+	}
+
+	defer func() { _ = f.Close() }()
+
+	raw, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", r.path, pebundle.ErrUnableToReadManifest, err)
+	}
+
+	var manifest base.Manifest
+
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", r.path, pebundle.ErrUnableToReadManifest, err)
+	}
+
+	return &manifest, nil
+}
+
+func (r *dirRuleBundle) hasManifest() (bool, error) {
+	exists, err := afero.Exists(r.fs, r.manifestPath())
+	if err != nil {
+		return false, fmt.Errorf("%s: %w: %v", r.path, pebundle.ErrUnableToReadManifest, err)
+	}
+
+	return exists, nil
+}
+
+func (r *dirRuleBundle) manifestPath() string {
+	return filepath.Join(r.path, "manifest.json")
+}
@@ -0,0 +1,116 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultMaxAttempts is the number of times every mirror is tried if
+// Client.MaxAttempts is not set.
+const DefaultMaxAttempts = 3
+
+// ParseMirrors parses a comma-separated list of mirror URLs.
+func ParseMirrors(s string) []string {
+	var mirrors []string
+
+	for _, mirror := range strings.Split(s, ",") {
+		if mirror = strings.TrimSpace(mirror); mirror != "" {
+			mirrors = append(mirrors, strings.TrimSuffix(mirror, "/"))
+		}
+	}
+
+	return mirrors
+}
+
+// urls returns the URLs to download the rules from, in order. Duplicates and
+// empty URLs are ignored.
+func (c *Client) urls() []string {
+	var (
+		urls []string
+		seen = make(map[string]bool)
+	)
+
+	for _, url := range append(append([]string(nil), c.Mirrors...), c.URL) {
+		if url == "" || seen[url] {
+			continue
+		}
+
+		seen[url] = true
+		urls = append(urls, url)
+	}
+
+	return urls
+}
+
+// fromMirrors calls f with every mirror URL, in order, until f succeeds. If f
+// fails for every mirror, the mirrors are tried again after a delay, which is
+// doubled after every attempt. fromMirrors returns the errors of the last
+// attempt if f never succeeds.
+func (c *Client) fromMirrors(f func(url string) error) error {
+	urls := c.urls()
+	if len(urls) == 0 {
+		return fmt.Errorf("no rules URL configured")
+	}
+
+	attempts := c.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultMaxAttempts
+	}
+
+	backoff := c.Backoff
+
+	var errs []error
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 && backoff > 0 {
+			c.sleep(backoff)
+			backoff *= 2
+		}
+
+		errs = nil
+
+		for _, url := range urls {
+			err := f(url)
+			if err == nil {
+				c.logger().Info().Msgf("rules served by %s", url)
+				return nil
+			}
+
+			c.logger().Warn().Err(err).Msgf("rules not available from %s (attempt %d of %d)", url, attempt, attempts)
+			errs = append(errs, fmt.Errorf("%s: %w", url, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Client) sleep(d time.Duration) {
+	if c.Sleep != nil {
+		c.Sleep(d)
+		return
+	}
+
+	time.Sleep(d)
+}
+
+func (c *Client) logger() *zerolog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+
+	logger := zerolog.Nop()
+	return &logger
+}
+
+// compatibleWith returns a function that accepts a manifest if it contains a
+// bundle compatible with the policy-engine version.
+func compatibleWith(policyEngineVersion string) func(manifest) error {
+	return func(m manifest) error {
+		_, err := determineBundleVersion(policyEngineVersion, m)
+		return err
+	}
+}
@@ -0,0 +1,147 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMirrors(t *testing.T) {
+	require.Equal(t, []string{"https://a.example.com", "https://b.example.com/rules"}, ParseMirrors(" https://a.example.com/, https://b.example.com/rules ,,"))
+	require.Nil(t, ParseMirrors(""))
+}
+
+func TestClient_Mirrors(t *testing.T) {
+	var requests atomic.Int32
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	// The manifest of this mirror only has bundles for newer engines.
+	var outdated *httptest.Server
+	outdated = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/versions.json" {
+			_ = json.NewEncoder(w).Encode(manifest{
+				PreferredVersion: "v0.31.0",
+				Versions: map[string]bundleMetadata{
+					"v0.31.0": {
+						Checksum:               bundleChecksum(),
+						Url:                    outdated.URL + "/cli/iac/rules/v0.31.0/bundle.tar.gz",
+						MinPolicyEngineVersion: "0.30.9",
+					},
+				},
+			})
+		}
+		if strings.HasSuffix(r.URL.Path, "/bundle.tar.gz") {
+			t.Error("the bundle of an incompatible manifest must not be downloaded")
+		}
+	}))
+	defer outdated.Close()
+
+	var healthy *httptest.Server
+	healthy = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeVersionsMock(w, r, healthy, "v0.3.5")
+		if r.URL.Path == "/cli/iac/rules/v0.1.5-dev.20220901/bundle.tar.gz" {
+			_, _ = w.Write(bundle)
+		}
+	}))
+	defer healthy.Close()
+
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+
+	client := Client{
+		HTTPClient:                http.DefaultClient,
+		SkipSignatureVerification: true,
+		URL:                       healthy.URL,
+		Mirrors:                   []string{down.URL, outdated.URL},
+		Logger:                    &logger,
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, client.DownloadLatestBundle("v0.2.0", &b))
+	require.Equal(t, bundle, b.Bytes())
+	require.Equal(t, int32(2), requests.Load())
+	require.Contains(t, logs.String(), "rules served by "+healthy.URL)
+
+	version, err := client.GetCompatibleBundleVersion("v0.2.0")
+	require.NoError(t, err)
+	require.Equal(t, "v0.1.5-dev.20220901", version)
+
+	b.Reset()
+	require.NoError(t, client.DownloadPinnedBundle("v0.1.5-dev.20220901", "v0.2.0", &b))
+	require.Equal(t, bundle, b.Bytes())
+}
+
+func TestClient_MirrorsRetry(t *testing.T) {
+	var requests atomic.Int32
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The mirror fails the first two requests.
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		writeVersionsMock(w, r, server, "v0.3.5")
+		if r.URL.Path == "/cli/iac/rules/v0.3.5/bundle.tar.gz" {
+			_, _ = w.Write(bundle)
+		}
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+
+	client := Client{
+		HTTPClient:                server.Client(),
+		SkipSignatureVerification: true,
+		Mirrors:                   []string{server.URL},
+		Backoff:                   time.Second,
+		Sleep:                     func(d time.Duration) { delays = append(delays, d) },
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, client.DownloadLatestBundle("v0.4.1", &b))
+	require.Equal(t, bundle, b.Bytes())
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second}, delays)
+}
+
+func TestClient_MirrorsFailure(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := Client{
+		HTTPClient:                server.Client(),
+		SkipSignatureVerification: true,
+		URL:                       server.URL,
+		Mirrors:                   []string{server.URL + "/mirror"},
+		MaxAttempts:               2,
+		Sleep:                     func(time.Duration) {},
+	}
+
+	var b bytes.Buffer
+	err := client.DownloadLatestBundle("v0.4.1", &b)
+	require.ErrorContains(t, err, server.URL+"/mirror: ")
+	require.Zero(t, b.Len())
+	require.Equal(t, int32(4), requests.Load())
+
+	require.Error(t, (&Client{}).DownloadLatestBundle("v0.4.1", &b))
+}
@@ -2,6 +2,7 @@ package rules
 
 import (
 	"bytes"
+	"crypto/ed25519"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -9,17 +10,31 @@ import (
 	"io"
 	"net/http"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/go-version"
+	"github.com/rs/zerolog"
 )
 
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// Client downloads rule bundles. The bundles are downloaded from the Mirrors,
+// in order, and then from URL. If PublicKeys are configured, the signature of
+// the manifest entry of every bundle is verified with them, unless
+// SkipSignatureVerification is set.
 type Client struct {
-	HTTPClient HTTPClient
-	URL        string
+	HTTPClient                HTTPClient
+	URL                       string
+	Mirrors                   []string
+	PublicKeys                []ed25519.PublicKey
+	SkipSignatureVerification bool
+	MaxAttempts               int
+	Backoff                   time.Duration
+	Sleep                     func(time.Duration)
+	Logger                    *zerolog.Logger
 }
 
 type manifest struct {
@@ -32,25 +47,62 @@ type bundleMetadata struct {
 	Url                    string `json:"url"`
 	MinPolicyEngineVersion string `json:"min_policy_engine_version"`
 	ReleaseDate            string `json:"release_date"`
+	Signature              string `json:"signature"`
 }
 
-func (c *Client) downloadManifest() (manifest, error) {
+// downloadManifest downloads the manifest from the first mirror that serves a
+// manifest accepted by validate.
+func (c *Client) downloadManifest(validate func(manifest) error) (manifest, error) {
+	var result manifest
+
+	err := c.fromMirrors(func(url string) error {
+		latestManifest, err := c.downloadManifestFrom(url)
+		if err != nil {
+			return err
+		}
+
+		if err := validate(latestManifest); err != nil {
+			return fmt.Errorf("validate manifest: %w", err)
+		}
+
+		result = latestManifest
+		return nil
+	})
+
+	return result, err
+}
+
+func (c *Client) downloadManifestFrom(url string) (manifest, error) {
 	var latestManifest manifest
 
-	if err := c.downloadJSON(fmt.Sprintf("%s/versions.json", c.URL), &latestManifest); err != nil {
+	if err := c.downloadJSON(fmt.Sprintf("%s/versions.json", url), &latestManifest); err != nil {
 		return manifest{}, fmt.Errorf("download latestManifest: %v", err)
 	}
 	return latestManifest, nil
 }
 
 func (c *Client) DownloadLatestBundle(currentEngineVersion string, w io.Writer) (e error) {
-	manifest, err := c.downloadManifest()
+	var data bytes.Buffer
+
+	err := c.fromMirrors(func(url string) error {
+		data.Reset()
+
+		manifest, err := c.downloadManifestFrom(url)
+		if err != nil {
+			return fmt.Errorf("download manifest: %v", err)
+		}
+
+		if err := c.downloadBundleForVersion(currentEngineVersion, manifest, &data); err != nil {
+			return fmt.Errorf("download bundle: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("download manifest: %v", err)
+		return err
 	}
 
-	if err := c.downloadBundleForVersion(currentEngineVersion, manifest, w); err != nil {
-		return fmt.Errorf("download bundle: %v", err)
+	if _, err := w.Write(data.Bytes()); err != nil {
+		return fmt.Errorf("write bundle: %v", err)
 	}
 	return nil
 }
@@ -60,24 +112,84 @@ func (c *Client) downloadBundleForVersion(currentEngineVersion string, manifest
 	if err != nil {
 		return fmt.Errorf("determine bundle version: %v", err)
 	}
-	bundle := manifest.Versions[compatibleBundleVersion]
+	return c.downloadBundle(compatibleBundleVersion, manifest.Versions[compatibleBundleVersion], w)
+}
 
-	hash := sha256.New()
+// downloadBundleFromMirrors downloads a version of a bundle from the first
+// mirror that serves it. The bundle is downloaded from its URL by the mirror
+// that URL belongs to, and is located with the manifest of the other mirrors.
+// The bundle must have the checksum of bundle, and is verified with the
+// signature of bundle.
+func (c *Client) downloadBundleFromMirrors(version string, bundle bundleMetadata, w io.Writer) error {
+	var data bytes.Buffer
+
+	err := c.fromMirrors(func(url string) error {
+		data.Reset()
+
+		if strings.HasPrefix(bundle.Url, url+"/") {
+			return c.downloadBundle(version, bundle, &data)
+		}
+
+		manifest, err := c.downloadManifestFrom(url)
+		if err != nil {
+			return fmt.Errorf("download manifest: %v", err)
+		}
+
+		mirrored, ok := manifest.Versions[version]
+		if !ok {
+			return fmt.Errorf("failed to find version %v as key in manifest", version)
+		}
+
+		mirroredBundle := bundle
+		mirroredBundle.Url = mirrored.Url
+
+		return c.downloadBundle(version, mirroredBundle, &data)
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data.Bytes()); err != nil {
+		return fmt.Errorf("write bundle: %v", err)
+	}
+	return nil
+}
 
-	if err := c.download(io.MultiWriter(w, hash), bundle.Url); err != nil {
+// downloadBundle downloads a version of a bundle and verifies its checksum
+// and signature. Nothing is written to w if the verification fails.
+func (c *Client) downloadBundle(version string, bundle bundleMetadata, w io.Writer) error {
+	var data bytes.Buffer
+
+	if err := c.download(&data, bundle.Url); err != nil {
 		return fmt.Errorf("download bundle: %v", err)
 	}
 
-	if checksum := hex.EncodeToString(hash.Sum(nil)); bundle.Checksum != checksum {
-		return fmt.Errorf("invalid checksum: expected %v, got %v", bundle.Checksum, checksum)
+	if err := c.verify(version, bundle, data.Bytes()); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data.Bytes()); err != nil {
+		return fmt.Errorf("write bundle: %v", err)
 	}
 	return nil
 }
 
+// verify verifies the checksum of the content of a version of a bundle, and
+// the signature of its manifest entry.
+func (c *Client) verify(version string, bundle bundleMetadata, data []byte) error {
+	hash := sha256.Sum256(data)
+
+	if checksum := hex.EncodeToString(hash[:]); bundle.Checksum != checksum {
+		return fmt.Errorf("invalid checksum: expected %v, got %v", bundle.Checksum, checksum)
+	}
+
+	return c.verifySignature(version, bundle)
+}
+
 func (c *Client) GetCompatibleBundleVersion(policyEngineVersion string) (string, error) {
-	manifest, err := c.downloadManifest()
+	manifest, err := c.downloadManifest(compatibleWith(policyEngineVersion))
 	if err != nil {
-		return "", fmt.Errorf("download manifest: %v", err)
+		return "", fmt.Errorf("download manifest: %w", err)
 	}
 
 	bundleVersion, err := determineBundleVersion(policyEngineVersion, manifest)
@@ -136,30 +248,46 @@ func isEngineCompatible(currentVersion string, minimumVersion string) bool {
 
 // DownloadPinnedBundle this function can be used if there is only a special need to pin a specific rules bundle version
 func (c *Client) DownloadPinnedBundle(pinnedBundleVersion string, currentEngineVersion string, w io.Writer) (e error) {
-	manifest, err := c.downloadManifest()
+	var data bytes.Buffer
+
+	err := c.fromMirrors(func(url string) error {
+		data.Reset()
+
+		manifest, err := c.downloadManifestFrom(url)
+		if err != nil {
+			return fmt.Errorf("download manifest: %v", err)
+		}
+
+		bundle, err := pinnedBundleMetadata(pinnedBundleVersion, currentEngineVersion, manifest)
+		if err != nil {
+			return err
+		}
+
+		return c.downloadBundle(pinnedBundleVersion, bundle, &data)
+	})
 	if err != nil {
-		return fmt.Errorf("download manifest: %v", err)
+		return err
 	}
-	versionMap, ok := manifest.Versions[pinnedBundleVersion]
-	minEngineVersionForBundle := versionMap.MinPolicyEngineVersion
 
-	if !ok {
-		return fmt.Errorf("failed to find version %v as key in manifest", pinnedBundleVersion)
+	if _, err := w.Write(data.Bytes()); err != nil {
+		return fmt.Errorf("write bundle: %v", err)
 	}
-	if !isEngineCompatible(currentEngineVersion, minEngineVersionForBundle) {
-		return fmt.Errorf("policy-engine version %v is not compatible with min required policy-engine version %v in bundle version %v", currentEngineVersion, minEngineVersionForBundle, pinnedBundleVersion)
+	return nil
+}
+
+func pinnedBundleMetadata(pinnedBundleVersion string, currentEngineVersion string, manifest manifest) (bundleMetadata, error) {
+	versionMap, ok := manifest.Versions[pinnedBundleVersion]
+	if !ok {
+		return bundleMetadata{}, fmt.Errorf("failed to find version %v as key in manifest", pinnedBundleVersion)
 	}
 
-	hash := sha256.New()
+	minEngineVersionForBundle := versionMap.MinPolicyEngineVersion
 
-	if err := c.download(io.MultiWriter(w, hash), versionMap.Url); err != nil {
-		return fmt.Errorf("download bundle: %v", err)
+	if !isEngineCompatible(currentEngineVersion, minEngineVersionForBundle) {
+		return bundleMetadata{}, fmt.Errorf("policy-engine version %v is not compatible with min required policy-engine version %v in bundle version %v", currentEngineVersion, minEngineVersionForBundle, pinnedBundleVersion)
 	}
 
-	if checksum := hex.EncodeToString(hash.Sum(nil)); versionMap.Checksum != checksum {
-		return fmt.Errorf("invalid checksum: expected %v, got %v", versionMap.Checksum, checksum)
-	}
-	return nil
+	return versionMap, nil
 }
 
 func (c *Client) downloadJSON(url string, v any) error {
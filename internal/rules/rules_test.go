@@ -43,8 +43,9 @@ func TestRules_DownloadLatestBundle(t *testing.T) {
 			defer server.Close()
 
 			rulesClient := &Client{
-				HTTPClient: server.Client(),
-				URL:        server.URL,
+				HTTPClient:                server.Client(),
+				SkipSignatureVerification: true,
+				URL:                       server.URL,
 			}
 
 			var b bytes.Buffer
@@ -69,8 +70,9 @@ func TestRules_GetCompatibleBundleVersion(t *testing.T) {
 			defer server.Close()
 
 			rulesClient := &Client{
-				HTTPClient: server.Client(),
-				URL:        server.URL,
+				HTTPClient:                server.Client(),
+				SkipSignatureVerification: true,
+				URL:                       server.URL,
 			}
 
 			bundleVersion, err := rulesClient.GetCompatibleBundleVersion(tt.currentEngineVersion)
@@ -107,8 +109,9 @@ func TestRules_DownloadLatestBundle_Failure(t *testing.T) {
 			defer server.Close()
 
 			rulesClient := &Client{
-				HTTPClient: server.Client(),
-				URL:        server.URL,
+				HTTPClient:                server.Client(),
+				SkipSignatureVerification: true,
+				URL:                       server.URL,
 			}
 
 			var b bytes.Buffer
@@ -133,8 +136,9 @@ func TestRules_DownloadPinnedBundle(t *testing.T) {
 	defer server.Close()
 
 	rulesClient := &Client{
-		HTTPClient: server.Client(),
-		URL:        server.URL,
+		HTTPClient:                server.Client(),
+		SkipSignatureVerification: true,
+		URL:                       server.URL,
 	}
 
 	var b bytes.Buffer
@@ -159,8 +163,9 @@ func TestRules_DownloadPinnedBundle_Regression_IAC_2958(t *testing.T) {
 	defer server.Close()
 
 	rulesClient := &Client{
-		HTTPClient: server.Client(),
-		URL:        server.URL,
+		HTTPClient:                server.Client(),
+		SkipSignatureVerification: true,
+		URL:                       server.URL,
 	}
 
 	var b bytes.Buffer
@@ -176,8 +181,9 @@ func TestRules_DownloadBundle_S3_Failure(t *testing.T) {
 	defer server.Close()
 
 	rulesClient := &Client{
-		HTTPClient: server.Client(),
-		URL:        server.URL,
+		HTTPClient:                server.Client(),
+		SkipSignatureVerification: true,
+		URL:                       server.URL,
 	}
 
 	var b bytes.Buffer
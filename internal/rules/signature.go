@@ -0,0 +1,99 @@
+package rules
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSignature is returned when the signature of a bundle is missing or
+// can't be verified with any of the public keys.
+var ErrInvalidSignature = errors.New("invalid bundle signature")
+
+// ParsePublicKeys parses a comma-separated list of base64-encoded ed25519
+// public keys.
+func ParsePublicKeys(s string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+
+	for _, encoded := range strings.Split(s, ",") {
+		encoded = strings.TrimSpace(encoded)
+		if encoded == "" {
+			continue
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decode public key: %v", err)
+		}
+
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid public key size: %d", len(key))
+		}
+
+		keys = append(keys, ed25519.PublicKey(key))
+	}
+
+	return keys, nil
+}
+
+// signedEntry is the part of the manifest entry of a bundle covered by its
+// signature. The signature binds the content of the bundle, through its
+// checksum, to its version and to the version of the policy engine it
+// requires, so that a mirror can't serve a correctly signed bundle as another
+// version. The URL is not signed, because every mirror serves the bundle from
+// its own URL.
+type signedEntry struct {
+	Version                string `json:"version"`
+	Checksum               string `json:"checksum"`
+	MinPolicyEngineVersion string `json:"min_policy_engine_version"`
+	ReleaseDate            string `json:"release_date"`
+}
+
+// signedPayload returns the data signed by the signature of a bundle: the
+// JSON encoding of its signedEntry.
+func signedPayload(version string, bundle bundleMetadata) []byte {
+	// Encoding a struct of strings can't fail.
+	data, _ := json.Marshal(signedEntry{
+		Version:                version,
+		Checksum:               bundle.Checksum,
+		MinPolicyEngineVersion: bundle.MinPolicyEngineVersion,
+		ReleaseDate:            bundle.ReleaseDate,
+	})
+
+	return data
+}
+
+// verifySignature verifies the base64-encoded ed25519 signature of data. The
+// signature is valid if it can be verified with one of the public keys.
+func verifySignature(keys []ed25519.PublicKey, signature string, data []byte) error {
+	if signature == "" {
+		return fmt.Errorf("%w: the bundle is not signed", ErrInvalidSignature)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: decode signature: %v", ErrInvalidSignature, err)
+	}
+
+	for _, key := range keys {
+		if ed25519.Verify(key, data, decoded) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: the signature doesn't match any public key", ErrInvalidSignature)
+}
+
+// verifySignature verifies the signature of the manifest entry of a version of
+// a bundle. The signature is only verified if public keys are configured and
+// the verification is not skipped.
+func (c *Client) verifySignature(version string, bundle bundleMetadata) error {
+	if c.SkipSignatureVerification || len(c.PublicKeys) == 0 {
+		return nil
+	}
+
+	return verifySignature(c.PublicKeys, bundle.Signature, signedPayload(version, bundle))
+}
@@ -0,0 +1,181 @@
+package rules
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func newSignedBundleServer(t *testing.T, signature string) *httptest.Server {
+	var server *httptest.Server
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/versions.json":
+			_ = json.NewEncoder(w).Encode(manifest{
+				PreferredVersion: "v1.0.0",
+				Versions: map[string]bundleMetadata{
+					"v1.0.0": {
+						Checksum:               bundleChecksum(),
+						Url:                    server.URL + "/v1.0.0/bundle.tar.gz",
+						MinPolicyEngineVersion: "0.1.0",
+						Signature:              signature,
+					},
+				},
+			})
+		case "/v1.0.0/bundle.tar.gz":
+			_, _ = w.Write(bundle)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// signBundle signs the manifest entry of the bundle served by
+// newSignedBundleServer as version.
+func signBundle(key ed25519.PrivateKey, version string) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, signedPayload(version, bundleMetadata{
+		Checksum:               bundleChecksum(),
+		MinPolicyEngineVersion: "0.1.0",
+	})))
+}
+
+func generateKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return publicKey, privateKey
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	first, _ := generateKey(t)
+	second, _ := generateKey(t)
+
+	keys, err := ParsePublicKeys(base64.StdEncoding.EncodeToString(first) + ", " + base64.StdEncoding.EncodeToString(second))
+	require.NoError(t, err)
+	require.Equal(t, []ed25519.PublicKey{first, second}, keys)
+
+	keys, err = ParsePublicKeys("")
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	_, err = ParsePublicKeys("not base64!")
+	require.Error(t, err)
+
+	_, err = ParsePublicKeys(base64.StdEncoding.EncodeToString([]byte("short")))
+	require.Error(t, err)
+}
+
+func TestClient_SignatureVerification(t *testing.T) {
+	publicKey, privateKey := generateKey(t)
+	otherPublicKey, otherPrivateKey := generateKey(t)
+
+	signature := signBundle(privateKey, "v1.0.0")
+	otherSignature := signBundle(otherPrivateKey, "v1.0.0")
+
+	tests := []struct {
+		name      string
+		signature string
+		keys      []ed25519.PublicKey
+		skip      bool
+		valid     bool
+	}{
+		{name: "valid signature", signature: signature, keys: []ed25519.PublicKey{publicKey}, valid: true},
+		{name: "rotated key", signature: otherSignature, keys: []ed25519.PublicKey{publicKey, otherPublicKey}, valid: true},
+		{name: "unknown key", signature: otherSignature, keys: []ed25519.PublicKey{publicKey}},
+		{name: "missing signature", keys: []ed25519.PublicKey{publicKey}},
+		{name: "malformed signature", signature: "not base64!", keys: []ed25519.PublicKey{publicKey}},
+		{name: "signature of another version", signature: signBundle(privateKey, "v0.9.0"), keys: []ed25519.PublicKey{publicKey}},
+		{name: "signature of the content only", signature: base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, bundle)), keys: []ed25519.PublicKey{publicKey}},
+		{name: "verification skipped", signature: otherSignature, keys: []ed25519.PublicKey{publicKey}, skip: true, valid: true},
+		{name: "no keys", valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSignedBundleServer(t, tt.signature)
+
+			client := Client{
+				HTTPClient:                server.Client(),
+				URL:                       server.URL,
+				PublicKeys:                tt.keys,
+				SkipSignatureVerification: tt.skip,
+			}
+
+			var b bytes.Buffer
+
+			err := client.DownloadLatestBundle("v1.0.0", &b)
+			if tt.valid {
+				require.NoError(t, err)
+				require.Equal(t, bundle, b.Bytes())
+			} else {
+				require.ErrorIs(t, err, ErrInvalidSignature)
+				require.Zero(t, b.Len())
+			}
+
+			b.Reset()
+
+			err = client.DownloadPinnedBundle("v1.0.0", "v1.0.0", &b)
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+func TestCachedClient_InvalidSignature(t *testing.T) {
+	publicKey, privateKey := generateKey(t)
+	_, otherPrivateKey := generateKey(t)
+
+	logger := zerolog.Nop()
+	dir := t.TempDir()
+
+	signed := newSignedBundleServer(t, signBundle(privateKey, "v1.0.0"))
+
+	client := CachedClient{
+		Client: &Client{
+			HTTPClient: signed.Client(),
+			URL:        signed.URL,
+			PublicKeys: []ed25519.PublicKey{publicKey},
+		},
+		Dir:    dir,
+		Logger: &logger,
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, client.DownloadLatestBundle("v1.0.0", &b))
+
+	// The mirror now serves a manifest signed with another key. The last good
+	// bundle must not hide the verification failure.
+	tampered := newSignedBundleServer(t, signBundle(otherPrivateKey, "v1.0.0"))
+
+	client.Client.HTTPClient = tampered.Client()
+	client.Client.URL = tampered.URL
+	require.NoError(t, os.Remove(filepath.Join(dir, "bundles", "v1.0.0.tar.gz")))
+
+	b.Reset()
+	require.ErrorIs(t, client.DownloadLatestBundle("v1.0.0", &b), ErrInvalidSignature)
+	require.Zero(t, b.Len())
+
+	// The bundle is downloaded again once the mirror serves a valid signature.
+	client.Client.HTTPClient = signed.Client()
+	client.Client.URL = signed.URL
+
+	b.Reset()
+	require.NoError(t, client.DownloadLatestBundle("v1.0.0", &b))
+	require.Equal(t, bundle, b.Bytes())
+}
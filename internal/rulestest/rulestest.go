@@ -0,0 +1,376 @@
+// Package rulestest runs custom rules against fixture IaC files, and compares
+// the results with the results expected for every fixture.
+package rulestest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/snyk/policy-engine/pkg/bundle"
+	"github.com/spf13/afero"
+
+	"github.com/snyk/cli-extension-iac/internal/junit"
+	engine "github.com/snyk/cli-extension-iac/internal/policyengine"
+)
+
+// ExpectedSuffix is the suffix of the files containing the expected results.
+// The expected results of the fixture "s3.tf" are stored in
+// "s3.tf.expected.json".
+const ExpectedSuffix = ".expected.json"
+
+const reportName = "Snyk IaC custom rules"
+
+// Expected maps the ID of a rule to the results expected for a fixture.
+type Expected map[string]ExpectedResults
+
+// ExpectedResults lists the resources for which a rule is expected to fail
+// and to pass. If Passing is nil, the passing resources are not checked.
+type ExpectedResults struct {
+	Failing []string `json:"failing"`
+	Passing []string `json:"passing,omitempty"`
+}
+
+// Runner runs the custom rules in Rules against the fixtures in the directory
+// Fixtures.
+type Runner struct {
+	FS       afero.Fs
+	Rules    bundle.Reader
+	Fixtures string
+	Logger   *zerolog.Logger
+}
+
+// Report contains the outcome of every test case.
+type Report struct {
+	Cases []Case `json:"cases"`
+}
+
+// Case is the outcome of a rule tested against a fixture. Diff lists the
+// unexpected results: a line starting with "-" is a result that was expected
+// but not reported, and a line starting with "+" a result that was reported
+// but not expected.
+type Case struct {
+	Fixture string   `json:"fixture"`
+	RuleID  string   `json:"rule_id,omitempty"`
+	Passed  bool     `json:"passed"`
+	Diff    []string `json:"diff,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Failures returns the number of test cases that failed.
+func (r *Report) Failures() int {
+	var n int
+
+	for _, c := range r.Cases {
+		if !c.Passed {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Run evaluates the rules against every fixture that has a file of expected
+// results. Run returns an error if the rules can't be loaded. Errors about a
+// single fixture are reported as failed test cases.
+func (r Runner) Run(ctx context.Context) (*Report, error) {
+	fixtures, err := r.fixtures()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("no fixture with expected results found in %s", r.Fixtures)
+	}
+
+	e := engine.NewEngine(ctx, engine.EngineOptions{
+		CustomRuleBundles: []bundle.Reader{r.Rules},
+		Logger:            r.Logger,
+	})
+
+	if errs := e.InitializationErrors(); len(errs) > 0 {
+		var messages []string
+
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+
+		return nil, fmt.Errorf("load rules: %s", strings.Join(messages, "; "))
+	}
+
+	var report Report
+
+	for _, fixture := range fixtures {
+		report.Cases = append(report.Cases, r.runFixture(ctx, e, fixture)...)
+	}
+
+	return &report, nil
+}
+
+// fixtures returns the paths of the fixtures that have a file of expected
+// results, in lexical order.
+func (r Runner) fixtures() ([]string, error) {
+	var fixtures []string
+
+	err := afero.Walk(r.FS, r.Fixtures, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() && strings.HasSuffix(path, ExpectedSuffix) {
+			fixtures = append(fixtures, strings.TrimSuffix(path, ExpectedSuffix))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read fixtures: %v", err)
+	}
+
+	sort.Strings(fixtures)
+
+	return fixtures, nil
+}
+
+func (r Runner) runFixture(ctx context.Context, e *engine.Engine, fixture string) []Case {
+	name := filepath.ToSlash(fixture)
+	if rel, err := filepath.Rel(r.Fixtures, fixture); err == nil {
+		name = filepath.ToSlash(rel)
+	}
+
+	failed := func(err error) []Case {
+		return []Case{{Fixture: name, Error: err.Error()}}
+	}
+
+	expected, err := r.readExpected(fixture)
+	if err != nil {
+		return failed(err)
+	}
+
+	options := engine.RunOptions{
+		FS:    r.FS,
+		Paths: []string{fixture},
+	}
+
+	loader, errs, _ := e.LoadInput(options)
+	if len(errs) > 0 {
+		return failed(fmt.Errorf("load fixture: %v", errs[0]))
+	}
+
+	inputs := loader.ToStates()
+	if len(inputs) == 0 {
+		return failed(fmt.Errorf("load fixture: no IaC input found"))
+	}
+
+	actual := make(map[string]*actualResults)
+
+	for _, result := range e.Eval(ctx, options, inputs).Results {
+		for _, ruleResults := range result.RuleResults {
+			a := actual[ruleResults.Id]
+			if a == nil {
+				a = &actualResults{}
+				actual[ruleResults.Id] = a
+			}
+
+			for _, ruleResult := range ruleResults.Results {
+				resource := ruleResult.ResourceId
+				if resource == "" {
+					resource = ruleResult.ResourceType
+				}
+
+				if ruleResult.Passed {
+					a.passing = append(a.passing, resource)
+				} else {
+					a.failing = append(a.failing, resource)
+				}
+			}
+		}
+	}
+
+	var ids []string
+
+	for id := range expected {
+		ids = append(ids, id)
+	}
+
+	// Rules that are not in the expected results must not fail.
+	for id, a := range actual {
+		if _, ok := expected[id]; !ok && len(a.failing) > 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+
+	var cases []Case
+
+	for _, id := range ids {
+		a := actual[id]
+		if a == nil {
+			a = &actualResults{}
+		}
+
+		diff := diffResources("failing", expected[id].Failing, a.failing)
+
+		if expected[id].Passing != nil {
+			diff = append(diff, diffResources("passing", expected[id].Passing, a.passing)...)
+		}
+
+		cases = append(cases, Case{
+			Fixture: name,
+			RuleID:  id,
+			Passed:  len(diff) == 0,
+			Diff:    diff,
+		})
+	}
+
+	return cases
+}
+
+func (r Runner) readExpected(fixture string) (Expected, error) {
+	data, err := afero.ReadFile(r.FS, fixture+ExpectedSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("read expected results: %v", err)
+	}
+
+	var expected Expected
+
+	if err := json.Unmarshal(data, &expected); err != nil {
+		return nil, fmt.Errorf("parse expected results: %v", err)
+	}
+
+	return expected, nil
+}
+
+type actualResults struct {
+	failing []string
+	passing []string
+}
+
+// diffResources compares the expected and the actual resources of a kind of
+// result, regardless of their order.
+func diffResources(kind string, expected, actual []string) []string {
+	var diff []string
+
+	remaining := make(map[string]int)
+
+	for _, resource := range actual {
+		remaining[resource]++
+	}
+
+	for _, resource := range sorted(expected) {
+		if remaining[resource] > 0 {
+			remaining[resource]--
+			continue
+		}
+
+		diff = append(diff, fmt.Sprintf("- %s %s", kind, resource))
+	}
+
+	for _, resource := range sorted(actual) {
+		if remaining[resource] > 0 {
+			remaining[resource]--
+			diff = append(diff, fmt.Sprintf("+ %s %s", kind, resource))
+		}
+	}
+
+	return diff
+}
+
+func sorted(values []string) []string {
+	result := append([]string(nil), values...)
+	sort.Strings(result)
+	return result
+}
+
+// Render writes a human-readable summary of the report.
+func Render(w io.Writer, r *Report) error {
+	var b strings.Builder
+
+	for _, c := range r.Cases {
+		status := "PASS"
+		if !c.Passed {
+			status = "FAIL"
+		}
+
+		if c.RuleID != "" {
+			fmt.Fprintf(&b, "%s %s: %s\n", status, c.Fixture, c.RuleID)
+		} else {
+			fmt.Fprintf(&b, "%s %s\n", status, c.Fixture)
+		}
+
+		if c.Error != "" {
+			fmt.Fprintf(&b, "    %s\n", c.Error)
+		}
+
+		for _, line := range c.Diff {
+			fmt.Fprintf(&b, "    %s\n", line)
+		}
+	}
+
+	fmt.Fprintf(&b, "\n%d tests, %d failed\n", len(r.Cases), r.Failures())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// JUnit converts the report to JUnit test suites. Every fixture is a test
+// suite, and every rule tested against the fixture is a test case.
+func JUnit(r *Report) *junit.TestSuites {
+	suites := junit.TestSuites{
+		Name: reportName,
+	}
+
+	index := make(map[string]int)
+
+	for _, c := range r.Cases {
+		i, ok := index[c.Fixture]
+		if !ok {
+			i = len(suites.Suites)
+			index[c.Fixture] = i
+			suites.Suites = append(suites.Suites, junit.TestSuite{Name: c.Fixture})
+		}
+
+		tc := junit.TestCase{
+			Name:      c.RuleID,
+			Classname: c.Fixture,
+		}
+
+		if tc.Name == "" {
+			tc.Name = c.Fixture
+		}
+
+		switch {
+		case c.Error != "":
+			tc.Failure = &junit.Failure{
+				Message: c.Error,
+				Type:    "error",
+			}
+		case !c.Passed:
+			tc.Failure = &junit.Failure{
+				Message: "unexpected rule results",
+				Type:    "assertion",
+				Text:    strings.Join(c.Diff, "\n"),
+			}
+		}
+
+		suite := &suites.Suites[i]
+		suite.Tests++
+		suites.Tests++
+
+		if tc.Failure != nil {
+			suite.Failures++
+			suites.Failures++
+		}
+
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	return &suites
+}
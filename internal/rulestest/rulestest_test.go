@@ -0,0 +1,101 @@
+package rulestest
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/rules"
+)
+
+func TestRunner(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewOsFs()
+
+	customRules, err := rules.ReadLocalBundle(fs, filepath.Join("testdata", "rules"))
+	require.NoError(t, err)
+
+	runner := Runner{
+		FS:       fs,
+		Rules:    customRules,
+		Fixtures: filepath.Join("testdata", "fixtures"),
+		Logger:   &logger,
+	}
+
+	report, err := runner.Run(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, []Case{
+		{
+			Fixture: "regression.tf",
+			RuleID:  "CUSTOM-1",
+			Diff: []string{
+				"+ failing aws_s3_bucket.logs",
+				"- passing aws_s3_bucket.logs",
+			},
+		},
+		{
+			Fixture: "s3.tf",
+			RuleID:  "CUSTOM-1",
+			Passed:  true,
+		},
+	}, report.Cases)
+	require.Equal(t, 1, report.Failures())
+
+	var b bytes.Buffer
+	require.NoError(t, Render(&b, report))
+	require.Equal(t, "FAIL regression.tf: CUSTOM-1\n    + failing aws_s3_bucket.logs\n    - passing aws_s3_bucket.logs\nPASS s3.tf: CUSTOM-1\n\n2 tests, 1 failed\n", b.String())
+}
+
+func TestRunnerFixtureError(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("fixtures", "main.tf"), []byte(`resource "aws_s3_bucket" "b" {}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("fixtures", "main.tf.expected.json"), []byte(`not json`), 0644))
+
+	customRules, err := rules.ReadLocalBundle(afero.NewOsFs(), filepath.Join("testdata", "rules"))
+	require.NoError(t, err)
+
+	report, err := Runner{FS: fs, Rules: customRules, Fixtures: "fixtures", Logger: &logger}.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Cases, 1)
+	require.False(t, report.Cases[0].Passed)
+	require.Contains(t, report.Cases[0].Error, "parse expected results")
+}
+
+func TestRunnerInvalidRules(t *testing.T) {
+	logger := zerolog.Nop()
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("rules", "rule.rego"), []byte("package rules.broken\n\ndeny {"), 0644))
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("fixtures", "main.tf.expected.json"), []byte(`{}`), 0644))
+
+	customRules, err := rules.ReadLocalBundle(fs, "rules")
+	require.NoError(t, err)
+
+	_, err = Runner{FS: fs, Rules: customRules, Fixtures: "fixtures", Logger: &logger}.Run(context.Background())
+	require.ErrorContains(t, err, "load rules")
+}
+
+func TestJUnit(t *testing.T) {
+	suites := JUnit(&Report{Cases: []Case{
+		{Fixture: "s3.tf", RuleID: "CUSTOM-1", Passed: true},
+		{Fixture: "s3.tf", RuleID: "CUSTOM-2", Diff: []string{"+ failing aws_s3_bucket.public"}},
+		{Fixture: "broken.tf", Error: "load fixture: no IaC input found"},
+	}})
+
+	require.Equal(t, 3, suites.Tests)
+	require.Equal(t, 2, suites.Failures)
+	require.Len(t, suites.Suites, 2)
+	require.Equal(t, "s3.tf", suites.Suites[0].Name)
+	require.Equal(t, 1, suites.Suites[0].Failures)
+	require.Equal(t, "+ failing aws_s3_bucket.public", suites.Suites[0].TestCases[1].Failure.Text)
+	require.Equal(t, "broken.tf", suites.Suites[1].TestCases[0].Name)
+	require.Equal(t, "error", suites.Suites[1].TestCases[0].Failure.Type)
+}
@@ -0,0 +1,7 @@
+resource "aws_s3_bucket" "public" {
+  acl = "public-read"
+}
+
+resource "aws_s3_bucket" "private" {
+  acl = "private"
+}
@@ -0,0 +1,16 @@
+package rules.custom_s3_acl
+
+metadata := {
+	"id": "CUSTOM-1",
+	"title": "S3 bucket is public",
+	"severity": "high",
+}
+
+input_type := "tf"
+
+resource_type := "aws_s3_bucket"
+
+deny[info] {
+	input.acl == "public-read"
+	info := {"message": "The bucket is public"}
+}
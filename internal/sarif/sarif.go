@@ -0,0 +1,324 @@
+// Package sarif renders scan results in the SARIF 2.1.0 format.
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+const (
+	schemaURI      = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/main/sarif-2.1/schema/sarif-schema-2.1.0.json"
+	schemaVersion  = "2.1.0"
+	toolName       = "Snyk IaC"
+	informationURI = "https://docs.snyk.io/products/snyk-infrastructure-as-code"
+)
+
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri,omitempty"`
+	Rules          []Rule `json:"rules"`
+}
+
+type Rule struct {
+	ID                   string               `json:"id"`
+	Name                 string               `json:"name,omitempty"`
+	ShortDescription     Message              `json:"shortDescription"`
+	FullDescription      Message              `json:"fullDescription"`
+	Help                 *Message             `json:"help,omitempty"`
+	HelpURI              string               `json:"helpUri,omitempty"`
+	DefaultConfiguration DefaultConfiguration `json:"defaultConfiguration"`
+	Properties           RuleProperties       `json:"properties"`
+}
+
+type DefaultConfiguration struct {
+	Level string `json:"level"`
+}
+
+type RuleProperties struct {
+	Tags             []string `json:"tags,omitempty"`
+	ProblemSeverity  string   `json:"problem.severity,omitempty"`
+	SecuritySeverity string   `json:"security-severity,omitempty"`
+}
+
+type Message struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown,omitempty"`
+}
+
+type Result struct {
+	RuleID           string        `json:"ruleId"`
+	RuleIndex        int           `json:"ruleIndex"`
+	Level            string        `json:"level"`
+	Message          Message       `json:"message"`
+	Locations        []Location    `json:"locations,omitempty"`
+	RelatedLocations []Location    `json:"relatedLocations,omitempty"`
+	Suppressions     []Suppression `json:"suppressions,omitempty"`
+}
+
+type Location struct {
+	ID               *int              `json:"id,omitempty"`
+	PhysicalLocation *PhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []LogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type LogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind,omitempty"`
+}
+
+type Suppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+var levelBySeverity = map[string]string{
+	"low":      "note",
+	"medium":   "warning",
+	"high":     "error",
+	"critical": "error",
+}
+
+// securitySeverityBySeverity maps a severity to the numeric score used by
+// GitHub code scanning to rank security alerts.
+var securitySeverityBySeverity = map[string]string{
+	"low":      "3.9",
+	"medium":   "6.9",
+	"high":     "8.9",
+	"critical": "10.0",
+}
+
+// FromResults converts the scan results to a SARIF log containing a single
+// run. Every rule referenced by a vulnerability is reported once in the tool
+// driver, in order of appearance. Ignored vulnerabilities are reported with an
+// external suppression, justified by the reason of the ignore.
+func FromResults(r *results.Results) *Log {
+	run := Run{
+		Tool: Tool{
+			Driver: Driver{
+				Name:           toolName,
+				InformationURI: informationURI,
+				Rules:          []Rule{},
+			},
+		},
+		Results: []Result{},
+	}
+
+	if r != nil {
+		ruleIndexes := make(map[string]int)
+
+		vulnerabilities := append(append([]results.Vulnerability(nil), r.Vulnerabilities...), r.IgnoredVulnerabilities...)
+
+		for _, v := range vulnerabilities {
+			index, ok := ruleIndexes[v.Rule.ID]
+			if !ok {
+				index = len(run.Tool.Driver.Rules)
+				ruleIndexes[v.Rule.ID] = index
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, ruleFromVulnerability(v))
+			}
+
+			run.Results = append(run.Results, resultFromVulnerability(v, index))
+		}
+	}
+
+	return &Log{
+		Schema:  schemaURI,
+		Version: schemaVersion,
+		Runs:    []Run{run},
+	}
+}
+
+// Write renders the scan results as an indented SARIF document.
+func Write(w io.Writer, r *results.Results) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(FromResults(r)); err != nil {
+		return fmt.Errorf("encode SARIF log: %v", err)
+	}
+
+	return nil
+}
+
+func ruleFromVulnerability(v results.Vulnerability) Rule {
+	var tags []string
+
+	tags = append(tags, "security")
+	tags = append(tags, v.Rule.Labels...)
+	tags = append(tags, v.Rule.Controls...)
+
+	description := v.Rule.Description
+	if description == "" {
+		description = v.Rule.Title
+	}
+
+	rule := Rule{
+		ID:   v.Rule.ID,
+		Name: v.Rule.Title,
+		ShortDescription: Message{
+			Text: v.Rule.Title,
+		},
+		FullDescription: Message{
+			Text: description,
+		},
+		HelpURI: v.Rule.Documentation,
+		DefaultConfiguration: DefaultConfiguration{
+			Level: levelFromSeverity(v.Severity),
+		},
+		Properties: RuleProperties{
+			Tags:             tags,
+			ProblemSeverity:  levelFromSeverity(v.Severity),
+			SecuritySeverity: securitySeverityBySeverity[v.Severity],
+		},
+	}
+
+	if v.Rule.Documentation != "" {
+		rule.Help = &Message{
+			Text:     fmt.Sprintf("The issue is %s. Documentation: %s", v.Rule.Title, v.Rule.Documentation),
+			Markdown: fmt.Sprintf("**%s**\n\n[Documentation](%s)", v.Rule.Title, v.Rule.Documentation),
+		}
+	}
+
+	return rule
+}
+
+func resultFromVulnerability(v results.Vulnerability, ruleIndex int) Result {
+	message := v.Message
+	if message == "" {
+		message = v.Rule.Title
+	}
+
+	result := Result{
+		RuleID:    v.Rule.ID,
+		RuleIndex: ruleIndex,
+		Level:     levelFromSeverity(v.Severity),
+		Message: Message{
+			Text: message,
+		},
+	}
+
+	location := Location{
+		PhysicalLocation: physicalLocation(v.Resource.File, v.Resource.Line, v.Resource.Column),
+	}
+
+	if v.Resource.FormattedPath != "" {
+		location.LogicalLocations = []LogicalLocation{
+			{
+				FullyQualifiedName: v.Resource.FormattedPath,
+				Kind:               "resource",
+			},
+		}
+	}
+
+	// The construct path of a resource loaded from a CDK cloud assembly points
+	// to the code that defines it, instead of its generated logical ID. It
+	// names the same resource, so it has the same kind.
+
+	if v.Resource.ConstructPath != "" {
+		location.LogicalLocations = append(location.LogicalLocations, LogicalLocation{
+			FullyQualifiedName: v.Resource.ConstructPath,
+			Kind:               "resource",
+		})
+	}
+
+	if location.PhysicalLocation != nil || location.LogicalLocations != nil {
+		result.Locations = []Location{location}
+	}
+
+	// The first element of the source location is the location of the
+	// vulnerability itself, which is already reported as the primary location.
+	// The remaining elements are the locations of the containing resources
+	// (e.g. module calls), reported as related locations.
+
+	for i, loc := range v.Resource.SourceLocation {
+		if i == 0 {
+			continue
+		}
+
+		if physical := physicalLocation(loc.File, loc.Line, loc.Column); physical != nil {
+			id := len(result.RelatedLocations)
+			result.RelatedLocations = append(result.RelatedLocations, Location{
+				ID:               &id,
+				PhysicalLocation: physical,
+			})
+		}
+	}
+
+	if v.Ignored {
+		suppression := Suppression{
+			Kind: "external",
+		}
+
+		if v.Ignore != nil {
+			suppression.Justification = v.Ignore.Reason
+		}
+
+		result.Suppressions = []Suppression{suppression}
+	}
+
+	return result
+}
+
+func physicalLocation(file string, line, column int) *PhysicalLocation {
+	if file == "" {
+		return nil
+	}
+
+	location := PhysicalLocation{
+		ArtifactLocation: ArtifactLocation{
+			URI: filepath.ToSlash(file),
+		},
+	}
+
+	if line > 0 {
+		location.Region = &Region{
+			StartLine: line,
+		}
+
+		if column > 0 {
+			location.Region.StartColumn = column
+		}
+	}
+
+	return &location
+}
+
+func levelFromSeverity(severity string) string {
+	if level, ok := levelBySeverity[severity]; ok {
+		return level
+	}
+
+	return "warning"
+}
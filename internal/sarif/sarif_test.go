@@ -0,0 +1,233 @@
+package sarif_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/results"
+	"github.com/snyk/cli-extension-iac/internal/sarif"
+)
+
+func TestFromNilResults(t *testing.T) {
+	log := sarif.FromResults(nil)
+
+	require.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	require.Empty(t, log.Runs[0].Results)
+	require.Empty(t, log.Runs[0].Tool.Driver.Rules)
+}
+
+func TestFromResults(t *testing.T) {
+	input := &results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			{
+				Rule: results.Rule{
+					ID:            "SNYK-CC-TF-1",
+					Title:         "rule-title",
+					Description:   "rule-description",
+					Labels:        []string{"label"},
+					Documentation: "https://security.snyk.io/rules/cloud/SNYK-CC-TF-1",
+				},
+				Message:  "result-message",
+				Severity: "high",
+				Resource: results.Resource{
+					ID:            "aws_s3_bucket.bucket",
+					File:          "modules/bucket/main.tf",
+					Line:          10,
+					Column:        3,
+					FormattedPath: "resource.aws_s3_bucket[bucket].acl",
+					SourceLocation: []results.Location{
+						{File: "modules/bucket/main.tf", Line: 10, Column: 3},
+						{File: "modules/bucket/main.tf", Line: 8, Column: 1},
+						{File: "main.tf", Line: 1, Column: 1},
+					},
+				},
+			},
+			{
+				Rule: results.Rule{
+					ID:       "CUSTOM-1",
+					Title:    "custom-title",
+					Controls: []string{"CIS-AWS_v1.4.0_1.1"},
+				},
+				Severity: "low",
+				Ignored:  true,
+				Resource: results.Resource{
+					File: "main.tf",
+				},
+			},
+			{
+				Rule: results.Rule{
+					ID:    "SNYK-CC-TF-1",
+					Title: "rule-title",
+				},
+				Severity: "high",
+			},
+		},
+	}
+
+	log := sarif.FromResults(input)
+
+	require.Len(t, log.Runs, 1)
+
+	run := log.Runs[0]
+
+	require.Equal(t, []sarif.Rule{
+		{
+			ID:               "SNYK-CC-TF-1",
+			Name:             "rule-title",
+			ShortDescription: sarif.Message{Text: "rule-title"},
+			FullDescription:  sarif.Message{Text: "rule-description"},
+			Help: &sarif.Message{
+				Text:     "The issue is rule-title. Documentation: https://security.snyk.io/rules/cloud/SNYK-CC-TF-1",
+				Markdown: "**rule-title**\n\n[Documentation](https://security.snyk.io/rules/cloud/SNYK-CC-TF-1)",
+			},
+			HelpURI:              "https://security.snyk.io/rules/cloud/SNYK-CC-TF-1",
+			DefaultConfiguration: sarif.DefaultConfiguration{Level: "error"},
+			Properties: sarif.RuleProperties{
+				Tags:             []string{"security", "label"},
+				ProblemSeverity:  "error",
+				SecuritySeverity: "8.9",
+			},
+		},
+		{
+			ID:                   "CUSTOM-1",
+			Name:                 "custom-title",
+			ShortDescription:     sarif.Message{Text: "custom-title"},
+			FullDescription:      sarif.Message{Text: "custom-title"},
+			DefaultConfiguration: sarif.DefaultConfiguration{Level: "note"},
+			Properties: sarif.RuleProperties{
+				Tags:             []string{"security", "CIS-AWS_v1.4.0_1.1"},
+				ProblemSeverity:  "note",
+				SecuritySeverity: "3.9",
+			},
+		},
+	}, run.Tool.Driver.Rules)
+
+	require.Len(t, run.Results, 3)
+
+	first, second := 0, 1
+
+	require.Equal(t, sarif.Result{
+		RuleID:    "SNYK-CC-TF-1",
+		RuleIndex: 0,
+		Level:     "error",
+		Message:   sarif.Message{Text: "result-message"},
+		Locations: []sarif.Location{
+			{
+				PhysicalLocation: &sarif.PhysicalLocation{
+					ArtifactLocation: sarif.ArtifactLocation{URI: "modules/bucket/main.tf"},
+					Region:           &sarif.Region{StartLine: 10, StartColumn: 3},
+				},
+				LogicalLocations: []sarif.LogicalLocation{
+					{FullyQualifiedName: "resource.aws_s3_bucket[bucket].acl", Kind: "resource"},
+				},
+			},
+		},
+		RelatedLocations: []sarif.Location{
+			{
+				ID: &first,
+				PhysicalLocation: &sarif.PhysicalLocation{
+					ArtifactLocation: sarif.ArtifactLocation{URI: "modules/bucket/main.tf"},
+					Region:           &sarif.Region{StartLine: 8, StartColumn: 1},
+				},
+			},
+			{
+				ID: &second,
+				PhysicalLocation: &sarif.PhysicalLocation{
+					ArtifactLocation: sarif.ArtifactLocation{URI: "main.tf"},
+					Region:           &sarif.Region{StartLine: 1, StartColumn: 1},
+				},
+			},
+		},
+	}, run.Results[0])
+
+	require.Equal(t, sarif.Result{
+		RuleID:    "CUSTOM-1",
+		RuleIndex: 1,
+		Level:     "note",
+		Message:   sarif.Message{Text: "custom-title"},
+		Locations: []sarif.Location{
+			{
+				PhysicalLocation: &sarif.PhysicalLocation{
+					ArtifactLocation: sarif.ArtifactLocation{URI: "main.tf"},
+				},
+			},
+		},
+		Suppressions: []sarif.Suppression{
+			{Kind: "external"},
+		},
+	}, run.Results[1])
+
+	require.Equal(t, 0, run.Results[2].RuleIndex)
+	require.Nil(t, run.Results[2].Locations)
+}
+
+func TestFromResultsIgnoredVulnerabilities(t *testing.T) {
+	input := &results.Results{
+		IgnoredVulnerabilities: []results.Vulnerability{
+			{
+				Rule:     results.Rule{ID: "SNYK-CC-TF-1", Title: "rule-title"},
+				Severity: "medium",
+				Ignored:  true,
+				Ignore: &results.Ignore{
+					Source: results.IgnoreSourcePolicy,
+					Reason: "accepted risk",
+				},
+			},
+		},
+	}
+
+	log := sarif.FromResults(input)
+
+	require.Len(t, log.Runs[0].Tool.Driver.Rules, 1)
+	require.Len(t, log.Runs[0].Results, 1)
+	require.Equal(t, []sarif.Suppression{
+		{Kind: "external", Justification: "accepted risk"},
+	}, log.Runs[0].Results[0].Suppressions)
+}
+
+func TestFromResultsConstructPath(t *testing.T) {
+	input := &results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			{
+				Rule:     results.Rule{ID: "SNYK-CC-00001", Title: "rule-title"},
+				Severity: "medium",
+				Resource: results.Resource{
+					File:          "cdk.out/AppStack.template.json",
+					FormattedPath: "Resources.Bucket83908E77",
+					ConstructPath: "AppStack/Bucket/Resource",
+				},
+			},
+		},
+	}
+
+	log := sarif.FromResults(input)
+
+	require.Len(t, log.Runs[0].Results, 1)
+	require.Equal(t, []sarif.Location{
+		{
+			PhysicalLocation: &sarif.PhysicalLocation{
+				ArtifactLocation: sarif.ArtifactLocation{URI: "cdk.out/AppStack.template.json"},
+			},
+			LogicalLocations: []sarif.LogicalLocation{
+				{FullyQualifiedName: "Resources.Bucket83908E77", Kind: "resource"},
+				{FullyQualifiedName: "AppStack/Bucket/Resource", Kind: "resource"},
+			},
+		},
+	}, log.Runs[0].Results[0].Locations)
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, sarif.Write(&buf, &results.Results{}))
+
+	var log map[string]any
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	require.Equal(t, "2.1.0", log["version"])
+	require.Contains(t, log, "$schema")
+}
@@ -0,0 +1,32 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/snyk/cli-extension-iac/internal/registry"
+)
+
+// FileRegistryClient reads the IaC organization settings from a local JSON
+// file, instead of requesting them from the registry. The file has the same
+// format as the response of the registry.
+type FileRegistryClient struct {
+	Path string
+}
+
+func (c *FileRegistryClient) ReadIACOrgSettings(_ context.Context, _ registry.ReadIACOrgSettingsRequest) (*registry.ReadIACOrgSettingsResponse, error) {
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read settings file: %v", err)
+	}
+
+	var response registry.ReadIACOrgSettingsResponse
+
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("decode settings file %s: %v", c.Path, err)
+	}
+
+	return &response, nil
+}
@@ -0,0 +1,65 @@
+package settings_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/settings"
+)
+
+func TestReadSettingsFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+
+	data := `{
+  "customPolicies": {"SNYK-CC-1": {"severity": "low"}},
+  "entitlements": {"infrastructureAsCode": true},
+  "meta": {
+    "org": "org",
+    "orgPublicId": "org-public-id",
+    "ignoreSettings": {"reasonRequired": true}
+  }
+}`
+
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+
+	reader := settings.Reader{
+		RegistryClient: &settings.FileRegistryClient{Path: path},
+	}
+
+	got, err := reader.ReadSettings(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, &settings.Settings{
+		Org:         "org",
+		OrgPublicID: "org-public-id",
+		CustomSeverities: settings.CustomSeverities{
+			"SNYK-CC-1": "low",
+		},
+		Entitlements: settings.Entitlements{
+			InfrastructureAsCode: true,
+		},
+		IgnoreSettings: settings.IgnoreSettings{
+			ReasonRequired: true,
+		},
+	}, got)
+}
+
+func TestReadSettingsFromFileError(t *testing.T) {
+	dir := t.TempDir()
+
+	invalid := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalid, []byte("{"), 0644))
+
+	for _, path := range []string{filepath.Join(dir, "missing.json"), invalid} {
+		reader := settings.Reader{
+			RegistryClient: &settings.FileRegistryClient{Path: path},
+		}
+
+		_, err := reader.ReadSettings(context.Background())
+		require.Error(t, err)
+	}
+}
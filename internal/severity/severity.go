@@ -0,0 +1,170 @@
+// Package severity decides the severity of the vulnerabilities. The severity
+// of a rule can be customized by the organization, and overridden locally by a
+// severity overrides file. The same decisions apply to every output of a scan.
+package severity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/snyk/cli-extension-iac/internal/results"
+)
+
+const (
+	// SourceOrg identifies severities customized by the organization.
+	SourceOrg = "org"
+
+	// SourceLocal identifies severities overridden by the local severity
+	// overrides file.
+	SourceLocal = "local"
+
+	// None removes the vulnerabilities of a rule from the results.
+	None = "none"
+)
+
+// Severities lists the valid severities, from the lowest to the highest.
+var Severities = []string{"low", "medium", "high", "critical"}
+
+// Policy overrides the severity of the rules. Local severities take
+// precedence over the severities of the organization. Both map rule IDs to a
+// severity, or to None.
+type Policy struct {
+	Org   map[string]string
+	Local map[string]string
+}
+
+type override struct {
+	severity string
+	source   string
+}
+
+func (p Policy) overrides() map[string]override {
+	overrides := make(map[string]override)
+
+	for id, severity := range p.Org {
+		overrides[id] = override{severity: strings.ToLower(severity), source: SourceOrg}
+	}
+
+	for id, severity := range p.Local {
+		overrides[id] = override{severity: strings.ToLower(severity), source: SourceLocal}
+	}
+
+	return overrides
+}
+
+// CustomSeverities returns the severity of every overridden rule, for the
+// outputs based on the raw results of the engine.
+func (p Policy) CustomSeverities() map[string]string {
+	overrides := p.overrides()
+
+	severities := make(map[string]string, len(overrides))
+
+	for id, o := range overrides {
+		severities[id] = o.severity
+	}
+
+	return severities
+}
+
+// Apply overrides the severity of the failed and passed vulnerabilities.
+// Overridden vulnerabilities keep their original severity and the source of
+// the override. Vulnerabilities of rules overridden with None are removed.
+func (p Policy) Apply(r *results.Results) *results.Results {
+	if r == nil {
+		return nil
+	}
+
+	overrides := p.overrides()
+
+	if len(overrides) == 0 {
+		return r
+	}
+
+	result := *r
+
+	result.Vulnerabilities = applyOverrides(r.Vulnerabilities, overrides)
+	result.PassedVulnerabilities = applyOverrides(r.PassedVulnerabilities, overrides)
+
+	return &result
+}
+
+func applyOverrides(vulnerabilities []results.Vulnerability, overrides map[string]override) []results.Vulnerability {
+	var kept []results.Vulnerability
+
+	for _, v := range vulnerabilities {
+		o, ok := overrides[v.Rule.ID]
+		if !ok {
+			kept = append(kept, v)
+			continue
+		}
+
+		if o.severity == None {
+			continue
+		}
+
+		if o.severity != v.Severity {
+			v.OriginalSeverity = v.Severity
+			v.Severity = o.severity
+			v.SeveritySource = o.source
+		}
+
+		kept = append(kept, v)
+	}
+
+	return kept
+}
+
+// ParseOverrides parses the content of a severity overrides file, in YAML or
+// JSON. The file maps rule IDs to a severity:
+//
+//	severities:
+//	  SNYK-CC-TF-1: low
+//	  CUSTOM-1: none
+func ParseOverrides(data []byte) (map[string]string, error) {
+	var file struct {
+		Severities map[string]string `yaml:"severities"`
+	}
+
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("unmarshal severity overrides: %v", err)
+	}
+
+	var ids []string
+
+	for id := range file.Severities {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	overrides := make(map[string]string, len(file.Severities))
+
+	for _, id := range ids {
+		severity := strings.ToLower(strings.TrimSpace(file.Severities[id]))
+
+		if !isValid(severity) {
+			return nil, fmt.Errorf("invalid severity %q for rule %s, valid severities are: %s, %s", file.Severities[id], id, strings.Join(Severities, ", "), None)
+		}
+
+		overrides[id] = severity
+	}
+
+	return overrides, nil
+}
+
+func isValid(severity string) bool {
+	if severity == None {
+		return true
+	}
+
+	for _, s := range Severities {
+		if s == severity {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,122 @@
+package severity_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/snyk/cli-extension-iac/internal/results"
+	"github.com/snyk/cli-extension-iac/internal/severity"
+)
+
+func TestApply(t *testing.T) {
+	input := &results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			{Rule: results.Rule{ID: "SNYK-CC-TF-1"}, Severity: "high"},
+			{Rule: results.Rule{ID: "SNYK-CC-TF-2"}, Severity: "medium"},
+			{Rule: results.Rule{ID: "SNYK-CC-TF-3"}, Severity: "low"},
+			{Rule: results.Rule{ID: "SNYK-CC-TF-4"}, Severity: "low"},
+		},
+		PassedVulnerabilities: []results.Vulnerability{
+			{Rule: results.Rule{ID: "SNYK-CC-TF-1"}, Severity: "high"},
+		},
+	}
+
+	policy := severity.Policy{
+		Org: map[string]string{
+			"SNYK-CC-TF-1": "Critical",
+			"SNYK-CC-TF-2": "low",
+			"SNYK-CC-TF-3": "None",
+		},
+		Local: map[string]string{
+			"SNYK-CC-TF-2": "high",
+			"SNYK-CC-TF-4": "low",
+		},
+	}
+
+	expected := &results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			{Rule: results.Rule{ID: "SNYK-CC-TF-1"}, Severity: "critical", OriginalSeverity: "high", SeveritySource: severity.SourceOrg},
+			{Rule: results.Rule{ID: "SNYK-CC-TF-2"}, Severity: "high", OriginalSeverity: "medium", SeveritySource: severity.SourceLocal},
+			{Rule: results.Rule{ID: "SNYK-CC-TF-4"}, Severity: "low"},
+		},
+		PassedVulnerabilities: []results.Vulnerability{
+			{Rule: results.Rule{ID: "SNYK-CC-TF-1"}, Severity: "critical", OriginalSeverity: "high", SeveritySource: severity.SourceOrg},
+		},
+	}
+
+	require.Equal(t, expected, policy.Apply(input))
+
+	// The input is not modified.
+	require.Equal(t, "high", input.Vulnerabilities[0].Severity)
+	require.Len(t, input.Vulnerabilities, 4)
+}
+
+func TestApplyWithoutOverrides(t *testing.T) {
+	input := &results.Results{
+		Vulnerabilities: []results.Vulnerability{
+			{Rule: results.Rule{ID: "SNYK-CC-TF-1"}, Severity: "high"},
+		},
+	}
+
+	require.Same(t, input, severity.Policy{}.Apply(input))
+	require.Nil(t, severity.Policy{}.Apply(nil))
+}
+
+func TestCustomSeverities(t *testing.T) {
+	policy := severity.Policy{
+		Org:   map[string]string{"SNYK-CC-TF-1": "High", "SNYK-CC-TF-2": "low"},
+		Local: map[string]string{"SNYK-CC-TF-2": "none"},
+	}
+
+	require.Equal(t, map[string]string{"SNYK-CC-TF-1": "high", "SNYK-CC-TF-2": "none"}, policy.CustomSeverities())
+}
+
+func TestParseOverrides(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]string
+		err      string
+	}{
+		{
+			name:     "yaml",
+			input:    "severities:\n  SNYK-CC-TF-1: Low\n  CUSTOM-1: none\n",
+			expected: map[string]string{"SNYK-CC-TF-1": "low", "CUSTOM-1": "none"},
+		},
+		{
+			name:     "json",
+			input:    `{"severities": {"SNYK-CC-TF-1": "critical"}}`,
+			expected: map[string]string{"SNYK-CC-TF-1": "critical"},
+		},
+		{
+			name:     "empty",
+			input:    "",
+			expected: map[string]string{},
+		},
+		{
+			name:  "invalid severity",
+			input: "severities:\n  SNYK-CC-TF-1: urgent\n",
+			err:   `invalid severity "urgent" for rule SNYK-CC-TF-1, valid severities are: low, medium, high, critical, none`,
+		},
+		{
+			name:  "invalid file",
+			input: "severities: [",
+			err:   "unmarshal severity overrides",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overrides, err := severity.ParseOverrides([]byte(tt.input))
+
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, overrides)
+		})
+	}
+}
@@ -0,0 +1,298 @@
+// Package terragrunt reads the Terraform source and the inputs of Terragrunt
+// units, so that the Terraform module of a unit can be scanned with the
+// variables Terragrunt would pass to it.
+package terragrunt
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ConfigFile is the name of the configuration of a Terragrunt unit.
+const ConfigFile = "terragrunt.hcl"
+
+// IsTerragrunt returns true if the directory contains a terragrunt.hcl file.
+func IsTerragrunt(fsys afero.Fs, dir string) bool {
+	info, err := fsys.Stat(filepath.Join(dir, ConfigFile))
+	return err == nil && !info.IsDir()
+}
+
+// Config is the Terraform source and the inputs of a Terragrunt unit.
+type Config struct {
+	Dir string
+
+	// Source is the source of the Terraform module, as written in the
+	// configuration. It is empty if the module is the unit directory.
+	Source string
+
+	// ModuleDir is the directory of the Terraform module. It is empty if the
+	// source is remote or can't be evaluated.
+	ModuleDir string
+
+	// Remote is true if the source is not a local path.
+	Remote bool
+
+	// Inputs are the inputs of the unit. The inputs that can't be evaluated
+	// are left out, and reported as warnings.
+	Inputs map[string]cty.Value
+
+	// Files are the configurations read to load the unit.
+	Files []string
+
+	// Warnings are the diagnostics of the expressions that couldn't be
+	// evaluated, one per expression.
+	Warnings []hcl.Diagnostics
+}
+
+// configSchema is the part of a Terragrunt configuration that is loaded. The
+// other blocks and attributes, like include, locals or dependency, are not
+// evaluated, so the expressions referring to them can't be evaluated either.
+var configSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "inputs"},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "terraform"},
+	},
+}
+
+var terraformSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "source"},
+	},
+}
+
+// Load loads the Terraform source and the inputs of the Terragrunt unit in a
+// directory. The expressions are evaluated without variables and functions,
+// since they only exist in Terragrunt.
+func Load(fsys afero.Fs, dir string) (*Config, error) {
+	path := filepath.Join(dir, ConfigFile)
+
+	data, err := afero.ReadFile(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %v", path, err)
+	}
+
+	file, diags := hclparse.NewParser().ParseHCL(data, path)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	content, _, diags := file.Body.PartialContent(configSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	config := Config{
+		Dir:    dir,
+		Inputs: map[string]cty.Value{},
+		Files:  []string{path},
+	}
+
+	sourceEvaluated := true
+
+	for _, block := range content.Blocks {
+		terraform, _, diags := block.Body.PartialContent(terraformSchema)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		if attribute, ok := terraform.Attributes["source"]; ok {
+			config.Source, sourceEvaluated = config.evaluateSource(attribute, data)
+		}
+	}
+
+	if attribute, ok := content.Attributes["inputs"]; ok {
+		config.evaluateInputs(attribute.Expr)
+	}
+
+	switch {
+	case !sourceEvaluated:
+	case config.Source == "":
+		config.ModuleDir = dir
+	case isLocal(config.Source):
+		// A double slash separates the root of the source, which Terragrunt
+		// downloads, from the directory of the module in it.
+		source := strings.Replace(config.Source, "//", "/", 1)
+
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(dir, source)
+		}
+
+		config.ModuleDir = source
+
+		if info, err := fsys.Stat(config.ModuleDir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("terraform source %s is not a directory", config.Source)
+		}
+	default:
+		config.Remote = true
+	}
+
+	return &config, nil
+}
+
+// evaluateSource returns the source of the terraform block, and true if it
+// can be evaluated. A source that can't be evaluated is returned as written.
+func (c *Config) evaluateSource(attribute *hcl.Attribute, data []byte) (string, bool) {
+	value, diags := attribute.Expr.Value(nil)
+
+	if !diags.HasErrors() && (value.IsNull() || !value.IsWhollyKnown() || value.Type() != cty.String) {
+		diags = diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid terraform source",
+			Detail:   "The source of the terraform block must be a string.",
+			Subject:  attribute.Expr.Range().Ptr(),
+		})
+	}
+
+	if diags.HasErrors() {
+		c.Warnings = append(c.Warnings, diags)
+		return string(attribute.Expr.Range().SliceBytes(data)), false
+	}
+
+	return value.AsString(), true
+}
+
+// evaluateInputs evaluates every input on its own, so that an input that
+// can't be evaluated doesn't prevent the others from being set.
+func (c *Config) evaluateInputs(expr hcl.Expression) {
+	object, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		value, diags := expr.Value(nil)
+
+		if !diags.HasErrors() && (value.IsNull() || !value.IsWhollyKnown() || !value.Type().IsObjectType() && !value.Type().IsMapType()) {
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid inputs",
+				Detail:   "The inputs must be an object.",
+				Subject:  expr.Range().Ptr(),
+			})
+		}
+
+		if diags.HasErrors() {
+			c.Warnings = append(c.Warnings, diags)
+			return
+		}
+
+		for name, value := range value.AsValueMap() {
+			c.Inputs[name] = value
+		}
+
+		return
+	}
+
+	for _, item := range object.Items {
+		key, diags := item.KeyExpr.Value(nil)
+		if !diags.HasErrors() && (key.IsNull() || !key.IsKnown() || key.Type() != cty.String) {
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid input name",
+				Detail:   "The name of an input must be a string.",
+				Subject:  item.KeyExpr.Range().Ptr(),
+			})
+		}
+
+		if diags.HasErrors() {
+			c.Warnings = append(c.Warnings, diags)
+			continue
+		}
+
+		value, diags := item.ValueExpr.Value(nil)
+		if diags.HasErrors() {
+			c.Warnings = append(c.Warnings, diags)
+			continue
+		}
+
+		c.Inputs[key.AsString()] = value
+	}
+}
+
+// isLocal returns true if the source of a module is a local path.
+func isLocal(source string) bool {
+	return strings.HasPrefix(source, "./") ||
+		strings.HasPrefix(source, "../") ||
+		filepath.IsAbs(source)
+}
+
+// Cache loads the configurations of the Terragrunt units and keeps them, so
+// that every unit is evaluated once. The zero value is an empty cache. A Cache
+// must only be used with a single file system.
+type Cache struct {
+	loaded map[string]cachedConfig
+}
+
+type cachedConfig struct {
+	config *Config
+	err    error
+}
+
+// Load loads the configuration of the Terragrunt unit in a directory, unless
+// it was already loaded.
+func (c *Cache) Load(fsys afero.Fs, dir string) (*Config, error) {
+	key := filepath.Clean(dir)
+
+	if cached, ok := c.loaded[key]; ok {
+		return cached.config, cached.err
+	}
+
+	config, err := Load(fsys, dir)
+
+	if c.loaded == nil {
+		c.loaded = map[string]cachedConfig{}
+	}
+
+	c.loaded[key] = cachedConfig{config: config, err: err}
+
+	return config, err
+}
+
+// Sources returns the local module directories of the Terragrunt units under
+// a directory. These directories are scanned as part of the units that use
+// them, and shouldn't be scanned on their own. Hidden directories are skipped,
+// and so are the directories more than maxDepth levels below root, unless
+// maxDepth is 0.
+func (c *Cache) Sources(fsys afero.Fs, root string, maxDepth int) map[string]bool {
+	sources := map[string]bool{}
+
+	_ = afero.Walk(fsys, root, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info.IsDir() && path != root {
+			// The cache directories of Terragrunt contain copies of the units.
+			if strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			if rel, err := filepath.Rel(root, path); err == nil && maxDepth > 0 && strings.Count(filepath.ToSlash(rel), "/")+1 > maxDepth {
+				return filepath.SkipDir
+			}
+		}
+
+		if info.IsDir() || info.Name() != ConfigFile {
+			return nil
+		}
+
+		config, err := c.Load(fsys, filepath.Dir(path))
+		if err != nil {
+			return nil
+		}
+
+		if config.ModuleDir != "" && filepath.Clean(config.ModuleDir) != filepath.Clean(config.Dir) {
+			sources[filepath.Clean(config.ModuleDir)] = true
+		}
+
+		return nil
+	})
+
+	return sources
+}
@@ -0,0 +1,186 @@
+package terragrunt_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/snyk/cli-extension-iac/internal/terragrunt"
+)
+
+func newFs(t *testing.T, files map[string]string) afero.Fs {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, filepath.FromSlash(path), []byte(content), 0644))
+	}
+
+	return fs
+}
+
+func TestLoad(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"repo/modules/vpc/main.tf": `variable "name" {}`,
+		"repo/live/prod/vpc/terragrunt.hcl": `
+include "root" {
+  path = find_in_parent_folders()
+}
+
+terraform {
+  source = "../../../modules//vpc"
+}
+
+locals {
+  prefix = "prod"
+}
+
+inputs = {
+  name    = "${local.prefix}-vpc"
+  account = "123456789012"
+  cidr    = "10.${1 + 1}.0.0/16"
+  tags    = { team = "core" }
+  role    = run_cmd("whoami")
+}
+`,
+	})
+
+	config, err := terragrunt.Load(fs, filepath.FromSlash("repo/live/prod/vpc"))
+	require.NoError(t, err)
+
+	require.Equal(t, "../../../modules//vpc", config.Source)
+	require.Equal(t, filepath.FromSlash("repo/modules/vpc"), config.ModuleDir)
+	require.False(t, config.Remote)
+	require.Equal(t, []string{filepath.FromSlash("repo/live/prod/vpc/terragrunt.hcl")}, config.Files)
+
+	require.Equal(t, map[string]cty.Value{
+		"account": cty.StringVal("123456789012"),
+		"cidr":    cty.StringVal("10.2.0.0/16"),
+		"tags": cty.ObjectVal(map[string]cty.Value{
+			"team": cty.StringVal("core"),
+		}),
+	}, config.Inputs)
+
+	// Every input that refers to Terragrunt is reported on its own.
+	require.Len(t, config.Warnings, 2)
+	require.Equal(t, 15, config.Warnings[0][0].Subject.Start.Line)
+	require.Equal(t, 19, config.Warnings[1][0].Subject.Start.Line)
+}
+
+func TestLoadWithoutSource(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"unit/terragrunt.hcl": `inputs = { name = "unit" }`,
+	})
+
+	config, err := terragrunt.Load(fs, "unit")
+	require.NoError(t, err)
+
+	require.Empty(t, config.Source)
+	require.Equal(t, "unit", config.ModuleDir)
+	require.Equal(t, map[string]cty.Value{"name": cty.StringVal("unit")}, config.Inputs)
+	require.Empty(t, config.Warnings)
+}
+
+func TestLoadRemoteSource(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"unit/terragrunt.hcl": `
+terraform {
+  source = "tfr:///terraform-aws-modules/vpc/aws?version=5.0.0"
+}
+
+inputs = {
+  name = local.missing
+  cidr = "10.0.0.0/16"
+}
+`,
+	})
+
+	config, err := terragrunt.Load(fs, "unit")
+	require.NoError(t, err)
+
+	require.Equal(t, "tfr:///terraform-aws-modules/vpc/aws?version=5.0.0", config.Source)
+	require.Empty(t, config.ModuleDir)
+	require.True(t, config.Remote)
+	require.Equal(t, map[string]cty.Value{"cidr": cty.StringVal("10.0.0.0/16")}, config.Inputs)
+	require.Len(t, config.Warnings, 1)
+}
+
+func TestLoadSourceNotEvaluated(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"unit/terragrunt.hcl": `
+terraform {
+  source = "${get_parent_terragrunt_dir()}/modules/vpc"
+}
+
+inputs = local.inputs
+`,
+	})
+
+	config, err := terragrunt.Load(fs, "unit")
+	require.NoError(t, err)
+
+	// The source is kept as written, and the module is not loaded.
+	require.Equal(t, `"${get_parent_terragrunt_dir()}/modules/vpc"`, config.Source)
+	require.Empty(t, config.ModuleDir)
+	require.False(t, config.Remote)
+	require.Empty(t, config.Inputs)
+	require.Len(t, config.Warnings, 2)
+}
+
+func TestLoadErrors(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"invalid/terragrunt.hcl": `inputs = {`,
+		"missing/terragrunt.hcl": `terraform { source = "../modules/missing" }`,
+	})
+
+	for _, dir := range []string{"invalid", "missing", "empty"} {
+		t.Run(dir, func(t *testing.T) {
+			_, err := terragrunt.Load(fs, dir)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestSources(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"repo/terragrunt.hcl":                          `inputs = {}`,
+		"repo/modules/vpc/main.tf":                     `variable "name" {}`,
+		"repo/live/vpc/terragrunt.hcl":                 `terraform { source = "../../modules/vpc" }`,
+		"repo/live/remote/terragrunt.hcl":              `terraform { source = "git::https://example.com/modules.git" }`,
+		"repo/live/.terragrunt-cache/x/terragrunt.hcl": `terraform { source = "../../../modules/vpc" }`,
+	})
+
+	var cache terragrunt.Cache
+
+	require.Equal(t, map[string]bool{
+		filepath.FromSlash("repo/modules/vpc"): true,
+	}, cache.Sources(fs, "repo", 0))
+
+	// The units deeper than the depth are not loaded.
+	require.Empty(t, (&terragrunt.Cache{}).Sources(fs, "repo", 1))
+}
+
+func TestCache(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"unit/terragrunt.hcl": `inputs = { name = "unit" }`,
+	})
+
+	var cache terragrunt.Cache
+
+	first, err := cache.Load(fs, "unit")
+	require.NoError(t, err)
+
+	// The configuration is not evaluated again.
+	require.NoError(t, fs.Remove(filepath.Join("unit", "terragrunt.hcl")))
+
+	second, err := cache.Load(fs, filepath.Join("unit", "."))
+	require.NoError(t, err)
+	require.Same(t, first, second)
+
+	_, err = cache.Load(fs, "missing")
+	require.Error(t, err)
+}
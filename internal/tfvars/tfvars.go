@@ -0,0 +1,195 @@
+// Package tfvars resolves the values of the variables of a Terraform module
+// that are set outside of the module: environment variables, var files and
+// variables set on the command line.
+package tfvars
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// EnvPrefix is the prefix of the environment variables that set the value of
+// a Terraform variable.
+const EnvPrefix = "TF_VAR_"
+
+// IsVarFile returns true if a file has the extension of a var file.
+func IsVarFile(path string) bool {
+	return strings.HasSuffix(path, ".tfvars") || strings.HasSuffix(path, ".tfvars.json")
+}
+
+// Arg is a var file or a variable set on the command line, in the name=value
+// format. Only one of its fields is set.
+type Arg struct {
+	VarFile string
+	Var     string
+}
+
+// AutoFiles returns the var files Terraform loads automatically from the
+// directory of a module, in the order of precedence: terraform.tfvars,
+// terraform.tfvars.json, the *.auto.tfvars files, then the *.auto.tfvars.json
+// files, in the lexical order of their names. This is the order the Policy
+// Engine loads them in when it scans a directory.
+func AutoFiles(fsys afero.Fs, dir string) ([]string, error) {
+	var files []string
+
+	for _, name := range []string{"terraform.tfvars", "terraform.tfvars.json"} {
+		path := filepath.Join(dir, name)
+
+		if info, err := fsys.Stat(path); err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+	}
+
+	for _, glob := range []string{"*.auto.tfvars", "*.auto.tfvars.json"} {
+		matches, err := afero.Glob(fsys, filepath.Join(dir, glob))
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(matches)
+
+		files = append(files, matches...)
+	}
+
+	return files, nil
+}
+
+// Names returns the names of the variables set by var files.
+func Names(fsys afero.Fs, paths []string) (map[string]bool, error) {
+	names := map[string]bool{}
+	parser := hclparse.NewParser()
+
+	for _, path := range paths {
+		data, err := afero.ReadFile(fsys, path)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %v", path, err)
+		}
+
+		var file *hcl.File
+		var diags hcl.Diagnostics
+
+		if strings.HasSuffix(path, ".json") {
+			file, diags = parser.ParseJSON(data, path)
+		} else {
+			file, diags = parser.ParseHCL(data, path)
+		}
+
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		attributes, _ := file.Body.JustAttributes()
+
+		for name := range attributes {
+			names[name] = true
+		}
+	}
+
+	return names, nil
+}
+
+// ParseVar parses a variable set on the command line, in the name=value
+// format.
+func ParseVar(s string) (string, cty.Value, error) {
+	name, value, ok := strings.Cut(s, "=")
+
+	name = strings.TrimSpace(name)
+
+	if !ok || name == "" {
+		return "", cty.NilVal, fmt.Errorf("invalid variable %q, expected the name=value format", s)
+	}
+
+	return name, parseValue(value), nil
+}
+
+// ParseVars parses variables set on the command line. Later values of a
+// variable take precedence.
+func ParseVars(vars []string) (map[string]cty.Value, error) {
+	values := map[string]cty.Value{}
+
+	for _, s := range vars {
+		name, value, err := ParseVar(s)
+		if err != nil {
+			return nil, err
+		}
+
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+// ParseEnv returns the variables set by the TF_VAR_ variables of an
+// environment, in the format of os.Environ.
+func ParseEnv(environ []string) map[string]cty.Value {
+	values := map[string]cty.Value{}
+
+	for _, s := range environ {
+		key, value, ok := strings.Cut(s, "=")
+		if !ok || !strings.HasPrefix(key, EnvPrefix) || key == EnvPrefix {
+			continue
+		}
+
+		values[strings.TrimPrefix(key, EnvPrefix)] = parseValue(value)
+	}
+
+	return values
+}
+
+// parseValue parses the value of a variable set on the command line or in the
+// environment. Terraform reads these values as strings for the variables of a
+// primitive type, and as expressions for the variables of a collection or
+// structural type. Since the types of the variables are not known here, the
+// values that look like a list or an object are parsed as expressions, and
+// the other values are strings, which Terraform converts to the primitive
+// type of the variable.
+func parseValue(s string) cty.Value {
+	trimmed := strings.TrimSpace(s)
+
+	if !strings.HasPrefix(trimmed, "[") && !strings.HasPrefix(trimmed, "{") {
+		return cty.StringVal(s)
+	}
+
+	expr, diags := hclsyntax.ParseExpression([]byte(trimmed), "<value>", hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.StringVal(s)
+	}
+
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.StringVal(s)
+	}
+
+	return value
+}
+
+// Encode returns a JSON var file that sets variables. The variables whose
+// value is null or not known are left out.
+func Encode(values map[string]cty.Value) ([]byte, error) {
+	encoded := map[string]json.RawMessage{}
+
+	for name, value := range values {
+		if value.IsNull() || !value.IsWhollyKnown() {
+			continue
+		}
+
+		data, err := ctyjson.SimpleJSONValue{Value: value}.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("marshal variable %s: %v", name, err)
+		}
+
+		encoded[name] = data
+	}
+
+	return json.Marshal(encoded)
+}
@@ -0,0 +1,109 @@
+package tfvars_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/snyk/cli-extension-iac/internal/tfvars"
+)
+
+func TestIsVarFile(t *testing.T) {
+	require.True(t, tfvars.IsVarFile("prod.tfvars"))
+	require.True(t, tfvars.IsVarFile("prod.tfvars.json"))
+	require.False(t, tfvars.IsVarFile("prod.json"))
+	require.False(t, tfvars.IsVarFile("main.tf"))
+}
+
+func TestAutoFiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	for _, name := range []string{"main.tf", "b.auto.tfvars", "a.auto.tfvars.json", "terraform.tfvars.json", "terraform.tfvars", "prod.tfvars"} {
+		require.NoError(t, afero.WriteFile(fs, filepath.Join("module", name), nil, 0644))
+	}
+
+	files, err := tfvars.AutoFiles(fs, "module")
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		filepath.Join("module", "terraform.tfvars"),
+		filepath.Join("module", "terraform.tfvars.json"),
+		filepath.Join("module", "b.auto.tfvars"),
+		filepath.Join("module", "a.auto.tfvars.json"),
+	}, files)
+}
+
+func TestNames(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "terraform.tfvars", []byte(`name = "module"`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "network.auto.tfvars.json", []byte(`{"cidr": "10.0.0.0/8"}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "invalid.tfvars", []byte(`name =`), 0644))
+
+	names, err := tfvars.Names(fs, []string{"terraform.tfvars", "network.auto.tfvars.json"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"name": true, "cidr": true}, names)
+
+	_, err = tfvars.Names(fs, []string{"invalid.tfvars"})
+	require.Error(t, err)
+}
+
+func TestParseVars(t *testing.T) {
+	values, err := tfvars.ParseVars([]string{
+		"name=first",
+		"name=second",
+		"count=3",
+		"query=a=b",
+		`cidrs=["0.0.0.0/0"]`,
+		`tags={ owner = "platform" }`,
+		"invalid=[",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]cty.Value{
+		"name":  cty.StringVal("second"),
+		"count": cty.StringVal("3"),
+		"query": cty.StringVal("a=b"),
+		"cidrs": cty.TupleVal([]cty.Value{cty.StringVal("0.0.0.0/0")}),
+		"tags": cty.ObjectVal(map[string]cty.Value{
+			"owner": cty.StringVal("platform"),
+		}),
+		"invalid": cty.StringVal("["),
+	}, values)
+
+	for _, v := range []string{"name", "=value"} {
+		_, err := tfvars.ParseVars([]string{v})
+		require.Error(t, err, v)
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	require.Equal(t, map[string]cty.Value{
+		"region": cty.StringVal("eu-west-1"),
+		"ports":  cty.TupleVal([]cty.Value{cty.StringVal("22")}),
+	}, tfvars.ParseEnv([]string{
+		"HOME=/root",
+		"TF_VAR_region=eu-west-1",
+		`TF_VAR_ports=["22"]`,
+		"TF_VAR_=ignored",
+		"TF_LOG=debug",
+	}))
+}
+
+func TestEncode(t *testing.T) {
+	data, err := tfvars.Encode(map[string]cty.Value{
+		"name":    cty.StringVal("unit"),
+		"unknown": cty.DynamicVal,
+		"null":    cty.NullVal(cty.String),
+		"ports":   cty.TupleVal([]cty.Value{cty.NumberIntVal(22), cty.NumberIntVal(443)}),
+	})
+	require.NoError(t, err)
+
+	var values map[string]any
+	require.NoError(t, json.Unmarshal(data, &values))
+	require.Equal(t, map[string]any{"name": "unit", "ports": []any{22.0, 443.0}}, values)
+}
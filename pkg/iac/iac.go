@@ -3,6 +3,8 @@ package iac
 import (
 	"github.com/snyk/go-application-framework/pkg/workflow"
 
+	"github.com/snyk/cli-extension-iac/internal/commands/iacignore"
+	"github.com/snyk/cli-extension-iac/internal/commands/iacrules"
 	"github.com/snyk/cli-extension-iac/internal/commands/iactest"
 )
 
@@ -12,5 +14,15 @@ func Init(e workflow.Engine) error {
 		return err
 	}
 
+	// Register the "iac ignore" command
+	if err := iacignore.RegisterWorkflows(e); err != nil {
+		return err
+	}
+
+	// Register the "iac rules" commands
+	if err := iacrules.RegisterWorkflows(e); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -8,6 +8,8 @@ import (
 	"github.com/snyk/go-application-framework/pkg/workflow"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/snyk/cli-extension-iac/internal/commands/iacignore"
+	"github.com/snyk/cli-extension-iac/internal/commands/iacrules"
 	"github.com/snyk/cli-extension-iac/internal/commands/iactest"
 	"github.com/snyk/cli-extension-iac/pkg/iac"
 )
@@ -23,6 +25,9 @@ func TestInit(t *testing.T) {
 	assert.NoError(t, err)
 
 	assertWorkflowExists(t, e, iactest.WorkflowID)
+	assertWorkflowExists(t, e, iacignore.WorkflowID)
+	assertWorkflowExists(t, e, iacrules.TestWorkflowID)
+	assertWorkflowExists(t, e, iacrules.ListWorkflowID)
 }
 
 func assertWorkflowExists(t *testing.T, e workflow.Engine, id *url.URL) {